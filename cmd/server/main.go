@@ -21,13 +21,19 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/aras-services/aras-auth/config"
+	"github.com/aras-services/aras-auth/internal/condition"
 	httphandler "github.com/aras-services/aras-auth/internal/delivery/http"
+	"github.com/aras-services/aras-auth/internal/domain"
 	authmiddleware "github.com/aras-services/aras-auth/internal/middleware"
 	"github.com/aras-services/aras-auth/internal/provider"
+	"github.com/aras-services/aras-auth/internal/provider/factory"
 	"github.com/aras-services/aras-auth/internal/provider/local"
+	"github.com/aras-services/aras-auth/internal/repository/memory"
+	"github.com/aras-services/aras-auth/internal/repository/notify"
 	"github.com/aras-services/aras-auth/internal/repository/postgres"
 	"github.com/aras-services/aras-auth/internal/service"
 	"github.com/aras-services/aras-auth/internal/usecase"
+	"github.com/aras-services/aras-auth/pkg/password"
 )
 
 // Version information - set during build time via ldflags
@@ -54,7 +60,6 @@ func printVersion() {
 // while maintaining clear separation of concerns across architectural layers.
 func main() {
 
-
 	// Check for version flag before any initialization
 	if len(os.Args) > 1 {
 		for _, arg := range os.Args[1:] {
@@ -106,6 +111,63 @@ func main() {
 	roleRepo := postgres.NewRoleRepository(db)             // Implements domain interfaces
 	permissionRepo := postgres.NewPermissionRepository(db) // Dependency Inversion Principle
 	tokenRepo := postgres.NewTokenRepository(db)           // Depends on abstractions, not concrete types
+	passwordHistoryRepo := postgres.NewPasswordHistoryRepository(db)
+	resetTokenRepo := postgres.NewPasswordResetTokenRepository(db)
+	providerRepo := postgres.NewProviderRepository(db)
+	passwordlessChallengeRepo := postgres.NewPasswordlessChallengeRepository(db)
+	samlAssertionRepo := postgres.NewSAMLAssertionRepository(db)
+	emailChangeRepo := postgres.NewEmailChangeRepository(db)
+	userIdentityRepo := postgres.NewUserIdentityRepository(db)
+	scimTokenRepo := postgres.NewSCIMTokenRepository(db)
+	scimTargetRepo := postgres.NewSCIMTargetRepository(db)
+	scimSyncStateRepo := postgres.NewSCIMSyncStateRepository(db)
+	resourceGrantRepo := postgres.NewResourceGrantRepository(db)
+	denyRuleRepo := postgres.NewDenyRuleRepository(db)
+	relationNamespaceRepo := postgres.NewRelationNamespaceRepository(db)
+	relationTupleRepo := postgres.NewRelationTupleRepository(db)
+
+	// Observer Pattern: user and group writes are reported to outbound SCIM provisioning
+	// whatever the path (management API, registration, JIT provisioning, inbound SCIM)
+	// The sync use case itself reads through the undecorated repositories
+	scimSyncUseCase := usecase.NewSCIMSyncUseCase( // Outbound SCIM provisioning to downstream apps
+		scimTargetRepo,
+		scimSyncStateRepo,
+		userRepo,
+		groupRepo,
+		&http.Client{Timeout: cfg.SCIMSync.Timeout},
+		domain.SCIMSyncPolicy{
+			PollInterval:   cfg.SCIMSync.PollInterval,
+			BatchSize:      cfg.SCIMSync.BatchSize,
+			MaxAttempts:    cfg.SCIMSync.MaxAttempts,
+			RetryBaseDelay: cfg.SCIMSync.RetryBaseDelay,
+			RetryMaxDelay:  cfg.SCIMSync.RetryMaxDelay,
+		},
+	)
+	userRepo = notify.NewUserRepository(userRepo, scimSyncUseCase)
+	groupRepo = notify.NewGroupRepository(groupRepo, scimSyncUseCase)
+	emailChangeRepo = notify.NewEmailChangeRepository(emailChangeRepo, scimSyncUseCase)
+
+	// Brute-force Protection Backends: Strategy pattern over in-memory and PostgreSQL state
+	// In-memory state suits a single node; PostgreSQL shares counters across a cluster
+	var loginAttemptStore domain.LoginAttemptStore
+	switch cfg.Lockout.Backend {
+	case "memory":
+		loginAttemptStore = memory.NewLoginAttemptStore()
+	case "postgres":
+		loginAttemptStore = postgres.NewLoginAttemptRepository(db)
+	default:
+		logger.Fatal("Unknown lockout backend", zap.String("backend", cfg.Lockout.Backend))
+	}
+
+	var rateLimiter domain.RateLimiter
+	switch cfg.RateLimit.Backend {
+	case "memory":
+		rateLimiter = memory.NewRateLimiter()
+	case "postgres":
+		rateLimiter = postgres.NewRateLimitRepository(db)
+	default:
+		logger.Fatal("Unknown rate limit backend", zap.String("backend", cfg.RateLimit.Backend))
+	}
 
 	// PHASE 4: Service Layer Initialization (Cross-cutting Concerns)
 	// JWT Service handles token generation and validation
@@ -117,43 +179,202 @@ func main() {
 		tokenRepo,             // Repository dependency injection
 	)
 
+	// Password Hasher: Strategy pattern over argon2id and bcrypt
+	// The configured algorithm is used for new hashes; existing hashes of any supported
+	// algorithm still verify and are upgraded transparently on the next successful login
+	hasher, err := password.NewHasher(password.Options{
+		Algorithm: password.Algorithm(cfg.Password.Algorithm),
+		Argon2: password.Argon2Params{
+			Memory:      cfg.Password.Argon2Memory,
+			Iterations:  cfg.Password.Argon2Iterations,
+			Parallelism: cfg.Password.Argon2Parallelism,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+		BcryptCost: cfg.Password.BcryptCost,
+		Pepper:     cfg.Password.Pepper,
+	})
+	if err != nil {
+		logger.Fatal("Invalid password hashing configuration", zap.Error(err))
+	}
+	password.SetDefaultHasher(hasher)
+
+	// Grant Conditions: CEL expressions on role assignments, evaluated at permission checks
+	conditionEvaluator, err := condition.NewCELEvaluator()
+	if err != nil {
+		logger.Fatal("Failed to initialize condition evaluator", zap.Error(err))
+	}
+
+	// Password Policy: history and expiry rules shared by the local provider and auth use case
+	passwordPolicy := domain.PasswordPolicy{
+		HistorySize:      cfg.Password.HistorySize,
+		MaxAge:           cfg.Password.MaxAge,
+		ExpiringRoles:    cfg.Password.ExpiryRoles,
+		ResetTokenExpiry: cfg.Password.ResetTokenExpiry,
+	}
+
+	// Lockout Service: per-account failed-login tracking with exponential backoff
+	lockoutService := service.NewLockoutService(loginAttemptStore, domain.LockoutPolicy{
+		FreeAttempts:    cfg.Lockout.FreeAttempts,
+		BaseDelay:       cfg.Lockout.BaseDelay,
+		MaxDelay:        cfg.Lockout.MaxDelay,
+		MaxAttempts:     cfg.Lockout.MaxAttempts,
+		LockoutDuration: cfg.Lockout.Duration,
+		ResetAfter:      cfg.Lockout.ResetAfter,
+	})
+
+	// Email Service: SMTP delivery for password resets and notifications
+	emailSender := service.NewSMTPEmailSender(
+		cfg.SMTP.Host,
+		cfg.SMTP.Port,
+		cfg.SMTP.Username,
+		cfg.SMTP.Password,
+		cfg.SMTP.From,
+	)
+
 	// PHASE 5: Provider Registry Pattern (Plugin Architecture)
 	// Registry Pattern: Manages pluggable authentication providers
 	// Enables Open/Closed Principle - open for extension, closed for modification
 	// Supports multiple identity providers (local, OAuth, LDAP, SAML, etc.)
 	providerRegistry := provider.NewProviderRegistry()
 
-	// Register local authentication provider
-	// Adapter Pattern: Adapts local user repository to provider interface
-	localProvider := local.NewLocalProvider(userRepo)
-	if err := providerRegistry.RegisterProvider(localProvider); err != nil {
-		logger.Fatal("Failed to register local provider", zap.Error(err))
-	}
+	// Factory Pattern: Builds local, LDAP, OIDC and SAML providers from rows of the providers table
+	// Providers are loaded once the provider use case is ready and replaced at runtime on change
+	// Provisioner maps external identities to local users and creates users just in time
+	provisioner := provider.NewProvisioner(userRepo, userIdentityRepo, roleRepo, groupRepo)
+
+	providerFactory := factory.NewProviderFactory(
+		userRepo,
+		groupRepo,
+		passwordHistoryRepo,
+		samlAssertionRepo,
+		provisioner,
+		passwordPolicy,
+		cfg.Server.PublicURL,
+	)
 
 	// PHASE 6: Use Case Layer Initialization (Business Logic Layer)
 	// Use Case Pattern: Encapsulates business logic and orchestrates domain operations
 	// Each use case handles a specific business capability and coordinates between
 	// repositories, services, and external dependencies
-	authUseCase := usecase.NewAuthUseCase(providerRegistry, jwtService, userRepo) // Authentication business logic
-	userUseCase := usecase.NewUserUseCase(userRepo)                               // User management business logic
-	groupUseCase := usecase.NewGroupUseCase(groupRepo)                            // Group management business logic
-	authzUseCase := usecase.NewAuthzUseCase(roleRepo, permissionRepo)             // Authorization business logic
+	authUseCase := usecase.NewAuthUseCase( // Authentication business logic
+		providerRegistry,
+		providerRepo,
+		jwtService,
+		userRepo,
+		roleRepo,
+		lockoutService,
+		resetTokenRepo,
+		emailSender,
+		passwordPolicy,
+		cfg.Server.PublicURL,
+	)
+	passwordlessUseCase := usecase.NewPasswordlessUseCase( // Email link and code sign-in
+		authUseCase,
+		providerRegistry,
+		userRepo,
+		groupRepo,
+		providerRepo,
+		passwordlessChallengeRepo,
+		emailSender,
+		domain.PasswordlessPolicy{
+			Expiry:      cfg.Passwordless.Expiry,
+			MaxAttempts: cfg.Passwordless.MaxAttempts,
+		},
+		cfg.Server.PublicURL,
+	)
+	emailChangeUseCase := usecase.NewEmailChangeUseCase( // Email address change with re-verification
+		providerRegistry,
+		jwtService,
+		userRepo,
+		userIdentityRepo,
+		emailChangeRepo,
+		emailSender,
+		cfg.EmailChange.Expiry,
+		cfg.Server.PublicURL,
+	)
+
+	federationUseCase := usecase.NewFederationUseCase(authUseCase, providerRegistry, jwtService) // Sign-in via external OIDC and SAML providers
+	userUseCase := usecase.NewUserUseCase(userRepo, lockoutService)                              // User management business logic
+	groupUseCase := usecase.NewGroupUseCase(groupRepo)                                           // Group management business logic
+
+	authzUseCase := usecase.NewAuthzUseCase( // Authorization business logic, including resource-scoped grants and deny rules
+		roleRepo,
+		permissionRepo,
+		resourceGrantRepo,
+		denyRuleRepo,
+		userRepo,
+		groupRepo,
+		conditionEvaluator,
+	)
+	relationUseCase := usecase.NewRelationUseCase(relationNamespaceRepo, relationTupleRepo) // Relation-based access control (relation tuples)
+
+	identityUseCase := usecase.NewIdentityUseCase( // Linking and unlinking external identities
+		userIdentityRepo,
+		userRepo,
+		providerRepo,
+		providerRegistry,
+		lockoutService,
+		federationUseCase,
+	)
+
+	providerUseCase := usecase.NewProviderUseCase( // Provider administration and hot reload
+		providerRepo,
+		providerFactory,
+		providerRegistry,
+	)
+
+	scimUseCase := usecase.NewSCIMUseCase( // SCIM 2.0 user and group provisioning
+		userRepo,
+		groupRepo,
+		scimTokenRepo,
+		jwtService,
+		providerRegistry,
+		cfg.Server.PublicURL,
+	)
+
+	// Load identity providers configured in the providers table
+	// A misconfigured provider is logged and skipped so it cannot take down local login
+	if err := providerUseCase.ReloadProviders(context.Background()); err != nil {
+		logger.Fatal("Failed to load identity providers", zap.Error(err))
+	}
+
+	// Adapter Pattern: Adapts local user repository to provider interface
+	// The built-in local provider is registered when the table has no row for it
+	if _, err := providerRepo.GetByName(domain.ProviderTypeLocal); err != nil {
+		localProvider := local.NewLocalProvider(domain.ProviderTypeLocal, true, userRepo, passwordHistoryRepo, passwordPolicy)
+		if err := providerRegistry.RegisterProvider(localProvider); err != nil {
+			logger.Fatal("Failed to register local provider", zap.Error(err))
+		}
+	}
 
 	// PHASE 7: Handler Layer Initialization (Interface Adapters)
 	// Adapter Pattern: HTTP handlers adapt external HTTP requests to use cases
 	// Each handler is responsible for HTTP-specific concerns (parsing, validation, response formatting)
 	// while delegating business logic to use cases
-	authHandler := httphandler.NewAuthHandler(authUseCase)    // Authentication HTTP interface
-	userHandler := httphandler.NewUserHandler(userUseCase)    // User management HTTP interface
-	groupHandler := httphandler.NewGroupHandler(groupUseCase) // Group management HTTP interface
-	authzHandler := httphandler.NewAuthzHandler(authzUseCase) // Authorization HTTP interface
+	authHandler := httphandler.NewAuthHandler(authUseCase, passwordlessUseCase, emailChangeUseCase, federationUseCase) // Authentication HTTP interface
+	userHandler := httphandler.NewUserHandler(userUseCase, emailChangeUseCase, identityUseCase)                        // User management HTTP interface
+	groupHandler := httphandler.NewGroupHandler(groupUseCase)                                                          // Group management HTTP interface
+	authzHandler := httphandler.NewAuthzHandler(authzUseCase)                                                          // Authorization HTTP interface
+	providerHandler := httphandler.NewProviderHandler(providerUseCase)                                                 // Provider administration HTTP interface
+	scimHandler := httphandler.NewSCIMHandler(scimUseCase)                                                             // SCIM 2.0 provisioning interface
+	scimTargetHandler := httphandler.NewSCIMTargetHandler(scimSyncUseCase)                                             // Outbound SCIM target administration
+	relationHandler := httphandler.NewRelationHandler(relationUseCase)                                                 // Relation tuple check, expand and lookup interface
 
 	// PHASE 8: Middleware Initialization (Cross-cutting Concerns)
 	// Middleware Pattern: Chain of Responsibility for cross-cutting concerns
 	// Each middleware wraps handlers with additional behavior (auth, logging, CORS, etc.)
-	authMiddleware := authmiddleware.NewAuthMiddleware(jwtService)     // JWT token validation
-	rbacMiddleware := authmiddleware.NewRBACMiddleware(permissionRepo) // Role-based access control
-	corsMiddleware := authmiddleware.NewCORSMiddleware()               // Cross-origin resource sharing
+	authMiddleware := authmiddleware.NewAuthMiddleware(jwtService)                         // JWT token validation
+	rbacMiddleware := authmiddleware.NewRBACMiddleware(permissionRepo, conditionEvaluator) // Role-based access control
+	corsMiddleware := authmiddleware.NewCORSMiddleware()                                   // Cross-origin resource sharing
+	rateLimitMiddleware := authmiddleware.NewRateLimitMiddleware(rateLimiter)
+	scimAuthMiddleware := authmiddleware.NewSCIMAuthMiddleware(scimUseCase) // SCIM client bearer tokens
+
+	trustedProxies, err := cfg.GetTrustedProxies()
+	if err != nil {
+		logger.Fatal("Failed to configure trusted proxies", zap.Error(err))
+	}
+	realIPMiddleware := authmiddleware.NewRealIPMiddleware(trustedProxies) // Client IP from trusted proxy headers only
 
 	// PHASE 9: Router Configuration and Middleware Chain Setup
 	// Router Pattern: Hierarchical route organization with middleware scoping
@@ -163,11 +384,11 @@ func main() {
 	// Middleware Chain: Chain of Responsibility pattern
 	// Middleware is applied in order - each wraps the next handler in the chain
 	// This enables composable cross-cutting concerns with clear separation
+	r.Use(realIPMiddleware.Handler)             // Real IP extraction (behind trusted proxies), before logging
 	r.Use(middleware.Logger)                    // Request logging middleware
 	r.Use(middleware.Recoverer)                 // Panic recovery middleware
 	r.Use(corsMiddleware)                       // CORS handling middleware
 	r.Use(middleware.RequestID)                 // Request ID generation for tracing
-	r.Use(middleware.RealIP)                    // Real IP extraction (behind proxies)
 	r.Use(middleware.Timeout(60 * time.Second)) // Request timeout protection
 
 	// Health Check Endpoint Pattern
@@ -184,7 +405,31 @@ func main() {
 	r.Route("/api/v1", func(r chi.Router) {
 		// Public Routes: No authentication required
 		// Authentication endpoints (login, register, password reset) are publicly accessible
-		authHandler.RegisterRoutes(r)
+		// Credential endpoints are rate limited per client IP and per submitted email
+		// Change-password additionally accepts tokens restricted to rotating an expired password
+		authHandler.RegisterRoutes(r, httphandler.AuthRouteMiddleware{
+			RequireAuth: authMiddleware.AllowPasswordChange,
+			Login: rateLimitMiddleware.Limit("login", authmiddleware.RateLimitRule{
+				PerIP:    cfg.RateLimit.LoginPerIP,
+				PerEmail: cfg.RateLimit.LoginPerEmail,
+				Window:   cfg.RateLimit.Window,
+			}),
+			Register: rateLimitMiddleware.Limit("register", authmiddleware.RateLimitRule{
+				PerIP:    cfg.RateLimit.RegisterPerIP,
+				PerEmail: cfg.RateLimit.RegisterPerEmail,
+				Window:   cfg.RateLimit.Window,
+			}),
+			ForgotPassword: rateLimitMiddleware.Limit("forgot-password", authmiddleware.RateLimitRule{
+				PerIP:    cfg.RateLimit.ForgotPasswordPerIP,
+				PerEmail: cfg.RateLimit.ForgotPasswordPerEmail,
+				Window:   cfg.RateLimit.Window,
+			}),
+			Passwordless: rateLimitMiddleware.Limit("passwordless", authmiddleware.RateLimitRule{
+				PerIP:    cfg.RateLimit.PasswordlessPerIP,
+				PerEmail: cfg.RateLimit.PasswordlessPerEmail,
+				Window:   cfg.RateLimit.Window,
+			}),
+		})
 
 		// Protected Routes: Require authentication
 		// Route Group Pattern: Scoped middleware application
@@ -194,6 +439,25 @@ func main() {
 			// User Management Routes: Authenticated users can manage their own data
 			userHandler.RegisterRoutes(r)
 
+			// Account Administration Routes: lockout inspection and unlock
+			r.Group(func(r chi.Router) {
+				r.Use(rbacMiddleware.RequirePermission("users", "update")) // RBAC middleware for account administration
+				userHandler.RegisterAdminRoutes(r)
+			})
+
+			// Provider Administration Routes: read access to list, update access to modify
+			r.Group(func(r chi.Router) {
+				r.Use(rbacMiddleware.RequirePermission("providers", "read"))
+				providerHandler.RegisterRoutes(r, rbacMiddleware.RequirePermission("providers", "update"))
+			})
+
+			// SCIM Administration Routes: tokens for inbound clients and outbound targets
+			r.Group(func(r chi.Router) {
+				r.Use(rbacMiddleware.RequirePermission("scim", "update"))
+				scimHandler.RegisterAdminRoutes(r)
+				scimTargetHandler.RegisterRoutes(r)
+			})
+
 			// Group Management Routes: Require specific permissions
 			// Nested Route Groups: Fine-grained permission control
 			r.Group(func(r chi.Router) {
@@ -202,14 +466,25 @@ func main() {
 			})
 
 			// Authorization Routes: Require admin-level permissions
-			// Role and permission management requires elevated privileges
+			// Role and permission management requires elevated privileges; changes to role
+			// inheritance, resource grants and deny rules additionally require update access
 			r.Group(func(r chi.Router) {
 				r.Use(rbacMiddleware.RequirePermission("roles", "read")) // RBAC middleware for role operations
-				authzHandler.RegisterRoutes(r)
+				authzHandler.RegisterRoutes(r, rbacMiddleware.RequirePermission("roles", "update"))
+			})
+
+			// Relation Routes: read access to query relation tuples, update access to write them
+			r.Group(func(r chi.Router) {
+				r.Use(rbacMiddleware.RequirePermission("relations", "read"))
+				relationHandler.RegisterRoutes(r, rbacMiddleware.RequirePermission("relations", "update"))
 			})
 		})
 	})
 
+	// SCIM 2.0 Routes: provisioning by identity providers such as Okta and Entra ID
+	// Mounted outside /api/v1 and authenticated with SCIM tokens rather than user JWTs
+	scimHandler.RegisterRoutes(r, scimAuthMiddleware.RequireToken)
+
 	// PHASE 11: Server Initialization and Startup
 	// HTTP Server Configuration: Using Go's standard http.Server with custom handler
 	// Server address is constructed using the config's encapsulated helper method
@@ -218,12 +493,93 @@ func main() {
 		Handler: r,                   // Chi router as the main handler
 	}
 
+	// TLS Configuration: HTTPS with optional client certificates for /auth/cert (mTLS)
+	tlsConfig, err := cfg.GetTLSConfig()
+	if err != nil {
+		logger.Fatal("Failed to configure TLS", zap.Error(err))
+	}
+	server.TLSConfig = tlsConfig
+
+	// Background Maintenance: periodically purge expired lockout, rate limit, challenge, SAML replay and relation tuple history state
+	maintenanceCtx, stopMaintenance := context.WithCancel(context.Background())
+	defer stopMaintenance()
+	go func() {
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-maintenanceCtx.Done():
+				return
+			case <-ticker.C:
+				if err := loginAttemptStore.PurgeExpired(cfg.Lockout.ResetAfter); err != nil {
+					logger.Warn("Failed to purge login attempts", zap.Error(err))
+				}
+				if err := rateLimiter.PurgeExpired(); err != nil {
+					logger.Warn("Failed to purge rate limits", zap.Error(err))
+				}
+				if err := passwordlessChallengeRepo.DeleteExpired(); err != nil {
+					logger.Warn("Failed to purge passwordless challenges", zap.Error(err))
+				}
+				if err := samlAssertionRepo.DeleteExpired(); err != nil {
+					logger.Warn("Failed to purge consumed SAML assertions", zap.Error(err))
+				}
+				if cfg.Relations.SnapshotRetention > 0 {
+					if err := relationTupleRepo.PurgeHistory(cfg.Relations.SnapshotRetention); err != nil {
+						logger.Warn("Failed to purge relation tuple history", zap.Error(err))
+					}
+				}
+			}
+		}
+	}()
+
+	// Provider Hot Reload: apply provider changes made on other instances or directly in SQL
+	if cfg.Providers.ReloadInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.Providers.ReloadInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-maintenanceCtx.Done():
+					return
+				case <-ticker.C:
+					if err := providerUseCase.ReloadProviders(maintenanceCtx); err != nil {
+						logger.Warn("Failed to reload identity providers", zap.Error(err))
+					}
+				}
+			}
+		}()
+	}
+
+	// Outbound SCIM: deliver queued user and group changes and periodically reconcile targets
+	go scimSyncUseCase.Run(maintenanceCtx)
+	if cfg.SCIMSync.ReconcileInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(cfg.SCIMSync.ReconcileInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-maintenanceCtx.Done():
+					return
+				case <-ticker.C:
+					if err := scimSyncUseCase.ReconcileAll(maintenanceCtx); err != nil {
+						logger.Warn("Failed to reconcile SCIM targets", zap.Error(err))
+					}
+				}
+			}
+		}()
+	}
+
 	// Concurrent Server Startup Pattern
 	// Start server in a goroutine to allow main thread to handle shutdown signals
 	// This enables graceful shutdown without blocking the startup process
 	go func() {
-		logger.Info("Starting server", zap.String("addr", cfg.GetServerAddr()))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Info("Starting server", zap.String("addr", cfg.GetServerAddr()), zap.Bool("tls", tlsConfig != nil))
+		serve := server.ListenAndServe
+		if tlsConfig != nil {
+			// The certificate is already loaded into server.TLSConfig
+			serve = func() error { return server.ListenAndServeTLS("", "") }
+		}
+		if err := serve(); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
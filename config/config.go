@@ -5,7 +5,12 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"net/netip"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v6"
@@ -16,19 +21,33 @@ import (
 // improved maintainability. The env tags enable automatic mapping from environment variables
 // to Go structs, reducing boilerplate code and providing type safety.
 type Config struct {
-	Server   ServerConfig   `envPrefix:"SERVER_"`
-	Database DatabaseConfig `envPrefix:"DB_"`
-	JWT      JWTConfig      `envPrefix:"JWT_"`
-	SMTP     SMTPConfig     `envPrefix:"SMTP_"`
-	Admin    AdminConfig    `envPrefix:"ADMIN_"`
+	Server       ServerConfig       `envPrefix:"SERVER_"`
+	Database     DatabaseConfig     `envPrefix:"DB_"`
+	JWT          JWTConfig          `envPrefix:"JWT_"`
+	SMTP         SMTPConfig         `envPrefix:"SMTP_"`
+	Admin        AdminConfig        `envPrefix:"ADMIN_"`
+	Password     PasswordConfig     `envPrefix:"PASSWORD_"`
+	Lockout      LockoutConfig      `envPrefix:"LOCKOUT_"`
+	RateLimit    RateLimitConfig    `envPrefix:"RATE_LIMIT_"`
+	Passwordless PasswordlessConfig `envPrefix:"PASSWORDLESS_"`
+	EmailChange  EmailChangeConfig  `envPrefix:"EMAIL_CHANGE_"`
+	Providers    ProvidersConfig    `envPrefix:"PROVIDERS_"`
+	SCIMSync     SCIMSyncConfig     `envPrefix:"SCIM_SYNC_"`
+	Relations    RelationsConfig    `envPrefix:"RELATIONS_"`
+	TLS          TLSConfig          `envPrefix:"TLS_"`
 }
 
 // ServerConfig encapsulates HTTP server configuration following the Single Responsibility Principle.
 // Each field uses env tags for declarative data binding, enabling automatic
 // unmarshaling from environment variables without manual parsing.
 type ServerConfig struct {
-	Host string `env:"HOST" envDefault:"0.0.0.0"` // Server bind address (default: "0.0.0.0")
-	Port int    `env:"PORT" envDefault:"7600"`    // Server port number (default: 7600)
+	Host      string `env:"HOST" envDefault:"0.0.0.0"`                     // Server bind address (default: "0.0.0.0")
+	Port      int    `env:"PORT" envDefault:"7600"`                        // Server port number (default: 7600)
+	PublicURL string `env:"PUBLIC_URL" envDefault:"http://localhost:7600"` // Base URL used in links sent to users
+	// TrustedProxies lists the reverse proxies (CIDR ranges or addresses) whose
+	// X-Forwarded-For and X-Real-IP headers identify the client. Headers from other peers
+	// are ignored.
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 }
 
 // DatabaseConfig contains PostgreSQL connection parameters using strong typing for
@@ -69,6 +88,107 @@ type AdminConfig struct {
 	Password string `env:"PASSWORD" envDefault:"admin123"`             // Default admin password
 }
 
+// PasswordConfig controls how passwords are hashed and which history and expiry policies
+// apply. Hashes created with a different algorithm or older parameters keep working and
+// are transparently upgraded on login. The pepper is a server-side secret applied to
+// argon2id hashes only; losing or changing it invalidates every peppered hash, so it
+// must be managed like JWT_SECRET_KEY.
+type PasswordConfig struct {
+	Algorithm         string        `env:"ALGORITHM" envDefault:"argon2id"`                  // Hash algorithm: "argon2id" or "bcrypt"
+	Argon2Memory      uint32        `env:"ARGON2_MEMORY" envDefault:"65536"`                 // argon2id memory in KiB (default: 64 MiB)
+	Argon2Iterations  uint32        `env:"ARGON2_ITERATIONS" envDefault:"3"`                 // argon2id time cost
+	Argon2Parallelism uint8         `env:"ARGON2_PARALLELISM" envDefault:"2"`                // argon2id lanes
+	BcryptCost        int           `env:"BCRYPT_COST" envDefault:"12"`                      // bcrypt cost when Algorithm is "bcrypt"
+	Pepper            string        `env:"PEPPER" envDefault:""`                             // Optional server-side pepper (argon2id only)
+	HistorySize       int           `env:"HISTORY_SIZE" envDefault:"5"`                      // Number of previous passwords that cannot be reused (0 disables)
+	MaxAge            time.Duration `env:"MAX_AGE" envDefault:"2160h"`                       // Password lifetime before rotation is required (0 disables)
+	ExpiryRoles       []string      `env:"EXPIRY_ROLES" envSeparator:"," envDefault:"admin"` // Roles whose members are subject to MaxAge (empty: all users)
+	ResetTokenExpiry  time.Duration `env:"RESET_TOKEN_EXPIRY" envDefault:"1h"`               // Lifetime of password reset links
+}
+
+// LockoutConfig controls failed-login tracking per account. After FreeAttempts consecutive
+// failures each further attempt is delayed with exponential backoff, and reaching MaxAttempts
+// locks the account for Duration. The "memory" backend suits a single node; use "postgres"
+// when several instances must share state.
+type LockoutConfig struct {
+	Backend      string        `env:"BACKEND" envDefault:"memory"`  // State backend: "memory" or "postgres"
+	FreeAttempts int           `env:"FREE_ATTEMPTS" envDefault:"3"` // Failures allowed before backoff starts
+	BaseDelay    time.Duration `env:"BASE_DELAY" envDefault:"1s"`   // First backoff delay, doubled per failure
+	MaxDelay     time.Duration `env:"MAX_DELAY" envDefault:"5m"`    // Upper bound for backoff delays
+	MaxAttempts  int           `env:"MAX_ATTEMPTS" envDefault:"10"` // Failures that trigger a lockout (0 disables)
+	Duration     time.Duration `env:"DURATION" envDefault:"30m"`    // Lockout duration once MaxAttempts is reached
+	ResetAfter   time.Duration `env:"RESET_AFTER" envDefault:"24h"` // Idle time after which failures are forgotten
+}
+
+// RateLimitConfig defines per-IP and per-email request limits for the public authentication
+// endpoints. Limits are counted in fixed windows of Window length; 0 disables a limit.
+type RateLimitConfig struct {
+	Backend                string        `env:"BACKEND" envDefault:"memory"`              // State backend: "memory" or "postgres"
+	Window                 time.Duration `env:"WINDOW" envDefault:"15m"`                  // Counting window
+	LoginPerIP             int           `env:"LOGIN_PER_IP" envDefault:"100"`            // Login requests per IP
+	LoginPerEmail          int           `env:"LOGIN_PER_EMAIL" envDefault:"20"`          // Login requests per email
+	RegisterPerIP          int           `env:"REGISTER_PER_IP" envDefault:"10"`          // Registrations per IP
+	RegisterPerEmail       int           `env:"REGISTER_PER_EMAIL" envDefault:"3"`        // Registrations per email
+	ForgotPasswordPerIP    int           `env:"FORGOT_PASSWORD_PER_IP" envDefault:"10"`   // Reset requests per IP
+	ForgotPasswordPerEmail int           `env:"FORGOT_PASSWORD_PER_EMAIL" envDefault:"3"` // Reset requests per email
+	PasswordlessPerIP      int           `env:"PASSWORDLESS_PER_IP" envDefault:"10"`      // Passwordless sign-in requests per IP
+	PasswordlessPerEmail   int           `env:"PASSWORDLESS_PER_EMAIL" envDefault:"5"`    // Passwordless sign-in requests per email
+}
+
+// PasswordlessConfig controls emailed sign-in links and one-time codes. Passwordless
+// sign-in is enabled per provider or per group by administrators, not globally.
+type PasswordlessConfig struct {
+	Expiry      time.Duration `env:"EXPIRY" envDefault:"10m"`     // Lifetime of a sign-in link and code
+	MaxAttempts int           `env:"MAX_ATTEMPTS" envDefault:"5"` // Code guesses allowed per challenge
+}
+
+// EmailChangeConfig controls the email address change flow. The change is applied only
+// after the new address confirms it; the old address can cancel it until then.
+type EmailChangeConfig struct {
+	Expiry time.Duration `env:"EXPIRY" envDefault:"24h"` // Lifetime of confirmation and cancel links
+}
+
+// ProvidersConfig controls how identity providers are loaded from the providers table.
+// Changes made through the admin API apply immediately on the instance that served them;
+// the periodic reload propagates them to other instances and picks up direct SQL edits.
+type ProvidersConfig struct {
+	ReloadInterval time.Duration `env:"RELOAD_INTERVAL" envDefault:"1m"` // 0 disables periodic reload
+}
+
+// RelationsConfig controls the history kept by the relation tuple store. Deleted tuples
+// and revisions older than SnapshotRetention are purged, after which consistency tokens
+// from before then can no longer be evaluated as exact snapshots.
+type RelationsConfig struct {
+	SnapshotRetention time.Duration `env:"SNAPSHOT_RETENTION" envDefault:"168h"` // 0 keeps the whole history
+}
+
+// SCIMSyncConfig controls delivery of user and group changes to outbound SCIM targets.
+// Targets themselves are managed through the admin API. Failed deliveries are retried
+// with exponential backoff until MaxAttempts, after which they wait for the next
+// reconciliation or a manual retry.
+type SCIMSyncConfig struct {
+	PollInterval      time.Duration `env:"POLL_INTERVAL" envDefault:"30s"`      // How often due retries are delivered
+	BatchSize         int           `env:"BATCH_SIZE" envDefault:"50"`          // Changes delivered per round
+	MaxAttempts       int           `env:"MAX_ATTEMPTS" envDefault:"8"`         // Deliveries before a change is marked failed
+	RetryBaseDelay    time.Duration `env:"RETRY_BASE_DELAY" envDefault:"30s"`   // First retry delay, doubled per failure
+	RetryMaxDelay     time.Duration `env:"RETRY_MAX_DELAY" envDefault:"1h"`     // Upper bound for retry delays
+	Timeout           time.Duration `env:"TIMEOUT" envDefault:"10s"`            // Timeout of each request to a target
+	ReconcileInterval time.Duration `env:"RECONCILE_INTERVAL" envDefault:"24h"` // Full reconciliation of every target (0 disables)
+}
+
+// TLSConfig enables HTTPS and mutual TLS. Without CertFile the server listens on plain
+// HTTP (e.g. behind a TLS-terminating proxy) and client certificates are unavailable.
+// ClientAuth is one of none, request (accept any certificate; mtls providers must then
+// verify it with their ca_bundle), verify_if_given (verify certificates against
+// ClientCAFile but allow connections without one) or require; it defaults to
+// verify_if_given when ClientCAFile is set and none otherwise.
+type TLSConfig struct {
+	CertFile     string `env:"CERT_FILE" envDefault:""`      // Server certificate (PEM); enables HTTPS
+	KeyFile      string `env:"KEY_FILE" envDefault:""`       // Server private key (PEM)
+	ClientCAFile string `env:"CLIENT_CA_FILE" envDefault:""` // CA bundle (PEM) for verifying client certificates
+	ClientAuth   string `env:"CLIENT_AUTH" envDefault:""`    // Client certificate policy (default: verify_if_given with a CA file, else none)
+}
+
 // Load implements the Configuration Management Pattern with support for environment variables only.
 // It follows the 12-Factor App methodology by reading all configuration from environment variables
 // with sensible defaults. This approach provides maximum flexibility across different deployment
@@ -123,3 +243,88 @@ func (c *Config) GetDSN() string {
 func (c *Config) GetServerAddr() string {
 	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port)
 }
+
+// GetTrustedProxies parses SERVER_TRUSTED_PROXIES. A bare address is trusted as a
+// single-host range.
+func (c *Config) GetTrustedProxies() ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range c.Server.TrustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SERVER_TRUSTED_PROXIES entry %q", entry)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SERVER_TRUSTED_PROXIES entry %q", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// GetTLSConfig builds the server TLS configuration, or returns nil when HTTPS is not
+// configured. Client certificates are requested unless ClientAuth is "none"; connections
+// without one still succeed unless it is "require", so password logins keep working.
+func (c *Config) GetTLSConfig() (*tls.Config, error) {
+	if c.TLS.CertFile == "" {
+		if c.TLS.ClientCAFile != "" {
+			return nil, fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.TLS.CertFile, c.TLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	clientAuth := c.TLS.ClientAuth
+	if clientAuth == "" {
+		clientAuth = "none"
+		if c.TLS.ClientCAFile != "" {
+			clientAuth = "verify_if_given"
+		}
+	}
+
+	switch clientAuth {
+	case "none":
+		tlsConfig.ClientAuth = tls.NoClientCert
+	case "request":
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	case "verify_if_given":
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	case "require":
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("invalid TLS_CLIENT_AUTH %q", clientAuth)
+	}
+
+	if tlsConfig.ClientAuth >= tls.VerifyClientCertIfGiven {
+		if c.TLS.ClientCAFile == "" {
+			return nil, fmt.Errorf("TLS_CLIENT_AUTH=%s requires TLS_CLIENT_CA_FILE", clientAuth)
+		}
+		bundle, err := os.ReadFile(c.TLS.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA bundle: %w", err)
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(bundle) {
+			return nil, fmt.Errorf("client CA bundle %s contains no PEM certificates", c.TLS.ClientCAFile)
+		}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,153 @@
+// Package condition evaluates the conditions of role grants, written in the Common
+// Expression Language (CEL), against a domain.AccessContext
+package condition
+
+import (
+	"fmt"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// maxCostLimit bounds the work of evaluating a single condition
+const maxCostLimit = 100000
+
+// CELEvaluator compiles conditions once and caches the programs by expression.
+// Conditions see the variables:
+//
+//	request.ip    the client IP, "" when unknown
+//	request.time  the time of the request, a timestamp
+//	user          the user's attributes, including id and email
+//	resource      the resource's attributes, including type and id
+//	action        the requested action
+//
+// and the function inCIDR(ip, cidr), e.g. inCIDR(request.ip, "10.0.0.0/8").
+type CELEvaluator struct {
+	env *cel.Env
+
+	mu       sync.RWMutex
+	programs map[string]cel.Program
+}
+
+func NewCELEvaluator() (*CELEvaluator, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("user", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("resource", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("action", cel.StringType),
+		cel.Function("inCIDR",
+			cel.Overload("in_cidr_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(inCIDR),
+			),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create condition environment: %w", err)
+	}
+
+	return &CELEvaluator{
+		env:      env,
+		programs: map[string]cel.Program{},
+	}, nil
+}
+
+// Validate compiles the expression and checks that it yields a boolean
+func (e *CELEvaluator) Validate(expression string) error {
+	_, err := e.program(expression)
+	return err
+}
+
+// Evaluate reports whether the condition holds in the context. An empty condition always
+// holds. Errors, such as a missing attribute, mean the condition does not hold; use
+// has(user.department) to test for optional attributes.
+func (e *CELEvaluator) Evaluate(expression string, ctx *domain.AccessContext) (bool, error) {
+	if expression == "" {
+		return true, nil
+	}
+
+	program, err := e.program(expression)
+	if err != nil {
+		return false, err
+	}
+
+	requestTime := time.Now()
+	if ctx.Time != nil {
+		requestTime = *ctx.Time
+	}
+
+	out, _, err := program.Eval(map[string]interface{}{
+		"request":  map[string]interface{}{"ip": ctx.IP, "time": requestTime},
+		"user":     attributes(ctx.User),
+		"resource": attributes(ctx.Resource),
+		"action":   ctx.Action,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to evaluate condition: %w", err)
+	}
+
+	allowed, ok := out.Value().(bool)
+	return ok && allowed, nil
+}
+
+func (e *CELEvaluator) program(expression string) (cel.Program, error) {
+	e.mu.RLock()
+	program, ok := e.programs[expression]
+	e.mu.RUnlock()
+	if ok {
+		return program, nil
+	}
+
+	ast, issues := e.env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid condition: %w", issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("invalid condition: must evaluate to a bool, not %s", ast.OutputType())
+	}
+
+	program, err := e.env.Program(ast, cel.CostLimit(maxCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition: %w", err)
+	}
+
+	e.mu.Lock()
+	e.programs[expression] = program
+	e.mu.Unlock()
+
+	return program, nil
+}
+
+func attributes(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return map[string]interface{}{}
+	}
+	return values
+}
+
+func inCIDR(ipValue, cidrValue ref.Val) ref.Val {
+	ipStr, ok := ipValue.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(ipValue)
+	}
+	cidrStr, ok := cidrValue.Value().(string)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(cidrValue)
+	}
+
+	prefix, err := netip.ParsePrefix(cidrStr)
+	if err != nil {
+		return types.NewErr("invalid CIDR %q", cidrStr)
+	}
+	ip, err := netip.ParseAddr(ipStr)
+	if err != nil {
+		return types.False
+	}
+
+	return types.Bool(prefix.Contains(ip.Unmap()))
+}
@@ -1,8 +1,12 @@
 package http
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -10,34 +14,85 @@ import (
 
 	"github.com/aras-services/aras-auth/internal/domain"
 	"github.com/aras-services/aras-auth/internal/usecase"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
 )
 
+// passwordlessBindingCookie holds the browser secret a passwordless challenge is bound to
+const passwordlessBindingCookie = "aras_passwordless_binding"
+
+// federatedLoginCookie holds the state, nonce and PKCE verifier of a browser sign-in
+// with an external identity provider between the start and callback requests
+const federatedLoginCookie = "aras_federated_login"
+
+// federatedLoginMaxAge bounds how long the user may take at the external provider
+const federatedLoginMaxAge = 10 * time.Minute
+
 type AuthHandler struct {
-	authUseCase *usecase.AuthUseCase
-	validator   *validator.Validate
+	authUseCase         *usecase.AuthUseCase
+	passwordlessUseCase *usecase.PasswordlessUseCase
+	emailChangeUseCase  *usecase.EmailChangeUseCase
+	federationUseCase   *usecase.FederationUseCase
+	validator           *validator.Validate
 }
 
-func NewAuthHandler(authUseCase *usecase.AuthUseCase) *AuthHandler {
+func NewAuthHandler(
+	authUseCase *usecase.AuthUseCase,
+	passwordlessUseCase *usecase.PasswordlessUseCase,
+	emailChangeUseCase *usecase.EmailChangeUseCase,
+	federationUseCase *usecase.FederationUseCase,
+) *AuthHandler {
 	return &AuthHandler{
-		authUseCase: authUseCase,
-		validator:   validator.New(),
+		authUseCase:         authUseCase,
+		passwordlessUseCase: passwordlessUseCase,
+		emailChangeUseCase:  emailChangeUseCase,
+		federationUseCase:   federationUseCase,
+		validator:           validator.New(),
 	}
 }
 
-func (h *AuthHandler) RegisterRoutes(r chi.Router) {
+// AuthRouteMiddleware holds middleware applied to individual authentication routes.
+// Nil entries are skipped.
+type AuthRouteMiddleware struct {
+	// RequireAuth protects routes acting on the current user; it must accept password-change tokens
+	RequireAuth    func(http.Handler) http.Handler
+	Register       func(http.Handler) http.Handler
+	Login          func(http.Handler) http.Handler
+	ForgotPassword func(http.Handler) http.Handler
+	Passwordless   func(http.Handler) http.Handler
+}
+
+func (h *AuthHandler) RegisterRoutes(r chi.Router, mw AuthRouteMiddleware) {
 	r.Route("/auth", func(r chi.Router) {
-		r.Post("/register", h.Register)
-		r.Post("/login", h.Login)
+		r.With(optional(mw.Register)).Post("/register", h.Register)
+		r.With(optional(mw.Login)).Post("/login", h.Login)
+		r.With(optional(mw.Login)).Post("/cert", h.CertificateLogin)
 		r.Post("/refresh", h.RefreshToken)
 		r.Post("/logout", h.Logout)
 		r.Post("/verify-email", h.VerifyEmail)
-		r.Post("/forgot-password", h.ForgotPassword)
+		r.With(optional(mw.ForgotPassword)).Post("/forgot-password", h.ForgotPassword)
 		r.Post("/reset-password", h.ResetPassword)
-		r.Post("/change-password", h.ChangePassword)
+		r.With(optional(mw.RequireAuth)).Post("/change-password", h.ChangePassword)
 		r.Post("/introspect", h.IntrospectToken)
+		r.With(optional(mw.Passwordless)).Post("/passwordless/start", h.StartPasswordless)
+		r.Post("/passwordless/complete", h.CompletePasswordless)
+		r.Post("/email-change/confirm", h.ConfirmEmailChange)
+		r.Post("/email-change/cancel", h.CancelEmailChange)
+		r.Get("/providers", h.ListLoginProviders)
+		r.Get("/providers/{name}/start", h.StartFederatedLogin)
+		r.Get("/providers/{name}/callback", h.FederatedLoginCallback)
+		r.Get("/providers/{name}/metadata", h.SAMLMetadata)
+		r.Post("/providers/{name}/acs", h.SAMLAssertionConsumer)
 	})
 }
 
+// optional returns mw, or a pass-through middleware when mw is nil
+func optional(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	if mw == nil {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return mw
+}
+
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -73,10 +128,37 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	response, err := h.authUseCase.Login(r.Context(), &req)
 	if err != nil {
+		var retryErr *domain.RetryAfterError
+		if errors.As(err, &retryErr) {
+			WriteTooManyRequests(w, retryErr.Seconds(), "Too many failed login attempts, please try again later")
+			return
+		}
 		WriteUnauthorized(w, "Invalid credentials")
 		return
 	}
 
+	if response.PasswordExpired {
+		WriteSuccess(w, response, "Password expired; change your password to continue")
+		return
+	}
+
+	WriteSuccess(w, response, "Login successful")
+}
+
+// CertificateLogin issues tokens for the client certificate presented over mutual TLS.
+// ?provider= selects the mtls provider; otherwise the first enabled one is used.
+func (h *AuthHandler) CertificateLogin(w http.ResponseWriter, r *http.Request) {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		WriteUnauthorized(w, "Client certificate required")
+		return
+	}
+
+	response, err := h.authUseCase.CertificateLogin(r.Context(), r.URL.Query().Get("provider"), r.TLS)
+	if err != nil {
+		WriteUnauthorized(w, "Client certificate not accepted")
+		return
+	}
+
 	WriteSuccess(w, response, "Login successful")
 }
 
@@ -253,4 +335,262 @@ func (h *AuthHandler) IntrospectToken(w http.ResponseWriter, r *http.Request) {
 	WriteSuccess(w, introspection, "Token introspection successful")
 }
 
+func (h *AuthHandler) StartPasswordless(w http.ResponseWriter, r *http.Request) {
+	var req domain.StartPasswordlessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	// Bind the challenge to this browser so a forwarded email cannot be redeemed elsewhere
+	binding, err := securetoken.Generate(securetoken.DefaultLength)
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	response, err := h.passwordlessUseCase.Start(r.Context(), &req, binding)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "passwordless_failed", err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     passwordlessBindingCookie,
+		Value:    binding,
+		Path:     "/",
+		MaxAge:   int(response.ExpiresIn),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	WriteSuccess(w, response, "If the account can sign in without a password, a sign-in link and code have been sent")
+}
+
+func (h *AuthHandler) CompletePasswordless(w http.ResponseWriter, r *http.Request) {
+	var req domain.CompletePasswordlessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	var binding string
+	if cookie, err := r.Cookie(passwordlessBindingCookie); err == nil {
+		binding = cookie.Value
+	}
+
+	response, err := h.passwordlessUseCase.Complete(r.Context(), &req, binding)
+	if err != nil {
+		WriteUnauthorized(w, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     passwordlessBindingCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	WriteSuccess(w, response, "Login successful")
+}
+
+func (h *AuthHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req domain.EmailChangeTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := h.emailChangeUseCase.Confirm(r.Context(), &req); err != nil {
+		WriteError(w, http.StatusBadRequest, "email_change_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Email address changed successfully")
+}
+
+func (h *AuthHandler) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req domain.EmailChangeTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := h.emailChangeUseCase.Cancel(r.Context(), &req); err != nil {
+		WriteError(w, http.StatusBadRequest, "email_change_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Email change cancelled")
+}
+
+// ListLoginProviders lists the enabled identity providers for login pages. An optional
+// email query parameter narrows the list to the provider its domain is routed to.
+func (h *AuthHandler) ListLoginProviders(w http.ResponseWriter, r *http.Request) {
+	providers, err := h.authUseCase.ListLoginProviders(r.Context(), r.URL.Query().Get("email"))
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, providers, "Providers retrieved successfully")
+}
+
+// StartFederatedLogin redirects the browser to an external identity provider
+func (h *AuthHandler) StartFederatedLogin(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "name")
+
+	authURL, login, err := h.federationUseCase.Start(r.Context(), providerName)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "federation_failed", err)
+		return
+	}
+
+	if err := setFederatedLogin(w, r, login); err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// setFederatedLogin keeps the login secrets in the browser until the provider redirects back
+func setFederatedLogin(w http.ResponseWriter, r *http.Request, login *domain.FederatedLogin) error {
+	value, err := json.Marshal(login)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     federatedLoginCookie,
+		Value:    base64.RawURLEncoding.EncodeToString(value),
+		Path:     "/",
+		MaxAge:   int(federatedLoginMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: federatedLoginSameSite(r),
+	})
+
+	return nil
+}
 
+// FederatedLoginCallback completes a sign-in when the external identity provider redirects back
+func (h *AuthHandler) FederatedLoginCallback(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "name")
+	query := r.URL.Query()
+
+	login := takeFederatedLogin(w, r)
+
+	if providerError := query.Get("error"); providerError != "" {
+		message := providerError
+		if description := query.Get("error_description"); description != "" {
+			message += ": " + description
+		}
+		WriteError(w, http.StatusUnauthorized, "federation_failed", errors.New(message))
+		return
+	}
+
+	response, err := h.federationUseCase.Callback(r.Context(), providerName, login, query.Get("state"), query.Get("code"))
+	if err != nil {
+		WriteUnauthorized(w, err.Error())
+		return
+	}
+
+	WriteSuccess(w, response, "Login successful")
+}
+
+// SAMLMetadata serves the SAML service provider metadata to register with the identity provider
+func (h *AuthHandler) SAMLMetadata(w http.ResponseWriter, r *http.Request) {
+	metadata, err := h.federationUseCase.Metadata(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		WriteNotFound(w, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.WriteHeader(http.StatusOK)
+	w.Write(metadata)
+}
+
+// SAMLAssertionConsumer completes a SAML sign-in when the identity provider posts its response
+func (h *AuthHandler) SAMLAssertionConsumer(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "name")
+
+	if err := r.ParseForm(); err != nil {
+		WriteError(w, http.StatusBadRequest, "invalid_request", err)
+		return
+	}
+
+	login := takeFederatedLogin(w, r)
+
+	response, err := h.federationUseCase.ConsumeAssertion(r.Context(), providerName, login, r.PostForm.Get("RelayState"), r.PostForm.Get("SAMLResponse"))
+	if err != nil {
+		WriteUnauthorized(w, err.Error())
+		return
+	}
+
+	WriteSuccess(w, response, "Login successful")
+}
+
+// takeFederatedLogin reads the browser's pending sign-in, if any, and clears the cookie:
+// the login secrets are single-use whatever the outcome
+func takeFederatedLogin(w http.ResponseWriter, r *http.Request) *domain.FederatedLogin {
+	var login *domain.FederatedLogin
+	if cookie, err := r.Cookie(federatedLoginCookie); err == nil {
+		if value, err := base64.RawURLEncoding.DecodeString(cookie.Value); err == nil {
+			json.Unmarshal(value, &login)
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     federatedLoginCookie,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isSecureRequest(r),
+		SameSite: federatedLoginSameSite(r),
+	})
+
+	return login
+}
+
+// federatedLoginSameSite returns the SameSite mode of the sign-in cookie. Lax covers the
+// top-level redirect back from an OIDC provider, but a SAML IdP posts the response
+// cross-site, which only SameSite=None cookies survive; browsers require those to be Secure.
+func federatedLoginSameSite(r *http.Request) http.SameSite {
+	if isSecureRequest(r) {
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+// isSecureRequest reports whether the client connection uses HTTPS, directly or via a proxy
+func isSecureRequest(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -25,7 +26,9 @@ func NewAuthzHandler(authzUseCase *usecase.AuthzUseCase) *AuthzHandler {
 	}
 }
 
-func (h *AuthzHandler) RegisterRoutes(r chi.Router) {
+// RegisterRoutes registers the role, permission and authorization routes; requireUpdate
+// guards the routes that change what the authorization model grants or denies
+func (h *AuthzHandler) RegisterRoutes(r chi.Router, requireUpdate func(http.Handler) http.Handler) {
 	r.Route("/roles", func(r chi.Router) {
 		r.Post("/", h.CreateRole)
 		r.Get("/", h.ListRoles)
@@ -35,6 +38,10 @@ func (h *AuthzHandler) RegisterRoutes(r chi.Router) {
 		r.Post("/{id}/permissions", h.AssignPermissionToRole)
 		r.Delete("/{id}/permissions/{permissionId}", h.RemovePermissionFromRole)
 		r.Get("/{id}/permissions", h.GetRolePermissions)
+		r.With(optional(requireUpdate)).Post("/{id}/parents", h.AddRoleParent)
+		r.With(optional(requireUpdate)).Delete("/{id}/parents/{parentId}", h.RemoveRoleParent)
+		r.Get("/{id}/parents", h.GetRoleParents)
+		r.Get("/{id}/hierarchy", h.GetRoleHierarchy)
 	})
 
 	r.Route("/permissions", func(r chi.Router) {
@@ -51,6 +58,10 @@ func (h *AuthzHandler) RegisterRoutes(r chi.Router) {
 		r.Get("/", h.GetUserRoles)
 	})
 
+	r.Route("/users/{userId}/permissions", func(r chi.Router) {
+		r.Get("/", h.GetUserEffectivePermissions)
+	})
+
 	r.Route("/groups/{groupId}/roles", func(r chi.Router) {
 		r.Post("/", h.AssignRoleToGroup)
 		r.Delete("/{roleId}", h.RemoveRoleFromGroup)
@@ -59,6 +70,17 @@ func (h *AuthzHandler) RegisterRoutes(r chi.Router) {
 
 	r.Route("/authz", func(r chi.Router) {
 		r.Post("/check", h.CheckPermission)
+		r.Post("/check-batch", h.CheckPermissionBatch)
+		r.Post("/explain", h.ExplainPermission)
+		r.Get("/who-can", h.ListPermissionHolders)
+		r.With(optional(requireUpdate)).Post("/grants", h.CreateResourceGrant)
+		r.Get("/grants", h.ListResourceGrants)
+		r.Get("/grants/{id}", h.GetResourceGrant)
+		r.With(optional(requireUpdate)).Delete("/grants/{id}", h.DeleteResourceGrant)
+		r.With(optional(requireUpdate)).Post("/deny-rules", h.CreateDenyRule)
+		r.Get("/deny-rules", h.ListDenyRules)
+		r.Get("/deny-rules/{id}", h.GetDenyRule)
+		r.With(optional(requireUpdate)).Delete("/deny-rules/{id}", h.DeleteDenyRule)
 	})
 }
 
@@ -241,6 +263,97 @@ func (h *AuthzHandler) GetRolePermissions(w http.ResponseWriter, r *http.Request
 	WriteSuccess(w, permissions, "Role permissions retrieved successfully")
 }
 
+// Role inheritance handlers
+func (h *AuthzHandler) AddRoleParent(w http.ResponseWriter, r *http.Request) {
+	roleIDStr := chi.URLParam(r, "id")
+	roleID, err := uuid.Parse(roleIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid role ID")
+		return
+	}
+
+	var req domain.AddRoleParentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := h.authzUseCase.AddRoleParent(r.Context(), roleID, &req); err != nil {
+		if errors.Is(err, domain.ErrRoleCycle) {
+			WriteError(w, http.StatusConflict, "role_cycle", err)
+			return
+		}
+		WriteError(w, http.StatusBadRequest, "add_parent_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Parent role added successfully")
+}
+
+func (h *AuthzHandler) RemoveRoleParent(w http.ResponseWriter, r *http.Request) {
+	roleIDStr := chi.URLParam(r, "id")
+	parentIDStr := chi.URLParam(r, "parentId")
+
+	roleID, err := uuid.Parse(roleIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid role ID")
+		return
+	}
+
+	parentRoleID, err := uuid.Parse(parentIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid parent role ID")
+		return
+	}
+
+	if err := h.authzUseCase.RemoveRoleParent(r.Context(), roleID, parentRoleID); err != nil {
+		WriteError(w, http.StatusBadRequest, "remove_parent_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Parent role removed successfully")
+}
+
+func (h *AuthzHandler) GetRoleParents(w http.ResponseWriter, r *http.Request) {
+	roleIDStr := chi.URLParam(r, "id")
+	roleID, err := uuid.Parse(roleIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid role ID")
+		return
+	}
+
+	parents, err := h.authzUseCase.GetRoleParents(r.Context(), roleID)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "get_parents_failed", err)
+		return
+	}
+
+	WriteSuccess(w, parents, "Parent roles retrieved successfully")
+}
+
+// GetRoleHierarchy returns the inheritance graph around a role
+func (h *AuthzHandler) GetRoleHierarchy(w http.ResponseWriter, r *http.Request) {
+	roleIDStr := chi.URLParam(r, "id")
+	roleID, err := uuid.Parse(roleIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid role ID")
+		return
+	}
+
+	hierarchy, err := h.authzUseCase.GetRoleHierarchy(r.Context(), roleID)
+	if err != nil {
+		WriteNotFound(w, "Role not found")
+		return
+	}
+
+	WriteSuccess(w, hierarchy, "Role hierarchy retrieved successfully")
+}
+
 // Permission handlers
 func (h *AuthzHandler) CreatePermission(w http.ResponseWriter, r *http.Request) {
 	var req domain.CreatePermissionRequest
@@ -490,6 +603,52 @@ func (h *AuthzHandler) GetGroupRoles(w http.ResponseWriter, r *http.Request) {
 	WriteSuccess(w, roles, "Group roles retrieved successfully")
 }
 
+// GetUserEffectivePermissions lists the user's permissions with their sources, paginated
+// by ?page= and ?limit=
+func (h *AuthzHandler) GetUserEffectivePermissions(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "userId")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid user ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	response, err := h.authzUseCase.GetUserEffectivePermissions(r.Context(), userID, page, limit)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "get_permissions_failed", err)
+		return
+	}
+
+	WriteSuccess(w, response, "Effective permissions retrieved successfully")
+}
+
+// ListPermissionHolders lists the users who can perform ?action= on ?resource=, paginated
+// by ?page= and ?limit=
+func (h *AuthzHandler) ListPermissionHolders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	resource := query.Get("resource")
+	action := query.Get("action")
+	if resource == "" || action == "" {
+		WriteValidationError(w, "Resource and action are required")
+		return
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	response, err := h.authzUseCase.ListPermissionHolders(r.Context(), resource, action, page, limit)
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, response, "Permission holders retrieved successfully")
+}
+
 // Authorization check
 func (h *AuthzHandler) CheckPermission(w http.ResponseWriter, r *http.Request) {
 	var req domain.CheckPermissionRequest
@@ -512,4 +671,244 @@ func (h *AuthzHandler) CheckPermission(w http.ResponseWriter, r *http.Request) {
 	WriteSuccess(w, response, "Permission check completed")
 }
 
+// CheckPermissionBatch decides up to 100 checks at once, returning results in order
+func (h *AuthzHandler) CheckPermissionBatch(w http.ResponseWriter, r *http.Request) {
+	var req domain.BatchCheckPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	response, err := h.authzUseCase.CheckPermissionBatch(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "check_permission_failed", err)
+		return
+	}
+
+	WriteSuccess(w, response, "Permission checks completed")
+}
+
+// ExplainPermission takes the body of CheckPermission and returns the decision with the
+// paths granting the permission and the near misses
+func (h *AuthzHandler) ExplainPermission(w http.ResponseWriter, r *http.Request) {
+	var req domain.CheckPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	explanation, err := h.authzUseCase.ExplainPermission(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "explain_permission_failed", err)
+		return
+	}
+
+	WriteSuccess(w, explanation, "Permission explanation completed")
+}
+
+// Resource-scoped grant handlers
+func (h *AuthzHandler) CreateResourceGrant(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateResourceGrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	grant, err := h.authzUseCase.CreateResourceGrant(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "creation_failed", err)
+		return
+	}
+
+	WriteSuccess(w, grant, "Resource grant created successfully")
+}
+
+// ListResourceGrants lists grants, optionally filtered by ?subject_type=, ?subject_id=,
+// ?resource_type= and ?resource_id=
+func (h *AuthzHandler) ListResourceGrants(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.ResourceGrantFilter{
+		SubjectType:  query.Get("subject_type"),
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+	}
+
+	switch filter.SubjectType {
+	case "", domain.SubjectTypeUser, domain.SubjectTypeGroup:
+	default:
+		WriteValidationError(w, "Invalid subject type")
+		return
+	}
+
+	if subjectIDStr := query.Get("subject_id"); subjectIDStr != "" {
+		subjectID, err := uuid.Parse(subjectIDStr)
+		if err != nil {
+			WriteValidationError(w, "Invalid subject ID")
+			return
+		}
+		filter.SubjectID = &subjectID
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	response, err := h.authzUseCase.ListResourceGrants(r.Context(), filter, page, limit)
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, response, "Resource grants retrieved successfully")
+}
+
+func (h *AuthzHandler) GetResourceGrant(w http.ResponseWriter, r *http.Request) {
+	grantIDStr := chi.URLParam(r, "id")
+	grantID, err := uuid.Parse(grantIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid grant ID")
+		return
+	}
+
+	grant, err := h.authzUseCase.GetResourceGrant(r.Context(), grantID)
+	if err != nil {
+		WriteNotFound(w, "Resource grant not found")
+		return
+	}
+
+	WriteSuccess(w, grant, "Resource grant retrieved successfully")
+}
+
+func (h *AuthzHandler) DeleteResourceGrant(w http.ResponseWriter, r *http.Request) {
+	grantIDStr := chi.URLParam(r, "id")
+	grantID, err := uuid.Parse(grantIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid grant ID")
+		return
+	}
+
+	if err := h.authzUseCase.DeleteResourceGrant(r.Context(), grantID); err != nil {
+		WriteNotFound(w, "Resource grant not found")
+		return
+	}
+
+	WriteSuccess(w, nil, "Resource grant deleted successfully")
+}
+
+// Deny rule handlers
+func (h *AuthzHandler) CreateDenyRule(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateDenyRuleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	rule, err := h.authzUseCase.CreateDenyRule(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "creation_failed", err)
+		return
+	}
+
+	WriteSuccess(w, rule, "Deny rule created successfully")
+}
+
+// ListDenyRules lists deny rules, optionally filtered by ?subject_type=, ?subject_id= and
+// ?permission_id=
+func (h *AuthzHandler) ListDenyRules(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.DenyRuleFilter{
+		SubjectType: query.Get("subject_type"),
+	}
+
+	switch filter.SubjectType {
+	case "", domain.SubjectTypeUser, domain.SubjectTypeGroup, domain.SubjectTypeRole:
+	default:
+		WriteValidationError(w, "Invalid subject type")
+		return
+	}
+
+	if subjectIDStr := query.Get("subject_id"); subjectIDStr != "" {
+		subjectID, err := uuid.Parse(subjectIDStr)
+		if err != nil {
+			WriteValidationError(w, "Invalid subject ID")
+			return
+		}
+		filter.SubjectID = &subjectID
+	}
+
+	if permissionIDStr := query.Get("permission_id"); permissionIDStr != "" {
+		permissionID, err := uuid.Parse(permissionIDStr)
+		if err != nil {
+			WriteValidationError(w, "Invalid permission ID")
+			return
+		}
+		filter.PermissionID = &permissionID
+	}
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	response, err := h.authzUseCase.ListDenyRules(r.Context(), filter, page, limit)
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, response, "Deny rules retrieved successfully")
+}
+
+func (h *AuthzHandler) GetDenyRule(w http.ResponseWriter, r *http.Request) {
+	ruleIDStr := chi.URLParam(r, "id")
+	ruleID, err := uuid.Parse(ruleIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid deny rule ID")
+		return
+	}
+
+	rule, err := h.authzUseCase.GetDenyRule(r.Context(), ruleID)
+	if err != nil {
+		WriteNotFound(w, "Deny rule not found")
+		return
+	}
+
+	WriteSuccess(w, rule, "Deny rule retrieved successfully")
+}
+
+func (h *AuthzHandler) DeleteDenyRule(w http.ResponseWriter, r *http.Request) {
+	ruleIDStr := chi.URLParam(r, "id")
+	ruleID, err := uuid.Parse(ruleIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid deny rule ID")
+		return
+	}
+
+	if err := h.authzUseCase.DeleteDenyRule(r.Context(), ruleID); err != nil {
+		WriteNotFound(w, "Deny rule not found")
+		return
+	}
+
+	WriteSuccess(w, nil, "Deny rule deleted successfully")
+}
+
 
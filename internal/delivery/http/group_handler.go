@@ -2,6 +2,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -35,7 +36,12 @@ func (h *GroupHandler) RegisterRoutes(r chi.Router) {
 		r.Post("/{id}/members", h.AddMember)
 		r.Delete("/{id}/members/{userId}", h.RemoveMember)
 		r.Get("/{id}/members", h.GetMembers)
+		r.Post("/{id}/groups", h.AddSubgroup)
+		r.Delete("/{id}/groups/{subgroupId}", h.RemoveSubgroup)
+		r.Get("/{id}/groups", h.GetSubgroups)
 	})
+
+	r.Get("/users/{userId}/groups", h.GetUserGroups)
 }
 
 func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
@@ -200,6 +206,8 @@ func (h *GroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
 	WriteSuccess(w, nil, "Member removed successfully")
 }
 
+// GetMembers lists the members of a group including members of nested groups;
+// ?membership=direct limits them to direct members
 func (h *GroupHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
 	groupIDStr := chi.URLParam(r, "id")
 	groupID, err := uuid.Parse(groupIDStr)
@@ -208,7 +216,13 @@ func (h *GroupHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	members, err := h.groupUseCase.GetMembers(r.Context(), groupID)
+	scope, err := domain.ParseMembershipScope(r.URL.Query().Get("membership"))
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	members, err := h.groupUseCase.GetMembers(r.Context(), groupID, scope)
 	if err != nil {
 		WriteError(w, http.StatusBadRequest, "get_members_failed", err)
 		return
@@ -217,3 +231,108 @@ func (h *GroupHandler) GetMembers(w http.ResponseWriter, r *http.Request) {
 	WriteSuccess(w, members, "Members retrieved successfully")
 }
 
+// Nested group handlers
+func (h *GroupHandler) AddSubgroup(w http.ResponseWriter, r *http.Request) {
+	groupIDStr := chi.URLParam(r, "id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid group ID")
+		return
+	}
+
+	var req domain.AddSubgroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	if err := h.groupUseCase.AddSubgroup(r.Context(), groupID, &req); err != nil {
+		if errors.Is(err, domain.ErrGroupCycle) {
+			WriteError(w, http.StatusConflict, "group_cycle", err)
+			return
+		}
+		WriteError(w, http.StatusBadRequest, "add_subgroup_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Subgroup added successfully")
+}
+
+func (h *GroupHandler) RemoveSubgroup(w http.ResponseWriter, r *http.Request) {
+	groupIDStr := chi.URLParam(r, "id")
+	subgroupIDStr := chi.URLParam(r, "subgroupId")
+
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid group ID")
+		return
+	}
+
+	subgroupID, err := uuid.Parse(subgroupIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid subgroup ID")
+		return
+	}
+
+	if err := h.groupUseCase.RemoveSubgroup(r.Context(), groupID, subgroupID); err != nil {
+		WriteError(w, http.StatusBadRequest, "remove_subgroup_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Subgroup removed successfully")
+}
+
+// GetSubgroups lists nested groups; ?membership=direct limits them to direct children
+func (h *GroupHandler) GetSubgroups(w http.ResponseWriter, r *http.Request) {
+	groupIDStr := chi.URLParam(r, "id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid group ID")
+		return
+	}
+
+	scope, err := domain.ParseMembershipScope(r.URL.Query().Get("membership"))
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	groups, err := h.groupUseCase.GetSubgroups(r.Context(), groupID, scope)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "get_subgroups_failed", err)
+		return
+	}
+
+	WriteSuccess(w, groups, "Subgroups retrieved successfully")
+}
+
+// GetUserGroups lists the groups of a user; ?membership=direct omits the groups that
+// contain them only through nesting
+func (h *GroupHandler) GetUserGroups(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "userId")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid user ID")
+		return
+	}
+
+	scope, err := domain.ParseMembershipScope(r.URL.Query().Get("membership"))
+	if err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	groups, err := h.groupUseCase.GetUserGroups(r.Context(), userID, scope)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "get_groups_failed", err)
+		return
+	}
+
+	WriteSuccess(w, groups, "User groups retrieved successfully")
+}
+
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/usecase"
+)
+
+type ProviderHandler struct {
+	providerUseCase *usecase.ProviderUseCase
+	validator       *validator.Validate
+}
+
+func NewProviderHandler(providerUseCase *usecase.ProviderUseCase) *ProviderHandler {
+	return &ProviderHandler{
+		providerUseCase: providerUseCase,
+		validator:       validator.New(),
+	}
+}
+
+// RegisterRoutes registers the provider administration routes. requireUpdate guards
+// routes that modify provider configuration.
+func (h *ProviderHandler) RegisterRoutes(r chi.Router, requireUpdate func(http.Handler) http.Handler) {
+	r.Route("/admin/providers", func(r chi.Router) {
+		r.Get("/", h.ListProviders)
+		r.Get("/{name}", h.GetProvider)
+		r.With(optional(requireUpdate)).Post("/", h.CreateProvider)
+		r.With(optional(requireUpdate)).Post("/reload", h.ReloadProviders)
+		r.With(optional(requireUpdate)).Put("/{name}", h.UpdateProvider)
+		r.With(optional(requireUpdate)).Delete("/{name}", h.DeleteProvider)
+		r.With(optional(requireUpdate)).Put("/{name}/passwordless", h.SetPasswordless)
+	})
+}
+
+func (h *ProviderHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	providers, err := h.providerUseCase.ListProviders(r.Context())
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, providers, "Providers retrieved successfully")
+}
+
+func (h *ProviderHandler) GetProvider(w http.ResponseWriter, r *http.Request) {
+	provider, err := h.providerUseCase.GetProvider(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		WriteNotFound(w, "Provider not found")
+		return
+	}
+
+	WriteSuccess(w, provider, "Provider retrieved successfully")
+}
+
+func (h *ProviderHandler) CreateProvider(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	provider, err := h.providerUseCase.CreateProvider(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "creation_failed", err)
+		return
+	}
+
+	WriteSuccess(w, provider, "Provider created successfully")
+}
+
+func (h *ProviderHandler) UpdateProvider(w http.ResponseWriter, r *http.Request) {
+	var req domain.UpdateProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	provider, err := h.providerUseCase.UpdateProvider(r.Context(), chi.URLParam(r, "name"), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "update_failed", err)
+		return
+	}
+
+	WriteSuccess(w, provider, "Provider updated successfully")
+}
+
+func (h *ProviderHandler) DeleteProvider(w http.ResponseWriter, r *http.Request) {
+	if err := h.providerUseCase.DeleteProvider(r.Context(), chi.URLParam(r, "name")); err != nil {
+		WriteError(w, http.StatusBadRequest, "delete_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Provider deleted successfully")
+}
+
+// ReloadProviders re-reads the providers table, e.g. after editing it directly in SQL
+func (h *ProviderHandler) ReloadProviders(w http.ResponseWriter, r *http.Request) {
+	if err := h.providerUseCase.ReloadProviders(r.Context()); err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Providers reloaded successfully")
+}
+
+func (h *ProviderHandler) SetPasswordless(w http.ResponseWriter, r *http.Request) {
+	var req domain.SetPasswordlessRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	provider, err := h.providerUseCase.SetPasswordlessEnabled(r.Context(), chi.URLParam(r, "name"), req.Enabled)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "update_failed", err)
+		return
+	}
+
+	WriteSuccess(w, provider, "Passwordless sign-in updated successfully")
+}
@@ -0,0 +1,231 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/usecase"
+)
+
+// RelationHandler serves relation-based access control: namespace schemas, relation
+// tuples and the check, expand and lookup-resources queries
+type RelationHandler struct {
+	relationUseCase *usecase.RelationUseCase
+	validator       *validator.Validate
+}
+
+func NewRelationHandler(relationUseCase *usecase.RelationUseCase) *RelationHandler {
+	return &RelationHandler{
+		relationUseCase: relationUseCase,
+		validator:       validator.New(),
+	}
+}
+
+// RegisterRoutes registers the relation routes; requireUpdate guards the routes that
+// change schemas and tuples
+func (h *RelationHandler) RegisterRoutes(r chi.Router, requireUpdate func(http.Handler) http.Handler) {
+	r.Route("/relations", func(r chi.Router) {
+		r.Get("/namespaces", h.ListNamespaces)
+		r.Get("/namespaces/{name}", h.GetNamespace)
+		r.With(optional(requireUpdate)).Put("/namespaces/{name}", h.UpsertNamespace)
+		r.With(optional(requireUpdate)).Delete("/namespaces/{name}", h.DeleteNamespace)
+		r.Get("/tuples", h.ReadTuples)
+		r.With(optional(requireUpdate)).Post("/tuples", h.WriteTuples)
+		r.Post("/check", h.Check)
+		r.Post("/expand", h.Expand)
+		r.Post("/lookup-resources", h.LookupResources)
+	})
+}
+
+func (h *RelationHandler) ListNamespaces(w http.ResponseWriter, r *http.Request) {
+	namespaces, err := h.relationUseCase.ListNamespaces(r.Context())
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, namespaces, "Namespaces retrieved successfully")
+}
+
+func (h *RelationHandler) GetNamespace(w http.ResponseWriter, r *http.Request) {
+	namespace, err := h.relationUseCase.GetNamespace(r.Context(), chi.URLParam(r, "name"))
+	if err != nil {
+		WriteNotFound(w, "Namespace not found")
+		return
+	}
+
+	WriteSuccess(w, namespace, "Namespace retrieved successfully")
+}
+
+func (h *RelationHandler) UpsertNamespace(w http.ResponseWriter, r *http.Request) {
+	var req domain.UpsertNamespaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	namespace, err := h.relationUseCase.UpsertNamespace(r.Context(), chi.URLParam(r, "name"), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "update_failed", err)
+		return
+	}
+
+	WriteSuccess(w, namespace, "Namespace saved successfully")
+}
+
+func (h *RelationHandler) DeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	if err := h.relationUseCase.DeleteNamespace(r.Context(), chi.URLParam(r, "name")); err != nil {
+		WriteError(w, http.StatusBadRequest, "delete_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Namespace deleted successfully")
+}
+
+// ReadTuples lists the tuples of ?namespace=, optionally filtered by ?object_id=,
+// ?relation= and ?subject=, at the revision selected by ?at_least_as_fresh= or
+// ?at_exact_snapshot=
+func (h *RelationHandler) ReadTuples(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := domain.RelationTupleFilter{
+		Namespace: query.Get("namespace"),
+		ObjectID:  query.Get("object_id"),
+		Relation:  query.Get("relation"),
+	}
+	if filter.Namespace == "" {
+		WriteValidationError(w, "namespace is required")
+		return
+	}
+
+	if subjectStr := query.Get("subject"); subjectStr != "" {
+		subject, err := domain.ParseRelationSubject(subjectStr)
+		if err != nil {
+			WriteValidationError(w, err.Error())
+			return
+		}
+		filter.Subject = &subject
+	}
+
+	consistency := &domain.Consistency{
+		AtLeastAsFresh:  query.Get("at_least_as_fresh"),
+		AtExactSnapshot: query.Get("at_exact_snapshot"),
+	}
+
+	response, err := h.relationUseCase.ReadTuples(r.Context(), filter, consistency)
+	if err != nil {
+		writeRelationError(w, "read_failed", err)
+		return
+	}
+
+	WriteSuccess(w, response, "Relation tuples retrieved successfully")
+}
+
+func (h *RelationHandler) WriteTuples(w http.ResponseWriter, r *http.Request) {
+	var req domain.WriteRelationTuplesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	response, err := h.relationUseCase.WriteTuples(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "write_failed", err)
+		return
+	}
+
+	WriteSuccess(w, response, "Relation tuples written successfully")
+}
+
+func (h *RelationHandler) Check(w http.ResponseWriter, r *http.Request) {
+	var req domain.CheckRelationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	response, err := h.relationUseCase.Check(r.Context(), &req)
+	if err != nil {
+		writeRelationError(w, "check_failed", err)
+		return
+	}
+
+	WriteSuccess(w, response, "Relation check completed")
+}
+
+func (h *RelationHandler) Expand(w http.ResponseWriter, r *http.Request) {
+	var req domain.ExpandRelationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	response, err := h.relationUseCase.Expand(r.Context(), &req)
+	if err != nil {
+		writeRelationError(w, "expand_failed", err)
+		return
+	}
+
+	WriteSuccess(w, response, "Relation expanded successfully")
+}
+
+func (h *RelationHandler) LookupResources(w http.ResponseWriter, r *http.Request) {
+	var req domain.LookupResourcesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	response, err := h.relationUseCase.LookupResources(r.Context(), &req)
+	if err != nil {
+		writeRelationError(w, "lookup_failed", err)
+		return
+	}
+
+	WriteSuccess(w, response, "Resources looked up successfully")
+}
+
+// writeRelationError reports a token the store has not reached as a conflict the client
+// can retry, a snapshot whose history was purged as gone, and other failures as bad
+// requests
+func writeRelationError(w http.ResponseWriter, message string, err error) {
+	if errors.Is(err, domain.ErrStaleConsistencyToken) {
+		WriteError(w, http.StatusConflict, message, err)
+		return
+	}
+	if errors.Is(err, domain.ErrSnapshotExpired) {
+		WriteError(w, http.StatusGone, message, err)
+		return
+	}
+	WriteError(w, http.StatusBadRequest, message, err)
+}
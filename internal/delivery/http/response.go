@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 )
 
 type Response struct {
@@ -65,3 +66,10 @@ func WriteNotFound(w http.ResponseWriter, message string) {
 func WriteInternalError(w http.ResponseWriter, err error) {
 	WriteError(w, http.StatusInternalServerError, "internal_error", err)
 }
+
+func WriteTooManyRequests(w http.ResponseWriter, retryAfterSeconds int, message string) {
+	if retryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds))
+	}
+	WriteError(w, http.StatusTooManyRequests, "too_many_requests", fmt.Errorf(message))
+}
@@ -0,0 +1,440 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/usecase"
+	"github.com/aras-services/aras-auth/pkg/scim"
+)
+
+// SCIMHandler serves the SCIM 2.0 protocol under /scim/v2. Responses use the SCIM media
+// type and error format rather than the API's response envelope.
+type SCIMHandler struct {
+	scimUseCase *usecase.SCIMUseCase
+	validator   *validator.Validate
+}
+
+func NewSCIMHandler(scimUseCase *usecase.SCIMUseCase) *SCIMHandler {
+	return &SCIMHandler{
+		scimUseCase: scimUseCase,
+		validator:   validator.New(),
+	}
+}
+
+// RegisterRoutes registers the SCIM endpoints. Discovery endpoints are public;
+// requireToken guards the Users and Groups resources.
+func (h *SCIMHandler) RegisterRoutes(r chi.Router, requireToken func(http.Handler) http.Handler) {
+	r.Route("/scim/v2", func(r chi.Router) {
+		r.Get("/ServiceProviderConfig", h.GetServiceProviderConfig)
+		r.Get("/Schemas", h.ListSchemas)
+		r.Get("/Schemas/{id}", h.GetSchema)
+		r.Get("/ResourceTypes", h.ListResourceTypes)
+		r.Get("/ResourceTypes/{id}", h.GetResourceType)
+
+		r.Group(func(r chi.Router) {
+			r.Use(optional(requireToken))
+
+			r.Get("/Users", h.ListUsers)
+			r.Post("/Users", h.CreateUser)
+			r.Get("/Users/{id}", h.GetUser)
+			r.Put("/Users/{id}", h.ReplaceUser)
+			r.Patch("/Users/{id}", h.PatchUser)
+			r.Delete("/Users/{id}", h.DeleteUser)
+
+			r.Get("/Groups", h.ListGroups)
+			r.Post("/Groups", h.CreateGroup)
+			r.Get("/Groups/{id}", h.GetGroup)
+			r.Put("/Groups/{id}", h.ReplaceGroup)
+			r.Patch("/Groups/{id}", h.PatchGroup)
+			r.Delete("/Groups/{id}", h.DeleteGroup)
+		})
+	})
+}
+
+// RegisterAdminRoutes registers SCIM credential management; callers must restrict it to administrators
+func (h *SCIMHandler) RegisterAdminRoutes(r chi.Router) {
+	r.Route("/admin/scim/tokens", func(r chi.Router) {
+		r.Get("/", h.ListTokens)
+		r.Post("/", h.CreateToken)
+		r.Delete("/{id}", h.DeleteToken)
+	})
+}
+
+func (h *SCIMHandler) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeSCIM(w, http.StatusOK, h.scimUseCase.ServiceProviderConfig(), "")
+}
+
+func (h *SCIMHandler) ListSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas := h.scimUseCase.Schemas()
+
+	response := &scim.ListResponse{
+		Schemas:      []string{scim.MessageListResponse},
+		TotalResults: len(schemas),
+		StartIndex:   1,
+		ItemsPerPage: len(schemas),
+	}
+	for _, schema := range schemas {
+		response.Resources = append(response.Resources, schema)
+	}
+
+	writeSCIM(w, http.StatusOK, response, "")
+}
+
+func (h *SCIMHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	for _, schema := range h.scimUseCase.Schemas() {
+		if schema.ID == chi.URLParam(r, "id") {
+			writeSCIM(w, http.StatusOK, schema, "")
+			return
+		}
+	}
+
+	WriteSCIMError(w, scim.NewError(http.StatusNotFound, "", "schema not found"))
+}
+
+func (h *SCIMHandler) ListResourceTypes(w http.ResponseWriter, r *http.Request) {
+	resourceTypes := h.scimUseCase.ResourceTypes()
+
+	response := &scim.ListResponse{
+		Schemas:      []string{scim.MessageListResponse},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+	}
+	for _, resourceType := range resourceTypes {
+		response.Resources = append(response.Resources, resourceType)
+	}
+
+	writeSCIM(w, http.StatusOK, response, "")
+}
+
+func (h *SCIMHandler) GetResourceType(w http.ResponseWriter, r *http.Request) {
+	for _, resourceType := range h.scimUseCase.ResourceTypes() {
+		if resourceType.ID == chi.URLParam(r, "id") {
+			writeSCIM(w, http.StatusOK, resourceType, "")
+			return
+		}
+	}
+
+	WriteSCIMError(w, scim.NewError(http.StatusNotFound, "", "resource type not found"))
+}
+
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSCIMListQuery(r)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	response, err := h.scimUseCase.ListUsers(r.Context(), query)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIM(w, http.StatusOK, response, "")
+}
+
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.scimUseCase.GetUser(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIMResource(w, r, http.StatusOK, user, user.Meta.Version)
+}
+
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var resource scim.User
+	if err := decodeSCIM(r, &resource); err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	user, err := h.scimUseCase.CreateUser(r.Context(), &resource)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", user.Meta.Location)
+	writeSCIM(w, http.StatusCreated, user, user.Meta.Version)
+}
+
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	var resource scim.User
+	if err := decodeSCIM(r, &resource); err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	user, err := h.scimUseCase.ReplaceUser(r.Context(), chi.URLParam(r, "id"), &resource, r.Header.Get("If-Match"))
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIMResource(w, r, http.StatusOK, user, user.Meta.Version)
+}
+
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	patch, err := decodeSCIMPatch(r)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	user, err := h.scimUseCase.PatchUser(r.Context(), chi.URLParam(r, "id"), patch, r.Header.Get("If-Match"))
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIMResource(w, r, http.StatusOK, user, user.Meta.Version)
+}
+
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.scimUseCase.DeleteUser(r.Context(), chi.URLParam(r, "id"), r.Header.Get("If-Match")); err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	query, err := parseSCIMListQuery(r)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	response, err := h.scimUseCase.ListGroups(r.Context(), query)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIM(w, http.StatusOK, response, "")
+}
+
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.scimUseCase.GetGroup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIMResource(w, r, http.StatusOK, group, group.Meta.Version)
+}
+
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var resource scim.Group
+	if err := decodeSCIM(r, &resource); err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	group, err := h.scimUseCase.CreateGroup(r.Context(), &resource)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", group.Meta.Location)
+	writeSCIM(w, http.StatusCreated, group, group.Meta.Version)
+}
+
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	var resource scim.Group
+	if err := decodeSCIM(r, &resource); err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	group, err := h.scimUseCase.ReplaceGroup(r.Context(), chi.URLParam(r, "id"), &resource, r.Header.Get("If-Match"))
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIMResource(w, r, http.StatusOK, group, group.Meta.Version)
+}
+
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	patch, err := decodeSCIMPatch(r)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	group, err := h.scimUseCase.PatchGroup(r.Context(), chi.URLParam(r, "id"), patch, r.Header.Get("If-Match"))
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	writeSCIMResource(w, r, http.StatusOK, group, group.Meta.Version)
+}
+
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.scimUseCase.DeleteGroup(r.Context(), chi.URLParam(r, "id"), r.Header.Get("If-Match")); err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.scimUseCase.ListTokens(r.Context())
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, tokens, "SCIM tokens retrieved successfully")
+}
+
+func (h *SCIMHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateSCIMTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	token, err := h.scimUseCase.CreateToken(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "creation_failed", err)
+		return
+	}
+
+	WriteSuccess(w, token, "SCIM token created; store it now, it will not be shown again")
+}
+
+func (h *SCIMHandler) DeleteToken(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid token ID")
+		return
+	}
+
+	if err := h.scimUseCase.DeleteToken(r.Context(), tokenID); err != nil {
+		WriteNotFound(w, "SCIM token not found")
+		return
+	}
+
+	WriteSuccess(w, nil, "SCIM token revoked successfully")
+}
+
+// WriteSCIMError writes err in the SCIM error format. Errors that are not *scim.Error
+// are reported as internal errors.
+func WriteSCIMError(w http.ResponseWriter, err error) {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		scimErr = scim.NewError(http.StatusInternalServerError, "", err.Error())
+	}
+
+	writeSCIM(w, scimErr.HTTPStatus(), scimErr, "")
+}
+
+func writeSCIM(w http.ResponseWriter, status int, body interface{}, version string) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	if version != "" {
+		w.Header().Set("ETag", version)
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeSCIMResource writes a single resource, honoring If-None-Match and the attributes
+// and excludedAttributes query parameters
+func writeSCIMResource(w http.ResponseWriter, r *http.Request, status int, resource interface{}, version string) {
+	if r.Method == http.MethodGet && version != "" && r.Header.Get("If-None-Match") == version {
+		w.Header().Set("ETag", version)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	object, err := scim.ToMap(resource)
+	if err != nil {
+		WriteSCIMError(w, err)
+		return
+	}
+
+	query := r.URL.Query()
+	writeSCIM(w, status, scim.Project(object, splitSCIMList(query.Get("attributes")), splitSCIMList(query.Get("excludedAttributes"))), version)
+}
+
+func decodeSCIM(r *http.Request, target interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(target); err != nil {
+		return scim.Errorf(scim.ErrorInvalidSyntax, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func decodeSCIMPatch(r *http.Request) (*scim.PatchRequest, error) {
+	var patch scim.PatchRequest
+	if err := decodeSCIM(r, &patch); err != nil {
+		return nil, err
+	}
+	if len(patch.Operations) == 0 {
+		return nil, scim.Errorf(scim.ErrorInvalidSyntax, "Operations is required")
+	}
+	return &patch, nil
+}
+
+func parseSCIMListQuery(r *http.Request) (*usecase.SCIMListQuery, error) {
+	values := r.URL.Query()
+	query := &usecase.SCIMListQuery{
+		Filter:             values.Get("filter"),
+		StartIndex:         1,
+		Count:              -1,
+		Attributes:         splitSCIMList(values.Get("attributes")),
+		ExcludedAttributes: splitSCIMList(values.Get("excludedAttributes")),
+	}
+
+	if startIndex := values.Get("startIndex"); startIndex != "" {
+		n, err := strconv.Atoi(startIndex)
+		if err != nil {
+			return nil, scim.Errorf(scim.ErrorInvalidValue, "invalid startIndex")
+		}
+		query.StartIndex = n
+	}
+	if count := values.Get("count"); count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return nil, scim.Errorf(scim.ErrorInvalidValue, "invalid count")
+		}
+		if n < 0 {
+			n = 0
+		}
+		query.Count = n
+	}
+
+	return query, nil
+}
+
+func splitSCIMList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,191 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/usecase"
+)
+
+// SCIMTargetHandler manages downstream applications that users and groups are pushed to over SCIM
+type SCIMTargetHandler struct {
+	scimSyncUseCase *usecase.SCIMSyncUseCase
+	validator       *validator.Validate
+}
+
+func NewSCIMTargetHandler(scimSyncUseCase *usecase.SCIMSyncUseCase) *SCIMTargetHandler {
+	return &SCIMTargetHandler{
+		scimSyncUseCase: scimSyncUseCase,
+		validator:       validator.New(),
+	}
+}
+
+// RegisterRoutes registers SCIM target administration; callers must restrict it to administrators
+func (h *SCIMTargetHandler) RegisterRoutes(r chi.Router) {
+	r.Route("/admin/scim/targets", func(r chi.Router) {
+		r.Get("/", h.ListTargets)
+		r.Post("/", h.CreateTarget)
+		r.Get("/{id}", h.GetTarget)
+		r.Put("/{id}", h.UpdateTarget)
+		r.Delete("/{id}", h.DeleteTarget)
+		r.Get("/{id}/state", h.ListSyncState)
+		r.Post("/{id}/reconcile", h.Reconcile)
+		r.Post("/{id}/retry", h.RetryFailed)
+	})
+}
+
+func (h *SCIMTargetHandler) ListTargets(w http.ResponseWriter, r *http.Request) {
+	targets, err := h.scimSyncUseCase.ListTargets(r.Context())
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, targets, "SCIM targets retrieved successfully")
+}
+
+func (h *SCIMTargetHandler) GetTarget(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid target ID")
+		return
+	}
+
+	target, err := h.scimSyncUseCase.GetTarget(r.Context(), targetID)
+	if err != nil {
+		WriteNotFound(w, "SCIM target not found")
+		return
+	}
+
+	WriteSuccess(w, target, "SCIM target retrieved successfully")
+}
+
+func (h *SCIMTargetHandler) CreateTarget(w http.ResponseWriter, r *http.Request) {
+	var req domain.CreateSCIMTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	target, err := h.scimSyncUseCase.CreateTarget(r.Context(), &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "creation_failed", err)
+		return
+	}
+
+	WriteSuccess(w, target, "SCIM target created successfully")
+}
+
+func (h *SCIMTargetHandler) UpdateTarget(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid target ID")
+		return
+	}
+
+	var req domain.UpdateSCIMTargetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	target, err := h.scimSyncUseCase.UpdateTarget(r.Context(), targetID, &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "update_failed", err)
+		return
+	}
+
+	WriteSuccess(w, target, "SCIM target updated successfully")
+}
+
+func (h *SCIMTargetHandler) DeleteTarget(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid target ID")
+		return
+	}
+
+	if err := h.scimSyncUseCase.DeleteTarget(r.Context(), targetID); err != nil {
+		WriteNotFound(w, "SCIM target not found")
+		return
+	}
+
+	WriteSuccess(w, nil, "SCIM target deleted successfully")
+}
+
+// ListSyncState lists the sync state of a target, optionally filtered by ?status=
+func (h *SCIMTargetHandler) ListSyncState(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid target ID")
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", domain.SCIMSyncPending, domain.SCIMSyncSynced, domain.SCIMSyncFailed, domain.SCIMSyncDryRun:
+	default:
+		WriteValidationError(w, "Invalid status")
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	response, err := h.scimSyncUseCase.ListSyncState(r.Context(), targetID, status, page, limit)
+	if err != nil {
+		WriteNotFound(w, "SCIM target not found")
+		return
+	}
+
+	WriteSuccess(w, response, "SCIM sync state retrieved successfully")
+}
+
+// Reconcile re-enqueues every user and group for the target
+func (h *SCIMTargetHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid target ID")
+		return
+	}
+
+	if err := h.scimSyncUseCase.Reconcile(r.Context(), targetID); err != nil {
+		WriteError(w, http.StatusBadRequest, "reconcile_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "SCIM reconciliation started")
+}
+
+// RetryFailed schedules failed deliveries of the target again
+func (h *SCIMTargetHandler) RetryFailed(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid target ID")
+		return
+	}
+
+	count, err := h.scimSyncUseCase.RetryFailed(r.Context(), targetID)
+	if err != nil {
+		WriteNotFound(w, "SCIM target not found")
+		return
+	}
+
+	WriteSuccess(w, map[string]int{"retried": count}, "Failed SCIM deliveries rescheduled")
+}
@@ -14,14 +14,18 @@ import (
 )
 
 type UserHandler struct {
-	userUseCase *usecase.UserUseCase
-	validator   *validator.Validate
+	userUseCase        *usecase.UserUseCase
+	emailChangeUseCase *usecase.EmailChangeUseCase
+	identityUseCase    *usecase.IdentityUseCase
+	validator          *validator.Validate
 }
 
-func NewUserHandler(userUseCase *usecase.UserUseCase) *UserHandler {
+func NewUserHandler(userUseCase *usecase.UserUseCase, emailChangeUseCase *usecase.EmailChangeUseCase, identityUseCase *usecase.IdentityUseCase) *UserHandler {
 	return &UserHandler{
-		userUseCase: userUseCase,
-		validator:   validator.New(),
+		userUseCase:        userUseCase,
+		emailChangeUseCase: emailChangeUseCase,
+		identityUseCase:    identityUseCase,
+		validator:          validator.New(),
 	}
 }
 
@@ -29,12 +33,28 @@ func (h *UserHandler) RegisterRoutes(r chi.Router) {
 	r.Route("/users", func(r chi.Router) {
 		r.Get("/", h.ListUsers)
 		r.Get("/me", h.GetCurrentUser)
+		r.Get("/me/email", h.GetEmailChange)
+		r.Post("/me/email", h.RequestEmailChange)
+		r.Delete("/me/email", h.CancelEmailChange)
+		r.Get("/me/identities", h.ListIdentities)
+		r.Post("/me/identities", h.LinkIdentity)
+		r.Post("/me/identities/{provider}/start", h.StartIdentityLink)
+		r.Delete("/me/identities/{id}", h.UnlinkIdentity)
+		r.Post("/me/reauthenticate/{provider}/start", h.StartReauthentication)
 		r.Get("/{id}", h.GetUser)
 		r.Put("/{id}", h.UpdateUser)
 		r.Delete("/{id}", h.DeleteUser)
 	})
 }
 
+// RegisterAdminRoutes registers account administration routes; callers must restrict them to administrators
+func (h *UserHandler) RegisterAdminRoutes(r chi.Router) {
+	r.Route("/admin/users/{id}", func(r chi.Router) {
+		r.Get("/lockout", h.GetLockoutStatus)
+		r.Post("/unlock", h.UnlockUser)
+	})
+}
+
 func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
 	pageStr := r.URL.Query().Get("page")
@@ -148,3 +168,215 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	WriteSuccess(w, nil, "User deleted successfully")
 }
 
+func (h *UserHandler) GetLockoutStatus(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid user ID")
+		return
+	}
+
+	status, err := h.userUseCase.GetLockoutStatus(r.Context(), userID)
+	if err != nil {
+		WriteNotFound(w, "User not found")
+		return
+	}
+
+	WriteSuccess(w, status, "Lockout status retrieved successfully")
+}
+
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userIDStr := chi.URLParam(r, "id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		WriteValidationError(w, "Invalid user ID")
+		return
+	}
+
+	if err := h.userUseCase.UnlockUser(r.Context(), userID); err != nil {
+		WriteError(w, http.StatusBadRequest, "unlock_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "User unlocked successfully")
+}
+
+func (h *UserHandler) GetEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	change, err := h.emailChangeUseCase.GetPendingChange(r.Context(), userID)
+	if err != nil {
+		WriteNotFound(w, "No pending email change")
+		return
+	}
+
+	WriteSuccess(w, change, "Pending email change retrieved successfully")
+}
+
+func (h *UserHandler) RequestEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	change, err := h.emailChangeUseCase.RequestChange(r.Context(), userID, &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "email_change_failed", err)
+		return
+	}
+
+	WriteSuccess(w, change, "Confirmation email sent to the new address")
+}
+
+func (h *UserHandler) CancelEmailChange(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.emailChangeUseCase.CancelOwnChange(r.Context(), userID); err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Email change cancelled")
+}
+
+func (h *UserHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	identities, err := h.identityUseCase.ListIdentities(r.Context(), userID)
+	if err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, identities, "Identities retrieved successfully")
+}
+
+// LinkIdentity links a directory account by its credentials
+func (h *UserHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req domain.LinkIdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		WriteValidationError(w, "Invalid request body")
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		WriteValidationError(w, err.Error())
+		return
+	}
+
+	identity, err := h.identityUseCase.LinkWithPassword(r.Context(), userID, &req)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "link_failed", err)
+		return
+	}
+
+	WriteSuccess(w, identity, "Identity linked successfully")
+}
+
+// StartIdentityLink begins linking an OIDC or SAML identity. The client sends the browser
+// to authorization_url; the provider's usual callback completes the link.
+func (h *UserHandler) StartIdentityLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	authURL, login, err := h.identityUseCase.StartLink(r.Context(), chi.URLParam(r, "provider"), userID)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "link_failed", err)
+		return
+	}
+
+	if err := setFederatedLogin(w, r, login); err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]string{"authorization_url": authURL}, "Continue at the identity provider to link the account")
+}
+
+// StartReauthentication begins a sign-in through a linked OIDC or SAML identity. The
+// client sends the browser to authorization_url; the provider's usual callback returns a
+// reauth_token instead of a session.
+func (h *UserHandler) StartReauthentication(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	authURL, login, err := h.identityUseCase.StartReauthentication(r.Context(), chi.URLParam(r, "provider"), userID)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, "reauthentication_failed", err)
+		return
+	}
+
+	if err := setFederatedLogin(w, r, login); err != nil {
+		WriteInternalError(w, err)
+		return
+	}
+
+	WriteSuccess(w, map[string]string{"authorization_url": authURL}, "Continue at the identity provider to confirm your identity")
+}
+
+func (h *UserHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	identityID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		WriteValidationError(w, "Invalid identity ID")
+		return
+	}
+
+	if err := h.identityUseCase.Unlink(r.Context(), userID, identityID); err != nil {
+		WriteError(w, http.StatusBadRequest, "unlink_failed", err)
+		return
+	}
+
+	WriteSuccess(w, nil, "Identity unlinked successfully")
+}
+
+// currentUserID reads the authenticated user's ID set by the auth middleware,
+// writing an unauthorized response when it is missing or malformed
+func currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userIDStr, ok := r.Context().Value("user_id").(string)
+	if !ok {
+		WriteUnauthorized(w, "User not authenticated")
+		return uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		WriteUnauthorized(w, "Invalid user ID")
+		return uuid.Nil, false
+	}
+
+	return userID, true
+}
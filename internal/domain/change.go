@@ -0,0 +1,17 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// ChangeListener is notified after users, groups and group members are written, whatever
+// the path that wrote them. Implementations should return quickly and handle their own
+// failures.
+type ChangeListener interface {
+	UserChanged(ctx context.Context, userID uuid.UUID)
+	UserDeleted(ctx context.Context, userID uuid.UUID)
+	GroupChanged(ctx context.Context, groupID uuid.UUID)
+	GroupDeleted(ctx context.Context, groupID uuid.UUID)
+}
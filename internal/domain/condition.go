@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// AccessContext is what the conditions of role grants are evaluated against
+type AccessContext struct {
+	// IP is the address of the client the access is for
+	IP string `json:"ip,omitempty"`
+	// Time defaults to the time of the check
+	Time *time.Time `json:"time,omitempty"`
+	// User and Resource hold caller-supplied attributes, e.g. {"department": "sales"}
+	User     map[string]interface{} `json:"user,omitempty"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
+	Action   string                 `json:"-"`
+}
+
+// GrantConditions are the conditions along one path granting a permission: on the
+// assignment of the role (to the user, a group or on a resource instance) and on the
+// link between the role and the permission. Empty conditions always hold.
+type GrantConditions struct {
+	Assignment string `json:"assignment,omitempty"`
+	Permission string `json:"permission,omitempty"`
+}
+
+// Unconditional reports whether the path grants the permission in any context
+func (g GrantConditions) Unconditional() bool {
+	return g.Assignment == "" && g.Permission == ""
+}
+
+type ConditionEvaluator interface {
+	Validate(expression string) error
+	Evaluate(expression string, ctx *AccessContext) (bool, error)
+}
+
+// ConditionsHold reports whether the conditions of any of the paths hold in the context.
+// Conditions that fail to evaluate do not hold.
+func ConditionsHold(evaluator ConditionEvaluator, grants []GrantConditions, ctx *AccessContext) bool {
+	for _, grant := range grants {
+		if grant.Unconditional() {
+			return true
+		}
+	}
+
+	for _, grant := range grants {
+		if ok, err := evaluator.Evaluate(grant.Assignment, ctx); err != nil || !ok {
+			continue
+		}
+		if ok, err := evaluator.Evaluate(grant.Permission, ctx); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
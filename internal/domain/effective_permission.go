@@ -0,0 +1,49 @@
+package domain
+
+import "github.com/google/uuid"
+
+// PermissionSource is one way a user holds an effective permission: a role assigned to
+// the user (Via "user") or to a group the user is an effective member of (Via "group"),
+// and the role holding the permission, which the assigned role is or inherits from
+type PermissionSource struct {
+	Via              string     `json:"via"`
+	GroupID          *uuid.UUID `json:"group_id,omitempty"`
+	GroupName        string     `json:"group_name,omitempty"`
+	AssignedRoleID   uuid.UUID  `json:"assigned_role_id"`
+	AssignedRoleName string     `json:"assigned_role_name"`
+	RoleID           uuid.UUID  `json:"role_id"`
+	RoleName         string     `json:"role_name"`
+	// Conditions of the assignment and of the role-permission link, if any
+	AssignmentCondition string `json:"assignment_condition,omitempty"`
+	PermissionCondition string `json:"permission_condition,omitempty"`
+}
+
+// Unconditional reports whether the source grants the permission in any context
+func (s PermissionSource) Unconditional() bool {
+	return s.AssignmentCondition == "" && s.PermissionCondition == ""
+}
+
+// EffectivePermission is a permission a user holds globally, with every source of it
+type EffectivePermission struct {
+	PermissionID uuid.UUID          `json:"permission_id"`
+	Resource     string             `json:"resource"`
+	Action       string             `json:"action"`
+	Description  string             `json:"description"`
+	Sources      []PermissionSource `json:"sources"`
+	// Conditional is set when every source of the permission has a condition
+	Conditional bool `json:"conditional"`
+	// DeniedBy is a deny rule of the user covering the whole permission, if any. Rules
+	// covering part of it, e.g. users:delete of users:*, are only applied at checks.
+	DeniedBy *DenyRule `json:"denied_by,omitempty"`
+}
+
+// PermissionHolder is a user who can perform an action on a resource
+type PermissionHolder struct {
+	UserID    uuid.UUID  `json:"user_id"`
+	Email     string     `json:"email"`
+	FirstName string     `json:"first_name"`
+	LastName  string     `json:"last_name"`
+	Status    UserStatus `json:"status"`
+	// Conditional is set when the user holds the permission through conditional grants only
+	Conditional bool `json:"conditional"`
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChange is a pending request to change a user's email address. The change is only
+// applied once the new address confirms it; the old address can cancel it until then.
+type EmailChange struct {
+	ID              uuid.UUID  `json:"id" db:"id"`
+	UserID          uuid.UUID  `json:"user_id" db:"user_id"`
+	OldEmail        string     `json:"old_email" db:"old_email"`
+	NewEmail        string     `json:"new_email" db:"new_email"`
+	TokenHash       string     `json:"-" db:"token_hash"`
+	CancelTokenHash string     `json:"-" db:"cancel_token_hash"`
+	RevokeSessions  bool       `json:"revoke_sessions" db:"revoke_sessions"`
+	ExpiresAt       time.Time  `json:"expires_at" db:"expires_at"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	CancelledAt     *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ErrEmailChangeNotPending is returned when an email change was already confirmed or
+// cancelled, has expired, or the account no longer has the address it was requested from
+var ErrEmailChangeNotPending = errors.New("email change request is no longer pending")
+
+// EmailChangeRepository handles email change request persistence. Lookups only
+// return requests that are neither confirmed, cancelled nor expired.
+type EmailChangeRepository interface {
+	Create(change *EmailChange) error
+	GetPendingByUserID(userID uuid.UUID) (*EmailChange, error)
+	GetByTokenHash(tokenHash string) (*EmailChange, error)
+	GetByCancelTokenHash(cancelTokenHash string) (*EmailChange, error)
+	// Confirm marks the change confirmed and moves the user to the new address atomically
+	Confirm(change *EmailChange) error
+	MarkCancelled(id uuid.UUID) error
+	CancelPendingByUserID(userID uuid.UUID) error
+}
+
+type ChangeEmailRequest struct {
+	NewEmail        string `json:"new_email" validate:"required,email"`
+	CurrentPassword string `json:"current_password" validate:"required_without=ReauthToken"`
+	// ReauthToken is returned by a re-authentication through a linked sign-in provider,
+	// for users without a password
+	ReauthToken string `json:"reauth_token" validate:"required_without=CurrentPassword"`
+	// RevokeSessions signs out every other session once the change is confirmed
+	RevokeSessions bool `json:"revoke_sessions"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
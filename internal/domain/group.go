@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,26 +15,59 @@ type Group struct {
 	IsActive    bool      `json:"is_active" db:"is_active"`
 	IsDeleted   bool      `json:"is_deleted" db:"is_deleted"`
 	IsSystem    bool      `json:"is_system" db:"is_system"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// PasswordlessEnabled allows members to sign in with an emailed link or code
+	PasswordlessEnabled bool      `json:"passwordless_enabled" db:"passwordless_enabled"`
+	CreatedAt           time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type CreateGroupRequest struct {
-	Name        string `json:"name" validate:"required,min=1,max=100"`
-	Description string `json:"description"`
-	IsActive    *bool  `json:"is_active,omitempty"` // optional, default true
+	Name                string `json:"name" validate:"required,min=1,max=100"`
+	Description         string `json:"description"`
+	IsActive            *bool  `json:"is_active,omitempty"` // optional, default true
+	PasswordlessEnabled bool   `json:"passwordless_enabled,omitempty"`
 }
 
 type UpdateGroupRequest struct {
-	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
-	Description *string `json:"description,omitempty"`
-	IsActive    *bool   `json:"is_active,omitempty"`
+	Name                *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description         *string `json:"description,omitempty"`
+	IsActive            *bool   `json:"is_active,omitempty"`
+	PasswordlessEnabled *bool   `json:"passwordless_enabled,omitempty"`
 }
 
 type AddMemberRequest struct {
 	UserID uuid.UUID `json:"user_id" validate:"required"`
 }
 
+type AddSubgroupRequest struct {
+	GroupID uuid.UUID `json:"group_id" validate:"required"`
+}
+
+// ErrGroupCycle is returned when a subgroup would make a group contain itself
+var ErrGroupCycle = errors.New("nested group would create a cycle")
+
+// MembershipScope selects direct group membership or effective membership, which also
+// follows nested groups: members of a subgroup are effective members of every group
+// containing it, directly or transitively
+type MembershipScope string
+
+const (
+	MembershipDirect    MembershipScope = "direct"
+	MembershipEffective MembershipScope = "effective"
+)
+
+// ParseMembershipScope parses a membership query parameter; empty means effective
+func ParseMembershipScope(value string) (MembershipScope, error) {
+	switch MembershipScope(value) {
+	case "", MembershipEffective:
+		return MembershipEffective, nil
+	case MembershipDirect:
+		return MembershipDirect, nil
+	default:
+		return "", fmt.Errorf("membership must be direct or effective")
+	}
+}
+
 type GroupRepository interface {
 	Create(group *Group) error
 	GetByID(id uuid.UUID) (*Group, error)
@@ -42,6 +77,9 @@ type GroupRepository interface {
 	Count() (int, error)
 	AddMember(groupID, userID uuid.UUID) error
 	RemoveMember(groupID, userID uuid.UUID) error
-	GetMembers(groupID uuid.UUID) ([]*User, error)
-	GetUserGroups(userID uuid.UUID) ([]*Group, error)
+	GetMembers(groupID uuid.UUID, scope MembershipScope) ([]*User, error)
+	GetUserGroups(userID uuid.UUID, scope MembershipScope) ([]*Group, error)
+	AddSubgroup(groupID, subgroupID uuid.UUID) error
+	RemoveSubgroup(groupID, subgroupID uuid.UUID) error
+	GetSubgroups(groupID uuid.UUID, scope MembershipScope) ([]*Group, error)
 }
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserIdentity links a local user to an account at an external identity provider. Users
+// signing in through the provider are found by (provider, subject), not by email.
+type UserIdentity struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	Provider string    `json:"provider" db:"provider"`
+	// Subject is the provider's stable identifier of the account, e.g. the OIDC sub claim
+	Subject string `json:"subject" db:"subject"`
+	// Email is the address the provider asserted when the identity was linked
+	Email       string     `json:"email" db:"email"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty" db:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ErrIdentityNotFound is returned by UserIdentityRepository lookups that match no identity
+var ErrIdentityNotFound = errors.New("identity not found")
+
+// UserIdentityRepository handles external identity link persistence
+type UserIdentityRepository interface {
+	Create(identity *UserIdentity) error
+	GetByID(id uuid.UUID) (*UserIdentity, error)
+	GetByProviderSubject(provider, subject string) (*UserIdentity, error)
+	GetByUserID(userID uuid.UUID) ([]*UserIdentity, error)
+	UpdateLastLogin(id uuid.UUID) error
+	Delete(id uuid.UUID) error
+}
+
+// LinkIdentityRequest links a directory (LDAP) account by its credentials
+type LinkIdentityRequest struct {
+	Provider string `json:"provider" validate:"required"`
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// TokenScopeIdentityLink marks a token that only authorizes linking an external identity
+// to its user at the end of a browser sign-in
+const TokenScopeIdentityLink = "identity_link"
+
+// TokenScopeReauthenticationLogin marks the token a re-authentication sign-in carries
+// from its start to the provider's callback
+const TokenScopeReauthenticationLogin = "reauthentication_login"
+
+// TokenScopeReauthentication marks a token proving that the user just signed in again
+// through a linked provider. It stands in for the current password of users who have
+// none, e.g. when changing the email address.
+const TokenScopeReauthentication = "reauthentication"
+
+type identityLinkKey struct{}
+
+// WithIdentityLink marks ctx so that an external sign-in links the identity to userID
+// instead of signing in the user the identity belongs to
+func WithIdentityLink(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, identityLinkKey{}, userID)
+}
+
+// IdentityLinkTarget returns the user an external identity is being linked to, if any
+func IdentityLinkTarget(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(identityLinkKey{}).(uuid.UUID)
+	return userID, ok
+}
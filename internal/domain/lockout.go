@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// LockoutPolicy defines how failed logins are throttled per account
+type LockoutPolicy struct {
+	// FreeAttempts is the number of consecutive failures allowed before delays apply
+	FreeAttempts int
+	// BaseDelay is the delay after the first failure beyond FreeAttempts; it doubles per further failure
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff delay
+	MaxDelay time.Duration
+	// MaxAttempts is the number of consecutive failures that triggers a full lockout (0 disables it)
+	MaxAttempts int
+	// LockoutDuration is how long an account stays locked once MaxAttempts is reached
+	LockoutDuration time.Duration
+	// ResetAfter is the idle period after which the failure counter starts over
+	ResetAfter time.Duration
+}
+
+// LoginAttempts is the failed-login state tracked for one account key
+type LoginAttempts struct {
+	Key           string     `json:"-" db:"key"`
+	Failures      int        `json:"failures" db:"failures"`
+	LockedUntil   *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	LastFailureAt *time.Time `json:"last_failure_at,omitempty" db:"last_failure_at"`
+}
+
+// LoginAttemptStore persists failed-login counters. Keys are opaque and never contain
+// the raw email. RecordFailure must be atomic so concurrent failures are all counted.
+type LoginAttemptStore interface {
+	Get(key string) (*LoginAttempts, error)
+	RecordFailure(key string, resetAfter time.Duration) (*LoginAttempts, error)
+	SetLockedUntil(key string, until time.Time) error
+	Reset(key string) error
+	PurgeExpired(resetAfter time.Duration) error
+}
+
+// RateLimiter counts requests per key in fixed windows
+type RateLimiter interface {
+	// Allow records a request and reports whether it is within limit, and if not, how long until the window resets
+	Allow(key string, limit int, window time.Duration) (bool, time.Duration, error)
+	PurgeExpired() error
+}
+
+// LockoutService guards credential checks against brute force. Accounts are identified
+// by the submitted login name so unknown emails are throttled exactly like real ones.
+type LockoutService interface {
+	Check(ctx context.Context, identifier string) error
+	RecordFailure(ctx context.Context, identifier string) error
+	RecordSuccess(ctx context.Context, identifier string) error
+	Status(ctx context.Context, identifier string) (*LoginAttempts, error)
+	Unlock(ctx context.Context, identifier string) error
+}
+
+// RetryAfterError is returned when a request is throttled or an account is temporarily locked
+type RetryAfterError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("too many attempts, retry after %d seconds", e.Seconds())
+}
+
+// Seconds returns the retry delay rounded up to whole seconds, as used by the Retry-After header
+func (e *RetryAfterError) Seconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordPolicy defines history and expiry rules for local passwords
+type PasswordPolicy struct {
+	// HistorySize is the number of most recent passwords that cannot be reused (0 disables the check)
+	HistorySize int
+	// MaxAge is how long a password stays valid before it must be rotated (0 disables expiry)
+	MaxAge time.Duration
+	// ExpiringRoles limits expiry to users holding one of these roles; empty applies it to everyone
+	ExpiringRoles []string
+	// ResetTokenExpiry is the lifetime of password reset tokens
+	ResetTokenExpiry time.Duration
+}
+
+// PasswordHistoryRepository stores previous password hashes per user
+type PasswordHistoryRepository interface {
+	Add(userID uuid.UUID, passwordHash string) error
+	GetRecent(userID uuid.UUID, limit int) ([]string, error)
+	Prune(userID uuid.UUID, keep int) error
+}
+
+// PasswordResetToken represents a one-time password reset token
+type PasswordResetToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ErrInvalidResetToken is returned for reset tokens that are unknown, expired or used
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// PasswordResetTokenRepository handles password reset token persistence
+type PasswordResetTokenRepository interface {
+	Create(token *PasswordResetToken) error
+	// Consume marks the unused, unexpired token with tokenHash as used and calls apply with
+	// its user while the token is locked. The token is only spent when apply succeeds, and
+	// only one of several concurrent calls for the same token succeeds.
+	Consume(tokenHash string, apply func(userID uuid.UUID) error) error
+	DeleteByUserID(userID uuid.UUID) error
+}
+
+// EmailSender delivers transactional emails (password resets, notifications)
+type EmailSender interface {
+	SendEmail(ctx context.Context, to, subject, body string) error
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordlessPolicy defines how emailed sign-in links and codes behave
+type PasswordlessPolicy struct {
+	// Expiry is the lifetime of a challenge (both its link and its code)
+	Expiry time.Duration
+	// MaxAttempts is the number of code guesses allowed before the challenge is invalidated
+	MaxAttempts int
+}
+
+// PasswordlessChallenge is a pending passwordless sign-in. Only hashes of the link token,
+// the code and the browser binding are stored.
+type PasswordlessChallenge struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	Provider    string     `json:"provider" db:"provider"`
+	TokenHash   string     `json:"-" db:"token_hash"`
+	CodeHash    string     `json:"-" db:"code_hash"`
+	BindingHash string     `json:"-" db:"binding_hash"`
+	Attempts    int        `json:"attempts" db:"attempts"`
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`
+	ConsumedAt  *time.Time `json:"consumed_at,omitempty" db:"consumed_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+}
+
+// PasswordlessChallengeRepository handles passwordless challenge persistence.
+// Lookups only return challenges that are unconsumed and unexpired.
+type PasswordlessChallengeRepository interface {
+	Create(challenge *PasswordlessChallenge) error
+	GetByID(id uuid.UUID) (*PasswordlessChallenge, error)
+	GetByTokenHash(tokenHash string) (*PasswordlessChallenge, error)
+	IncrementAttempts(id uuid.UUID) (int, error)
+	Consume(id uuid.UUID) error
+	DeleteByUserID(userID uuid.UUID) error
+	DeleteExpired() error
+}
+
+type StartPasswordlessRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// CompletePasswordlessRequest redeems either the emailed link token or the
+// challenge ID together with the emailed six-digit code
+type CompletePasswordlessRequest struct {
+	Token       string     `json:"token,omitempty"`
+	ChallengeID *uuid.UUID `json:"challenge_id,omitempty"`
+	Code        string     `json:"code,omitempty" validate:"omitempty,len=6,numeric"`
+}
+
+type SetPasswordlessRequest struct {
+	Enabled bool `json:"enabled"`
+}
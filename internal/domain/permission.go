@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,6 +18,70 @@ type Permission struct {
 	IsSystem    bool      `json:"is_system" db:"is_system"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// Condition is set on the permissions of a role whose link to the role is conditional
+	Condition string `json:"condition,omitempty" db:"-"`
+}
+
+// PermissionWildcard matches every resource or every action in a granted permission,
+// e.g. "*:*", "users:*" or "*:read". A resource ending in ".*" matches the resources
+// below it but not the resource itself.
+const PermissionWildcard = "*"
+
+// Matches reports whether the permission, as granted, covers resource and action.
+// Resources are hierarchical with "." as separator: a grant on "billing" also covers
+// "billing.invoices" and "billing.invoices.lines".
+func (p *Permission) Matches(resource, action string) bool {
+	return PermissionMatches(p.Resource, p.Action, resource, action)
+}
+
+// PermissionMatches reports whether a granted resource and action pattern covers the
+// requested resource and action
+func PermissionMatches(grantedResource, grantedAction, resource, action string) bool {
+	if grantedAction != PermissionWildcard && grantedAction != action {
+		return false
+	}
+	return ResourceMatches(grantedResource, resource)
+}
+
+// ResourceMatches reports whether a granted resource pattern covers resource
+func ResourceMatches(granted, resource string) bool {
+	switch {
+	case granted == PermissionWildcard || granted == resource:
+		return true
+	case strings.HasSuffix(granted, ".*"):
+		return strings.HasPrefix(resource, strings.TrimSuffix(granted, "*"))
+	default:
+		return strings.HasPrefix(resource, granted+".")
+	}
+}
+
+// ParsePermission splits a "resource:action" string
+func ParsePermission(permission string) (resource, action string, err error) {
+	resource, action, ok := strings.Cut(permission, ":")
+	if !ok || resource == "" || action == "" || strings.Contains(action, ":") {
+		return "", "", fmt.Errorf("invalid permission %q, expected resource:action", permission)
+	}
+	return resource, action, nil
+}
+
+// ValidatePermissionPattern rejects wildcards that PermissionMatches would not honor,
+// such as "bill*" or "billing.*.read"
+func ValidatePermissionPattern(resource, action string) error {
+	if strings.Contains(action, PermissionWildcard) && action != PermissionWildcard {
+		return fmt.Errorf("action wildcard must be the whole action")
+	}
+	if resource == PermissionWildcard {
+		return nil
+	}
+	if strings.Contains(strings.TrimSuffix(resource, ".*"), PermissionWildcard) {
+		return fmt.Errorf("resource wildcard must be the whole resource or a trailing .*")
+	}
+	for _, segment := range strings.Split(resource, ".") {
+		if segment == "" {
+			return fmt.Errorf("resource %q has an empty segment", resource)
+		}
+	}
+	return nil
 }
 
 type CreatePermissionRequest struct {
@@ -34,12 +100,31 @@ type UpdatePermissionRequest struct {
 
 type AssignPermissionRequest struct {
 	PermissionID uuid.UUID `json:"permission_id" validate:"required"`
+	// Condition optionally restricts the link to contexts where the CEL expression holds
+	Condition string `json:"condition,omitempty" validate:"max=4096"`
 }
 
 type CheckPermissionRequest struct {
 	UserID   uuid.UUID `json:"user_id" validate:"required"`
 	Resource string    `json:"resource" validate:"required"`
 	Action   string    `json:"action" validate:"required"`
+	// ResourceID optionally names the instance of Resource being accessed, so that
+	// roles granted on that instance are considered besides global ones
+	ResourceID string `json:"resource_id,omitempty" validate:"max=255"`
+	// Context is what conditional grants are evaluated against
+	Context *AccessContext `json:"context,omitempty"`
+}
+
+// BatchCheckPermissionRequest checks up to 100 permissions at once
+type BatchCheckPermissionRequest struct {
+	Checks []CheckPermissionRequest `json:"checks" validate:"required,min=1,max=100,dive"`
+}
+
+// PermissionCheckGrants is what decides one check of a batch: the deny rule overriding
+// the grants, if any, and the conditions of the paths granting the permission
+type PermissionCheckGrants struct {
+	DenyRuleID *uuid.UUID
+	Grants     []GrantConditions
 }
 
 type PermissionRepository interface {
@@ -50,8 +135,17 @@ type PermissionRepository interface {
 	Delete(id uuid.UUID) error
 	List(limit, offset int) ([]*Permission, error)
 	Count() (int, error)
-	AssignToRole(roleID, permissionID uuid.UUID) error
+	AssignToRole(roleID, permissionID uuid.UUID, condition string) error
 	RemoveFromRole(roleID, permissionID uuid.UUID) error
 	GetRolePermissions(roleID uuid.UUID) ([]*Permission, error)
 	CheckUserPermission(userID uuid.UUID, resource, action string) (bool, error)
+	CheckUserResourcePermission(userID uuid.UUID, resource, resourceID, action string) (bool, error)
+	GetUserPermissionGrants(userID uuid.UUID, resource, resourceID, action string) ([]GrantConditions, error)
+	GetPermissionGrantsBatch(checks []CheckPermissionRequest) ([]PermissionCheckGrants, error)
+	GetUserDenyRule(userID uuid.UUID, resource, resourceID, action string) (*DenyRule, error)
+	GetUserPermissionPaths(userID uuid.UUID, resource, resourceID, action string) ([]*PermissionPath, error)
+	GetUserEffectivePermissions(userID uuid.UUID, limit, offset int) ([]*EffectivePermission, error)
+	CountUserEffectivePermissions(userID uuid.UUID) (int, error)
+	GetPermissionHolders(resource, action string, limit, offset int) ([]*PermissionHolder, error)
+	CountPermissionHolders(resource, action string) (int, error)
 }
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Ways a role reaches a user on a PermissionPath
+const (
+	PathViaUser          = "user"
+	PathViaGroup         = "group"
+	PathViaResourceGrant = "resource_grant"
+)
+
+// PathNode is a group, role or permission on a PermissionPath, with the flags deciding
+// whether the path grants anything
+type PathNode struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	IsActive  bool      `json:"is_active"`
+	IsDeleted bool      `json:"is_deleted"`
+}
+
+// PermissionPath is one derivation of a permission for a user: the user is a member of
+// Groups[0], which is nested in Groups[1] and so on; the last group (or the user itself
+// when Via is "user") is assigned Roles[0], which inherits Roles[1] and so on; and the
+// last role holds Permission.
+type PermissionPath struct {
+	Via string `json:"via"`
+	// ResourceGrantID is set when Via is "resource_grant"
+	ResourceGrantID     *uuid.UUID `json:"resource_grant_id,omitempty"`
+	Groups              []PathNode `json:"groups,omitempty"`
+	Roles               []PathNode `json:"roles"`
+	Permission          PathNode   `json:"permission"`
+	AssignmentCondition string     `json:"assignment_condition,omitempty"`
+	PermissionCondition string     `json:"permission_condition,omitempty"`
+	// Reasons explains why the path does not grant the permission; empty when it does
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// Blockers lists the inactive and deleted groups, roles and permission on the path,
+// which keep it from granting the permission regardless of conditions
+func (p *PermissionPath) Blockers() []string {
+	var blockers []string
+	check := func(kind string, node PathNode) {
+		switch {
+		case node.IsDeleted:
+			blockers = append(blockers, fmt.Sprintf("%s %q is deleted", kind, node.Name))
+		case !node.IsActive:
+			blockers = append(blockers, fmt.Sprintf("%s %q is inactive", kind, node.Name))
+		}
+	}
+
+	for _, group := range p.Groups {
+		check("group", group)
+	}
+	for _, role := range p.Roles {
+		check("role", role)
+	}
+	check("permission", p.Permission)
+
+	return blockers
+}
+
+// PermissionExplanation is the decision of a permission check with the paths behind it
+type PermissionExplanation struct {
+	HasPermission bool      `json:"has_permission"`
+	UserID        uuid.UUID `json:"user_id"`
+	Resource      string    `json:"resource"`
+	Action        string    `json:"action"`
+	ResourceID    string    `json:"resource_id,omitempty"`
+	// DeniedBy is the deny rule overriding the grants, if any
+	DeniedBy *DenyRule `json:"denied_by,omitempty"`
+	// Grants are the paths granting the permission
+	Grants []*PermissionPath `json:"grants"`
+	// NearMisses are the paths that would grant the permission but for an inactive or
+	// deleted group, role or permission, or a condition that does not hold
+	NearMisses []*PermissionPath `json:"near_misses"`
+}
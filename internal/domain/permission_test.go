@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// permissionMatchCase is a case of testdata/permission_matches.json, the table shared by
+// every implementation of the matcher: this package, the Go client and the SQL check
+type permissionMatchCase struct {
+	GrantedResource string `json:"granted_resource"`
+	GrantedAction   string `json:"granted_action"`
+	Resource        string `json:"resource"`
+	Action          string `json:"action"`
+	Want            bool   `json:"want"`
+}
+
+func TestPermissionMatches(t *testing.T) {
+	data, err := os.ReadFile("testdata/permission_matches.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []permissionMatchCase
+	if err := json.Unmarshal(data, &tests); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		got := PermissionMatches(tt.GrantedResource, tt.GrantedAction, tt.Resource, tt.Action)
+		if got != tt.Want {
+			t.Errorf("PermissionMatches(%q, %q, %q, %q) = %v, want %v",
+				tt.GrantedResource, tt.GrantedAction, tt.Resource, tt.Action, got, tt.Want)
+		}
+	}
+}
+
+func TestValidatePermissionPattern(t *testing.T) {
+	tests := []struct {
+		resource, action string
+		wantErr          bool
+	}{
+		{"users", "delete", false},
+		{"users", "*", false},
+		{"*", "read", false},
+		{"*", "*", false},
+		{"billing.invoices", "read", false},
+		{"billing.*", "read", false},
+		{"users", "del*", true},
+		{"users", "**", true},
+		{"bill*", "read", true},
+		{"billing.*.lines", "read", true},
+		{"billing.**", "read", true},
+		{"billing..invoices", "read", true},
+		{".billing", "read", true},
+		{"billing.", "read", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidatePermissionPattern(tt.resource, tt.action)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidatePermissionPattern(%q, %q) error = %v, wantErr %v", tt.resource, tt.action, err, tt.wantErr)
+		}
+	}
+}
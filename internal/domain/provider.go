@@ -2,7 +2,10 @@ package domain
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,14 +13,157 @@ import (
 
 // Provider represents an identity provider configuration
 type Provider struct {
-	ID        uuid.UUID       `json:"id" db:"id"`
-	Name      string          `json:"name" db:"name"`
-	Type      string          `json:"type" db:"type"`
-	Config    json.RawMessage `json:"config" db:"config"`
-	Enabled   bool            `json:"enabled" db:"enabled"`
-	IsSystem  bool            `json:"is_system" db:"is_system"`
-	CreatedAt time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+	ID       uuid.UUID       `json:"id" db:"id"`
+	Name     string          `json:"name" db:"name"`
+	Type     string          `json:"type" db:"type"`
+	Config   json.RawMessage `json:"config" db:"config"`
+	Enabled  bool            `json:"enabled" db:"enabled"`
+	IsSystem bool            `json:"is_system" db:"is_system"`
+	// PasswordlessEnabled allows users of this provider to sign in with an emailed link or code
+	PasswordlessEnabled bool `json:"passwordless_enabled" db:"passwordless_enabled"`
+	// Domains routes logins for these email domains to this provider (home-realm discovery)
+	Domains []string `json:"domains" db:"domains"`
+	// LoginPriority places the provider in the login chain tried in ascending order when
+	// neither the request nor the email domain selects a provider; nil keeps it out
+	LoginPriority *int `json:"login_priority,omitempty" db:"login_priority"`
+	// FallbackOn lists the failures (ProviderFailure*) after which the chain moves on to
+	// the next provider; any other failure ends the login
+	FallbackOn []string  `json:"fallback_on" db:"fallback_on"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Login failures a provider chain can fall back on
+const (
+	ProviderFailureNotFound           = "not_found"
+	ProviderFailureInvalidCredentials = "invalid_credentials"
+	ProviderFailureUnavailable        = "unavailable"
+)
+
+var (
+	// ErrUserNotFound is returned by Authenticate when the provider does not know the user,
+	// and by UserRepository.GetByID and GetByEmail when no user matches
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidCredentials is returned by Authenticate when the provider rejects the password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrProviderUnavailable is wrapped by Authenticate errors when the backend cannot be reached
+	ErrProviderUnavailable = errors.New("identity provider unavailable")
+	// ErrUntrustedCertificate is wrapped by AuthenticateCertificate errors when the
+	// provider does not trust the client certificate chain
+	ErrUntrustedCertificate = errors.New("client certificate is not trusted")
+)
+
+// ProviderInfo is the public description of an enabled provider for login pages
+type ProviderInfo struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// LoginURL starts browser sign-in for OIDC and SAML providers; password providers
+	// are used by passing their name to /auth/login
+	LoginURL string `json:"login_url,omitempty"`
+}
+
+// CreateProviderRequest configures a new identity provider
+type CreateProviderRequest struct {
+	Name          string          `json:"name" validate:"required,max=100"`
+	Type          string          `json:"type" validate:"required,oneof=local ldap oidc saml mtls webhook"`
+	Config        json.RawMessage `json:"config"`
+	Enabled       *bool           `json:"enabled"` // default: true
+	Domains       []string        `json:"domains" validate:"omitempty,dive,fqdn"`
+	LoginPriority *int            `json:"login_priority" validate:"omitempty,min=1"`
+	FallbackOn    []string        `json:"fallback_on" validate:"omitempty,dive,oneof=not_found invalid_credentials unavailable"` // default: not_found
+}
+
+// UpdateProviderRequest changes the configuration of an identity provider. Omitted
+// fields are left unchanged; the type cannot be changed. A login_priority of 0 removes
+// the provider from the login chain.
+type UpdateProviderRequest struct {
+	Config        json.RawMessage `json:"config"`
+	Enabled       *bool           `json:"enabled"`
+	Domains       []string        `json:"domains" validate:"omitempty,dive,fqdn"`
+	LoginPriority *int            `json:"login_priority" validate:"omitempty,min=0"`
+	FallbackOn    []string        `json:"fallback_on" validate:"omitempty,dive,oneof=not_found invalid_credentials unavailable"`
+}
+
+// Provider types stored in the providers table
+const (
+	ProviderTypeLocal   = "local"
+	ProviderTypeLDAP    = "ldap"
+	ProviderTypeOIDC    = "oidc"
+	ProviderTypeSAML    = "saml"
+	ProviderTypeMTLS    = "mtls"
+	ProviderTypeWebhook = "webhook"
+)
+
+// ProviderSecretPlaceholder replaces secret config values in provider responses. An update
+// sending it back keeps the stored value.
+const ProviderSecretPlaceholder = "********"
+
+// providerSecretKeys lists the config keys holding secrets for each provider type
+var providerSecretKeys = map[string][]string{
+	ProviderTypeLDAP:    {"bind_password"},
+	ProviderTypeOIDC:    {"client_secret"},
+	ProviderTypeSAML:    {"private_key"},
+	ProviderTypeWebhook: {"secret"},
+}
+
+// Redacted returns a copy of the provider whose secret config values are replaced by
+// ProviderSecretPlaceholder
+func (p *Provider) Redacted() *Provider {
+	redacted := *p
+
+	var config map[string]json.RawMessage
+	if err := json.Unmarshal(p.Config, &config); err != nil {
+		// Config that is not an object has no secret keys to keep
+		return &redacted
+	}
+
+	changed := false
+	for _, key := range providerSecretKeys[p.Type] {
+		if value, ok := config[key]; ok && string(value) != `""` && string(value) != "null" {
+			config[key] = json.RawMessage(`"` + ProviderSecretPlaceholder + `"`)
+			changed = true
+		}
+	}
+	if changed {
+		if encoded, err := json.Marshal(config); err == nil {
+			redacted.Config = encoded
+		}
+	}
+
+	return &redacted
+}
+
+// RestoreSecrets returns config with every secret key holding ProviderSecretPlaceholder
+// set back to its value in the stored config, so a redacted config can be sent back
+func (p *Provider) RestoreSecrets(config json.RawMessage) (json.RawMessage, error) {
+	var updated map[string]json.RawMessage
+	if err := json.Unmarshal(config, &updated); err != nil {
+		return nil, fmt.Errorf("invalid provider config: %w", err)
+	}
+
+	var stored map[string]json.RawMessage
+	if err := json.Unmarshal(p.Config, &stored); err != nil {
+		stored = nil
+	}
+
+	changed := false
+	for _, key := range providerSecretKeys[p.Type] {
+		var value string
+		if err := json.Unmarshal(updated[key], &value); err != nil || value != ProviderSecretPlaceholder {
+			continue
+		}
+		if previous, ok := stored[key]; ok {
+			updated[key] = previous
+		} else {
+			delete(updated, key)
+		}
+		changed = true
+	}
+	if !changed {
+		return config, nil
+	}
+
+	return json.Marshal(updated)
 }
 
 // IdentityProvider defines the interface for identity providers
@@ -54,6 +200,69 @@ type IdentityProvider interface {
 	IsEnabled() bool
 }
 
+// FederatedProvider is an identity provider that authenticates users by redirecting
+// the browser to an external issuer instead of checking a password
+type FederatedProvider interface {
+	IdentityProvider
+
+	// BeginLogin returns the issuer URL to redirect the browser to
+	BeginLogin(ctx context.Context, login *FederatedLogin) (string, error)
+
+	// CompleteLogin exchanges the authorization code returned to the callback and
+	// returns the local user for the asserted identity
+	CompleteLogin(ctx context.Context, login *FederatedLogin, code string) (*User, error)
+}
+
+// SAMLProvider is an identity provider that federates sign-in to a SAML 2.0 identity
+// provider, which posts signed assertions to the assertion consumer service (ACS)
+type SAMLProvider interface {
+	IdentityProvider
+
+	// BeginLogin returns the IdP URL to redirect the browser to (SP-initiated SSO)
+	BeginLogin(ctx context.Context, login *FederatedLogin) (string, error)
+
+	// ConsumeAssertion validates a base64-encoded SAMLResponse posted to the ACS and
+	// returns the local user. login is nil for IdP-initiated SSO.
+	ConsumeAssertion(ctx context.Context, login *FederatedLogin, samlResponse string) (*User, error)
+
+	// Metadata returns the service provider metadata XML
+	Metadata(ctx context.Context) ([]byte, error)
+}
+
+// CertificateProvider is an identity provider that authenticates users and service
+// accounts by the X.509 client certificate presented over mutual TLS
+type CertificateProvider interface {
+	IdentityProvider
+
+	// AuthenticateCertificate maps the peer certificate of a TLS connection to a local user
+	AuthenticateCertificate(ctx context.Context, state *tls.ConnectionState) (*User, error)
+}
+
+// PasswordProvider is an identity provider keeping password hashes in the users table. It
+// lets callers store a new password in the same write as other changes to the user.
+type PasswordProvider interface {
+	IdentityProvider
+
+	// HashNewPassword applies the password policy and reuse history to a new password
+	// and returns its hash without storing it
+	HashNewPassword(ctx context.Context, userID uuid.UUID, newPassword string) (string, error)
+
+	// RecordPasswordHistory adds a hash the caller stored to the reuse history
+	RecordPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash string) error
+}
+
+// FederatedLogin holds the per-login secrets that tie a callback to the browser and
+// request that started it
+type FederatedLogin struct {
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	// LinkToken is set when the sign-in links an identity to, or re-authenticates, an
+	// already signed-in user
+	LinkToken string `json:"link_token,omitempty"`
+}
+
 // ProviderRegistry manages multiple identity providers
 type ProviderRegistry interface {
 	// RegisterProvider registers a new identity provider
@@ -70,6 +279,18 @@ type ProviderRegistry interface {
 
 	// GetEnabledProviders returns only enabled providers
 	GetEnabledProviders() []IdentityProvider
+
+	// ReplaceProvider registers a provider, replacing any provider with the same name
+	ReplaceProvider(provider IdentityProvider) error
+
+	// UnregisterProvider removes a provider by name
+	UnregisterProvider(name string) error
+}
+
+// ProviderFactory instantiates identity providers from their stored configuration
+type ProviderFactory interface {
+	// Create builds the provider for a providers table row, validating its config
+	Create(provider *Provider) (IdentityProvider, error)
 }
 
 // TokenService handles JWT token operations
@@ -77,6 +298,9 @@ type TokenService interface {
 	// GenerateAccessToken creates a new access token for a user
 	GenerateAccessToken(userID uuid.UUID, email string) (string, error)
 
+	// GenerateScopedAccessToken creates an access token restricted to a scope (e.g. TokenScopePasswordChange)
+	GenerateScopedAccessToken(userID uuid.UUID, email, scope string) (string, error)
+
 	// GenerateRefreshToken creates a new refresh token for a user
 	GenerateRefreshToken(userID uuid.UUID) (string, error)
 
@@ -89,14 +313,21 @@ type TokenService interface {
 	// RevokeRefreshToken invalidates a refresh token
 	RevokeRefreshToken(token string) error
 
+	// RevokeAllUserTokens invalidates every refresh token of a user
+	RevokeAllUserTokens(userID uuid.UUID) error
+
 	// IntrospectToken provides token information for other services
 	IntrospectToken(token string) (*TokenIntrospection, error)
 }
 
+// TokenScopePasswordChange marks an access token that only permits changing an expired password
+const TokenScopePasswordChange = "password_change"
+
 // TokenClaims represents the claims in an access token
 type TokenClaims struct {
 	UserID    uuid.UUID `json:"user_id"`
 	Email     string    `json:"email"`
+	Scope     string    `json:"scope,omitempty"`
 	ExpiresAt int64     `json:"exp"`
 	IssuedAt  int64     `json:"iat"`
 	Issuer    string    `json:"iss"`
@@ -120,6 +351,15 @@ type TokenIntrospection struct {
 	Scope     string    `json:"scope,omitempty"`
 }
 
+// SAMLAssertionRepository records consumed SAML assertion IDs until the assertions expire,
+// so a captured response cannot be replayed on any instance
+type SAMLAssertionRepository interface {
+	// Consume records the assertion ID for the provider and reports false if it was
+	// already recorded
+	Consume(provider, assertionID string, expiresAt time.Time) (bool, error)
+	DeleteExpired() error
+}
+
 // RefreshToken represents a refresh token in the database
 type RefreshToken struct {
 	ID        uuid.UUID `json:"id" db:"id"`
@@ -140,3 +380,13 @@ type RefreshTokenRepository interface {
 	DeleteExpired() error
 	CleanupExpiredTokens() (int, error)
 }
+
+// ProviderRepository handles persisted provider configuration
+type ProviderRepository interface {
+	GetByName(name string) (*Provider, error)
+	List() ([]*Provider, error)
+	Create(provider *Provider) error
+	Update(provider *Provider) error
+	Delete(name string) error
+	SetPasswordlessEnabled(name string, enabled bool) error
+}
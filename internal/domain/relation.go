@@ -0,0 +1,327 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Relation-based access control (ReBAC) after Google Zanzibar: access is stored as
+// relation tuples such as "doc:1#viewer@user:alice" (user alice is a viewer of doc 1)
+// or "doc:1#viewer@group:eng#member" (members of group eng are viewers of doc 1), and
+// namespace configs define relations computed from other relations.
+
+var (
+	// ErrRelationDepthExceeded is returned when evaluating a relation follows more
+	// usersets than allowed, usually because of a very deep or cyclic hierarchy
+	ErrRelationDepthExceeded = errors.New("relation evaluation exceeded the maximum depth")
+	// ErrStaleConsistencyToken is returned for a consistency token the store has not reached
+	ErrStaleConsistencyToken = errors.New("consistency token is newer than the relation store")
+	// ErrSnapshotExpired is returned for an exact snapshot older than the history the
+	// store still keeps
+	ErrSnapshotExpired = errors.New("consistency token is older than the snapshot retention window")
+)
+
+var relationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// RelationSubject is who a tuple relates the object to: an object ("user:alice",
+// "folder:9") or, with Relation set, a userset ("group:eng#member")
+type RelationSubject struct {
+	Namespace string `json:"namespace"`
+	ObjectID  string `json:"object_id"`
+	Relation  string `json:"relation,omitempty"`
+}
+
+func (s RelationSubject) String() string {
+	if s.Relation == "" {
+		return s.Namespace + ":" + s.ObjectID
+	}
+	return s.Namespace + ":" + s.ObjectID + "#" + s.Relation
+}
+
+// RelationTuple states that Subject has Relation to the object Namespace:ObjectID
+type RelationTuple struct {
+	Namespace string          `json:"namespace"`
+	ObjectID  string          `json:"object_id"`
+	Relation  string          `json:"relation"`
+	Subject   RelationSubject `json:"subject"`
+}
+
+func (t RelationTuple) String() string {
+	return t.Namespace + ":" + t.ObjectID + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+// ParseRelationObject parses "namespace:object_id". Object IDs may contain ":" but not
+// "#" or whitespace.
+func ParseRelationObject(object string) (namespace, objectID string, err error) {
+	namespace, objectID, ok := strings.Cut(object, ":")
+	if !ok {
+		return "", "", fmt.Errorf("invalid object %q, expected namespace:object_id", object)
+	}
+	if err := validateRelationName("namespace", namespace); err != nil {
+		return "", "", err
+	}
+	if objectID == "" || len(objectID) > 255 || strings.ContainsAny(objectID, "# \t\r\n") {
+		return "", "", fmt.Errorf("invalid object ID %q", objectID)
+	}
+	return namespace, objectID, nil
+}
+
+// ParseRelationSubject parses "namespace:object_id" or "namespace:object_id#relation"
+func ParseRelationSubject(subject string) (RelationSubject, error) {
+	object, relation, hasRelation := strings.Cut(subject, "#")
+	namespace, objectID, err := ParseRelationObject(object)
+	if err != nil {
+		return RelationSubject{}, err
+	}
+	if hasRelation {
+		if err := validateRelationName("relation", relation); err != nil {
+			return RelationSubject{}, err
+		}
+	}
+	return RelationSubject{Namespace: namespace, ObjectID: objectID, Relation: relation}, nil
+}
+
+// ParseRelationTuple parses "namespace:object_id#relation@subject"
+func ParseRelationTuple(tuple string) (*RelationTuple, error) {
+	object, rest, ok := strings.Cut(tuple, "#")
+	if !ok {
+		return nil, fmt.Errorf("invalid relation tuple %q, expected namespace:object_id#relation@subject", tuple)
+	}
+	relation, subject, ok := strings.Cut(rest, "@")
+	if !ok {
+		return nil, fmt.Errorf("invalid relation tuple %q, expected namespace:object_id#relation@subject", tuple)
+	}
+
+	namespace, objectID, err := ParseRelationObject(object)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRelationName("relation", relation); err != nil {
+		return nil, err
+	}
+	parsedSubject, err := ParseRelationSubject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RelationTuple{Namespace: namespace, ObjectID: objectID, Relation: relation, Subject: parsedSubject}, nil
+}
+
+func validateRelationName(kind, name string) error {
+	if !relationNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid %s %q, expected lowercase letters, digits and underscores", kind, name)
+	}
+	return nil
+}
+
+// NamespaceConfig is the schema of a namespace: the relations its objects can have and
+// how each relation is computed. Direct tuples always count; Union adds the subjects of
+// other usersets, e.g. for "doc":
+//
+//	"viewer": {"union": [
+//	    {"computed_userset": "editor"},
+//	    {"tuple_to_userset": {"tupleset": "parent", "computed_userset": "viewer"}}
+//	]}
+//
+// makes editors of a document and viewers of its parent folder viewers of the document.
+type NamespaceConfig struct {
+	Name      string                    `json:"name"`
+	Relations map[string]RelationConfig `json:"relations"`
+	CreatedAt time.Time                 `json:"created_at"`
+	UpdatedAt time.Time                 `json:"updated_at"`
+}
+
+type RelationConfig struct {
+	Union []UsersetRewrite `json:"union,omitempty"`
+}
+
+// UsersetRewrite is one rule of a relation; exactly one field is set
+type UsersetRewrite struct {
+	// ComputedUserset is another relation of the same object
+	ComputedUserset string `json:"computed_userset,omitempty"`
+	// TupleToUserset follows the objects in a relation of this object
+	TupleToUserset *TupleToUserset `json:"tuple_to_userset,omitempty"`
+}
+
+// TupleToUserset takes the subjects of ComputedUserset on every object related to this
+// object through Tupleset, e.g. the viewers of each parent folder
+type TupleToUserset struct {
+	Tupleset        string `json:"tupleset"`
+	ComputedUserset string `json:"computed_userset"`
+}
+
+// HasRelation reports whether the namespace defines the relation
+func (c *NamespaceConfig) HasRelation(relation string) bool {
+	_, ok := c.Relations[relation]
+	return ok
+}
+
+// Validate checks the names and that rewrites refer to relations of the namespace.
+// The relation of a TupleToUserset is resolved in the namespace of each related object
+// when evaluated.
+func (c *NamespaceConfig) Validate() error {
+	if err := validateRelationName("namespace", c.Name); err != nil {
+		return err
+	}
+	if len(c.Relations) == 0 {
+		return fmt.Errorf("namespace %s defines no relations", c.Name)
+	}
+
+	for name, relation := range c.Relations {
+		if err := validateRelationName("relation", name); err != nil {
+			return err
+		}
+		for _, rewrite := range relation.Union {
+			switch {
+			case rewrite.ComputedUserset != "" && rewrite.TupleToUserset == nil:
+				if !c.HasRelation(rewrite.ComputedUserset) {
+					return fmt.Errorf("relation %s: computed userset %s is not defined", name, rewrite.ComputedUserset)
+				}
+			case rewrite.ComputedUserset == "" && rewrite.TupleToUserset != nil:
+				if !c.HasRelation(rewrite.TupleToUserset.Tupleset) {
+					return fmt.Errorf("relation %s: tupleset %s is not defined", name, rewrite.TupleToUserset.Tupleset)
+				}
+				if err := validateRelationName("relation", rewrite.TupleToUserset.ComputedUserset); err != nil {
+					return fmt.Errorf("relation %s: %w", name, err)
+				}
+			default:
+				return fmt.Errorf("relation %s: each rewrite needs exactly one of computed_userset and tuple_to_userset", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Consistency tokens ("zookies") identify a revision of the relation store. Every write
+// returns the token of the revision it created and every read the token of the revision
+// it was evaluated at.
+const consistencyTokenPrefix = "rt1."
+
+func EncodeConsistencyToken(revision int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(consistencyTokenPrefix + strconv.FormatInt(revision, 10)))
+}
+
+func DecodeConsistencyToken(token string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(decoded), consistencyTokenPrefix) {
+		return 0, fmt.Errorf("invalid consistency token")
+	}
+	revision, err := strconv.ParseInt(strings.TrimPrefix(string(decoded), consistencyTokenPrefix), 10, 64)
+	if err != nil || revision < 0 {
+		return 0, fmt.Errorf("invalid consistency token")
+	}
+	return revision, nil
+}
+
+// Consistency selects the revision a read is evaluated at. By default reads see the
+// latest revision. AtLeastAsFresh fails when the store has not reached the token (for
+// instance a token from another deployment); AtExactSnapshot evaluates at the token's
+// revision, e.g. to reproduce an earlier decision.
+type Consistency struct {
+	AtLeastAsFresh  string `json:"at_least_as_fresh,omitempty"`
+	AtExactSnapshot string `json:"at_exact_snapshot,omitempty"`
+}
+
+type UpsertNamespaceRequest struct {
+	Relations map[string]RelationConfig `json:"relations" validate:"required"`
+}
+
+// WriteRelationTuplesRequest writes and deletes tuples in one revision. Deletes are
+// applied first; writing an existing tuple or deleting a missing one is not an error.
+type WriteRelationTuplesRequest struct {
+	Writes  []string `json:"writes" validate:"max=1000"`
+	Deletes []string `json:"deletes" validate:"max=1000"`
+}
+
+type WriteRelationTuplesResponse struct {
+	ConsistencyToken string `json:"consistency_token"`
+}
+
+type CheckRelationRequest struct {
+	Object      string       `json:"object" validate:"required"`
+	Relation    string       `json:"relation" validate:"required"`
+	Subject     string       `json:"subject" validate:"required"`
+	Consistency *Consistency `json:"consistency,omitempty"`
+}
+
+type CheckRelationResponse struct {
+	Allowed          bool   `json:"allowed"`
+	ConsistencyToken string `json:"consistency_token"`
+}
+
+type ExpandRelationRequest struct {
+	Object      string       `json:"object" validate:"required"`
+	Relation    string       `json:"relation" validate:"required"`
+	Consistency *Consistency `json:"consistency,omitempty"`
+}
+
+// UsersetTree is the expansion of object#relation: a union of the direct subjects
+// (Subjects, which may be usersets to expand in turn) and the rewritten usersets
+// (Children)
+type UsersetTree struct {
+	Object   string            `json:"object"`
+	Relation string            `json:"relation"`
+	Subjects []RelationSubject `json:"subjects"`
+	Children []*UsersetTree    `json:"children,omitempty"`
+}
+
+type ExpandRelationResponse struct {
+	Tree             *UsersetTree `json:"tree"`
+	ConsistencyToken string       `json:"consistency_token"`
+}
+
+type LookupResourcesRequest struct {
+	Namespace   string       `json:"namespace" validate:"required"`
+	Relation    string       `json:"relation" validate:"required"`
+	Subject     string       `json:"subject" validate:"required"`
+	Limit       int          `json:"limit" validate:"min=0,max=1000"`
+	Consistency *Consistency `json:"consistency,omitempty"`
+}
+
+type LookupResourcesResponse struct {
+	ObjectIDs        []string `json:"object_ids"`
+	ConsistencyToken string   `json:"consistency_token"`
+}
+
+type ReadRelationTuplesResponse struct {
+	Tuples           []*RelationTuple `json:"tuples"`
+	ConsistencyToken string           `json:"consistency_token"`
+}
+
+// RelationTupleFilter selects tuples; empty fields match everything but Namespace is required
+type RelationTupleFilter struct {
+	Namespace string
+	ObjectID  string
+	Relation  string
+	Subject   *RelationSubject
+}
+
+type RelationNamespaceRepository interface {
+	Upsert(config *NamespaceConfig) error
+	GetByName(name string) (*NamespaceConfig, error)
+	List() ([]*NamespaceConfig, error)
+	Delete(name string) error
+}
+
+// RelationTupleRepository stores tuples by revision: a tuple is visible at the
+// revisions from the one that wrote it up to the one that deleted it
+type RelationTupleRepository interface {
+	Write(writes, deletes []*RelationTuple) (revision int64, err error)
+	CurrentRevision() (int64, error)
+	// OldestRevision is the oldest revision exact snapshots can still be evaluated at
+	OldestRevision() (int64, error)
+	// PurgeHistory drops the tuples deleted and the revisions written more than retention
+	// ago, keeping every snapshot taken since answerable
+	PurgeHistory(retention time.Duration) error
+	Read(filter RelationTupleFilter, revision int64) ([]*RelationTuple, error)
+	HasTuples(namespace, relation string) (bool, error)
+	// ReachableObjects lists the objects of the namespace connected to the subject by
+	// following tuples from subject to object, a superset of those it has any relation to
+	ReachableObjects(subject RelationSubject, namespace string, revision int64) ([]string, error)
+}
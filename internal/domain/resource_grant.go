@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Subject types a resource grant can be given to
+const (
+	SubjectTypeUser  = "user"
+	SubjectTypeGroup = "group"
+)
+
+// ResourceGrant assigns a role to a user or group on a single resource instance, e.g.
+// "editor" on document 123. The role's permissions apply to that instance only, and
+// only where the permission's resource matches ResourceType.
+type ResourceGrant struct {
+	ID           uuid.UUID `json:"id" db:"id"`
+	RoleID       uuid.UUID `json:"role_id" db:"role_id"`
+	RoleName     string    `json:"role_name,omitempty" db:"role_name"`
+	SubjectType  string    `json:"subject_type" db:"subject_type"`
+	SubjectID    uuid.UUID `json:"subject_id" db:"subject_id"`
+	ResourceType string    `json:"resource_type" db:"resource_type"`
+	ResourceID   string    `json:"resource_id" db:"resource_id"`
+	Condition    string    `json:"condition,omitempty" db:"condition"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateResourceGrantRequest struct {
+	RoleID       uuid.UUID `json:"role_id" validate:"required"`
+	SubjectType  string    `json:"subject_type" validate:"required,oneof=user group"`
+	SubjectID    uuid.UUID `json:"subject_id" validate:"required"`
+	ResourceType string    `json:"resource_type" validate:"required,min=1,max=100"`
+	ResourceID   string    `json:"resource_id" validate:"required,min=1,max=255"`
+	Condition    string    `json:"condition,omitempty" validate:"max=4096"`
+}
+
+// ResourceGrantFilter narrows a grant listing; empty fields match everything
+type ResourceGrantFilter struct {
+	SubjectType  string
+	SubjectID    *uuid.UUID
+	ResourceType string
+	ResourceID   string
+}
+
+type ResourceGrantRepository interface {
+	Create(grant *ResourceGrant) error
+	GetByID(id uuid.UUID) (*ResourceGrant, error)
+	Delete(id uuid.UUID) error
+	List(filter ResourceGrantFilter, limit, offset int) ([]*ResourceGrant, error)
+	Count(filter ResourceGrantFilter) (int, error)
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,8 +16,15 @@ type Role struct {
 	IsSystem    bool      `json:"is_system" db:"is_system"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	// Inherited is set on roles a user holds only through role inheritance
+	Inherited bool `json:"inherited,omitempty" db:"-"`
+	// Condition is set on roles whose direct assignment to the user or group is conditional
+	Condition string `json:"condition,omitempty" db:"-"`
 }
 
+// ErrRoleCycle is returned when a parent role would make a role inherit from itself
+var ErrRoleCycle = errors.New("role inheritance would create a cycle")
+
 type CreateRoleRequest struct {
 	Name        string `json:"name" validate:"required,min=1,max=100"`
 	Description string `json:"description"`
@@ -31,6 +39,27 @@ type UpdateRoleRequest struct {
 
 type AssignRoleRequest struct {
 	RoleID uuid.UUID `json:"role_id" validate:"required"`
+	// Condition optionally restricts the assignment to contexts where the CEL expression holds
+	Condition string `json:"condition,omitempty" validate:"max=4096"`
+}
+
+type AddRoleParentRequest struct {
+	ParentRoleID uuid.UUID `json:"parent_role_id" validate:"required"`
+}
+
+// RoleEdge is a parent-child relationship: RoleID inherits the permissions of ParentRoleID
+type RoleEdge struct {
+	RoleID       uuid.UUID `json:"role_id"`
+	ParentRoleID uuid.UUID `json:"parent_role_id"`
+}
+
+// RoleHierarchy is the inheritance graph around a role: the roles it inherits from
+// (ancestors), the roles inheriting from it (descendants) and the edges between them
+type RoleHierarchy struct {
+	Role        *Role      `json:"role"`
+	Ancestors   []*Role    `json:"ancestors"`
+	Descendants []*Role    `json:"descendants"`
+	Edges       []RoleEdge `json:"edges"`
 }
 
 type RoleRepository interface {
@@ -41,10 +70,17 @@ type RoleRepository interface {
 	Delete(id uuid.UUID) error
 	List(limit, offset int) ([]*Role, error)
 	Count() (int, error)
-	AssignToUser(userID, roleID uuid.UUID) error
+	AssignToUser(userID, roleID uuid.UUID, condition string) error
 	RemoveFromUser(userID, roleID uuid.UUID) error
-	AssignToGroup(groupID, roleID uuid.UUID) error
+	AssignToGroup(groupID, roleID uuid.UUID, condition string) error
 	RemoveFromGroup(groupID, roleID uuid.UUID) error
 	GetUserRoles(userID uuid.UUID) ([]*Role, error)
+	// GetUserEffectiveRoles returns every role the user holds directly, through (nested)
+	// groups or by inheritance
+	GetUserEffectiveRoles(userID uuid.UUID) ([]*Role, error)
 	GetGroupRoles(groupID uuid.UUID) ([]*Role, error)
+	AddParent(roleID, parentRoleID uuid.UUID) error
+	RemoveParent(roleID, parentRoleID uuid.UUID) error
+	GetParents(roleID uuid.UUID) ([]*Role, error)
+	GetHierarchy(roleID uuid.UUID) (*RoleHierarchy, error)
 }
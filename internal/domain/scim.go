@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SCIMToken is the bearer credential of a SCIM provisioning client such as an HR system
+// or Okta. Only the hash of the token is stored.
+type SCIMToken struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+// SCIMTokenRepository handles SCIM credential persistence
+type SCIMTokenRepository interface {
+	Create(token *SCIMToken) error
+	GetByTokenHash(tokenHash string) (*SCIMToken, error)
+	List() ([]*SCIMToken, error)
+	UpdateLastUsed(id uuid.UUID) error
+	Delete(id uuid.UUID) error
+}
+
+type CreateSCIMTokenRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=100"`
+}
+
+// CreateSCIMTokenResponse carries the plaintext token, which is only shown once
+type CreateSCIMTokenResponse struct {
+	*SCIMToken
+	Token string `json:"token"`
+}
+
+// SCIMTarget is a downstream application that users and groups are pushed to over SCIM.
+// The bearer token is stored as given since it must be presented to the target.
+type SCIMTarget struct {
+	ID               uuid.UUID  `json:"id" db:"id"`
+	Name             string     `json:"name" db:"name"`
+	BaseURL          string     `json:"base_url" db:"base_url"`
+	Token            string     `json:"-" db:"token"`
+	Enabled          bool       `json:"enabled" db:"enabled"`
+	DryRun           bool       `json:"dry_run" db:"dry_run"`
+	SyncGroups       bool       `json:"sync_groups" db:"sync_groups"`
+	LastReconciledAt *time.Time `json:"last_reconciled_at,omitempty" db:"last_reconciled_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SCIMTargetRepository handles outbound SCIM target persistence
+type SCIMTargetRepository interface {
+	Create(target *SCIMTarget) error
+	GetByID(id uuid.UUID) (*SCIMTarget, error)
+	GetByName(name string) (*SCIMTarget, error)
+	List() ([]*SCIMTarget, error)
+	Update(target *SCIMTarget) error
+	UpdateLastReconciled(id uuid.UUID) error
+	Delete(id uuid.UUID) error
+}
+
+type CreateSCIMTargetRequest struct {
+	Name       string `json:"name" validate:"required,min=1,max=100"`
+	BaseURL    string `json:"base_url" validate:"required,url"`
+	Token      string `json:"token" validate:"required"`
+	Enabled    *bool  `json:"enabled,omitempty"`     // optional, default true
+	DryRun     bool   `json:"dry_run,omitempty"`     // record planned requests without sending them
+	SyncGroups *bool  `json:"sync_groups,omitempty"` // optional, default true
+}
+
+// UpdateSCIMTargetRequest changes a target. Omitted fields are left unchanged.
+type UpdateSCIMTargetRequest struct {
+	BaseURL    *string `json:"base_url,omitempty" validate:"omitempty,url"`
+	Token      *string `json:"token,omitempty" validate:"omitempty,min=1"`
+	Enabled    *bool   `json:"enabled,omitempty"`
+	DryRun     *bool   `json:"dry_run,omitempty"`
+	SyncGroups *bool   `json:"sync_groups,omitempty"`
+}
+
+// SCIM sync resource types, operations and states
+const (
+	SCIMResourceUser  = "User"
+	SCIMResourceGroup = "Group"
+
+	SCIMOperationUpsert = "upsert"
+	SCIMOperationDelete = "delete"
+
+	SCIMSyncPending = "pending"
+	SCIMSyncSynced  = "synced"
+	SCIMSyncFailed  = "failed"
+	SCIMSyncDryRun  = "dry_run"
+)
+
+// SCIMSyncState tracks one local user or group on one target: the remote ID it maps to
+// and the operation still to be delivered. Revision increases on every enqueue so a
+// worker never overwrites a change made while it was delivering the previous one.
+type SCIMSyncState struct {
+	TargetID      uuid.UUID  `json:"target_id" db:"target_id"`
+	ResourceType  string     `json:"resource_type" db:"resource_type"`
+	LocalID       uuid.UUID  `json:"local_id" db:"local_id"`
+	Display       string     `json:"display" db:"display"`
+	RemoteID      string     `json:"remote_id,omitempty" db:"remote_id"`
+	Operation     string     `json:"operation" db:"operation"`
+	Status        string     `json:"status" db:"status"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`
+	LastRequest   string     `json:"last_request,omitempty" db:"last_request"`
+	Revision      int        `json:"-" db:"revision"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	SyncedAt      *time.Time `json:"synced_at,omitempty" db:"synced_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// SCIMSyncStateRepository handles the outbound SCIM sync state
+type SCIMSyncStateRepository interface {
+	// Enqueue schedules an operation for immediate delivery, creating the state if needed
+	Enqueue(targetID uuid.UUID, resourceType string, localID uuid.UUID, display, operation string) error
+	// EnqueueDelete schedules deletion on every target the resource has state on
+	EnqueueDelete(resourceType string, localID uuid.UUID) error
+	Get(targetID uuid.UUID, resourceType string, localID uuid.UUID) (*SCIMSyncState, error)
+	// ClaimDue returns pending states of enabled targets that are due and postpones them
+	// by lease so other instances do not deliver them concurrently
+	ClaimDue(limit int, lease time.Duration) ([]*SCIMSyncState, error)
+	List(targetID uuid.UUID, status string, limit, offset int) ([]*SCIMSyncState, error)
+	ListLocalIDs(targetID uuid.UUID, resourceType string) ([]uuid.UUID, error)
+	SetRemoteID(targetID uuid.UUID, resourceType string, localID uuid.UUID, remoteID string) error
+	// Update stores the delivery outcome unless the state was enqueued again since it was read
+	Update(state *SCIMSyncState) error
+	// Delete removes the state unless it was enqueued again since it was read
+	Delete(state *SCIMSyncState) error
+	RetryFailed(targetID uuid.UUID) (int, error)
+}
+
+// SCIMSyncPolicy controls delivery of outbound SCIM changes
+type SCIMSyncPolicy struct {
+	PollInterval   time.Duration // How often due changes are delivered when not woken by a change
+	BatchSize      int           // States claimed per delivery round
+	MaxAttempts    int           // Failed deliveries before a state is marked failed
+	RetryBaseDelay time.Duration // First retry delay, doubled per failure
+	RetryMaxDelay  time.Duration // Upper bound for retry delays
+}
@@ -16,17 +16,18 @@ const (
 )
 
 type User struct {
-	ID            uuid.UUID  `json:"id" db:"id"`
-	Email         string     `json:"email" db:"email" validate:"required,email"`
-	PasswordHash  string     `json:"-" db:"password_hash"`
-	FirstName     string     `json:"first_name" db:"first_name"`
-	LastName      string     `json:"last_name" db:"last_name"`
-	Status        UserStatus `json:"status" db:"status"`
-	EmailVerified bool       `json:"email_verified" db:"email_verified"`
-	IsDeleted     bool       `json:"is_deleted" db:"is_deleted"`
-	IsSystem      bool       `json:"is_system" db:"is_system"`
-	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	ID                uuid.UUID  `json:"id" db:"id"`
+	Email             string     `json:"email" db:"email" validate:"required,email"`
+	PasswordHash      string     `json:"-" db:"password_hash"`
+	FirstName         string     `json:"first_name" db:"first_name"`
+	LastName          string     `json:"last_name" db:"last_name"`
+	Status            UserStatus `json:"status" db:"status"`
+	EmailVerified     bool       `json:"email_verified" db:"email_verified"`
+	IsDeleted         bool       `json:"is_deleted" db:"is_deleted"`
+	IsSystem          bool       `json:"is_system" db:"is_system"`
+	PasswordChangedAt time.Time  `json:"password_changed_at" db:"password_changed_at"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 type CreateUserRequest struct {
@@ -45,6 +46,7 @@ type UpdateUserRequest struct {
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	Provider string `json:"provider,omitempty"` // optional, defaults to the default provider
 }
 
 type ChangePasswordRequest struct {
@@ -70,5 +72,10 @@ type UserRepository interface {
 	List(limit, offset int) ([]*User, error)
 	Count() (int, error)
 	UpdatePassword(id uuid.UUID, passwordHash string) error
+	UpdatePasswordHash(id uuid.UUID, passwordHash string) error
 	UpdateEmailVerified(id uuid.UUID, verified bool) error
+	UpdateEmail(id uuid.UUID, email string) error
+	// UpdateAccount writes the email and profile fields of user and, when passwordHash is
+	// set, a password change, in a single statement
+	UpdateAccount(user *User, passwordHash string) error
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 
 	httphandler "github.com/aras-services/aras-auth/internal/delivery/http"
@@ -41,6 +42,16 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
+		// Restricted tokens (e.g. issued for an expired password) are only valid on routes that opt in
+		if claims.Scope == domain.TokenScopePasswordChange {
+			httphandler.WriteError(w, http.StatusForbidden, "password_expired", fmt.Errorf("Password has expired and must be changed"))
+			return
+		}
+		if claims.Scope != "" {
+			httphandler.WriteForbidden(w, "Token scope does not permit this operation")
+			return
+		}
+
 		// Add user information to context
 		ctx := context.WithValue(r.Context(), "user_id", claims.UserID.String())
 		ctx = context.WithValue(ctx, "user_email", claims.Email)
@@ -50,6 +61,35 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	})
 }
 
+// AllowPasswordChange authenticates like RequireAuth but also accepts tokens restricted to
+// changing an expired password. It must only wrap the change-password route.
+func (m *AuthMiddleware) AllowPasswordChange(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+			httphandler.WriteUnauthorized(w, "Authorization header required")
+			return
+		}
+
+		claims, err := m.tokenService.ValidateAccessToken(authHeader[7:])
+		if err != nil {
+			httphandler.WriteUnauthorized(w, "Invalid or expired token")
+			return
+		}
+
+		if claims.Scope != "" && claims.Scope != domain.TokenScopePasswordChange {
+			httphandler.WriteForbidden(w, "Token scope does not permit this operation")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "user_id", claims.UserID.String())
+		ctx = context.WithValue(ctx, "user_email", claims.Email)
+		ctx = context.WithValue(ctx, "token_claims", claims)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Extract token from Authorization header
@@ -66,9 +106,9 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 		}
 		token := authHeader[7:]
 
-		// Validate token
+		// Validate token; restricted tokens are treated as anonymous
 		claims, err := m.tokenService.ValidateAccessToken(token)
-		if err != nil {
+		if err != nil || claims.Scope != "" {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	httphandler "github.com/aras-services/aras-auth/internal/delivery/http"
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
+)
+
+// maxRateLimitBodySize bounds how much of the request body is buffered to find the email
+const maxRateLimitBodySize = 1 << 20
+
+// RateLimitRule limits requests to a route per client IP and per submitted email.
+// A zero limit disables that dimension.
+type RateLimitRule struct {
+	PerIP    int
+	PerEmail int
+	Window   time.Duration
+}
+
+type RateLimitMiddleware struct {
+	limiter domain.RateLimiter
+}
+
+func NewRateLimitMiddleware(limiter domain.RateLimiter) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter: limiter,
+	}
+}
+
+// Limit applies rule to the wrapped route. name scopes the counters so each route is
+// limited independently. Throttled requests get the same response whether or not the
+// email belongs to an account.
+func (m *RateLimitMiddleware) Limit(name string, rule RateLimitRule) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rule.PerIP > 0 {
+				key := fmt.Sprintf("ratelimit:%s:ip:%s", name, clientIP(r))
+				if !m.allow(w, key, rule.PerIP, rule.Window) {
+					return
+				}
+			}
+
+			if rule.PerEmail > 0 {
+				if email := peekEmail(r); email != "" {
+					key := fmt.Sprintf("ratelimit:%s:email:%s", name, securetoken.Hash(email))
+					if !m.allow(w, key, rule.PerEmail, rule.Window) {
+						return
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (m *RateLimitMiddleware) allow(w http.ResponseWriter, key string, limit int, window time.Duration) bool {
+	allowed, retryAfter, err := m.limiter.Allow(key, limit, window)
+	if err != nil {
+		// Fail open: a limiter outage must not take authentication down with it
+		fmt.Printf("Warning: rate limiter unavailable: %v\n", err)
+		return true
+	}
+
+	if !allowed {
+		httphandler.WriteTooManyRequests(w, int(math.Ceil(retryAfter.Seconds())), "Too many requests, please try again later")
+		return false
+	}
+
+	return true
+}
+
+// clientIP returns the request's remote IP. It relies on RealIPMiddleware to have
+// rewritten RemoteAddr when the request came through a trusted proxy.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// peekEmail extracts the normalized "email" field from a JSON body and restores
+// the body so the handler can decode it again
+func peekEmail(r *http.Request) string {
+	if r.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRateLimitBodySize))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var payload struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+
+	return strings.ToLower(strings.TrimSpace(payload.Email))
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"net/http"
-	"strings"
 
 	"github.com/google/uuid"
 
@@ -12,14 +11,40 @@ import (
 
 type RBACMiddleware struct {
 	permissionRepo domain.PermissionRepository
+	conditions     domain.ConditionEvaluator
 }
 
-func NewRBACMiddleware(permissionRepo domain.PermissionRepository) *RBACMiddleware {
+func NewRBACMiddleware(permissionRepo domain.PermissionRepository, conditions domain.ConditionEvaluator) *RBACMiddleware {
 	return &RBACMiddleware{
 		permissionRepo: permissionRepo,
+		conditions:     conditions,
 	}
 }
 
+// hasPermission checks the permission with grant conditions evaluated against the request.
+// Deny rules override grants.
+func (m *RBACMiddleware) hasPermission(r *http.Request, userID uuid.UUID, resource, action string) (bool, error) {
+	denyRule, err := m.permissionRepo.GetUserDenyRule(userID, resource, "", action)
+	if err != nil || denyRule != nil {
+		return false, err
+	}
+
+	grants, err := m.permissionRepo.GetUserPermissionGrants(userID, resource, "", action)
+	if err != nil || len(grants) == 0 {
+		return false, err
+	}
+
+	email, _ := r.Context().Value("user_email").(string)
+	return domain.ConditionsHold(m.conditions, grants, &domain.AccessContext{
+		IP:       clientIP(r),
+		User:     map[string]interface{}{"id": userID.String(), "email": email},
+		Resource: map[string]interface{}{"type": resource, "id": ""},
+		Action:   action,
+	}), nil
+}
+
+// RequirePermission allows users granted a permission covering resource and action,
+// including wildcard ("users:*", "*:read", "*:*") and parent resource grants
 func (m *RBACMiddleware) RequirePermission(resource, action string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -37,7 +62,7 @@ func (m *RBACMiddleware) RequirePermission(resource, action string) func(http.Ha
 			}
 
 			// Check permission
-			hasPermission, err := m.permissionRepo.CheckUserPermission(userID, resource, action)
+			hasPermission, err := m.hasPermission(r, userID, resource, action)
 			if err != nil {
 				httphandler.WriteInternalError(w, err)
 				return
@@ -53,6 +78,7 @@ func (m *RBACMiddleware) RequirePermission(resource, action string) func(http.Ha
 	}
 }
 
+// RequireAnyPermission allows users holding at least one of the "resource:action" permissions
 func (m *RBACMiddleware) RequireAnyPermission(permissions ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -72,13 +98,12 @@ func (m *RBACMiddleware) RequireAnyPermission(permissions ...string) func(http.H
 			// Check if user has any of the required permissions
 			hasAnyPermission := false
 			for _, permission := range permissions {
-				parts := strings.Split(permission, ":")
-				if len(parts) != 2 {
+				resource, action, err := domain.ParsePermission(permission)
+				if err != nil {
 					continue
 				}
 
-				resource, action := parts[0], parts[1]
-				hasPermission, err := m.permissionRepo.CheckUserPermission(userID, resource, action)
+				hasPermission, err := m.hasPermission(r, userID, resource, action)
 				if err != nil {
 					httphandler.WriteInternalError(w, err)
 					return
@@ -100,6 +125,7 @@ func (m *RBACMiddleware) RequireAnyPermission(permissions ...string) func(http.H
 	}
 }
 
+// RequireAllPermissions allows users holding every one of the "resource:action" permissions
 func (m *RBACMiddleware) RequireAllPermissions(permissions ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -118,14 +144,13 @@ func (m *RBACMiddleware) RequireAllPermissions(permissions ...string) func(http.
 
 			// Check if user has all required permissions
 			for _, permission := range permissions {
-				parts := strings.Split(permission, ":")
-				if len(parts) != 2 {
+				resource, action, err := domain.ParsePermission(permission)
+				if err != nil {
 					httphandler.WriteForbidden(w, "Invalid permission format")
 					return
 				}
 
-				resource, action := parts[0], parts[1]
-				hasPermission, err := m.permissionRepo.CheckUserPermission(userID, resource, action)
+				hasPermission, err := m.hasPermission(r, userID, resource, action)
 				if err != nil {
 					httphandler.WriteInternalError(w, err)
 					return
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIPMiddleware rewrites RemoteAddr to the client address reported by trusted reverse
+// proxies. X-Forwarded-For and X-Real-IP are ignored on connections from any other peer,
+// so clients cannot choose the IP seen by rate limits, request logs and request.ip
+// conditions.
+type RealIPMiddleware struct {
+	trusted []netip.Prefix
+}
+
+func NewRealIPMiddleware(trusted []netip.Prefix) *RealIPMiddleware {
+	return &RealIPMiddleware{
+		trusted: trusted,
+	}
+}
+
+func (m *RealIPMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := m.forwardedIP(r); ok {
+			r.RemoteAddr = ip.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedIP returns the client address of a request relayed by a trusted proxy.
+// X-Forwarded-For is read from the right: each trusted proxy appends the peer it received
+// the request from, so the first untrusted hop is the client and anything left of it was
+// supplied by the client itself.
+func (m *RealIPMiddleware) forwardedIP(r *http.Request) (netip.Addr, bool) {
+	peer, ok := parseAddr(r.RemoteAddr)
+	if !ok || !m.isTrusted(peer) {
+		return netip.Addr{}, false
+	}
+
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(strings.Join(values, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, ok := parseAddr(hops[i])
+			if !ok {
+				return netip.Addr{}, false
+			}
+			if i == 0 || !m.isTrusted(hop) {
+				return hop, true
+			}
+		}
+	}
+
+	return parseAddr(r.Header.Get("X-Real-IP"))
+}
+
+func (m *RealIPMiddleware) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range m.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr parses an address with or without a port
+func parseAddr(value string) (netip.Addr, bool) {
+	value = strings.TrimSpace(value)
+	if addrPort, err := netip.ParseAddrPort(value); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
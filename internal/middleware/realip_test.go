@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestRealIPMiddleware(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwardedFor  []string
+		realIP        string
+		wantClientIP  string
+		withoutConfig bool
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:51000", wantClientIP: "203.0.113.7"},
+		{name: "spoofed header from untrusted peer", remoteAddr: "203.0.113.7:51000", forwardedFor: []string{"198.51.100.1"}, realIP: "198.51.100.2", wantClientIP: "203.0.113.7"},
+		{name: "single trusted proxy", remoteAddr: "10.0.0.5:443", forwardedFor: []string{"198.51.100.1"}, wantClientIP: "198.51.100.1"},
+		{name: "client-supplied entries are skipped", remoteAddr: "10.0.0.5:443", forwardedFor: []string{"1.2.3.4, 198.51.100.1"}, wantClientIP: "198.51.100.1"},
+		{name: "chain of trusted proxies", remoteAddr: "10.0.0.5:443", forwardedFor: []string{"198.51.100.1, 10.0.0.9", "10.0.0.8"}, wantClientIP: "198.51.100.1"},
+		{name: "every hop trusted", remoteAddr: "10.0.0.5:443", forwardedFor: []string{"10.1.1.1, 10.0.0.9"}, wantClientIP: "10.1.1.1"},
+		{name: "malformed hop keeps the peer", remoteAddr: "10.0.0.5:443", forwardedFor: []string{"unknown, 10.0.0.9"}, wantClientIP: "10.0.0.5"},
+		{name: "X-Real-IP from trusted proxy", remoteAddr: "10.0.0.5:443", realIP: "198.51.100.3", wantClientIP: "198.51.100.3"},
+		{name: "IPv6 proxy", remoteAddr: "[fd00::1]:443", forwardedFor: []string{"2001:db8::1"}, wantClientIP: "2001:db8::1"},
+		{name: "IPv4-mapped proxy address", remoteAddr: "[::ffff:10.0.0.5]:443", forwardedFor: []string{"198.51.100.1"}, wantClientIP: "198.51.100.1"},
+		{name: "no trusted proxies configured", remoteAddr: "10.0.0.5:443", forwardedFor: []string{"198.51.100.1"}, withoutConfig: true, wantClientIP: "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewRealIPMiddleware(trusted)
+			if tt.withoutConfig {
+				m = NewRealIPMiddleware(nil)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			var got string
+			m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.wantClientIP {
+				t.Errorf("clientIP() = %q, want %q", got, tt.wantClientIP)
+			}
+		})
+	}
+}
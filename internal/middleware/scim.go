@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	httphandler "github.com/aras-services/aras-auth/internal/delivery/http"
+	"github.com/aras-services/aras-auth/internal/usecase"
+	"github.com/aras-services/aras-auth/pkg/scim"
+)
+
+type SCIMAuthMiddleware struct {
+	scimUseCase *usecase.SCIMUseCase
+}
+
+func NewSCIMAuthMiddleware(scimUseCase *usecase.SCIMUseCase) *SCIMAuthMiddleware {
+	return &SCIMAuthMiddleware{
+		scimUseCase: scimUseCase,
+	}
+}
+
+// RequireToken authenticates SCIM clients by their bearer token. Failures are reported
+// in the SCIM error format.
+func (m *SCIMAuthMiddleware) RequireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if len(authHeader) < 7 || authHeader[:7] != "Bearer " {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			httphandler.WriteSCIMError(w, scim.NewError(http.StatusUnauthorized, "", "Authorization header required"))
+			return
+		}
+
+		token, err := m.scimUseCase.Authenticate(r.Context(), authHeader[7:])
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			httphandler.WriteSCIMError(w, scim.NewError(http.StatusUnauthorized, "", "Invalid SCIM token"))
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), "scim_token_id", token.ID.String())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
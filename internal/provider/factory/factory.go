@@ -0,0 +1,64 @@
+package factory
+
+import (
+	"fmt"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+	"github.com/aras-services/aras-auth/internal/provider/ldap"
+	"github.com/aras-services/aras-auth/internal/provider/local"
+	"github.com/aras-services/aras-auth/internal/provider/mtls"
+	"github.com/aras-services/aras-auth/internal/provider/oidc"
+	"github.com/aras-services/aras-auth/internal/provider/saml"
+	"github.com/aras-services/aras-auth/internal/provider/webhook"
+)
+
+// ProviderFactory builds identity providers by type from rows of the providers table
+type ProviderFactory struct {
+	userRepo       domain.UserRepository
+	groupRepo      domain.GroupRepository
+	historyRepo    domain.PasswordHistoryRepository
+	assertionRepo  domain.SAMLAssertionRepository
+	provisioner    *provider.Provisioner
+	passwordPolicy domain.PasswordPolicy
+	publicURL      string
+}
+
+func NewProviderFactory(
+	userRepo domain.UserRepository,
+	groupRepo domain.GroupRepository,
+	historyRepo domain.PasswordHistoryRepository,
+	assertionRepo domain.SAMLAssertionRepository,
+	provisioner *provider.Provisioner,
+	passwordPolicy domain.PasswordPolicy,
+	publicURL string,
+) domain.ProviderFactory {
+	return &ProviderFactory{
+		userRepo:       userRepo,
+		groupRepo:      groupRepo,
+		historyRepo:    historyRepo,
+		assertionRepo:  assertionRepo,
+		provisioner:    provisioner,
+		passwordPolicy: passwordPolicy,
+		publicURL:      publicURL,
+	}
+}
+
+func (f *ProviderFactory) Create(stored *domain.Provider) (domain.IdentityProvider, error) {
+	switch stored.Type {
+	case domain.ProviderTypeLocal:
+		return local.NewLocalProvider(stored.Name, stored.Enabled, f.userRepo, f.historyRepo, f.passwordPolicy), nil
+	case domain.ProviderTypeLDAP:
+		return ldap.NewLDAPProvider(stored.Name, stored.Enabled, stored.Config, f.userRepo, f.groupRepo, f.provisioner)
+	case domain.ProviderTypeOIDC:
+		return oidc.NewOIDCProvider(stored.Name, stored.Enabled, stored.Config, f.publicURL, f.userRepo, f.provisioner)
+	case domain.ProviderTypeSAML:
+		return saml.NewSAMLProvider(stored.Name, stored.Enabled, stored.Config, f.publicURL, f.userRepo, f.groupRepo, f.assertionRepo, f.provisioner)
+	case domain.ProviderTypeMTLS:
+		return mtls.NewCertificateProvider(stored.Name, stored.Enabled, stored.Config, f.userRepo, f.provisioner)
+	case domain.ProviderTypeWebhook:
+		return webhook.NewWebhookProvider(stored.Name, stored.Enabled, stored.Config, f.userRepo, f.provisioner)
+	default:
+		return nil, fmt.Errorf("unsupported provider type %q", stored.Type)
+	}
+}
@@ -0,0 +1,154 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// Config is the LDAP provider configuration stored in providers.config. Users are
+// located either by binding directly with UserDNTemplate, or by searching BaseDN with
+// UserFilter (optionally after binding as BindDN) and then binding as the entry found.
+type Config struct {
+	URL                string `json:"url"`                  // ldap://host:389 or ldaps://host:636
+	StartTLS           bool   `json:"start_tls"`            // Upgrade ldap:// connections with StartTLS
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // Skip certificate verification (testing only)
+	CACert             string `json:"ca_cert"`              // PEM bundle trusted for the server certificate
+	TimeoutSeconds     int    `json:"timeout_seconds"`      // Dial and request timeout (default: 10)
+
+	BindDN       string `json:"bind_dn"`       // Service account used for searches (empty: anonymous)
+	BindPassword string `json:"bind_password"` // Service account password
+
+	// UserDNTemplate builds the user's DN from the login identifier, e.g.
+	// "uid=%s,ou=people,dc=example,dc=com", or "%s" to bind with an AD userPrincipalName
+	UserDNTemplate string `json:"user_dn_template"`
+	BaseDN         string `json:"base_dn"`     // Search base for users
+	UserFilter     string `json:"user_filter"` // Search filter, %s is the escaped identifier (default: "(mail=%s)")
+
+	Attributes AttributeMapping `json:"attributes"`
+
+	// GroupMapping maps directory group DNs to aras group IDs. Membership of mapped
+	// groups is synchronized on every login; unmapped groups are left untouched.
+	GroupMapping map[string]uuid.UUID `json:"group_mapping"`
+	// GroupBaseDN and GroupFilter look up groups for directories without memberOf.
+	// %s in GroupFilter is the escaped user DN, e.g. "(member=%s)".
+	GroupBaseDN string `json:"group_base_dn"`
+	GroupFilter string `json:"group_filter"`
+
+	Provisioning provider.ProvisioningConfig `json:"provisioning"`
+}
+
+// AttributeMapping names the directory attributes mapped onto domain.User fields
+type AttributeMapping struct {
+	// ID is a stable identifier of the entry such as entryUUID or objectGUID; the DN is
+	// used when unset, which breaks the identity link when an entry is renamed or moved
+	ID        string `json:"id"`
+	Email     string `json:"email"`      // default: "mail"
+	FirstName string `json:"first_name"` // default: "givenName"
+	LastName  string `json:"last_name"`  // default: "sn"
+	Groups    string `json:"groups"`     // default: "memberOf"
+}
+
+// ParseConfig decodes and validates a provider configuration, applying defaults
+func ParseConfig(raw json.RawMessage) (*Config, error) {
+	var cfg Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid ldap config: %w", err)
+		}
+	}
+
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("ldap config: url is required")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap config: invalid url: %w", err)
+	}
+	switch u.Scheme {
+	case "ldap":
+	case "ldaps":
+		if cfg.StartTLS {
+			return nil, fmt.Errorf("ldap config: start_tls cannot be used with ldaps://")
+		}
+	default:
+		return nil, fmt.Errorf("ldap config: url scheme must be ldap or ldaps")
+	}
+
+	if cfg.UserDNTemplate == "" && cfg.BaseDN == "" {
+		return nil, fmt.Errorf("ldap config: either user_dn_template or base_dn is required")
+	}
+	if cfg.UserDNTemplate != "" && strings.Count(cfg.UserDNTemplate, "%s") != 1 {
+		return nil, fmt.Errorf("ldap config: user_dn_template must contain exactly one %%s")
+	}
+	if cfg.UserFilter == "" {
+		cfg.UserFilter = "(mail=%s)"
+	}
+	if strings.Count(cfg.UserFilter, "%s") != 1 {
+		return nil, fmt.Errorf("ldap config: user_filter must contain exactly one %%s")
+	}
+	if cfg.GroupFilter != "" && strings.Count(cfg.GroupFilter, "%s") != 1 {
+		return nil, fmt.Errorf("ldap config: group_filter must contain exactly one %%s")
+	}
+
+	for groupDN := range cfg.GroupMapping {
+		if _, err := goldap.ParseDN(groupDN); err != nil {
+			return nil, fmt.Errorf("ldap config: invalid group DN %q: %w", groupDN, err)
+		}
+	}
+
+	if _, err := cfg.tlsConfig(); err != nil {
+		return nil, err
+	}
+
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 10
+	}
+	if cfg.Attributes.Email == "" {
+		cfg.Attributes.Email = "mail"
+	}
+	if cfg.Attributes.FirstName == "" {
+		cfg.Attributes.FirstName = "givenName"
+	}
+	if cfg.Attributes.LastName == "" {
+		cfg.Attributes.LastName = "sn"
+	}
+	if cfg.Attributes.Groups == "" {
+		cfg.Attributes.Groups = "memberOf"
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	u, _ := url.Parse(c.URL)
+
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if c.CACert != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CACert)) {
+			return nil, fmt.Errorf("ldap config: ca_cert contains no valid certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,390 @@
+package ldap
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// LDAPProvider authenticates users against an LDAP or Active Directory server. Users
+// are mirrored into the local users table on first login and linked by their entry, so tokens,
+// roles and groups work exactly as for local users. Their local password is unusable;
+// the directory remains the source of truth for credentials and profile attributes.
+type LDAPProvider struct {
+	name        string
+	enabled     bool
+	config      *Config
+	userRepo    domain.UserRepository
+	groupRepo   domain.GroupRepository
+	provisioner *provider.Provisioner
+}
+
+func NewLDAPProvider(name string, enabled bool, rawConfig json.RawMessage, userRepo domain.UserRepository, groupRepo domain.GroupRepository, provisioner *provider.Provisioner) (domain.IdentityProvider, error) {
+	cfg, err := ParseConfig(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LDAPProvider{
+		name:        name,
+		enabled:     enabled,
+		config:      cfg,
+		userRepo:    userRepo,
+		groupRepo:   groupRepo,
+		provisioner: provisioner,
+	}, nil
+}
+
+// directoryUser is the subset of a directory entry mapped onto domain.User
+type directoryUser struct {
+	DN        string
+	ID        string
+	Email     string
+	FirstName string
+	LastName  string
+	GroupDNs  []string
+}
+
+func (p *LDAPProvider) Authenticate(ctx context.Context, username, pwd string) (*domain.User, error) {
+	// An empty password would turn the bind into an unauthenticated bind, which
+	// many servers accept without checking anything
+	if pwd == "" {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := p.bindUser(conn, username, pwd)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry.Email == "" {
+		return nil, fmt.Errorf("directory entry %s has no %s attribute", entry.DN, p.config.Attributes.Email)
+	}
+
+	if err := p.lookupGroups(conn, entry); err != nil {
+		return nil, err
+	}
+
+	// Directory emails are maintained by administrators and count as verified
+	user, err := p.provisioner.ProvisionUser(ctx, p.name, p.config.Provisioning, &provider.ExternalUser{
+		Subject:       entry.ID,
+		Email:         entry.Email,
+		EmailVerified: true,
+		FirstName:     entry.FirstName,
+		LastName:      entry.LastName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != domain.UserStatusActive {
+		return nil, fmt.Errorf("account is not active")
+	}
+
+	if err := provider.SyncGroups(p.groupRepo, user.ID, p.config.GroupMapping, entry.GroupDNs, dnEqual); err != nil {
+		fmt.Printf("Warning: failed to synchronize ldap groups for user %s: %v\n", user.ID, err)
+	}
+
+	return user, nil
+}
+
+func (p *LDAPProvider) GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return p.userRepo.GetByID(id)
+}
+
+func (p *LDAPProvider) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return p.userRepo.GetByEmail(email)
+}
+
+func (p *LDAPProvider) CreateUser(ctx context.Context, user *domain.User) error {
+	return fmt.Errorf("ldap provider %s does not support creating users; create them in the directory", p.name)
+}
+
+func (p *LDAPProvider) UpdateUser(ctx context.Context, user *domain.User) error {
+	return p.userRepo.Update(user)
+}
+
+func (p *LDAPProvider) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return p.userRepo.Delete(id)
+}
+
+func (p *LDAPProvider) ChangePassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	return fmt.Errorf("ldap provider %s does not support changing passwords; change it in the directory", p.name)
+}
+
+func (p *LDAPProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, pwd string) (bool, error) {
+	if pwd == "" {
+		return false, nil
+	}
+
+	user, err := p.userRepo.GetByID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := p.bindUser(conn, user.Email, pwd); err != nil {
+		if errors.Is(err, domain.ErrInvalidCredentials) || errors.Is(err, domain.ErrUserNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (p *LDAPProvider) GetProviderName() string {
+	return p.name
+}
+
+func (p *LDAPProvider) IsEnabled() bool {
+	return p.enabled
+}
+
+// dial connects to the directory, upgrading the connection with StartTLS when configured
+func (p *LDAPProvider) dial() (*goldap.Conn, error) {
+	tlsConfig, err := p.config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := goldap.DialURL(p.config.URL, goldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to connect to ldap server: %w", domain.ErrProviderUnavailable, err)
+	}
+	conn.SetTimeout(p.config.timeout())
+
+	if p.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: ldap StartTLS failed: %w", domain.ErrProviderUnavailable, err)
+		}
+	}
+
+	return conn, nil
+}
+
+// bindUser verifies the user's password with a bind and returns their directory entry.
+// On return the connection is bound as the service account if one is configured, so
+// group lookups do not depend on what users may read about themselves.
+func (p *LDAPProvider) bindUser(conn *goldap.Conn, username, pwd string) (*directoryUser, error) {
+	if p.config.UserDNTemplate != "" {
+		dn := fmt.Sprintf(p.config.UserDNTemplate, goldap.EscapeDN(username))
+		if err := p.bind(conn, dn, pwd); err != nil {
+			return nil, err
+		}
+
+		// A UPN bind name is not a DN, so with a base DN the entry is found by search
+		var entry *goldap.Entry
+		var err error
+		if p.config.BaseDN != "" {
+			entry, err = p.searchUser(conn, username)
+		} else {
+			entry, err = p.readEntry(conn, dn)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := p.bindService(conn); err != nil {
+			return nil, err
+		}
+
+		return p.toDirectoryUser(entry), nil
+	}
+
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	entry, err := p.searchUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.bind(conn, entry.DN, pwd); err != nil {
+		return nil, err
+	}
+
+	if err := p.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	return p.toDirectoryUser(entry), nil
+}
+
+// bind authenticates as dn, mapping rejected credentials to domain.ErrInvalidCredentials
+func (p *LDAPProvider) bind(conn *goldap.Conn, dn, pwd string) error {
+	if err := conn.Bind(dn, pwd); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return domain.ErrInvalidCredentials
+		}
+		return fmt.Errorf("%w: ldap bind failed: %w", domain.ErrProviderUnavailable, err)
+	}
+	return nil
+}
+
+// bindService binds as the configured service account. Without one the connection
+// keeps its current identity: anonymous before the user bind, the user after it.
+func (p *LDAPProvider) bindService(conn *goldap.Conn) error {
+	if p.config.BindDN == "" {
+		return nil
+	}
+
+	if err := conn.Bind(p.config.BindDN, p.config.BindPassword); err != nil {
+		return fmt.Errorf("%w: ldap service bind failed: %w", domain.ErrProviderUnavailable, err)
+	}
+	return nil
+}
+
+func (p *LDAPProvider) searchUser(conn *goldap.Conn, username string) (*goldap.Entry, error) {
+	request := goldap.NewSearchRequest(
+		p.config.BaseDN,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		2, p.config.TimeoutSeconds, false,
+		fmt.Sprintf(p.config.UserFilter, goldap.EscapeFilter(username)),
+		p.attributes(),
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil && !goldap.IsErrorWithCode(err, goldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("%w: ldap user search failed: %w", domain.ErrProviderUnavailable, err)
+	}
+
+	if result == nil || len(result.Entries) == 0 {
+		return nil, domain.ErrUserNotFound
+	}
+
+	// Ambiguous matches are rejected rather than guessing which account was meant
+	if len(result.Entries) != 1 {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return result.Entries[0], nil
+}
+
+func (p *LDAPProvider) readEntry(conn *goldap.Conn, dn string) (*goldap.Entry, error) {
+	request := goldap.NewSearchRequest(
+		dn,
+		goldap.ScopeBaseObject, goldap.NeverDerefAliases,
+		1, p.config.TimeoutSeconds, false,
+		"(objectClass=*)",
+		p.attributes(),
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return nil, fmt.Errorf("ldap entry lookup failed: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, fmt.Errorf("ldap entry %s not found", dn)
+	}
+
+	return result.Entries[0], nil
+}
+
+// lookupGroups adds groups found through GroupBaseDN/GroupFilter to the entry's memberOf groups
+func (p *LDAPProvider) lookupGroups(conn *goldap.Conn, entry *directoryUser) error {
+	if p.config.GroupBaseDN == "" || p.config.GroupFilter == "" {
+		return nil
+	}
+
+	request := goldap.NewSearchRequest(
+		p.config.GroupBaseDN,
+		goldap.ScopeWholeSubtree, goldap.NeverDerefAliases,
+		0, p.config.TimeoutSeconds, false,
+		fmt.Sprintf(p.config.GroupFilter, goldap.EscapeFilter(entry.DN)),
+		[]string{"1.1"}, // no attributes, only DNs
+		nil,
+	)
+
+	result, err := conn.Search(request)
+	if err != nil {
+		return fmt.Errorf("ldap group search failed: %w", err)
+	}
+
+	for _, group := range result.Entries {
+		entry.GroupDNs = append(entry.GroupDNs, group.DN)
+	}
+
+	return nil
+}
+
+func (p *LDAPProvider) attributes() []string {
+	attributes := []string{
+		p.config.Attributes.Email,
+		p.config.Attributes.FirstName,
+		p.config.Attributes.LastName,
+		p.config.Attributes.Groups,
+	}
+	if p.config.Attributes.ID != "" {
+		attributes = append(attributes, p.config.Attributes.ID)
+	}
+	return attributes
+}
+
+func (p *LDAPProvider) toDirectoryUser(entry *goldap.Entry) *directoryUser {
+	return &directoryUser{
+		DN:        entry.DN,
+		ID:        p.entryID(entry),
+		Email:     strings.TrimSpace(entry.GetAttributeValue(p.config.Attributes.Email)),
+		FirstName: entry.GetAttributeValue(p.config.Attributes.FirstName),
+		LastName:  entry.GetAttributeValue(p.config.Attributes.LastName),
+		GroupDNs:  entry.GetAttributeValues(p.config.Attributes.Groups),
+	}
+}
+
+// entryID returns the configured ID attribute, hex-encoded when binary (objectGUID), or
+// the normalized DN
+func (p *LDAPProvider) entryID(entry *goldap.Entry) string {
+	if p.config.Attributes.ID != "" {
+		if raw := entry.GetRawAttributeValue(p.config.Attributes.ID); len(raw) > 0 {
+			if utf8.Valid(raw) && !bytes.ContainsRune(raw, 0) {
+				return string(raw)
+			}
+			return hex.EncodeToString(raw)
+		}
+	}
+
+	if dn, err := goldap.ParseDN(entry.DN); err == nil {
+		return strings.ToLower(dn.String())
+	}
+	return strings.ToLower(entry.DN)
+}
+
+// dnEqual compares DNs case-insensitively and independent of formatting
+func dnEqual(a, b string) bool {
+	dnA, err := goldap.ParseDN(a)
+	if err != nil {
+		return strings.EqualFold(a, b)
+	}
+	dnB, err := goldap.ParseDN(b)
+	if err != nil {
+		return strings.EqualFold(a, b)
+	}
+	return dnA.EqualFold(dnB)
+}
@@ -0,0 +1,421 @@
+package ldap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider/providertest"
+)
+
+const (
+	serviceDN       = "cn=svc,dc=example,dc=com"
+	servicePassword = "svc-secret"
+	staffGroupDN    = "cn=staff,ou=groups,dc=example,dc=com"
+)
+
+// fakeEntry is a directory entry with the password its DN binds with
+type fakeEntry struct {
+	dn         string
+	password   string
+	attributes map[string][]string
+}
+
+// fakeDirectory is an in-process LDAP server answering simple binds and searches with
+// equality, presence, and and or filters. It records the bind DNs and search filters
+// it receives.
+type fakeDirectory struct {
+	listener net.Listener
+	entries  []fakeEntry
+
+	mu      sync.Mutex
+	binds   []string
+	filters []string
+}
+
+func newFakeDirectory(t *testing.T, entries ...fakeEntry) *fakeDirectory {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	d := &fakeDirectory{listener: listener, entries: entries}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+
+	return d
+}
+
+func (d *fakeDirectory) url() string {
+	return "ldap://" + d.listener.Addr().String()
+}
+
+func (d *fakeDirectory) recordedFilters() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.filters...)
+}
+
+func (d *fakeDirectory) recordedBinds() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.binds...)
+}
+
+func (d *fakeDirectory) serve(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			dn := ber.DecodeString(op.Children[1].Data.Bytes())
+			pwd := ber.DecodeString(op.Children[2].Data.Bytes())
+			d.mu.Lock()
+			d.binds = append(d.binds, dn)
+			d.mu.Unlock()
+
+			code := int64(goldap.LDAPResultInvalidCredentials)
+			if d.checkPassword(dn, pwd) {
+				code = goldap.LDAPResultSuccess
+			}
+			conn.Write(ldapResult(messageID, goldap.ApplicationBindResponse, code).Bytes())
+
+		case goldap.ApplicationSearchRequest:
+			base := ber.DecodeString(op.Children[0].Data.Bytes())
+			scope, _ := op.Children[1].Value.(int64)
+			filter := op.Children[6]
+			decompiled, _ := goldap.DecompileFilter(filter)
+			d.mu.Lock()
+			d.filters = append(d.filters, decompiled)
+			d.mu.Unlock()
+
+			for _, entry := range d.entries {
+				if !dnEqual(entry.dn, base) && !(scope != goldap.ScopeBaseObject && strings.HasSuffix(strings.ToLower(entry.dn), ","+strings.ToLower(base))) {
+					continue
+				}
+				if matchFilter(filter, entry) {
+					conn.Write(searchEntry(messageID, entry).Bytes())
+				}
+			}
+			conn.Write(ldapResult(messageID, goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess).Bytes())
+
+		case goldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func (d *fakeDirectory) checkPassword(dn, pwd string) bool {
+	if dn == serviceDN {
+		return pwd == servicePassword
+	}
+	for _, entry := range d.entries {
+		if dnEqual(entry.dn, dn) {
+			return pwd != "" && pwd == entry.password
+		}
+	}
+	return false
+}
+
+func matchFilter(filter *ber.Packet, entry fakeEntry) bool {
+	switch filter.Tag {
+	case goldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+	case goldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterEqualityMatch:
+		attribute := ber.DecodeString(filter.Children[0].Data.Bytes())
+		value := ber.DecodeString(filter.Children[1].Data.Bytes())
+		for _, candidate := range entry.attributes[attribute] {
+			if strings.EqualFold(candidate, value) {
+				return true
+			}
+		}
+		return false
+	case goldap.FilterPresent:
+		attribute := ber.DecodeString(filter.Data.Bytes())
+		return attribute == "objectClass" || len(entry.attributes[attribute]) > 0
+	}
+	return false
+}
+
+func ldapResult(messageID int64, tag ber.Tag, code int64) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	result.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	packet.AppendChild(result)
+	return packet
+}
+
+func searchEntry(messageID int64, entry fakeEntry) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	result := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	result.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, "objectName"))
+	attributes := ber.NewSequence("attributes")
+	for name, values := range entry.attributes {
+		attribute := ber.NewSequence("attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		}
+		attribute.AppendChild(set)
+		attributes.AppendChild(attribute)
+	}
+	result.AppendChild(attributes)
+	packet.AppendChild(result)
+	return packet
+}
+
+func testEntries() []fakeEntry {
+	return []fakeEntry{
+		{
+			dn:       "uid=alice,ou=people,dc=example,dc=com",
+			password: "alice-password",
+			attributes: map[string][]string{
+				"mail":      {"alice@example.com"},
+				"givenName": {"Alice"},
+				"sn":        {"Liddell"},
+				"memberOf":  {"CN=Staff,OU=Groups,DC=example,DC=com"},
+			},
+		},
+		{
+			dn:         "uid=twin1,ou=people,dc=example,dc=com",
+			password:   "twin-password",
+			attributes: map[string][]string{"mail": {"twin@example.com"}},
+		},
+		{
+			dn:         "uid=twin2,ou=people,dc=example,dc=com",
+			password:   "twin-password",
+			attributes: map[string][]string{"mail": {"twin@example.com"}},
+		},
+	}
+}
+
+type testProvider struct {
+	*LDAPProvider
+	users  *providertest.UserRepository
+	groups *providertest.GroupRepository
+}
+
+func newTestProvider(t *testing.T, config map[string]interface{}) testProvider {
+	t.Helper()
+
+	raw, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := providertest.NewUserRepository()
+	groups := providertest.NewGroupRepository()
+	provisioner := providertest.NewProvisioner(users, providertest.NewUserIdentityRepository(), groups)
+
+	p, err := NewLDAPProvider("corp", true, raw, users, groups, provisioner)
+	if err != nil {
+		t.Fatalf("NewLDAPProvider: %v", err)
+	}
+
+	return testProvider{LDAPProvider: p.(*LDAPProvider), users: users, groups: groups}
+}
+
+func TestAuthenticateSearch(t *testing.T) {
+	directory := newFakeDirectory(t, testEntries()...)
+	staff := uuid.New()
+	p := newTestProvider(t, map[string]interface{}{
+		"url":           directory.url(),
+		"bind_dn":       serviceDN,
+		"bind_password": servicePassword,
+		"base_dn":       "ou=people,dc=example,dc=com",
+		"group_mapping": map[string]uuid.UUID{staffGroupDN: staff},
+	})
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  error
+	}{
+		{name: "valid credentials", username: "alice@example.com", password: "alice-password"},
+		{name: "wrong password", username: "alice@example.com", password: "wrong", wantErr: domain.ErrInvalidCredentials},
+		{name: "empty password", username: "alice@example.com", password: "", wantErr: domain.ErrInvalidCredentials},
+		{name: "unknown user", username: "nobody@example.com", password: "alice-password", wantErr: domain.ErrUserNotFound},
+		{name: "ambiguous match", username: "twin@example.com", password: "twin-password", wantErr: domain.ErrInvalidCredentials},
+		{name: "wildcard is literal", username: "*", password: "alice-password", wantErr: domain.ErrUserNotFound},
+		{name: "filter injection is literal", username: "*)(uid=alice", password: "alice-password", wantErr: domain.ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := p.Authenticate(context.Background(), tt.username, tt.password)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if user.Email != "alice@example.com" || user.FirstName != "Alice" || user.LastName != "Liddell" {
+				t.Errorf("Authenticate() user = %+v, want alice's profile", user)
+			}
+			if !p.groups.IsMember(staff, user.ID) {
+				t.Errorf("user was not added to the mapped staff group")
+			}
+		})
+	}
+
+	if got := len(p.users.All()); got != 1 {
+		t.Errorf("provisioned %d users, want 1", got)
+	}
+}
+
+func TestUserFilterEscaping(t *testing.T) {
+	tests := []struct {
+		name       string
+		username   string
+		userFilter string
+		want       string
+	}{
+		{name: "plain email", username: "carol@example.com", want: "(mail=carol@example.com)"},
+		{name: "wildcard", username: "*", want: `(mail=\2a)`},
+		{name: "closing parenthesis", username: "x)(uid=*", want: `(mail=x\29\28uid=\2a)`},
+		{name: "backslash", username: `a\b`, want: `(mail=a\5cb)`},
+		{name: "nul byte", username: "a\x00b", want: `(mail=a\00b)`},
+		{name: "custom filter", username: "*)(|(uid=*", userFilter: "(&(objectClass=person)(uid=%s))", want: `(&(objectClass=person)(uid=\2a\29\28|\28uid=\2a))`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := newFakeDirectory(t, testEntries()...)
+			config := map[string]interface{}{
+				"url":     directory.url(),
+				"base_dn": "ou=people,dc=example,dc=com",
+			}
+			if tt.userFilter != "" {
+				config["user_filter"] = tt.userFilter
+			}
+			p := newTestProvider(t, config)
+
+			if _, err := p.Authenticate(context.Background(), tt.username, "secret"); !errors.Is(err, domain.ErrUserNotFound) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, domain.ErrUserNotFound)
+			}
+
+			filters := directory.recordedFilters()
+			if len(filters) != 1 || filters[0] != tt.want {
+				t.Errorf("search filters = %q, want [%q]", filters, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserDNTemplateEscaping(t *testing.T) {
+	directory := newFakeDirectory(t, testEntries()...)
+	p := newTestProvider(t, map[string]interface{}{
+		"url":              directory.url(),
+		"user_dn_template": "uid=%s,ou=people,dc=example,dc=com",
+	})
+
+	if _, err := p.Authenticate(context.Background(), "alice,ou=people,dc=example,dc=com", "alice-password"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("Authenticate() error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	if _, err := p.Authenticate(context.Background(), "alice", "alice-password"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	want := []string{
+		`uid=alice\,ou=people\,dc=example\,dc=com,ou=people,dc=example,dc=com`,
+		"uid=alice,ou=people,dc=example,dc=com",
+	}
+	binds := directory.recordedBinds()
+	if len(binds) != len(want) {
+		t.Fatalf("bind DNs = %q, want %q", binds, want)
+	}
+	for i := range want {
+		if binds[i] != want[i] {
+			t.Errorf("bind DN %d = %q, want %q", i, binds[i], want[i])
+		}
+	}
+}
+
+func TestGroupFilterEscaping(t *testing.T) {
+	entry := fakeEntry{
+		dn:         "cn=ops (eu)*,ou=people,dc=example,dc=com",
+		password:   "ops-password",
+		attributes: map[string][]string{"mail": {"ops@example.com"}},
+	}
+	directory := newFakeDirectory(t, entry)
+	p := newTestProvider(t, map[string]interface{}{
+		"url":           directory.url(),
+		"base_dn":       "ou=people,dc=example,dc=com",
+		"group_base_dn": "ou=groups,dc=example,dc=com",
+		"group_filter":  "(member=%s)",
+	})
+
+	if _, err := p.Authenticate(context.Background(), "ops@example.com", "ops-password"); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	want := `(member=cn=ops \28eu\29\2a,ou=people,dc=example,dc=com)`
+	filters := directory.recordedFilters()
+	if len(filters) != 2 || filters[1] != want {
+		t.Errorf("search filters = %q, want group filter %q", filters, want)
+	}
+}
+
+func TestDNEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"cn=Staff,ou=Groups,dc=example,dc=com", "CN=staff, OU=groups, DC=example, DC=com", true},
+		{"cn=staff,ou=groups,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com", false},
+		{`cn=a\,b,dc=example,dc=com`, "cn=a,b,dc=example,dc=com", false},
+	}
+
+	for _, tt := range tests {
+		if got := dnEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("dnEqual(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
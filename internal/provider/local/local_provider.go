@@ -3,6 +3,7 @@ package local
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/google/uuid"
 
@@ -11,25 +12,48 @@ import (
 )
 
 type LocalProvider struct {
-	userRepo domain.UserRepository
+	name        string
+	enabled     bool
+	userRepo    domain.UserRepository
+	historyRepo domain.PasswordHistoryRepository
+	historySize int
 }
 
-func NewLocalProvider(userRepo domain.UserRepository) domain.IdentityProvider {
+func NewLocalProvider(name string, enabled bool, userRepo domain.UserRepository, historyRepo domain.PasswordHistoryRepository, policy domain.PasswordPolicy) domain.IdentityProvider {
 	return &LocalProvider{
-		userRepo: userRepo,
+		name:        name,
+		enabled:     enabled,
+		userRepo:    userRepo,
+		historyRepo: historyRepo,
+		historySize: policy.HistorySize,
 	}
 }
 
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// equalizeTiming verifies against a throwaway hash so that unknown emails take as
+// long to reject as wrong passwords and response times do not reveal account existence
+func equalizeTiming(pwd string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = password.HashPassword("aras-auth-timing-equalizer")
+	})
+	password.VerifyPassword(dummyHash, pwd)
+}
+
 func (p *LocalProvider) Authenticate(ctx context.Context, username, pwd string) (*domain.User, error) {
 	// For local provider, username is email
 	user, err := p.userRepo.GetByEmail(username)
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		equalizeTiming(pwd)
+		return nil, domain.ErrUserNotFound
 	}
 
 	// Verify password
 	if err := password.VerifyPassword(user.PasswordHash, pwd); err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, domain.ErrInvalidCredentials
 	}
 
 	// Check if user is active
@@ -37,6 +61,18 @@ func (p *LocalProvider) Authenticate(ctx context.Context, username, pwd string)
 		return nil, fmt.Errorf("account is not active")
 	}
 
+	// Upgrade hashes created with a weaker algorithm or older parameters while
+	// the plaintext password is available. Failures must not block the login.
+	if password.NeedsRehash(user.PasswordHash) {
+		if hashedPassword, err := password.HashPassword(pwd); err == nil {
+			if err := p.userRepo.UpdatePasswordHash(user.ID, hashedPassword); err != nil {
+				fmt.Printf("Warning: failed to rehash password for user %s: %v\n", user.ID, err)
+			} else {
+				user.PasswordHash = hashedPassword
+			}
+		}
+	}
+
 	return user, nil
 }
 
@@ -63,7 +99,18 @@ func (p *LocalProvider) CreateUser(ctx context.Context, user *domain.User) error
 		user.Status = domain.UserStatusPending
 	}
 
-	return p.userRepo.Create(user)
+	if err := p.userRepo.Create(user); err != nil {
+		return err
+	}
+
+	// Record the initial password so it counts towards the reuse history
+	if p.historySize > 0 {
+		if err := p.historyRepo.Add(user.ID, user.PasswordHash); err != nil {
+			fmt.Printf("Warning: failed to record password history for user %s: %v\n", user.ID, err)
+		}
+	}
+
+	return nil
 }
 
 func (p *LocalProvider) UpdateUser(ctx context.Context, user *domain.User) error {
@@ -75,19 +122,60 @@ func (p *LocalProvider) DeleteUser(ctx context.Context, id uuid.UUID) error {
 }
 
 func (p *LocalProvider) ChangePassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	hashedPassword, err := p.HashNewPassword(ctx, userID, newPassword)
+	if err != nil {
+		return err
+	}
+
+	// Update password in database
+	if err := p.userRepo.UpdatePassword(userID, hashedPassword); err != nil {
+		return err
+	}
+
+	return p.RecordPasswordHistory(ctx, userID, hashedPassword)
+}
+
+func (p *LocalProvider) HashNewPassword(ctx context.Context, userID uuid.UUID, newPassword string) (string, error) {
 	// Validate password strength
 	if !password.IsValidPassword(newPassword) {
-		return fmt.Errorf("password does not meet requirements")
+		return "", fmt.Errorf("password does not meet requirements")
+	}
+
+	// Reject reuse of the current password or any of the last N passwords
+	if p.historySize > 0 {
+		recent, err := p.historyRepo.GetRecent(userID, p.historySize)
+		if err != nil {
+			return "", fmt.Errorf("failed to load password history: %w", err)
+		}
+		for _, previousHash := range recent {
+			if password.VerifyPassword(previousHash, newPassword) == nil {
+				return "", fmt.Errorf("password was used recently; choose a password not among your last %d", p.historySize)
+			}
+		}
 	}
 
 	// Hash new password
 	hashedPassword, err := password.HashPassword(newPassword)
 	if err != nil {
-		return fmt.Errorf("failed to hash password: %w", err)
+		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	// Update password in database
-	return p.userRepo.UpdatePassword(userID, hashedPassword)
+	return hashedPassword, nil
+}
+
+func (p *LocalProvider) RecordPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	if p.historySize == 0 {
+		return nil
+	}
+
+	if err := p.historyRepo.Add(userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+	if err := p.historyRepo.Prune(userID, p.historySize); err != nil {
+		fmt.Printf("Warning: failed to prune password history for user %s: %v\n", userID, err)
+	}
+
+	return nil
 }
 
 func (p *LocalProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, pwd string) (bool, error) {
@@ -101,9 +189,9 @@ func (p *LocalProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, pw
 }
 
 func (p *LocalProvider) GetProviderName() string {
-	return "local"
+	return p.name
 }
 
 func (p *LocalProvider) IsEnabled() bool {
-	return true
+	return p.enabled
 }
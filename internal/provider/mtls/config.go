@@ -0,0 +1,116 @@
+package mtls
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// Certificate fields that can identify an account
+const (
+	IdentitySANEmail  = "san_email"
+	IdentitySANDNS    = "san_dns"
+	IdentitySANURI    = "san_uri"
+	IdentitySubjectCN = "subject_cn"
+	IdentitySubject   = "subject"
+)
+
+// Config is the client-certificate provider configuration stored in providers.config
+type Config struct {
+	// CABundle (PEM) restricts sign-in to certificates issued by these CAs. When empty,
+	// any certificate verified by the server against TLS_CLIENT_CA_FILE is accepted.
+	CABundle string `json:"ca_bundle"`
+	// Identity selects the certificate field identifying the account: san_email
+	// (default), san_dns, san_uri, subject_cn or subject (the full distinguished name)
+	Identity string `json:"identity"`
+
+	// Users maps identities to existing users by email, e.g. services signing in as a
+	// service account user. Mapped identities are never provisioned.
+	Users map[string]string `json:"users"`
+	// EmailDomain turns identities that are not email addresses into one, so a device
+	// certificate for "kiosk-12" signs in as kiosk-12@<email_domain>
+	EmailDomain string `json:"email_domain"`
+	// AllowedDomains restricts sign-in to email addresses in these domains
+	AllowedDomains []string `json:"allowed_domains"`
+
+	Provisioning provider.ProvisioningConfig `json:"provisioning"`
+
+	roots *x509.CertPool
+}
+
+// ParseConfig decodes and validates a provider configuration, applying defaults
+func ParseConfig(raw json.RawMessage) (*Config, error) {
+	var cfg Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid mtls config: %w", err)
+		}
+	}
+
+	if cfg.Identity == "" {
+		cfg.Identity = IdentitySANEmail
+	}
+	switch cfg.Identity {
+	case IdentitySANEmail, IdentitySANDNS, IdentitySANURI, IdentitySubjectCN, IdentitySubject:
+	default:
+		return nil, fmt.Errorf("mtls config: identity must be one of san_email, san_dns, san_uri, subject_cn or subject")
+	}
+
+	if cfg.CABundle != "" {
+		cfg.roots = x509.NewCertPool()
+		if !cfg.roots.AppendCertsFromPEM([]byte(cfg.CABundle)) {
+			return nil, fmt.Errorf("mtls config: ca_bundle contains no PEM certificates")
+		}
+	}
+
+	cfg.EmailDomain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(cfg.EmailDomain), "@"))
+
+	users := make(map[string]string, len(cfg.Users))
+	for identity, email := range cfg.Users {
+		users[cfg.normalize(identity)] = email
+	}
+	cfg.Users = users
+
+	for i, domain := range cfg.AllowedDomains {
+		cfg.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+	}
+
+	return &cfg, nil
+}
+
+// normalize lower-cases identities that are case-insensitive (email addresses and DNS names)
+func (c *Config) normalize(identity string) string {
+	identity = strings.TrimSpace(identity)
+	if c.Identity == IdentitySANEmail || c.Identity == IdentitySANDNS {
+		return strings.ToLower(identity)
+	}
+	return identity
+}
+
+// identity extracts the configured identity field from a certificate. Of several SAN
+// values of the same kind the first is used.
+func (c *Config) identity(cert *x509.Certificate) string {
+	var identity string
+	switch c.Identity {
+	case IdentitySANEmail:
+		if len(cert.EmailAddresses) > 0 {
+			identity = cert.EmailAddresses[0]
+		}
+	case IdentitySANDNS:
+		if len(cert.DNSNames) > 0 {
+			identity = cert.DNSNames[0]
+		}
+	case IdentitySANURI:
+		if len(cert.URIs) > 0 {
+			identity = cert.URIs[0].String()
+		}
+	case IdentitySubjectCN:
+		identity = cert.Subject.CommonName
+	case IdentitySubject:
+		identity = cert.Subject.String()
+	}
+	return c.normalize(identity)
+}
@@ -0,0 +1,168 @@
+package mtls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// CertificateProvider authenticates users and service accounts by X.509 client
+// certificates presented over mutual TLS. A certificate field (an email SAN by default)
+// identifies the account; it is mapped to a user explicitly through the users config or
+// linked and provisioned like other external identities.
+type CertificateProvider struct {
+	name        string
+	enabled     bool
+	config      *Config
+	userRepo    domain.UserRepository
+	provisioner *provider.Provisioner
+}
+
+func NewCertificateProvider(name string, enabled bool, rawConfig json.RawMessage, userRepo domain.UserRepository, provisioner *provider.Provisioner) (domain.CertificateProvider, error) {
+	cfg, err := ParseConfig(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateProvider{
+		name:        name,
+		enabled:     enabled,
+		config:      cfg,
+		userRepo:    userRepo,
+		provisioner: provisioner,
+	}, nil
+}
+
+func (p *CertificateProvider) AuthenticateCertificate(ctx context.Context, state *tls.ConnectionState) (*domain.User, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("client certificate required")
+	}
+	leaf := state.PeerCertificates[0]
+
+	if err := p.verify(state); err != nil {
+		return nil, err
+	}
+
+	identity := p.config.identity(leaf)
+	if identity == "" {
+		return nil, fmt.Errorf("client certificate has no %s", p.config.Identity)
+	}
+
+	user, err := p.mapIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != domain.UserStatusActive {
+		return nil, fmt.Errorf("account is not active")
+	}
+
+	return user, nil
+}
+
+// verify checks the certificate against the provider's CA bundle, or requires that the
+// TLS layer verified it against the server's client CAs
+func (p *CertificateProvider) verify(state *tls.ConnectionState) error {
+	if p.config.roots == nil {
+		if len(state.VerifiedChains) == 0 {
+			return fmt.Errorf("%w: not verified by the server", domain.ErrUntrustedCertificate)
+		}
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         p.config.roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("%w by provider %s: %v", domain.ErrUntrustedCertificate, p.name, err)
+	}
+
+	return nil
+}
+
+// mapIdentity resolves a certificate identity to a local user: through the explicit users
+// mapping, or as an email address (derived with email_domain when needed) that is linked
+// or provisioned like any external identity
+func (p *CertificateProvider) mapIdentity(ctx context.Context, identity string) (*domain.User, error) {
+	if email, ok := p.config.Users[identity]; ok {
+		user, err := p.userRepo.GetByEmail(email)
+		if err != nil {
+			return nil, fmt.Errorf("user %s mapped to certificate %s not found", email, identity)
+		}
+		return user, nil
+	}
+
+	email := identity
+	if !strings.Contains(email, "@") {
+		if p.config.EmailDomain == "" || (p.config.Identity != IdentitySubjectCN && p.config.Identity != IdentitySANDNS) {
+			return nil, fmt.Errorf("no user is mapped to certificate %s", identity)
+		}
+		email = strings.ToLower(strings.ReplaceAll(identity, " ", "-")) + "@" + p.config.EmailDomain
+	}
+
+	if !provider.EmailDomainAllowed(email, p.config.AllowedDomains) {
+		return nil, fmt.Errorf("email domain of %s is not allowed for provider %s", email, p.name)
+	}
+
+	// The CA is configured by an administrator and vouches for the identities it certifies
+	return p.provisioner.ProvisionUser(ctx, p.name, p.config.Provisioning, &provider.ExternalUser{
+		Subject:       identity,
+		Email:         email,
+		EmailVerified: true,
+	})
+}
+
+func (p *CertificateProvider) Authenticate(ctx context.Context, username, password string) (*domain.User, error) {
+	return nil, fmt.Errorf("provider %s requires a client certificate via /auth/cert", p.name)
+}
+
+func (p *CertificateProvider) GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return p.userRepo.GetByID(id)
+}
+
+func (p *CertificateProvider) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return p.userRepo.GetByEmail(email)
+}
+
+func (p *CertificateProvider) CreateUser(ctx context.Context, user *domain.User) error {
+	return fmt.Errorf("mtls provider %s does not support creating users", p.name)
+}
+
+func (p *CertificateProvider) UpdateUser(ctx context.Context, user *domain.User) error {
+	return p.userRepo.Update(user)
+}
+
+func (p *CertificateProvider) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return p.userRepo.Delete(id)
+}
+
+func (p *CertificateProvider) ChangePassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	return fmt.Errorf("mtls provider %s does not support changing passwords", p.name)
+}
+
+func (p *CertificateProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, password string) (bool, error) {
+	return false, fmt.Errorf("mtls provider %s does not support password verification", p.name)
+}
+
+func (p *CertificateProvider) GetProviderName() string {
+	return p.name
+}
+
+func (p *CertificateProvider) IsEnabled() bool {
+	return p.enabled
+}
@@ -0,0 +1,295 @@
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider/providertest"
+)
+
+// testCA is a certificate authority issuing test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// certSerial numbers the test certificates
+var certSerial int64
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// sign creates a certificate from template signed by parent (self-signed when parent is nil)
+func sign(t *testing.T, template *x509.Certificate, key *ecdsa.PrivateKey, parent *testCA) *x509.Certificate {
+	t.Helper()
+
+	certSerial++
+	template.SerialNumber = big.NewInt(certSerial)
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+
+	issuer, issuerKey := template, key
+	if parent != nil {
+		issuer, issuerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func newCA(t *testing.T, name string, parent *testCA) *testCA {
+	t.Helper()
+
+	key := newKey(t)
+	cert := sign(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, key, parent)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue signs a client certificate; template fields left empty get client defaults
+func (ca *testCA) issue(t *testing.T, template *x509.Certificate) *x509.Certificate {
+	t.Helper()
+
+	if template.ExtKeyUsage == nil {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	return sign(t, template, newKey(t), ca)
+}
+
+func (ca *testCA) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+}
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestAuthenticateCertificate(t *testing.T) {
+	root := newCA(t, "Test Root CA", nil)
+	intermediate := newCA(t, "Test Issuing CA", root)
+	other := newCA(t, "Other CA", nil)
+
+	serviceAccount := &domain.User{ID: uuid.New(), Email: "billing@example.com", Status: domain.UserStatusActive}
+	suspended := &domain.User{ID: uuid.New(), Email: "suspended@example.com", EmailVerified: true, Status: domain.UserStatusSuspended}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		// chain is presented by the client, leaf first
+		chain []*x509.Certificate
+		// verified marks the chain as verified by the TLS layer
+		verified  bool
+		wantEmail string
+		wantErr   string
+	}{
+		{
+			name:      "email SAN through an intermediate",
+			chain:     []*x509.Certificate{intermediate.issue(t, &x509.Certificate{EmailAddresses: []string{"Alice@Example.com"}}), intermediate.cert},
+			wantEmail: "alice@example.com",
+		},
+		{
+			name:    "missing intermediate",
+			chain:   []*x509.Certificate{intermediate.issue(t, &x509.Certificate{EmailAddresses: []string{"alice@example.com"}})},
+			wantErr: "not trusted",
+		},
+		{
+			name:     "issued by another CA",
+			chain:    []*x509.Certificate{other.issue(t, &x509.Certificate{EmailAddresses: []string{"alice@example.com"}})},
+			verified: true,
+			wantErr:  "not trusted",
+		},
+		{
+			name: "server certificate",
+			chain: []*x509.Certificate{intermediate.issue(t, &x509.Certificate{
+				EmailAddresses: []string{"alice@example.com"},
+				ExtKeyUsage:    []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			}), intermediate.cert},
+			wantErr: "not trusted",
+		},
+		{
+			name: "expired certificate",
+			chain: []*x509.Certificate{intermediate.issue(t, &x509.Certificate{
+				EmailAddresses: []string{"alice@example.com"},
+				NotBefore:      time.Now().Add(-48 * time.Hour),
+				NotAfter:       time.Now().Add(-24 * time.Hour),
+			}), intermediate.cert},
+			wantErr: "not trusted",
+		},
+		{
+			name:      "verified by the server without a bundle",
+			config:    map[string]interface{}{"ca_bundle": ""},
+			chain:     []*x509.Certificate{other.issue(t, &x509.Certificate{EmailAddresses: []string{"alice@example.com"}})},
+			verified:  true,
+			wantEmail: "alice@example.com",
+		},
+		{
+			name:    "not verified by the server without a bundle",
+			config:  map[string]interface{}{"ca_bundle": ""},
+			chain:   []*x509.Certificate{other.issue(t, &x509.Certificate{EmailAddresses: []string{"alice@example.com"}})},
+			wantErr: "not verified by the server",
+		},
+		{
+			name:    "no certificate",
+			wantErr: "client certificate required",
+		},
+		{
+			name:    "no email SAN",
+			chain:   []*x509.Certificate{root.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "alice"}})},
+			wantErr: "has no san_email",
+		},
+		{
+			name:      "DNS SAN with email domain",
+			config:    map[string]interface{}{"identity": IdentitySANDNS, "email_domain": "@Devices.Example.com"},
+			chain:     []*x509.Certificate{root.issue(t, &x509.Certificate{DNSNames: []string{"Kiosk-12.local"}})},
+			wantEmail: "kiosk-12.local@devices.example.com",
+		},
+		{
+			name:    "common name without email domain",
+			config:  map[string]interface{}{"identity": IdentitySubjectCN},
+			chain:   []*x509.Certificate{root.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "kiosk 12"}})},
+			wantErr: "no user is mapped",
+		},
+		{
+			name:      "common name mapped to a service account",
+			config:    map[string]interface{}{"identity": IdentitySubjectCN, "users": map[string]string{" billing-service ": "billing@example.com"}},
+			chain:     []*x509.Certificate{root.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "billing-service"}})},
+			wantEmail: "billing@example.com",
+		},
+		{
+			name:      "URI SAN mapped to a service account",
+			config:    map[string]interface{}{"identity": IdentitySANURI, "users": map[string]string{"spiffe://example.com/billing": "billing@example.com"}},
+			chain:     []*x509.Certificate{root.issue(t, &x509.Certificate{URIs: []*url.URL{mustURL(t, "spiffe://example.com/billing")}})},
+			wantEmail: "billing@example.com",
+		},
+		{
+			name:    "mapped user does not exist",
+			config:  map[string]interface{}{"identity": IdentitySubjectCN, "users": map[string]string{"billing-service": "nobody@example.com"}},
+			chain:   []*x509.Certificate{root.issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: "billing-service"}})},
+			wantErr: "not found",
+		},
+		{
+			name:    "email domain not allowed",
+			config:  map[string]interface{}{"allowed_domains": []string{"corp.example.com"}},
+			chain:   []*x509.Certificate{root.issue(t, &x509.Certificate{EmailAddresses: []string{"alice@example.com"}})},
+			wantErr: "not allowed",
+		},
+		{
+			name:    "suspended user",
+			chain:   []*x509.Certificate{root.issue(t, &x509.Certificate{EmailAddresses: []string{"suspended@example.com"}})},
+			wantErr: "not active",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings := map[string]interface{}{"ca_bundle": root.pem()}
+			for key, value := range tt.config {
+				settings[key] = value
+			}
+			raw, err := json.Marshal(settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			users := providertest.NewUserRepository(serviceAccount, suspended)
+			provisioner := providertest.NewProvisioner(users, providertest.NewUserIdentityRepository(), providertest.NewGroupRepository())
+			p, err := NewCertificateProvider("certs", true, raw, users, provisioner)
+			if err != nil {
+				t.Fatalf("NewCertificateProvider: %v", err)
+			}
+
+			state := &tls.ConnectionState{PeerCertificates: tt.chain}
+			if tt.verified {
+				state.VerifiedChains = [][]*x509.Certificate{tt.chain}
+			}
+
+			user, err := p.AuthenticateCertificate(context.Background(), state)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AuthenticateCertificate() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateCertificate() error = %v", err)
+			}
+			if user.Email != tt.wantEmail {
+				t.Errorf("AuthenticateCertificate() user = %s, want %s", user.Email, tt.wantEmail)
+			}
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{name: "defaults", config: `{}`},
+		{name: "unknown identity", config: `{"identity": "serial"}`, wantErr: "identity must be one of"},
+		{name: "bundle without certificates", config: `{"ca_bundle": "not a certificate"}`, wantErr: "no PEM certificates"},
+		{name: "malformed", config: `{"users": []}`, wantErr: "invalid mtls config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig(json.RawMessage(tt.config))
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			if cfg.Identity != IdentitySANEmail {
+				t.Errorf("default identity = %q, want %q", cfg.Identity, IdentitySANEmail)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// Config is the OIDC provider configuration stored in providers.config
+type Config struct {
+	Issuer       string `json:"issuer"`        // Issuer URL, e.g. https://accounts.google.com
+	ClientID     string `json:"client_id"`     // OAuth2 client ID registered with the issuer
+	ClientSecret string `json:"client_secret"` // OAuth2 client secret
+	// RedirectURL overrides the callback URL registered with the issuer
+	// (default: SERVER_PUBLIC_URL/api/v1/auth/providers/{name}/callback)
+	RedirectURL string   `json:"redirect_url"`
+	Scopes      []string `json:"scopes"` // default: openid, email, profile
+
+	Claims ClaimMapping `json:"claims"`
+
+	// AllowedDomains restricts sign-in to email addresses in these domains
+	AllowedDomains []string `json:"allowed_domains"`
+	// AllowUnverifiedEmail accepts identities whose email the issuer has not verified.
+	// Such identities get a new, unverified user; they are never linked to an existing one.
+	AllowUnverifiedEmail bool `json:"allow_unverified_email"`
+	// AuthParams are extra authorization request parameters, e.g. {"hd": "example.com"}
+	AuthParams map[string]string `json:"auth_params"`
+
+	Provisioning provider.ProvisioningConfig `json:"provisioning"`
+}
+
+// ClaimMapping names the id_token claims mapped onto domain.User fields
+type ClaimMapping struct {
+	Email         string `json:"email"`          // default: "email"
+	EmailVerified string `json:"email_verified"` // default: "email_verified"
+	FirstName     string `json:"first_name"`     // default: "given_name"
+	LastName      string `json:"last_name"`      // default: "family_name"
+}
+
+// ParseConfig decodes and validates a provider configuration, applying defaults
+func ParseConfig(raw json.RawMessage) (*Config, error) {
+	var cfg Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid oidc config: %w", err)
+		}
+	}
+
+	if cfg.Issuer == "" {
+		return nil, fmt.Errorf("oidc config: issuer is required")
+	}
+	if u, err := url.Parse(cfg.Issuer); err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("oidc config: issuer must be an absolute http(s) URL")
+	}
+	if cfg.ClientID == "" {
+		return nil, fmt.Errorf("oidc config: client_id is required")
+	}
+	if cfg.RedirectURL != "" {
+		if u, err := url.Parse(cfg.RedirectURL); err != nil || !u.IsAbs() {
+			return nil, fmt.Errorf("oidc config: redirect_url must be an absolute URL")
+		}
+	}
+
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	hasOpenID := false
+	for _, scope := range cfg.Scopes {
+		if scope == "openid" {
+			hasOpenID = true
+		}
+	}
+	if !hasOpenID {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+
+	if cfg.Claims.Email == "" {
+		cfg.Claims.Email = "email"
+	}
+	if cfg.Claims.EmailVerified == "" {
+		cfg.Claims.EmailVerified = "email_verified"
+	}
+	if cfg.Claims.FirstName == "" {
+		cfg.Claims.FirstName = "given_name"
+	}
+	if cfg.Claims.LastName == "" {
+		cfg.Claims.LastName = "family_name"
+	}
+
+	for i, domain := range cfg.AllowedDomains {
+		cfg.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// OIDCProvider federates sign-in to an external OpenID Connect issuer such as Keycloak
+// or Google Workspace using the authorization code flow with PKCE. Users are mirrored
+// into the local users table on first login and linked by the sub claim.
+type OIDCProvider struct {
+	name        string
+	enabled     bool
+	config      *Config
+	redirectURL string
+	userRepo    domain.UserRepository
+	provisioner *provider.Provisioner
+
+	// Discovery happens on first use so an unreachable issuer does not block startup
+	mu       sync.Mutex
+	issuer   *gooidc.Provider
+	verifier *gooidc.IDTokenVerifier
+}
+
+func NewOIDCProvider(name string, enabled bool, rawConfig json.RawMessage, publicURL string, userRepo domain.UserRepository, provisioner *provider.Provisioner) (domain.FederatedProvider, error) {
+	cfg, err := ParseConfig(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	redirectURL := cfg.RedirectURL
+	if redirectURL == "" {
+		redirectURL = fmt.Sprintf("%s/api/v1/auth/providers/%s/callback", strings.TrimRight(publicURL, "/"), name)
+	}
+
+	return &OIDCProvider{
+		name:        name,
+		enabled:     enabled,
+		config:      cfg,
+		redirectURL: redirectURL,
+		userRepo:    userRepo,
+		provisioner: provisioner,
+	}, nil
+}
+
+func (p *OIDCProvider) BeginLogin(ctx context.Context, login *domain.FederatedLogin) (string, error) {
+	oauth2Config, err := p.oauth2Config()
+	if err != nil {
+		return "", err
+	}
+
+	options := []oauth2.AuthCodeOption{
+		gooidc.Nonce(login.Nonce),
+		oauth2.S256ChallengeOption(login.CodeVerifier),
+	}
+	for key, value := range p.config.AuthParams {
+		options = append(options, oauth2.SetAuthURLParam(key, value))
+	}
+
+	return oauth2Config.AuthCodeURL(login.State, options...), nil
+}
+
+func (p *OIDCProvider) CompleteLogin(ctx context.Context, login *domain.FederatedLogin, code string) (*domain.User, error) {
+	oauth2Config, err := p.oauth2Config()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(login.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("issuer did not return an id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(login.Nonce)) != 1 {
+		return nil, fmt.Errorf("invalid id_token: nonce mismatch")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("invalid id_token claims: %w", err)
+	}
+
+	external, err := p.mapClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+	external.Subject = idToken.Subject
+
+	user, err := p.provisioner.ProvisionUser(ctx, p.name, p.config.Provisioning, external)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != domain.UserStatusActive {
+		return nil, fmt.Errorf("account is not active")
+	}
+
+	return user, nil
+}
+
+func (p *OIDCProvider) Authenticate(ctx context.Context, username, password string) (*domain.User, error) {
+	return nil, fmt.Errorf("provider %s requires browser sign-in via /auth/providers/%s/start", p.name, p.name)
+}
+
+func (p *OIDCProvider) GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return p.userRepo.GetByID(id)
+}
+
+func (p *OIDCProvider) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return p.userRepo.GetByEmail(email)
+}
+
+func (p *OIDCProvider) CreateUser(ctx context.Context, user *domain.User) error {
+	return fmt.Errorf("oidc provider %s does not support creating users", p.name)
+}
+
+func (p *OIDCProvider) UpdateUser(ctx context.Context, user *domain.User) error {
+	return p.userRepo.Update(user)
+}
+
+func (p *OIDCProvider) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return p.userRepo.Delete(id)
+}
+
+func (p *OIDCProvider) ChangePassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	return fmt.Errorf("oidc provider %s does not support changing passwords", p.name)
+}
+
+func (p *OIDCProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, password string) (bool, error) {
+	return false, fmt.Errorf("oidc provider %s does not support password verification", p.name)
+}
+
+func (p *OIDCProvider) GetProviderName() string {
+	return p.name
+}
+
+func (p *OIDCProvider) IsEnabled() bool {
+	return p.enabled
+}
+
+// oauth2Config discovers the issuer on first use and returns the client configuration
+func (p *OIDCProvider) oauth2Config() (*oauth2.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.issuer == nil {
+		// The key set keeps this context for later refreshes, so it must outlive the request
+		issuer, err := gooidc.NewProvider(context.Background(), p.config.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover oidc issuer %s: %w", p.config.Issuer, err)
+		}
+		p.issuer = issuer
+		p.verifier = issuer.Verifier(&gooidc.Config{ClientID: p.config.ClientID})
+	}
+
+	return &oauth2.Config{
+		ClientID:     p.config.ClientID,
+		ClientSecret: p.config.ClientSecret,
+		Endpoint:     p.issuer.Endpoint(),
+		RedirectURL:  p.redirectURL,
+		Scopes:       p.config.Scopes,
+	}, nil
+}
+
+// mapClaims maps id_token claims onto the external profile, enforcing the email policy
+func (p *OIDCProvider) mapClaims(claims map[string]interface{}) (*provider.ExternalUser, error) {
+	email := strings.TrimSpace(stringClaim(claims, p.config.Claims.Email))
+	if email == "" {
+		return nil, fmt.Errorf("id_token has no %s claim", p.config.Claims.Email)
+	}
+
+	// Unverified addresses are never linked to existing users by the provisioner
+	emailVerified := boolClaim(claims, p.config.Claims.EmailVerified)
+	if !p.config.AllowUnverifiedEmail && !emailVerified {
+		return nil, fmt.Errorf("email address %s is not verified by the issuer", email)
+	}
+
+	if !provider.EmailDomainAllowed(email, p.config.AllowedDomains) {
+		return nil, fmt.Errorf("email domain of %s is not allowed for provider %s", email, p.name)
+	}
+
+	return &provider.ExternalUser{
+		Email:         email,
+		EmailVerified: emailVerified,
+		FirstName:     stringClaim(claims, p.config.Claims.FirstName),
+		LastName:      stringClaim(claims, p.config.Claims.LastName),
+	}, nil
+}
+
+func stringClaim(claims map[string]interface{}, name string) string {
+	value, _ := claims[name].(string)
+	return value
+}
+
+// boolClaim reads a boolean claim; some issuers encode booleans as strings
+func boolClaim(claims map[string]interface{}, name string) bool {
+	switch value := claims[name].(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	default:
+		return false
+	}
+}
@@ -0,0 +1,332 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider/providertest"
+)
+
+const (
+	testClientID     = "aras"
+	testClientSecret = "client-secret"
+	testKeyID        = "test-key"
+)
+
+// fakeIssuer is an OpenID Connect issuer serving discovery, a key set and a token
+// endpoint that checks the PKCE verifier of each authorization code
+type fakeIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]issuedCode
+}
+
+// issuedCode is an authorization code with the PKCE challenge it was issued for and the
+// claims of the id_token it is redeemed for
+type issuedCode struct {
+	challenge string
+	claims    jwt.MapClaims
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &fakeIssuer{key: key, codes: make(map[string]issuedCode)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                issuer.url(),
+			"authorization_endpoint":                issuer.url() + "/authorize",
+			"token_endpoint":                        issuer.url() + "/token",
+			"jwks_uri":                              issuer.url() + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": testKeyID,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", issuer.token)
+
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+
+	return issuer
+}
+
+func (i *fakeIssuer) url() string {
+	return i.server.URL
+}
+
+// authorize issues a code for the authorization request URL as if the user signed in.
+// The id_token carries the request's nonce unless claims override it.
+func (i *fakeIssuer) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+
+	idClaims := jwt.MapClaims{
+		"iss":            i.url(),
+		"aud":            testClientID,
+		"sub":            "subject-1",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          query.Get("nonce"),
+		"email":          "alice@example.com",
+		"email_verified": true,
+		"given_name":     "Alice",
+		"family_name":    "Liddell",
+	}
+	for name, value := range claims {
+		if value == nil {
+			delete(idClaims, name)
+			continue
+		}
+		idClaims[name] = value
+	}
+
+	code := "code-" + query.Get("state")
+	i.mu.Lock()
+	i.codes[code] = issuedCode{challenge: query.Get("code_challenge"), claims: idClaims}
+	i.mu.Unlock()
+
+	return code
+}
+
+func (i *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != testClientID || clientSecret != testClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	i.mu.Lock()
+	issued, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+
+	// RFC 7636: the verifier must hash to the challenge sent with the authorization request
+	verifierHash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" ||
+		base64.RawURLEncoding.EncodeToString(verifierHash[:]) != issued.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, issued.claims)
+	token.Header["kid"] = testKeyID
+	idToken, err := token.SignedString(i.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func newTestProvider(t *testing.T, issuer *fakeIssuer, config map[string]interface{}) *OIDCProvider {
+	t.Helper()
+
+	settings := map[string]interface{}{
+		"issuer":        issuer.url(),
+		"client_id":     testClientID,
+		"client_secret": testClientSecret,
+	}
+	for key, value := range config {
+		settings[key] = value
+	}
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := providertest.NewUserRepository()
+	provisioner := providertest.NewProvisioner(users, providertest.NewUserIdentityRepository(), providertest.NewGroupRepository())
+
+	p, err := NewOIDCProvider("corp", true, raw, "https://auth.example.com", users, provisioner)
+	if err != nil {
+		t.Fatalf("NewOIDCProvider: %v", err)
+	}
+	return p.(*OIDCProvider)
+}
+
+func newLogin() *domain.FederatedLogin {
+	return &domain.FederatedLogin{
+		Provider:     "corp",
+		State:        "state-1",
+		Nonce:        "nonce-1",
+		CodeVerifier: "verifier-0123456789-0123456789-0123456789-0123456789",
+	}
+}
+
+func TestBeginLogin(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	p := newTestProvider(t, issuer, map[string]interface{}{
+		"auth_params": map[string]string{"hd": "example.com"},
+	})
+	login := newLogin()
+
+	authURL, err := p.BeginLogin(context.Background(), login)
+	if err != nil {
+		t.Fatalf("BeginLogin() error = %v", err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := sha256.Sum256([]byte(login.CodeVerifier))
+
+	want := map[string]string{
+		"client_id":             testClientID,
+		"redirect_uri":          "https://auth.example.com/api/v1/auth/providers/corp/callback",
+		"response_type":         "code",
+		"scope":                 "openid email profile",
+		"state":                 login.State,
+		"nonce":                 login.Nonce,
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(challenge[:]),
+		"code_challenge_method": "S256",
+		"hd":                    "example.com",
+	}
+	for name, value := range want {
+		if got := u.Query().Get(name); got != value {
+			t.Errorf("authorization URL %s = %q, want %q", name, got, value)
+		}
+	}
+	if u.Query().Has("code_verifier") {
+		t.Errorf("authorization URL leaks the code verifier")
+	}
+}
+
+func TestCompleteLogin(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		claims jwt.MapClaims
+		// verifier replaces the code verifier presented at the callback
+		verifier string
+		wantErr  string
+	}{
+		{name: "valid login"},
+		{name: "nonce mismatch", claims: jwt.MapClaims{"nonce": "other-nonce"}, wantErr: "nonce mismatch"},
+		{name: "missing nonce", claims: jwt.MapClaims{"nonce": nil}, wantErr: "nonce mismatch"},
+		{name: "wrong code verifier", verifier: "attacker-verifier-0123456789-0123456789-0123456789", wantErr: "failed to exchange authorization code"},
+		{name: "wrong audience", claims: jwt.MapClaims{"aud": "other-client"}, wantErr: "invalid id_token"},
+		{name: "wrong issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}, wantErr: "invalid id_token"},
+		{name: "expired id_token", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}, wantErr: "invalid id_token"},
+		{name: "unverified email", claims: jwt.MapClaims{"email_verified": false}, wantErr: "not verified"},
+		{name: "unverified email allowed", config: map[string]interface{}{"allow_unverified_email": true}, claims: jwt.MapClaims{"email_verified": "false"}},
+		{name: "domain not allowed", config: map[string]interface{}{"allowed_domains": []string{"corp.example.com"}}, wantErr: "not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newFakeIssuer(t)
+			p := newTestProvider(t, issuer, tt.config)
+			login := newLogin()
+
+			authURL, err := p.BeginLogin(context.Background(), login)
+			if err != nil {
+				t.Fatalf("BeginLogin() error = %v", err)
+			}
+			code := issuer.authorize(t, authURL, tt.claims)
+
+			if tt.verifier != "" {
+				login.CodeVerifier = tt.verifier
+			}
+			user, err := p.CompleteLogin(context.Background(), login, code)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CompleteLogin() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CompleteLogin() error = %v", err)
+			}
+			if user.Email != "alice@example.com" || user.FirstName != "Alice" || user.LastName != "Liddell" {
+				t.Errorf("CompleteLogin() user = %+v, want alice's profile", user)
+			}
+		})
+	}
+}
+
+func TestCompleteLoginLinksBySubject(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	p := newTestProvider(t, issuer, nil)
+
+	var userIDs []string
+	for _, email := range []string{"alice@example.com", "alice@new.example.com"} {
+		login := newLogin()
+		authURL, err := p.BeginLogin(context.Background(), login)
+		if err != nil {
+			t.Fatalf("BeginLogin() error = %v", err)
+		}
+		code := issuer.authorize(t, authURL, jwt.MapClaims{"email": email})
+
+		user, err := p.CompleteLogin(context.Background(), login, code)
+		if err != nil {
+			t.Fatalf("CompleteLogin() error = %v", err)
+		}
+		userIDs = append(userIDs, user.ID.String())
+	}
+
+	// The second sign-in asserts a new email for the same sub and must find the same user
+	if userIDs[0] != userIDs[1] {
+		t.Errorf("sign-ins with the same sub resolved to users %v", userIDs)
+	}
+}
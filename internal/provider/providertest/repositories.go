@@ -0,0 +1,290 @@
+// Package providertest provides in-memory repositories for testing identity providers
+// and the provisioner without a database. Repository methods a fake does not implement
+// panic through the nil embedded interface.
+package providertest
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// UserRepository is an in-memory domain.UserRepository. It returns copies, like a
+// database would.
+type UserRepository struct {
+	domain.UserRepository
+
+	mu    sync.Mutex
+	users map[uuid.UUID]*domain.User
+}
+
+func NewUserRepository(users ...*domain.User) *UserRepository {
+	r := &UserRepository{users: make(map[uuid.UUID]*domain.User)}
+	for _, user := range users {
+		r.users[user.ID] = copyUser(user)
+	}
+	return r
+}
+
+func (r *UserRepository) Create(user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.users[user.ID] = copyUser(user)
+	return nil
+}
+
+func (r *UserRepository) GetByID(id uuid.UUID) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	return copyUser(user), nil
+}
+
+func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, user := range r.users {
+		if strings.EqualFold(user.Email, email) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *UserRepository) Update(user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[user.ID]; !ok {
+		return domain.ErrUserNotFound
+	}
+	r.users[user.ID] = copyUser(user)
+	return nil
+}
+
+func (r *UserRepository) Delete(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return domain.ErrUserNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *UserRepository) UpdatePassword(id uuid.UUID, passwordHash string) error {
+	return r.UpdatePasswordHash(id, passwordHash)
+}
+
+func (r *UserRepository) UpdatePasswordHash(id uuid.UUID, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return domain.ErrUserNotFound
+	}
+	user.PasswordHash = passwordHash
+	return nil
+}
+
+// All returns every stored user
+func (r *UserRepository) All() []*domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := make([]*domain.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, copyUser(user))
+	}
+	return users
+}
+
+func copyUser(user *domain.User) *domain.User {
+	copied := *user
+	return &copied
+}
+
+// UserIdentityRepository is an in-memory domain.UserIdentityRepository
+type UserIdentityRepository struct {
+	mu         sync.Mutex
+	identities map[uuid.UUID]*domain.UserIdentity
+}
+
+func NewUserIdentityRepository(identities ...*domain.UserIdentity) *UserIdentityRepository {
+	r := &UserIdentityRepository{identities: make(map[uuid.UUID]*domain.UserIdentity)}
+	for _, identity := range identities {
+		copied := *identity
+		r.identities[identity.ID] = &copied
+	}
+	return r
+}
+
+func (r *UserIdentityRepository) Create(identity *domain.UserIdentity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copied := *identity
+	r.identities[identity.ID] = &copied
+	return nil
+}
+
+func (r *UserIdentityRepository) GetByID(id uuid.UUID) (*domain.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	identity, ok := r.identities[id]
+	if !ok {
+		return nil, domain.ErrIdentityNotFound
+	}
+	copied := *identity
+	return &copied, nil
+}
+
+func (r *UserIdentityRepository) GetByProviderSubject(providerName, subject string) (*domain.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, identity := range r.identities {
+		if identity.Provider == providerName && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, domain.ErrIdentityNotFound
+}
+
+func (r *UserIdentityRepository) GetByUserID(userID uuid.UUID) ([]*domain.UserIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var identities []*domain.UserIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			copied := *identity
+			identities = append(identities, &copied)
+		}
+	}
+	return identities, nil
+}
+
+func (r *UserIdentityRepository) UpdateLastLogin(id uuid.UUID) error {
+	return nil
+}
+
+func (r *UserIdentityRepository) Delete(id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.identities[id]; !ok {
+		return domain.ErrIdentityNotFound
+	}
+	delete(r.identities, id)
+	return nil
+}
+
+// GroupRepository is an in-memory domain.GroupRepository tracking direct memberships only
+type GroupRepository struct {
+	domain.GroupRepository
+
+	mu      sync.Mutex
+	members map[uuid.UUID]map[uuid.UUID]bool
+}
+
+func NewGroupRepository() *GroupRepository {
+	return &GroupRepository{members: make(map[uuid.UUID]map[uuid.UUID]bool)}
+}
+
+func (r *GroupRepository) AddMember(groupID, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.members[groupID] == nil {
+		r.members[groupID] = make(map[uuid.UUID]bool)
+	}
+	r.members[groupID][userID] = true
+	return nil
+}
+
+func (r *GroupRepository) RemoveMember(groupID, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.members[groupID], userID)
+	return nil
+}
+
+func (r *GroupRepository) GetUserGroups(userID uuid.UUID, scope domain.MembershipScope) ([]*domain.Group, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var groups []*domain.Group
+	for groupID, members := range r.members {
+		if members[userID] {
+			groups = append(groups, &domain.Group{ID: groupID})
+		}
+	}
+	return groups, nil
+}
+
+// IsMember reports whether the user is a direct member of the group
+func (r *GroupRepository) IsMember(groupID, userID uuid.UUID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.members[groupID][userID]
+}
+
+// SAMLAssertionRepository is an in-memory domain.SAMLAssertionRepository. Share one
+// between providers to stand for instances using the same database.
+type SAMLAssertionRepository struct {
+	mu       sync.Mutex
+	consumed map[string]time.Time
+}
+
+func NewSAMLAssertionRepository() *SAMLAssertionRepository {
+	return &SAMLAssertionRepository{consumed: make(map[string]time.Time)}
+}
+
+func (r *SAMLAssertionRepository) Consume(provider, assertionID string, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := provider + "/" + assertionID
+	if _, ok := r.consumed[key]; ok {
+		return false, nil
+	}
+	r.consumed[key] = expiresAt
+	return true, nil
+}
+
+func (r *SAMLAssertionRepository) DeleteExpired() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for key, expiresAt := range r.consumed {
+		if expiresAt.Before(now) {
+			delete(r.consumed, key)
+		}
+	}
+	return nil
+}
+
+// NewProvisioner returns a provisioner over the given fakes. Default roles are not
+// supported.
+func NewProvisioner(users *UserRepository, identities *UserIdentityRepository, groups *GroupRepository) *provider.Provisioner {
+	return provider.NewProvisioner(users, identities, nil, groups)
+}
@@ -0,0 +1,327 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/password"
+)
+
+// ErrIdentityNotLinked is returned when an external identity's email belongs to an
+// existing user it may not be linked to automatically
+var ErrIdentityNotLinked = errors.New("an account with this email already exists; sign in to it and link this identity from /users/me/identities")
+
+// ExternalUser is the profile an external identity provider asserts for a user
+type ExternalUser struct {
+	// Subject is the provider's stable identifier of the account
+	Subject string
+	Email   string
+	// EmailVerified reports whether the provider vouches for the email address
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+}
+
+// ProvisioningConfig controls just-in-time provisioning of users signing in through an
+// external provider. It is part of each external provider's config as "provisioning".
+type ProvisioningConfig struct {
+	// CreateUsers creates a local user on first sign-in (default: true)
+	CreateUsers *bool `json:"create_users"`
+	// LinkByEmail links a first sign-in to the existing user with the same email when
+	// both addresses are verified (default: true)
+	LinkByEmail *bool `json:"link_by_email"`
+	// DefaultRoles (names) and DefaultGroups (IDs) are granted to created users
+	DefaultRoles  []string    `json:"default_roles"`
+	DefaultGroups []uuid.UUID `json:"default_groups"`
+}
+
+func (c ProvisioningConfig) createUsers() bool {
+	return c.CreateUsers == nil || *c.CreateUsers
+}
+
+func (c ProvisioningConfig) linkByEmail() bool {
+	return c.LinkByEmail == nil || *c.LinkByEmail
+}
+
+// Provisioner maps external identities to local users through the user_identities table
+type Provisioner struct {
+	userRepo     domain.UserRepository
+	identityRepo domain.UserIdentityRepository
+	roleRepo     domain.RoleRepository
+	groupRepo    domain.GroupRepository
+}
+
+func NewProvisioner(userRepo domain.UserRepository, identityRepo domain.UserIdentityRepository, roleRepo domain.RoleRepository, groupRepo domain.GroupRepository) *Provisioner {
+	return &Provisioner{
+		userRepo:     userRepo,
+		identityRepo: identityRepo,
+		roleRepo:     roleRepo,
+		groupRepo:    groupRepo,
+	}
+}
+
+// ProvisionUser returns the local user for an identity authenticated by providerName.
+//
+// A linked identity resolves to its user, whose name is refreshed from the external profile.
+// On first sign-in the identity is linked to the user with the same email, but only when
+// the provider verified the email and the local user verified it too, so neither an
+// unverified external email nor a squatted local registration can take over an account.
+// Otherwise a new user is created without a usable local password.
+//
+// When ctx carries a link target (domain.WithIdentityLink) the identity is linked to that
+// user instead, unless it already belongs to someone else.
+func (p *Provisioner) ProvisionUser(ctx context.Context, providerName string, config ProvisioningConfig, external *ExternalUser) (*domain.User, error) {
+	if external.Subject == "" {
+		return nil, fmt.Errorf("external identity has no subject")
+	}
+	email := strings.TrimSpace(external.Email)
+
+	linkTo, linking := domain.IdentityLinkTarget(ctx)
+
+	identity, err := p.identityRepo.GetByProviderSubject(providerName, external.Subject)
+	if err == nil {
+		if linking && identity.UserID != linkTo {
+			return nil, fmt.Errorf("this %s account is already linked to another user", providerName)
+		}
+
+		user, err := p.userRepo.GetByID(identity.UserID)
+		if err == nil {
+			p.refreshProfile(user, external)
+			if err := p.identityRepo.UpdateLastLogin(identity.ID); err != nil {
+				fmt.Printf("Warning: failed to update identity %s: %v\n", identity.ID, err)
+			}
+			return user, nil
+		}
+		if !errors.Is(err, domain.ErrUserNotFound) {
+			return nil, err
+		}
+
+		// The user was deleted; the stale link must not block a fresh sign-in
+		if err := p.identityRepo.Delete(identity.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if linking {
+		user, err := p.userRepo.GetByID(linkTo)
+		if err != nil {
+			return nil, err
+		}
+		linked, err := p.identityRepo.GetByUserID(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, other := range linked {
+			if other.Provider == providerName {
+				return nil, fmt.Errorf("another %s account is already linked; unlink it first", providerName)
+			}
+		}
+		if err := p.link(user, providerName, external); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if email == "" {
+		return nil, fmt.Errorf("external identity has no email address")
+	}
+
+	if user, err := p.userRepo.GetByEmail(email); err == nil {
+		if !config.linkByEmail() || !external.EmailVerified || !user.EmailVerified {
+			return nil, ErrIdentityNotLinked
+		}
+		if err := p.link(user, providerName, external); err != nil {
+			return nil, err
+		}
+		p.refreshProfile(user, external)
+		return user, nil
+	}
+
+	if !config.createUsers() {
+		return nil, fmt.Errorf("no account exists for %s; ask an administrator to create one", email)
+	}
+
+	now := time.Now()
+	user := &domain.User{
+		ID:                uuid.New(),
+		Email:             email,
+		PasswordHash:      password.Unusable,
+		FirstName:         external.FirstName,
+		LastName:          external.LastName,
+		Status:            domain.UserStatusActive,
+		EmailVerified:     external.EmailVerified,
+		PasswordChangedAt: now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := p.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+
+	if err := p.link(user, providerName, external); err != nil {
+		return nil, err
+	}
+
+	p.grantDefaults(user, config)
+
+	return user, nil
+}
+
+// LinkedUser returns the local user an identity of providerName is linked to, without
+// provisioning or refreshing anything. It returns domain.ErrUserNotFound when the identity
+// is not linked.
+func (p *Provisioner) LinkedUser(providerName, subject string) (*domain.User, error) {
+	identity, err := p.identityRepo.GetByProviderSubject(providerName, subject)
+	if err != nil {
+		if errors.Is(err, domain.ErrIdentityNotFound) {
+			return nil, domain.ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	return p.userRepo.GetByID(identity.UserID)
+}
+
+// IsLinked reports whether the user has an identity of providerName
+func (p *Provisioner) IsLinked(providerName string, userID uuid.UUID) (bool, error) {
+	identities, err := p.identityRepo.GetByUserID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, identity := range identities {
+		if identity.Provider == providerName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (p *Provisioner) link(user *domain.User, providerName string, external *ExternalUser) error {
+	now := time.Now()
+	identity := &domain.UserIdentity{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		Provider:    providerName,
+		Subject:     external.Subject,
+		Email:       strings.TrimSpace(external.Email),
+		LastLoginAt: &now,
+		CreatedAt:   now,
+	}
+
+	if err := p.identityRepo.Create(identity); err != nil {
+		return fmt.Errorf("failed to link %s identity: %w", providerName, err)
+	}
+
+	return nil
+}
+
+// refreshProfile copies the external name onto the user. The email is left alone: it
+// changes only through the verified email change flow.
+func (p *Provisioner) refreshProfile(user *domain.User, external *ExternalUser) {
+	if user.FirstName == external.FirstName && user.LastName == external.LastName {
+		return
+	}
+
+	user.FirstName = external.FirstName
+	user.LastName = external.LastName
+	if err := p.userRepo.Update(user); err != nil {
+		fmt.Printf("Warning: failed to update provisioned user %s: %v\n", user.ID, err)
+	}
+}
+
+// grantDefaults assigns the configured default roles and groups to a created user.
+// Failures are logged so a misnamed role does not block sign-in.
+func (p *Provisioner) grantDefaults(user *domain.User, config ProvisioningConfig) {
+	for _, roleName := range config.DefaultRoles {
+		role, err := p.roleRepo.GetByName(roleName)
+		if err != nil {
+			fmt.Printf("Warning: default role %s not found: %v\n", roleName, err)
+			continue
+		}
+		if err := p.roleRepo.AssignToUser(user.ID, role.ID, ""); err != nil {
+			fmt.Printf("Warning: failed to assign default role %s to user %s: %v\n", roleName, user.ID, err)
+		}
+	}
+
+	for _, groupID := range config.DefaultGroups {
+		if err := p.groupRepo.AddMember(groupID, user.ID); err != nil {
+			fmt.Printf("Warning: failed to add user %s to default group %s: %v\n", user.ID, groupID, err)
+		}
+	}
+}
+
+// SyncGroups makes the user's membership of mapped aras groups match the groups asserted by
+// an external provider. mapping keys are external group identifiers compared with match;
+// groups that do not appear in the mapping are never touched.
+func SyncGroups(groupRepo domain.GroupRepository, userID uuid.UUID, mapping map[string]uuid.UUID, externalGroups []string, match func(mapped, external string) bool) error {
+	if len(mapping) == 0 {
+		return nil
+	}
+
+	wanted := make(map[uuid.UUID]bool)
+	managed := make(map[uuid.UUID]bool)
+	for mappedGroup, groupID := range mapping {
+		managed[groupID] = true
+		for _, externalGroup := range externalGroups {
+			if match(mappedGroup, externalGroup) {
+				wanted[groupID] = true
+				break
+			}
+		}
+	}
+
+	current, err := groupRepo.GetUserGroups(userID, domain.MembershipDirect)
+	if err != nil {
+		return err
+	}
+
+	isMember := make(map[uuid.UUID]bool)
+	for _, group := range current {
+		isMember[group.ID] = true
+		if managed[group.ID] && !wanted[group.ID] {
+			if err := groupRepo.RemoveMember(group.ID, userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for groupID := range wanted {
+		if !isMember[groupID] {
+			if err := groupRepo.AddMember(groupID, userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// EmailDomainAllowed reports whether email belongs to one of the lower-case domains.
+// An empty list allows every domain.
+func EmailDomainAllowed(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	emailDomain := strings.ToLower(email[at+1:])
+	for _, allowed := range domains {
+		if emailDomain == allowed {
+			return true
+		}
+	}
+
+	return false
+}
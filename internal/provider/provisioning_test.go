@@ -0,0 +1,82 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+	"github.com/aras-services/aras-auth/internal/provider/providertest"
+)
+
+var errOutage = errors.New("connection refused")
+
+// unavailableUsers fails user lookups by ID as a database outage would
+type unavailableUsers struct {
+	*providertest.UserRepository
+}
+
+func (r *unavailableUsers) GetByID(id uuid.UUID) (*domain.User, error) {
+	return nil, errOutage
+}
+
+func TestProvisionUserLinkedToMissingUser(t *testing.T) {
+	tests := []struct {
+		name string
+		// outage fails user lookups instead of reporting the user as deleted
+		outage  bool
+		wantErr error
+	}{
+		{name: "deleted user is replaced"},
+		{name: "lookup failure keeps the link", outage: true, wantErr: errOutage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staleUserID := uuid.New()
+			identity := &domain.UserIdentity{
+				ID:        uuid.New(),
+				UserID:    staleUserID,
+				Provider:  "corp",
+				Subject:   "subject-1",
+				CreatedAt: time.Now(),
+			}
+			users := providertest.NewUserRepository()
+			identities := providertest.NewUserIdentityRepository(identity)
+
+			var userRepo domain.UserRepository = users
+			if tt.outage {
+				userRepo = &unavailableUsers{users}
+			}
+			provisioner := provider.NewProvisioner(userRepo, identities, nil, providertest.NewGroupRepository())
+
+			user, err := provisioner.ProvisionUser(context.Background(), "corp", provider.ProvisioningConfig{}, &provider.ExternalUser{
+				Subject:       "subject-1",
+				Email:         "alice@example.com",
+				EmailVerified: true,
+			})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ProvisionUser() error = %v, want %v", err, tt.wantErr)
+				}
+				if _, err := identities.GetByID(identity.ID); err != nil {
+					t.Errorf("identity link was deleted after a failed lookup")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProvisionUser() error = %v", err)
+			}
+
+			linked, err := identities.GetByProviderSubject("corp", "subject-1")
+			if err != nil || linked.UserID != user.ID || user.ID == staleUserID {
+				t.Errorf("identity links to %v, want the new user %s", linked, user.ID)
+			}
+		})
+	}
+}
@@ -42,6 +42,45 @@ func (r *ProviderRegistry) RegisterProvider(provider domain.IdentityProvider) er
 	return nil
 }
 
+func (r *ProviderRegistry) ReplaceProvider(provider domain.IdentityProvider) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := provider.GetProviderName()
+	if name == "" {
+		return fmt.Errorf("provider name cannot be empty")
+	}
+
+	r.providers[name] = provider
+
+	if r.defaultProvider == "" || name == "local" {
+		r.defaultProvider = name
+	}
+
+	return nil
+}
+
+func (r *ProviderRegistry) UnregisterProvider(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.providers[name]; !exists {
+		return fmt.Errorf("provider %s not found", name)
+	}
+
+	delete(r.providers, name)
+
+	// Fall back to the local provider, or to none, when the default is removed
+	if r.defaultProvider == name {
+		r.defaultProvider = ""
+		if _, exists := r.providers["local"]; exists {
+			r.defaultProvider = "local"
+		}
+	}
+
+	return nil
+}
+
 func (r *ProviderRegistry) GetProvider(name string) (domain.IdentityProvider, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -0,0 +1,130 @@
+package saml
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// Config is the SAML service provider configuration stored in providers.config
+type Config struct {
+	// IDPMetadataURL or IDPMetadata (XML) describe the identity provider; the URL is
+	// fetched on first use
+	IDPMetadataURL string `json:"idp_metadata_url"`
+	IDPMetadata    string `json:"idp_metadata"`
+
+	// EntityID identifies this service provider (default: its metadata URL)
+	EntityID string `json:"entity_id"`
+	// Certificate and PrivateKey (PEM, RSA) are published in the SP metadata and used to
+	// sign authentication requests and decrypt encrypted assertions
+	Certificate  string `json:"certificate"`
+	PrivateKey   string `json:"private_key"`
+	SignRequests bool   `json:"sign_requests"`
+
+	// AllowIDPInitiated accepts unsolicited responses started from the IdP portal
+	AllowIDPInitiated bool   `json:"allow_idp_initiated"`
+	NameIDFormat      string `json:"name_id_format"` // default: emailAddress
+
+	Attributes AttributeMapping `json:"attributes"`
+
+	// GroupMapping maps values of the groups attribute to aras group IDs. Membership of
+	// mapped groups is synchronized on every login; unmapped groups are left untouched.
+	GroupMapping map[string]uuid.UUID `json:"group_mapping"`
+	// AllowedDomains restricts sign-in to email addresses in these domains
+	AllowedDomains []string `json:"allowed_domains"`
+
+	Provisioning provider.ProvisioningConfig `json:"provisioning"`
+}
+
+// AttributeMapping names the assertion attributes (Name or FriendlyName) mapped onto
+// domain.User fields. The email falls back to the NameID when the attribute is missing.
+type AttributeMapping struct {
+	// ID is a stable user identifier attribute; the NameID is used when unset, so use a
+	// persistent NameID format or set this when NameIDs are emails or transient
+	ID        string `json:"id"`
+	Email     string `json:"email"`      // default: "email"
+	FirstName string `json:"first_name"` // default: "firstName"
+	LastName  string `json:"last_name"`  // default: "lastName"
+	Groups    string `json:"groups"`     // default: "groups"
+}
+
+// ParseConfig decodes and validates a provider configuration, applying defaults
+func ParseConfig(raw json.RawMessage) (*Config, error) {
+	var cfg Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid saml config: %w", err)
+		}
+	}
+
+	if (cfg.IDPMetadataURL == "") == (cfg.IDPMetadata == "") {
+		return nil, fmt.Errorf("saml config: exactly one of idp_metadata_url or idp_metadata is required")
+	}
+	if cfg.IDPMetadataURL != "" {
+		if u, err := url.Parse(cfg.IDPMetadataURL); err != nil || !u.IsAbs() {
+			return nil, fmt.Errorf("saml config: idp_metadata_url must be an absolute URL")
+		}
+	}
+
+	if (cfg.Certificate == "") != (cfg.PrivateKey == "") {
+		return nil, fmt.Errorf("saml config: certificate and private_key must be set together")
+	}
+	if cfg.SignRequests && cfg.Certificate == "" {
+		return nil, fmt.Errorf("saml config: sign_requests requires certificate and private_key")
+	}
+	if cfg.Certificate != "" {
+		if _, _, err := cfg.keyPair(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.NameIDFormat == "" {
+		cfg.NameIDFormat = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
+	}
+	if cfg.Attributes.Email == "" {
+		cfg.Attributes.Email = "email"
+	}
+	if cfg.Attributes.FirstName == "" {
+		cfg.Attributes.FirstName = "firstName"
+	}
+	if cfg.Attributes.LastName == "" {
+		cfg.Attributes.LastName = "lastName"
+	}
+	if cfg.Attributes.Groups == "" {
+		cfg.Attributes.Groups = "groups"
+	}
+
+	for i, domain := range cfg.AllowedDomains {
+		cfg.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+	}
+
+	return &cfg, nil
+}
+
+// keyPair parses the SP certificate and RSA private key
+func (c *Config) keyPair() (*x509.Certificate, *rsa.PrivateKey, error) {
+	pair, err := tls.X509KeyPair([]byte(c.Certificate), []byte(c.PrivateKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("saml config: invalid certificate or private_key: %w", err)
+	}
+
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("saml config: private_key must be an RSA key")
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("saml config: invalid certificate: %w", err)
+	}
+
+	return cert, key, nil
+}
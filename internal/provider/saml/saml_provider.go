@@ -0,0 +1,376 @@
+package saml
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	gosaml "github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/google/uuid"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+var errInvalidResponse = errors.New("invalid SAML response")
+
+// SAMLProvider is a SAML 2.0 service provider. It supports SP-initiated SSO with the
+// HTTP-Redirect binding and, when allowed, IdP-initiated SSO; assertions are posted to
+// the ACS. Users are mirrored into the local users table on first login and linked by
+// their subject (NameID by default).
+type SAMLProvider struct {
+	name          string
+	enabled       bool
+	config        *Config
+	userRepo      domain.UserRepository
+	groupRepo     domain.GroupRepository
+	assertionRepo domain.SAMLAssertionRepository
+	provisioner   *provider.Provisioner
+
+	metadataURL url.URL
+	acsURL      url.URL
+
+	// IdP metadata is loaded on first use so an unreachable IdP does not block startup
+	mu          sync.Mutex
+	idpMetadata *gosaml.EntityDescriptor
+}
+
+func NewSAMLProvider(name string, enabled bool, rawConfig json.RawMessage, publicURL string, userRepo domain.UserRepository, groupRepo domain.GroupRepository, assertionRepo domain.SAMLAssertionRepository, provisioner *provider.Provisioner) (domain.SAMLProvider, error) {
+	cfg, err := ParseConfig(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	base := fmt.Sprintf("%s/api/v1/auth/providers/%s", strings.TrimRight(publicURL, "/"), url.PathEscape(name))
+	metadataURL, err := url.Parse(base + "/metadata")
+	if err != nil {
+		return nil, fmt.Errorf("invalid public URL: %w", err)
+	}
+	acsURL, err := url.Parse(base + "/acs")
+	if err != nil {
+		return nil, fmt.Errorf("invalid public URL: %w", err)
+	}
+
+	p := &SAMLProvider{
+		name:          name,
+		enabled:       enabled,
+		config:        cfg,
+		userRepo:      userRepo,
+		groupRepo:     groupRepo,
+		assertionRepo: assertionRepo,
+		provisioner:   provisioner,
+		metadataURL:   *metadataURL,
+		acsURL:        *acsURL,
+	}
+
+	if cfg.IDPMetadata != "" {
+		if p.idpMetadata, err = samlsp.ParseMetadata([]byte(cfg.IDPMetadata)); err != nil {
+			return nil, fmt.Errorf("saml config: invalid idp_metadata: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+func (p *SAMLProvider) BeginLogin(ctx context.Context, login *domain.FederatedLogin) (string, error) {
+	sp, err := p.serviceProvider(ctx, false)
+	if err != nil {
+		return "", err
+	}
+
+	ssoURL := sp.GetSSOBindingLocation(gosaml.HTTPRedirectBinding)
+	if ssoURL == "" {
+		return "", fmt.Errorf("identity provider has no HTTP-Redirect single sign-on endpoint")
+	}
+
+	request, err := sp.MakeAuthenticationRequest(ssoURL, gosaml.HTTPRedirectBinding, gosaml.HTTPPostBinding)
+	if err != nil {
+		return "", fmt.Errorf("failed to create authentication request: %w", err)
+	}
+	// The request ID is derived from the browser-held nonce so the ACS can check
+	// InResponseTo without storing outstanding requests
+	request.ID = requestID(login)
+
+	redirectURL, err := request.Redirect(login.State, sp)
+	if err != nil {
+		return "", fmt.Errorf("failed to create authentication request: %w", err)
+	}
+
+	return redirectURL.String(), nil
+}
+
+func (p *SAMLProvider) ConsumeAssertion(ctx context.Context, login *domain.FederatedLogin, samlResponse string) (*domain.User, error) {
+	if login == nil && !p.config.AllowIDPInitiated {
+		return nil, fmt.Errorf("unsolicited SAML responses are not allowed for provider %s", p.name)
+	}
+
+	sp, err := p.serviceProvider(ctx, login == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	responseXML, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, errInvalidResponse
+	}
+
+	var possibleRequestIDs []string
+	if login != nil {
+		possibleRequestIDs = []string{requestID(login)}
+	}
+
+	assertion, err := sp.ParseXMLResponse(responseXML, possibleRequestIDs)
+	if err != nil {
+		var invalid *gosaml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		fmt.Printf("Warning: rejected SAML response for provider %s: %v\n", p.name, err)
+		return nil, errInvalidResponse
+	}
+
+	// An IdP-initiated response must not answer a request; otherwise a response captured
+	// from another browser's SP-initiated login could be replayed as unsolicited
+	if login == nil && inResponseTo(assertion) != "" {
+		return nil, errInvalidResponse
+	}
+
+	fresh, err := p.consume(assertion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record SAML assertion: %w", err)
+	}
+	if !fresh {
+		return nil, errInvalidResponse
+	}
+
+	external, groups, err := p.mapAssertion(assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := p.provisioner.ProvisionUser(ctx, p.name, p.config.Provisioning, external)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != domain.UserStatusActive {
+		return nil, fmt.Errorf("account is not active")
+	}
+
+	if err := provider.SyncGroups(p.groupRepo, user.ID, p.config.GroupMapping, groups, strings.EqualFold); err != nil {
+		fmt.Printf("Warning: failed to synchronize saml groups for user %s: %v\n", user.ID, err)
+	}
+
+	return user, nil
+}
+
+func (p *SAMLProvider) Metadata(ctx context.Context) ([]byte, error) {
+	sp, err := p.serviceProvider(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func (p *SAMLProvider) Authenticate(ctx context.Context, username, password string) (*domain.User, error) {
+	return nil, fmt.Errorf("provider %s requires browser sign-in via /auth/providers/%s/start", p.name, p.name)
+}
+
+func (p *SAMLProvider) GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return p.userRepo.GetByID(id)
+}
+
+func (p *SAMLProvider) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return p.userRepo.GetByEmail(email)
+}
+
+func (p *SAMLProvider) CreateUser(ctx context.Context, user *domain.User) error {
+	return fmt.Errorf("saml provider %s does not support creating users", p.name)
+}
+
+func (p *SAMLProvider) UpdateUser(ctx context.Context, user *domain.User) error {
+	return p.userRepo.Update(user)
+}
+
+func (p *SAMLProvider) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return p.userRepo.Delete(id)
+}
+
+func (p *SAMLProvider) ChangePassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	return fmt.Errorf("saml provider %s does not support changing passwords", p.name)
+}
+
+func (p *SAMLProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, password string) (bool, error) {
+	return false, fmt.Errorf("saml provider %s does not support password verification", p.name)
+}
+
+func (p *SAMLProvider) GetProviderName() string {
+	return p.name
+}
+
+func (p *SAMLProvider) IsEnabled() bool {
+	return p.enabled
+}
+
+// serviceProvider returns the crewjam service provider, loading IdP metadata on first use.
+// A fresh value is built per call since AllowIDPInitiated differs between requests.
+func (p *SAMLProvider) serviceProvider(ctx context.Context, allowIDPInitiated bool) (*gosaml.ServiceProvider, error) {
+	idpMetadata, err := p.loadIDPMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &gosaml.ServiceProvider{
+		EntityID:          p.config.EntityID,
+		MetadataURL:       p.metadataURL,
+		AcsURL:            p.acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: gosaml.NameIDFormat(p.config.NameIDFormat),
+		AllowIDPInitiated: allowIDPInitiated,
+	}
+
+	if p.config.Certificate != "" {
+		cert, key, err := p.config.keyPair()
+		if err != nil {
+			return nil, err
+		}
+		sp.Certificate = cert
+		sp.Key = key
+		if p.config.SignRequests {
+			sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+		}
+	}
+
+	return sp, nil
+}
+
+func (p *SAMLProvider) loadIDPMetadata(ctx context.Context) (*gosaml.EntityDescriptor, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.idpMetadata != nil {
+		return p.idpMetadata, nil
+	}
+
+	metadataURL, err := url.Parse(p.config.IDPMetadataURL)
+	if err != nil {
+		return nil, err
+	}
+
+	fetchCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	metadata, err := samlsp.FetchMetadata(fetchCtx, http.DefaultClient, *metadataURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch idp metadata from %s: %w", p.config.IDPMetadataURL, err)
+	}
+	p.idpMetadata = metadata
+
+	return metadata, nil
+}
+
+// consume records the assertion ID, returning false if it was already consumed. The ID
+// is kept until the assertion could no longer pass validation, allowing for clock skew.
+func (p *SAMLProvider) consume(assertion *gosaml.Assertion) (bool, error) {
+	expiresAt := time.Now().Add(gosaml.MaxIssueDelay)
+	if assertion.Conditions != nil && assertion.Conditions.NotOnOrAfter.After(expiresAt) {
+		expiresAt = assertion.Conditions.NotOnOrAfter
+	}
+
+	return p.assertionRepo.Consume(p.name, assertion.ID, expiresAt.Add(gosaml.MaxClockSkew))
+}
+
+// mapAssertion maps assertion attributes onto the external profile and group list
+func (p *SAMLProvider) mapAssertion(assertion *gosaml.Assertion) (*provider.ExternalUser, []string, error) {
+	var nameID string
+	if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		nameID = strings.TrimSpace(assertion.Subject.NameID.Value)
+	}
+
+	email := firstValue(assertion, p.config.Attributes.Email)
+	if email == "" && strings.Contains(nameID, "@") {
+		email = nameID
+	}
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, nil, fmt.Errorf("SAML assertion has no %s attribute or email NameID", p.config.Attributes.Email)
+	}
+
+	if !provider.EmailDomainAllowed(email, p.config.AllowedDomains) {
+		return nil, nil, fmt.Errorf("email domain of %s is not allowed for provider %s", email, p.name)
+	}
+
+	subject := nameID
+	if p.config.Attributes.ID != "" {
+		subject = firstValue(assertion, p.config.Attributes.ID)
+	}
+	if subject == "" {
+		return nil, nil, fmt.Errorf("SAML assertion has no subject")
+	}
+
+	// The IdP is configured by an administrator and vouches for the addresses it asserts
+	return &provider.ExternalUser{
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: true,
+		FirstName:     firstValue(assertion, p.config.Attributes.FirstName),
+		LastName:      firstValue(assertion, p.config.Attributes.LastName),
+	}, values(assertion, p.config.Attributes.Groups), nil
+}
+
+// requestID derives the AuthnRequest ID from the login nonce. IDs must be XML NCNames,
+// hence the prefix.
+func requestID(login *domain.FederatedLogin) string {
+	return "id-" + login.Nonce
+}
+
+func inResponseTo(assertion *gosaml.Assertion) string {
+	if assertion.Subject == nil {
+		return ""
+	}
+	for _, confirmation := range assertion.Subject.SubjectConfirmations {
+		if confirmation.SubjectConfirmationData != nil && confirmation.SubjectConfirmationData.InResponseTo != "" {
+			return confirmation.SubjectConfirmationData.InResponseTo
+		}
+	}
+	return ""
+}
+
+// values returns all values of the attribute matching name by Name or FriendlyName
+func values(assertion *gosaml.Assertion, name string) []string {
+	var result []string
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			if attribute.Name != name && attribute.FriendlyName != name {
+				continue
+			}
+			for _, value := range attribute.Values {
+				result = append(result, value.Value)
+			}
+		}
+	}
+	return result
+}
+
+func firstValue(assertion *gosaml.Assertion, name string) string {
+	if all := values(assertion, name); len(all) > 0 {
+		return all[0]
+	}
+	return ""
+}
@@ -0,0 +1,294 @@
+package saml
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	gosaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider/providertest"
+)
+
+const testIDPMetadataURL = "https://idp.example.com/metadata"
+
+// fakeIDP is an in-process SAML identity provider issuing signed responses for the
+// service provider metadata of the provider under test
+type fakeIDP struct {
+	idp *gosaml.IdentityProvider
+}
+
+func newFakeIDP(t *testing.T) *fakeIDP {
+	t.Helper()
+
+	key, cert := newKeyPair(t, "idp.example.com")
+	metadataURL, _ := url.Parse(testIDPMetadataURL)
+	ssoURL, _ := url.Parse("https://idp.example.com/sso")
+
+	return &fakeIDP{idp: &gosaml.IdentityProvider{
+		Key:             key,
+		Certificate:     cert,
+		MetadataURL:     *metadataURL,
+		SSOURL:          *ssoURL,
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+	}}
+}
+
+func (i *fakeIDP) metadata(t *testing.T) string {
+	t.Helper()
+
+	metadata, err := xml.Marshal(i.idp.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(metadata)
+}
+
+// respond returns a base64-encoded response for alice answering requestID; an empty
+// requestID makes an IdP-initiated response
+func (i *fakeIDP) respond(t *testing.T, p *SAMLProvider, requestID string) string {
+	t.Helper()
+
+	spMetadata, err := p.Metadata(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sp gosaml.EntityDescriptor
+	if err := xml.Unmarshal(spMetadata, &sp); err != nil {
+		t.Fatal(err)
+	}
+	descriptor := sp.SPSSODescriptors[0]
+
+	req := &gosaml.IdpAuthnRequest{
+		IDP:                     i.idp,
+		HTTPRequest:             httptest.NewRequest(http.MethodPost, "https://idp.example.com/sso", nil),
+		Request:                 gosaml.AuthnRequest{ID: requestID, IssueInstant: gosaml.TimeNow()},
+		ServiceProviderMetadata: &sp,
+		SPSSODescriptor:         &descriptor,
+		ACSEndpoint:             &descriptor.AssertionConsumerServices[0],
+		Now:                     gosaml.TimeNow(),
+	}
+
+	session := &gosaml.Session{
+		ID:         "session-1",
+		CreateTime: time.Now(),
+		NameID:     "alice@example.com",
+		CustomAttributes: []gosaml.Attribute{
+			{Name: "email", Values: []gosaml.AttributeValue{{Value: "alice@example.com"}}},
+			{Name: "firstName", Values: []gosaml.AttributeValue{{Value: "Alice"}}},
+		},
+	}
+	if err := (gosaml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		t.Fatal(err)
+	}
+	if err := req.MakeResponse(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := etree.NewDocument()
+	doc.SetRoot(req.ResponseEl)
+	response, err := doc.WriteToString()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString([]byte(response))
+}
+
+func newTestProvider(t *testing.T, idp *fakeIDP, config map[string]interface{}, assertions *providertest.SAMLAssertionRepository) *SAMLProvider {
+	t.Helper()
+
+	settings := map[string]interface{}{"idp_metadata": idp.metadata(t)}
+	for key, value := range config {
+		settings[key] = value
+	}
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	users := providertest.NewUserRepository()
+	groups := providertest.NewGroupRepository()
+	provisioner := providertest.NewProvisioner(users, providertest.NewUserIdentityRepository(), groups)
+
+	p, err := NewSAMLProvider("corp", true, raw, "https://auth.example.com", users, groups, assertions, provisioner)
+	if err != nil {
+		t.Fatalf("NewSAMLProvider: %v", err)
+	}
+	return p.(*SAMLProvider)
+}
+
+func newLogin(nonce string) *domain.FederatedLogin {
+	return &domain.FederatedLogin{Provider: "corp", State: "state-1", Nonce: nonce}
+}
+
+func TestConsumeAssertion(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		// forged has the response signed by a key the provider's IdP metadata lacks
+		forged bool
+		// tamper edits the signed response XML
+		tamper func(response string) string
+		// requestID is the request the response answers; login is the browser's login,
+		// nil for an IdP-initiated sign-in
+		requestID string
+		login     *domain.FederatedLogin
+		wantErr   string
+	}{
+		{name: "SP-initiated sign-in", requestID: "id-nonce-1", login: newLogin("nonce-1")},
+		{name: "signed by an unknown key", forged: true, requestID: "id-nonce-1", login: newLogin("nonce-1"), wantErr: errInvalidResponse.Error()},
+		{
+			name: "assertion altered after signing",
+			tamper: func(response string) string {
+				return strings.ReplaceAll(response, "alice@example.com", "mallory@example.com")
+			},
+			requestID: "id-nonce-1",
+			login:     newLogin("nonce-1"),
+			wantErr:   errInvalidResponse.Error(),
+		},
+		{name: "response to another browser's request", requestID: "id-nonce-2", login: newLogin("nonce-1"), wantErr: errInvalidResponse.Error()},
+		{name: "unsolicited response not allowed", wantErr: "unsolicited SAML responses are not allowed"},
+		{name: "unsolicited response allowed", config: map[string]interface{}{"allow_idp_initiated": true}},
+		{name: "solicited response posted as unsolicited", config: map[string]interface{}{"allow_idp_initiated": true}, requestID: "id-nonce-1", wantErr: errInvalidResponse.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp := newFakeIDP(t)
+			p := newTestProvider(t, idp, tt.config, providertest.NewSAMLAssertionRepository())
+
+			issuer := idp
+			if tt.forged {
+				issuer = newFakeIDP(t)
+			}
+			response := issuer.respond(t, p, tt.requestID)
+			if tt.tamper != nil {
+				decoded, err := base64.StdEncoding.DecodeString(response)
+				if err != nil {
+					t.Fatal(err)
+				}
+				response = base64.StdEncoding.EncodeToString([]byte(tt.tamper(string(decoded))))
+			}
+
+			user, err := p.ConsumeAssertion(context.Background(), tt.login, response)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ConsumeAssertion() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConsumeAssertion() error = %v", err)
+			}
+			if user.Email != "alice@example.com" || user.FirstName != "Alice" {
+				t.Errorf("ConsumeAssertion() user = %+v, want alice's profile", user)
+			}
+		})
+	}
+}
+
+func TestConsumeAssertionRejectsReplay(t *testing.T) {
+	idp := newFakeIDP(t)
+	assertions := providertest.NewSAMLAssertionRepository()
+	// Two instances sharing the consumed assertion store
+	first := newTestProvider(t, idp, nil, assertions)
+	second := newTestProvider(t, idp, nil, assertions)
+
+	response := idp.respond(t, first, "id-nonce-1")
+
+	if _, err := first.ConsumeAssertion(context.Background(), newLogin("nonce-1"), response); err != nil {
+		t.Fatalf("ConsumeAssertion() error = %v", err)
+	}
+	for name, p := range map[string]*SAMLProvider{"same instance": first, "other instance": second} {
+		if _, err := p.ConsumeAssertion(context.Background(), newLogin("nonce-1"), response); !errors.Is(err, errInvalidResponse) {
+			t.Errorf("%s: replayed ConsumeAssertion() error = %v, want %v", name, err, errInvalidResponse)
+		}
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	key, cert := newKeyPair(t, "auth.example.com")
+	keyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	certPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+
+	tests := []struct {
+		name    string
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{name: "metadata URL", config: map[string]interface{}{"idp_metadata_url": "https://idp.example.com/metadata"}},
+		{name: "no metadata", config: map[string]interface{}{}, wantErr: "exactly one of"},
+		{name: "both metadata sources", config: map[string]interface{}{"idp_metadata_url": "https://idp.example.com/metadata", "idp_metadata": "<x/>"}, wantErr: "exactly one of"},
+		{name: "relative metadata URL", config: map[string]interface{}{"idp_metadata_url": "/metadata"}, wantErr: "absolute URL"},
+		{name: "certificate without key", config: map[string]interface{}{"idp_metadata_url": "https://idp.example.com/metadata", "certificate": certPEM}, wantErr: "must be set together"},
+		{name: "signed requests without key pair", config: map[string]interface{}{"idp_metadata_url": "https://idp.example.com/metadata", "sign_requests": true}, wantErr: "requires certificate"},
+		{name: "key pair", config: map[string]interface{}{"idp_metadata_url": "https://idp.example.com/metadata", "certificate": certPEM, "private_key": keyPEM, "sign_requests": true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := ParseConfig(raw)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseConfig() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseConfig() error = %v", err)
+			}
+			if cfg.Attributes.Email != "email" || cfg.NameIDFormat == "" {
+				t.Errorf("ParseConfig() did not apply defaults: %+v", cfg)
+			}
+		})
+	}
+}
+
+// newKeyPair returns an RSA key and a self-signed certificate for it
+func newKeyPair(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key, cert
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aras-services/aras-auth/internal/provider"
+)
+
+// Config is the webhook provider configuration stored in providers.config
+type Config struct {
+	URL            string `json:"url"`             // Endpoint receiving authenticate and lookup calls
+	Secret         string `json:"secret"`          // Shared HMAC-SHA256 key signing requests and responses
+	TimeoutSeconds int    `json:"timeout_seconds"` // Request timeout (default: 5)
+	// MaxClockSkewSeconds bounds the age of a response timestamp (default: 300)
+	MaxClockSkewSeconds int `json:"max_clock_skew_seconds"`
+
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker"`
+
+	// LazyMigrate stores a local hash of the password after each successful webhook
+	// authentication. Later sign-ins of migrated users are verified locally without
+	// calling the webhook, so accounts move over as their owners sign in.
+	LazyMigrate bool `json:"lazy_migrate"`
+	// AllowedDomains restricts sign-in to email addresses in these domains
+	AllowedDomains []string `json:"allowed_domains"`
+
+	Provisioning provider.ProvisioningConfig `json:"provisioning"`
+}
+
+// CircuitBreakerConfig stops calling an unhealthy endpoint: after FailureThreshold
+// consecutive failures calls fail fast for OpenSeconds, then a single trial call decides
+// whether the circuit closes again
+type CircuitBreakerConfig struct {
+	FailureThreshold int `json:"failure_threshold"` // default: 5
+	OpenSeconds      int `json:"open_seconds"`      // default: 30
+}
+
+// ParseConfig decodes and validates a provider configuration, applying defaults
+func ParseConfig(raw json.RawMessage) (*Config, error) {
+	var cfg Config
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("invalid webhook config: %w", err)
+		}
+	}
+
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook config: url is required")
+	}
+	u, err := url.Parse(cfg.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("webhook config: invalid url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("webhook config: url scheme must be http or https")
+	}
+
+	if len(cfg.Secret) < 32 {
+		return nil, fmt.Errorf("webhook config: secret must be at least 32 characters")
+	}
+
+	if cfg.TimeoutSeconds <= 0 {
+		cfg.TimeoutSeconds = 5
+	}
+	if cfg.MaxClockSkewSeconds <= 0 {
+		cfg.MaxClockSkewSeconds = 300
+	}
+	if cfg.CircuitBreaker.FailureThreshold <= 0 {
+		cfg.CircuitBreaker.FailureThreshold = 5
+	}
+	if cfg.CircuitBreaker.OpenSeconds <= 0 {
+		cfg.CircuitBreaker.OpenSeconds = 30
+	}
+
+	for i, domain := range cfg.AllowedDomains {
+		cfg.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+func (c *Config) maxClockSkew() time.Duration {
+	return time.Duration(c.MaxClockSkewSeconds) * time.Second
+}
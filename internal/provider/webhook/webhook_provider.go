@@ -0,0 +1,363 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider"
+	"github.com/aras-services/aras-auth/pkg/password"
+)
+
+// Headers carrying the HMAC signature of webhook requests and responses
+const (
+	HeaderTimestamp = "X-Aras-Timestamp"
+	HeaderRequestID = "X-Aras-Request-Id"
+	HeaderSignature = "X-Aras-Signature"
+)
+
+// Actions sent in the "action" field of webhook requests
+const (
+	ActionAuthenticate = "authenticate"
+	ActionLookup       = "lookup"
+)
+
+// Sign returns the X-Aras-Signature value of a request or response body: the hex
+// HMAC-SHA256 of "<timestamp>.<request id>.<body>" prefixed with "sha256=". Responses are
+// signed with the request ID of the request they answer.
+func Sign(secret, timestamp, requestID string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + requestID + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Request is the body POSTed to the webhook endpoint
+type Request struct {
+	Action   string `json:"action"`
+	Email    string `json:"email"`
+	Password string `json:"password,omitempty"`
+}
+
+// Response is the body the endpoint answers with status 200. It answers 404 for unknown
+// users and 401 for a wrong password; other statuses count as failures.
+type Response struct {
+	User *RemoteUser `json:"user"`
+}
+
+// RemoteUser is an account of the legacy user store
+type RemoteUser struct {
+	// ID is the stable identifier of the account in the legacy store
+	ID            string `json:"id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	// Disabled accounts are rejected at sign-in
+	Disabled bool `json:"disabled"`
+}
+
+// WebhookProvider delegates authentication and user lookup to an HTTP endpoint in front
+// of a user store that cannot be migrated yet. Accounts are mirrored into the local users
+// table and linked like other external identities. With lazy_migrate, each successful
+// sign-in also stores the password locally, after which the webhook is no longer asked.
+type WebhookProvider struct {
+	name        string
+	enabled     bool
+	config      *Config
+	userRepo    domain.UserRepository
+	provisioner *provider.Provisioner
+	httpClient  *http.Client
+	breaker     *circuitBreaker
+}
+
+func NewWebhookProvider(name string, enabled bool, rawConfig json.RawMessage, userRepo domain.UserRepository, provisioner *provider.Provisioner) (domain.IdentityProvider, error) {
+	cfg, err := ParseConfig(rawConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookProvider{
+		name:        name,
+		enabled:     enabled,
+		config:      cfg,
+		userRepo:    userRepo,
+		provisioner: provisioner,
+		httpClient:  &http.Client{Timeout: cfg.timeout()},
+		breaker:     newCircuitBreaker(cfg.CircuitBreaker),
+	}, nil
+}
+
+func (p *WebhookProvider) Authenticate(ctx context.Context, username, pwd string) (*domain.User, error) {
+	if pwd == "" {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if user := p.migratedUser(username); user != nil {
+		if err := password.VerifyPassword(user.PasswordHash, pwd); err != nil {
+			return nil, domain.ErrInvalidCredentials
+		}
+		if user.Status != domain.UserStatusActive {
+			return nil, fmt.Errorf("account is not active")
+		}
+		return user, nil
+	}
+
+	remote, err := p.call(ctx, &Request{Action: ActionAuthenticate, Email: username, Password: pwd})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := p.provision(ctx, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Status != domain.UserStatusActive {
+		return nil, fmt.Errorf("account is not active")
+	}
+
+	if p.config.LazyMigrate && !password.IsUsable(user.PasswordHash) {
+		p.migratePassword(user, pwd)
+	}
+
+	return user, nil
+}
+
+// migratedUser returns the local user for email when lazy migration is enabled and the
+// user is linked to this provider and already has a local password, or nil when the
+// webhook must be asked. Local users that were never linked are not matched, so their
+// passwords are never checked on behalf of the webhook.
+func (p *WebhookProvider) migratedUser(email string) *domain.User {
+	if !p.config.LazyMigrate {
+		return nil
+	}
+	user, err := p.userRepo.GetByEmail(email)
+	if err != nil || !password.IsUsable(user.PasswordHash) {
+		return nil
+	}
+	linked, err := p.provisioner.IsLinked(p.name, user.ID)
+	if err != nil || !linked {
+		return nil
+	}
+	return user
+}
+
+// migratePassword stores a local hash of the password. Failures must not block the login;
+// the migration is retried on the next sign-in.
+func (p *WebhookProvider) migratePassword(user *domain.User, pwd string) {
+	hashedPassword, err := password.HashPassword(pwd)
+	if err != nil {
+		fmt.Printf("Warning: failed to hash migrated password for user %s: %v\n", user.ID, err)
+		return
+	}
+	if err := p.userRepo.UpdatePasswordHash(user.ID, hashedPassword); err != nil {
+		fmt.Printf("Warning: failed to migrate password for user %s: %v\n", user.ID, err)
+		return
+	}
+	user.PasswordHash = hashedPassword
+}
+
+// provision maps an account of the legacy store to its local user
+func (p *WebhookProvider) provision(ctx context.Context, remote *RemoteUser) (*domain.User, error) {
+	if remote.ID == "" || remote.Email == "" {
+		return nil, fmt.Errorf("%w: webhook %s returned a user without id or email", domain.ErrProviderUnavailable, p.name)
+	}
+	if remote.Disabled {
+		return nil, fmt.Errorf("account is not active")
+	}
+	if !provider.EmailDomainAllowed(remote.Email, p.config.AllowedDomains) {
+		return nil, fmt.Errorf("email domain of %s is not allowed for provider %s", remote.Email, p.name)
+	}
+
+	return p.provisioner.ProvisionUser(ctx, p.name, p.config.Provisioning, &provider.ExternalUser{
+		Subject:       remote.ID,
+		Email:         remote.Email,
+		EmailVerified: remote.EmailVerified,
+		FirstName:     remote.FirstName,
+		LastName:      remote.LastName,
+	})
+}
+
+// call sends a signed request and returns the verified user of the response. 401 and 404
+// map to domain.ErrInvalidCredentials and domain.ErrUserNotFound; transport errors, other
+// statuses and bad signatures count towards the circuit breaker and map to
+// domain.ErrProviderUnavailable.
+func (p *WebhookProvider) call(ctx context.Context, payload *Request) (*RemoteUser, error) {
+	if !p.breaker.allow() {
+		return nil, fmt.Errorf("%w: webhook %s circuit is open", domain.ErrProviderUnavailable, p.name)
+	}
+
+	remote, err := p.send(ctx, payload)
+	if err != nil && errors.Is(err, domain.ErrProviderUnavailable) {
+		p.breaker.failure()
+	} else {
+		p.breaker.success()
+	}
+
+	return remote, err
+}
+
+func (p *WebhookProvider) send(ctx context.Context, payload *Request) (*RemoteUser, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	requestID := uuid.New().String()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderRequestID, requestID)
+	req.Header.Set(HeaderSignature, Sign(p.config.Secret, timestamp, requestID, body))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: webhook %s request failed: %w", domain.ErrProviderUnavailable, p.name, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read webhook %s response: %w", domain.ErrProviderUnavailable, p.name, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusUnauthorized, http.StatusNotFound:
+	default:
+		return nil, fmt.Errorf("%w: webhook %s returned status %d", domain.ErrProviderUnavailable, p.name, resp.StatusCode)
+	}
+
+	if err := p.verifyResponse(resp, requestID, respBody); err != nil {
+		return nil, fmt.Errorf("%w: webhook %s response rejected: %w", domain.ErrProviderUnavailable, p.name, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		return nil, domain.ErrInvalidCredentials
+	case http.StatusNotFound:
+		return nil, domain.ErrUserNotFound
+	}
+
+	var decoded Response
+	if err := json.Unmarshal(respBody, &decoded); err != nil || decoded.User == nil {
+		return nil, fmt.Errorf("%w: webhook %s returned an invalid response", domain.ErrProviderUnavailable, p.name)
+	}
+
+	return decoded.User, nil
+}
+
+// verifyResponse checks the response signature, that it answers this request, and that
+// its timestamp is recent
+func (p *WebhookProvider) verifyResponse(resp *http.Response, requestID string, body []byte) error {
+	timestamp := resp.Header.Get(HeaderTimestamp)
+	signature := resp.Header.Get(HeaderSignature)
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("response is not signed")
+	}
+
+	expected := Sign(p.config.Secret, timestamp, requestID, body)
+	if !hmac.Equal([]byte(strings.ToLower(signature)), []byte(expected)) {
+		return fmt.Errorf("invalid response signature")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid response timestamp")
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > p.config.maxClockSkew() || skew < -p.config.maxClockSkew() {
+		return fmt.Errorf("response timestamp outside the allowed clock skew")
+	}
+
+	return nil
+}
+
+func (p *WebhookProvider) GetUser(ctx context.Context, id uuid.UUID) (*domain.User, error) {
+	return p.userRepo.GetByID(id)
+}
+
+// GetUserByEmail looks the user up through the webhook and returns the linked local user.
+// It never provisions: accounts are mirrored only after a successful Authenticate, and an
+// account that has not signed in yet is reported as domain.ErrUserNotFound. Migrated users
+// are resolved locally.
+func (p *WebhookProvider) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	if user := p.migratedUser(email); user != nil {
+		return user, nil
+	}
+
+	remote, err := p.call(ctx, &Request{Action: ActionLookup, Email: email})
+	if err != nil {
+		return nil, err
+	}
+	if remote.ID == "" {
+		return nil, fmt.Errorf("%w: webhook %s returned a user without id", domain.ErrProviderUnavailable, p.name)
+	}
+
+	return p.provisioner.LinkedUser(p.name, remote.ID)
+}
+
+func (p *WebhookProvider) CreateUser(ctx context.Context, user *domain.User) error {
+	return fmt.Errorf("webhook provider %s does not support creating users", p.name)
+}
+
+func (p *WebhookProvider) UpdateUser(ctx context.Context, user *domain.User) error {
+	return p.userRepo.Update(user)
+}
+
+func (p *WebhookProvider) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return p.userRepo.Delete(id)
+}
+
+func (p *WebhookProvider) ChangePassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	return fmt.Errorf("webhook provider %s does not support changing passwords", p.name)
+}
+
+func (p *WebhookProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, pwd string) (bool, error) {
+	if pwd == "" {
+		return false, nil
+	}
+
+	user, err := p.userRepo.GetByID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	if migrated := p.migratedUser(user.Email); migrated != nil {
+		return password.VerifyPassword(migrated.PasswordHash, pwd) == nil, nil
+	}
+
+	if _, err := p.call(ctx, &Request{Action: ActionAuthenticate, Email: user.Email, Password: pwd}); err != nil {
+		if errors.Is(err, domain.ErrInvalidCredentials) || errors.Is(err, domain.ErrUserNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (p *WebhookProvider) GetProviderName() string {
+	return p.name
+}
+
+func (p *WebhookProvider) IsEnabled() bool {
+	return p.enabled
+}
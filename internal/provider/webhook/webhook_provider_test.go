@@ -0,0 +1,359 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/internal/provider/providertest"
+	"github.com/aras-services/aras-auth/pkg/password"
+)
+
+const testSecret = "secret-0123456789-0123456789-012345"
+
+// legacyAccount is an account of the fake legacy user store
+type legacyAccount struct {
+	user     RemoteUser
+	password string
+}
+
+// fakeEndpoint is a webhook endpoint in front of a legacy user store. It rejects requests
+// with an invalid signature and signs its responses, unless tamper alters them.
+type fakeEndpoint struct {
+	server   *httptest.Server
+	accounts map[string]legacyAccount
+
+	// status replaces the response status when set
+	status int
+	// tamper changes the signed headers of a response to the request with requestID
+	tamper func(header http.Header, requestID string, body []byte)
+
+	mu      sync.Mutex
+	actions []string
+}
+
+func newFakeEndpoint(t *testing.T, accounts ...legacyAccount) *fakeEndpoint {
+	t.Helper()
+
+	endpoint := &fakeEndpoint{accounts: make(map[string]legacyAccount)}
+	for _, account := range accounts {
+		endpoint.accounts[account.user.Email] = account
+	}
+	endpoint.server = httptest.NewServer(http.HandlerFunc(endpoint.serve))
+	t.Cleanup(endpoint.server.Close)
+
+	return endpoint
+}
+
+func (e *fakeEndpoint) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	requestID := r.Header.Get(HeaderRequestID)
+	expected := Sign(testSecret, r.Header.Get(HeaderTimestamp), requestID, body)
+	if !hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(expected)) {
+		http.Error(w, "invalid signature", http.StatusBadRequest)
+		return
+	}
+
+	var req Request
+	json.Unmarshal(body, &req)
+	e.mu.Lock()
+	e.actions = append(e.actions, req.Action)
+	e.mu.Unlock()
+
+	status, response := http.StatusOK, []byte(`{}`)
+	account, ok := e.accounts[req.Email]
+	switch {
+	case !ok:
+		status = http.StatusNotFound
+	case req.Action == ActionAuthenticate && req.Password != account.password:
+		status = http.StatusUnauthorized
+	default:
+		response, _ = json.Marshal(Response{User: &account.user})
+	}
+	if e.status != 0 {
+		status = e.status
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	w.Header().Set(HeaderTimestamp, timestamp)
+	w.Header().Set(HeaderSignature, Sign(testSecret, timestamp, requestID, response))
+	if e.tamper != nil {
+		e.tamper(w.Header(), requestID, response)
+	}
+	w.WriteHeader(status)
+	w.Write(response)
+}
+
+// calls returns the actions the endpoint received
+func (e *fakeEndpoint) calls() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.actions...)
+}
+
+func newTestProvider(t *testing.T, endpoint *fakeEndpoint, config map[string]interface{}, users ...*domain.User) (*WebhookProvider, *providertest.UserRepository) {
+	t.Helper()
+
+	settings := map[string]interface{}{
+		"url":    endpoint.server.URL,
+		"secret": testSecret,
+	}
+	for key, value := range config {
+		settings[key] = value
+	}
+	raw, err := json.Marshal(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userRepo := providertest.NewUserRepository(users...)
+	provisioner := providertest.NewProvisioner(userRepo, providertest.NewUserIdentityRepository(), providertest.NewGroupRepository())
+
+	p, err := NewWebhookProvider("legacy", true, raw, userRepo, provisioner)
+	if err != nil {
+		t.Fatalf("NewWebhookProvider: %v", err)
+	}
+	return p.(*WebhookProvider), userRepo
+}
+
+var alice = legacyAccount{
+	user:     RemoteUser{ID: "legacy-1", Email: "alice@example.com", EmailVerified: true, FirstName: "Alice"},
+	password: "alice-legacy-password",
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.req-1.<body>' | openssl dgst -sha256 -hmac <secret>
+	body := []byte(`{"action":"lookup","email":"alice@example.com"}`)
+	want := "sha256=19d4f236de7e5bc60e0ddc3cad7a17876a47764561b1a7e560da9ab2f4163d53"
+
+	if got := Sign(testSecret, "1700000000", "req-1", body); got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+	if got := Sign(testSecret, "1700000000", "req-2", body); got == want {
+		t.Errorf("Sign() does not cover the request ID")
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	disabled := legacyAccount{
+		user:     RemoteUser{ID: "legacy-2", Email: "bob@example.com", Disabled: true},
+		password: "bob-legacy-password",
+	}
+	staleTimestamp := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		status   int
+		tamper   func(header http.Header, requestID string, body []byte)
+		wantErr  error
+		// wantMsg is matched when the error is not a sentinel
+		wantMsg string
+	}{
+		{name: "valid password", email: alice.user.Email, password: alice.password},
+		{name: "wrong password", email: alice.user.Email, password: "guess", wantErr: domain.ErrInvalidCredentials},
+		{name: "unknown user", email: "nobody@example.com", password: "guess", wantErr: domain.ErrUserNotFound},
+		{name: "disabled account", email: disabled.user.Email, password: disabled.password, wantMsg: "account is not active"},
+		{
+			name: "unsigned response", email: alice.user.Email, password: alice.password,
+			tamper:  func(header http.Header, _ string, _ []byte) { header.Del(HeaderSignature) },
+			wantErr: domain.ErrProviderUnavailable,
+		},
+		{
+			name: "signature with another secret", email: alice.user.Email, password: alice.password,
+			tamper: func(header http.Header, requestID string, body []byte) {
+				header.Set(HeaderSignature, Sign("another-secret-0123456789-0123456789", header.Get(HeaderTimestamp), requestID, body))
+			},
+			wantErr: domain.ErrProviderUnavailable,
+		},
+		{
+			name: "signature for another request", email: alice.user.Email, password: alice.password,
+			tamper: func(header http.Header, _ string, body []byte) {
+				header.Set(HeaderSignature, Sign(testSecret, header.Get(HeaderTimestamp), uuid.New().String(), body))
+			},
+			wantErr: domain.ErrProviderUnavailable,
+		},
+		{
+			name: "timestamp outside the clock skew", email: alice.user.Email, password: alice.password,
+			tamper: func(header http.Header, requestID string, body []byte) {
+				header.Set(HeaderTimestamp, staleTimestamp)
+				header.Set(HeaderSignature, Sign(testSecret, staleTimestamp, requestID, body))
+			},
+			wantErr: domain.ErrProviderUnavailable,
+		},
+		{
+			name: "server error", email: alice.user.Email, password: alice.password,
+			status:  http.StatusInternalServerError,
+			wantErr: domain.ErrProviderUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := newFakeEndpoint(t, alice, disabled)
+			endpoint.status = tt.status
+			endpoint.tamper = tt.tamper
+			p, _ := newTestProvider(t, endpoint, nil)
+
+			user, err := p.Authenticate(context.Background(), tt.email, tt.password)
+
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantMsg != "":
+				if err == nil || err.Error() != tt.wantMsg {
+					t.Fatalf("Authenticate() error = %v, want %q", err, tt.wantMsg)
+				}
+			case err != nil:
+				t.Fatalf("Authenticate() error = %v", err)
+			case user.Email != tt.email || user.FirstName != "Alice":
+				t.Errorf("Authenticate() user = %+v, want the provisioned legacy account", user)
+			}
+		})
+	}
+}
+
+// Responses are signed with the request ID, so a captured response cannot answer a later
+// request with the same body
+func TestAuthenticateRejectsReplayedResponse(t *testing.T) {
+	endpoint := newFakeEndpoint(t, alice)
+	p, _ := newTestProvider(t, endpoint, nil)
+
+	var captured http.Header
+	endpoint.tamper = func(header http.Header, _ string, _ []byte) { captured = header.Clone() }
+	if _, err := p.Authenticate(context.Background(), alice.user.Email, alice.password); err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+
+	// Replay the previous response headers for a new request
+	endpoint.tamper = func(header http.Header, _ string, _ []byte) {
+		header.Set(HeaderTimestamp, captured.Get(HeaderTimestamp))
+		header.Set(HeaderSignature, captured.Get(HeaderSignature))
+	}
+	if _, err := p.Authenticate(context.Background(), alice.user.Email, alice.password); !errors.Is(err, domain.ErrProviderUnavailable) {
+		t.Errorf("Authenticate() with a replayed response error = %v, want %v", err, domain.ErrProviderUnavailable)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	endpoint := newFakeEndpoint(t, alice)
+	endpoint.status = http.StatusBadGateway
+	p, _ := newTestProvider(t, endpoint, map[string]interface{}{
+		"circuit_breaker": map[string]int{"failure_threshold": 2, "open_seconds": 60},
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Authenticate(context.Background(), alice.user.Email, alice.password); !errors.Is(err, domain.ErrProviderUnavailable) {
+			t.Fatalf("call %d error = %v, want %v", i+1, err, domain.ErrProviderUnavailable)
+		}
+	}
+
+	if got := len(endpoint.calls()); got != 2 {
+		t.Errorf("endpoint received %d calls, want 2 before the circuit opened", got)
+	}
+}
+
+func TestGetUserByEmailDoesNotProvision(t *testing.T) {
+	endpoint := newFakeEndpoint(t, alice)
+	p, users := newTestProvider(t, endpoint, nil)
+	ctx := context.Background()
+
+	if _, err := p.GetUserByEmail(ctx, alice.user.Email); !errors.Is(err, domain.ErrUserNotFound) {
+		t.Fatalf("GetUserByEmail() before sign-in error = %v, want %v", err, domain.ErrUserNotFound)
+	}
+	if got := len(users.All()); got != 0 {
+		t.Fatalf("lookup provisioned %d users", got)
+	}
+
+	signedIn, err := p.Authenticate(ctx, alice.user.Email, alice.password)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	user, err := p.GetUserByEmail(ctx, alice.user.Email)
+	if err != nil || user.ID != signedIn.ID {
+		t.Errorf("GetUserByEmail() after sign-in = %v, %v, want user %s", user, err, signedIn.ID)
+	}
+}
+
+func TestLazyMigrate(t *testing.T) {
+	localHash, err := password.HashPassword("local-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// carol has a local account that was never linked to the webhook provider
+	carol := &domain.User{ID: uuid.New(), Email: "carol@example.com", PasswordHash: localHash, Status: domain.UserStatusActive}
+
+	endpoint := newFakeEndpoint(t, alice, legacyAccount{
+		user:     RemoteUser{ID: "legacy-3", Email: carol.Email},
+		password: "carol-legacy-password",
+	})
+	p, users := newTestProvider(t, endpoint, map[string]interface{}{"lazy_migrate": true}, carol)
+	ctx := context.Background()
+
+	user, err := p.Authenticate(ctx, alice.user.Email, alice.password)
+	if err != nil {
+		t.Fatalf("first Authenticate() error = %v", err)
+	}
+	migrated, _ := users.GetByID(user.ID)
+	if err := password.VerifyPassword(migrated.PasswordHash, alice.password); err != nil {
+		t.Fatalf("password was not migrated: %v", err)
+	}
+
+	if _, err := p.Authenticate(ctx, alice.user.Email, alice.password); err != nil {
+		t.Fatalf("second Authenticate() error = %v", err)
+	}
+	if _, err := p.Authenticate(ctx, alice.user.Email, "guess"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("migrated Authenticate() with a wrong password error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	if got := len(endpoint.calls()); got != 1 {
+		t.Errorf("endpoint received %d calls, want only the first sign-in", got)
+	}
+
+	// The local password of an unlinked user must not sign in through the webhook provider
+	if _, err := p.Authenticate(ctx, carol.Email, "local-password"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("Authenticate() with an unlinked local password error = %v, want %v", err, domain.ErrInvalidCredentials)
+	}
+	if got := len(endpoint.calls()); got != 2 {
+		t.Errorf("endpoint received %d calls, want the unlinked user to be checked by the webhook", got)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "valid", config: `{"url": "https://legacy.example.com/auth", "secret": "` + testSecret + `"}`},
+		{name: "missing url", config: `{"secret": "` + testSecret + `"}`, wantErr: true},
+		{name: "unsupported scheme", config: `{"url": "ftp://legacy.example.com", "secret": "` + testSecret + `"}`, wantErr: true},
+		{name: "short secret", config: `{"url": "https://legacy.example.com/auth", "secret": "short"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := ParseConfig(json.RawMessage(tt.config))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.TimeoutSeconds != 5 || cfg.MaxClockSkewSeconds != 300 || cfg.CircuitBreaker.FailureThreshold != 5) {
+				t.Errorf("ParseConfig() defaults = %+v", cfg)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// LoginAttemptStore keeps failed-login counters in process memory. It is only
+// suitable for single-node deployments; use the Postgres store for clusters.
+type LoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]*domain.LoginAttempts
+}
+
+func NewLoginAttemptStore() domain.LoginAttemptStore {
+	return &LoginAttemptStore{
+		attempts: make(map[string]*domain.LoginAttempts),
+	}
+}
+
+func (s *LoginAttemptStore) Get(key string) (*domain.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempts, ok := s.attempts[key]; ok {
+		copied := *attempts
+		return &copied, nil
+	}
+
+	return &domain.LoginAttempts{Key: key}, nil
+}
+
+func (s *LoginAttemptStore) RecordFailure(key string, resetAfter time.Duration) (*domain.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	attempts, ok := s.attempts[key]
+	if !ok {
+		attempts = &domain.LoginAttempts{Key: key}
+		s.attempts[key] = attempts
+	}
+
+	// Failures older than resetAfter no longer count toward the streak
+	if attempts.LastFailureAt == nil || attempts.LastFailureAt.Before(now.Add(-resetAfter)) {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailureAt = &now
+
+	copied := *attempts
+	return &copied, nil
+}
+
+func (s *LoginAttemptStore) SetLockedUntil(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if attempts, ok := s.attempts[key]; ok {
+		attempts.LockedUntil = &until
+	}
+
+	return nil
+}
+
+func (s *LoginAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.attempts, key)
+	return nil
+}
+
+func (s *LoginAttemptStore) PurgeExpired(resetAfter time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, attempts := range s.attempts {
+		stale := attempts.LastFailureAt == nil || attempts.LastFailureAt.Before(now.Add(-resetAfter))
+		locked := attempts.LockedUntil != nil && attempts.LockedUntil.After(now)
+		if stale && !locked {
+			delete(s.attempts, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type rateWindow struct {
+	count     int
+	expiresAt time.Time
+}
+
+// RateLimiter counts requests in fixed windows in process memory. It is only
+// suitable for single-node deployments; use the Postgres limiter for clusters.
+type RateLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+func NewRateLimiter() domain.RateLimiter {
+	return &RateLimiter{
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+func (l *RateLimiter) Allow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w, ok := l.windows[key]
+	if !ok || !w.expiresAt.After(now) {
+		w = &rateWindow{expiresAt: now.Add(window)}
+		l.windows[key] = w
+	}
+	w.count++
+
+	if w.count > limit {
+		return false, w.expiresAt.Sub(now), nil
+	}
+
+	return true, 0, nil
+}
+
+func (l *RateLimiter) PurgeExpired() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for key, w := range l.windows {
+		if !w.expiresAt.After(now) {
+			delete(l.windows, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// EmailChangeRepository reports confirmed email changes to a domain.ChangeListener. The
+// confirmation updates the user's email in the same transaction, bypassing the user
+// repository.
+type EmailChangeRepository struct {
+	domain.EmailChangeRepository
+	listener domain.ChangeListener
+}
+
+func NewEmailChangeRepository(changes domain.EmailChangeRepository, listener domain.ChangeListener) domain.EmailChangeRepository {
+	return &EmailChangeRepository{EmailChangeRepository: changes, listener: listener}
+}
+
+func (r *EmailChangeRepository) Confirm(change *domain.EmailChange) error {
+	if err := r.EmailChangeRepository.Confirm(change); err != nil {
+		return err
+	}
+	r.listener.UserChanged(context.Background(), change.UserID)
+	return nil
+}
@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// GroupRepository reports every successful write of a group or its direct members to a
+// domain.ChangeListener, including group sync by external providers and inbound SCIM
+type GroupRepository struct {
+	domain.GroupRepository
+	listener domain.ChangeListener
+}
+
+func NewGroupRepository(groups domain.GroupRepository, listener domain.ChangeListener) domain.GroupRepository {
+	return &GroupRepository{GroupRepository: groups, listener: listener}
+}
+
+func (r *GroupRepository) Create(group *domain.Group) error {
+	if err := r.GroupRepository.Create(group); err != nil {
+		return err
+	}
+	r.listener.GroupChanged(context.Background(), group.ID)
+	return nil
+}
+
+func (r *GroupRepository) Update(group *domain.Group) error {
+	if err := r.GroupRepository.Update(group); err != nil {
+		return err
+	}
+	r.listener.GroupChanged(context.Background(), group.ID)
+	return nil
+}
+
+func (r *GroupRepository) Delete(id uuid.UUID) error {
+	if err := r.GroupRepository.Delete(id); err != nil {
+		return err
+	}
+	r.listener.GroupDeleted(context.Background(), id)
+	return nil
+}
+
+func (r *GroupRepository) AddMember(groupID, userID uuid.UUID) error {
+	if err := r.GroupRepository.AddMember(groupID, userID); err != nil {
+		return err
+	}
+	r.listener.GroupChanged(context.Background(), groupID)
+	return nil
+}
+
+func (r *GroupRepository) RemoveMember(groupID, userID uuid.UUID) error {
+	if err := r.GroupRepository.RemoveMember(groupID, userID); err != nil {
+		return err
+	}
+	r.listener.GroupChanged(context.Background(), groupID)
+	return nil
+}
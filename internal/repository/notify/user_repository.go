@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// UserRepository reports every successful write of a user to a domain.ChangeListener, so
+// registration, just-in-time provisioning and inbound SCIM are seen the same way as
+// changes through the management API
+type UserRepository struct {
+	domain.UserRepository
+	listener domain.ChangeListener
+}
+
+func NewUserRepository(users domain.UserRepository, listener domain.ChangeListener) domain.UserRepository {
+	return &UserRepository{UserRepository: users, listener: listener}
+}
+
+func (r *UserRepository) Create(user *domain.User) error {
+	if err := r.UserRepository.Create(user); err != nil {
+		return err
+	}
+	r.listener.UserChanged(context.Background(), user.ID)
+	return nil
+}
+
+func (r *UserRepository) Update(user *domain.User) error {
+	if err := r.UserRepository.Update(user); err != nil {
+		return err
+	}
+	r.listener.UserChanged(context.Background(), user.ID)
+	return nil
+}
+
+func (r *UserRepository) Delete(id uuid.UUID) error {
+	if err := r.UserRepository.Delete(id); err != nil {
+		return err
+	}
+	r.listener.UserDeleted(context.Background(), id)
+	return nil
+}
+
+func (r *UserRepository) UpdateEmailVerified(id uuid.UUID, verified bool) error {
+	if err := r.UserRepository.UpdateEmailVerified(id, verified); err != nil {
+		return err
+	}
+	r.listener.UserChanged(context.Background(), id)
+	return nil
+}
+
+func (r *UserRepository) UpdateEmail(id uuid.UUID, email string) error {
+	if err := r.UserRepository.UpdateEmail(id, email); err != nil {
+		return err
+	}
+	r.listener.UserChanged(context.Background(), id)
+	return nil
+}
+
+func (r *UserRepository) UpdateAccount(user *domain.User, passwordHash string) error {
+	if err := r.UserRepository.UpdateAccount(user, passwordHash); err != nil {
+		return err
+	}
+	r.listener.UserChanged(context.Background(), user.ID)
+	return nil
+}
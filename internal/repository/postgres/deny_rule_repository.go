@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// denyRuleFilterCondition applies a domain.DenyRuleFilter passed as $1-$3
+const denyRuleFilterCondition = `
+	($1 = '' OR d.subject_type = $1)
+	AND ($2::uuid IS NULL OR d.subject_id = $2)
+	AND ($3::uuid IS NULL OR d.permission_id = $3)`
+
+const denyRuleColumns = `d.id, d.subject_type, d.subject_id, d.permission_id, p.resource, p.action, d.resource_id, d.description, d.created_at`
+
+type DenyRuleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDenyRuleRepository(db *pgxpool.Pool) domain.DenyRuleRepository {
+	return &DenyRuleRepository{db: db}
+}
+
+func (r *DenyRuleRepository) Create(rule *domain.DenyRule) error {
+	query := `
+		INSERT INTO deny_rules (id, subject_type, subject_id, permission_id, resource_id, description, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(context.Background(), query,
+		rule.ID, rule.SubjectType, rule.SubjectID, rule.PermissionID,
+		rule.ResourceID, rule.Description, rule.CreatedAt)
+	return err
+}
+
+func (r *DenyRuleRepository) GetByID(id uuid.UUID) (*domain.DenyRule, error) {
+	query := `
+		SELECT ` + denyRuleColumns + `
+		FROM deny_rules d
+		INNER JOIN permissions p ON p.id = d.permission_id
+		WHERE d.id = $1
+	`
+
+	rule, err := scanDenyRule(r.db.QueryRow(context.Background(), query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("deny rule not found")
+		}
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+func (r *DenyRuleRepository) Delete(id uuid.UUID) error {
+	query := `DELETE FROM deny_rules WHERE id = $1`
+
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("deny rule not found")
+	}
+
+	return nil
+}
+
+func (r *DenyRuleRepository) List(filter domain.DenyRuleFilter, limit, offset int) ([]*domain.DenyRule, error) {
+	query := `
+		SELECT ` + denyRuleColumns + `
+		FROM deny_rules d
+		INNER JOIN permissions p ON p.id = d.permission_id
+		WHERE ` + denyRuleFilterCondition + `
+		ORDER BY d.created_at DESC
+		LIMIT $4 OFFSET $5
+	`
+
+	rows, err := r.db.Query(context.Background(), query,
+		filter.SubjectType, filter.SubjectID, filter.PermissionID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*domain.DenyRule
+	for rows.Next() {
+		rule, err := scanDenyRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *DenyRuleRepository) Count(filter domain.DenyRuleFilter) (int, error) {
+	query := `SELECT COUNT(*) FROM deny_rules d WHERE ` + denyRuleFilterCondition
+
+	var count int
+	err := r.db.QueryRow(context.Background(), query,
+		filter.SubjectType, filter.SubjectID, filter.PermissionID).Scan(&count)
+	return count, err
+}
+
+// scanDenyRule scans a row selected with denyRuleColumns
+func scanDenyRule(row pgx.Row) (*domain.DenyRule, error) {
+	var rule domain.DenyRule
+	err := row.Scan(
+		&rule.ID, &rule.SubjectType, &rule.SubjectID, &rule.PermissionID, &rule.Resource, &rule.Action,
+		&rule.ResourceID, &rule.Description, &rule.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type EmailChangeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEmailChangeRepository(db *pgxpool.Pool) domain.EmailChangeRepository {
+	return &EmailChangeRepository{db: db}
+}
+
+func (r *EmailChangeRepository) Create(change *domain.EmailChange) error {
+	query := `
+		INSERT INTO email_changes (id, user_id, old_email, new_email, token_hash, cancel_token_hash, revoke_sessions, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(context.Background(), query,
+		change.ID, change.UserID, change.OldEmail, change.NewEmail,
+		change.TokenHash, change.CancelTokenHash, change.RevokeSessions,
+		change.ExpiresAt, change.CreatedAt)
+	return err
+}
+
+func (r *EmailChangeRepository) GetPendingByUserID(userID uuid.UUID) (*domain.EmailChange, error) {
+	query := `
+		SELECT id, user_id, old_email, new_email, token_hash, cancel_token_hash, revoke_sessions, expires_at, confirmed_at, cancelled_at, created_at
+		FROM email_changes
+		WHERE user_id = $1
+		  AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	return r.scanOne(query, userID)
+}
+
+func (r *EmailChangeRepository) GetByTokenHash(tokenHash string) (*domain.EmailChange, error) {
+	query := `
+		SELECT id, user_id, old_email, new_email, token_hash, cancel_token_hash, revoke_sessions, expires_at, confirmed_at, cancelled_at, created_at
+		FROM email_changes
+		WHERE token_hash = $1
+		  AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+	`
+
+	return r.scanOne(query, tokenHash)
+}
+
+func (r *EmailChangeRepository) GetByCancelTokenHash(cancelTokenHash string) (*domain.EmailChange, error) {
+	query := `
+		SELECT id, user_id, old_email, new_email, token_hash, cancel_token_hash, revoke_sessions, expires_at, confirmed_at, cancelled_at, created_at
+		FROM email_changes
+		WHERE cancel_token_hash = $1
+		  AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+	`
+
+	return r.scanOne(query, cancelTokenHash)
+}
+
+func (r *EmailChangeRepository) scanOne(query string, arg interface{}) (*domain.EmailChange, error) {
+	var change domain.EmailChange
+	err := r.db.QueryRow(context.Background(), query, arg).Scan(
+		&change.ID, &change.UserID, &change.OldEmail, &change.NewEmail,
+		&change.TokenHash, &change.CancelTokenHash, &change.RevokeSessions,
+		&change.ExpiresAt, &change.ConfirmedAt, &change.CancelledAt, &change.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("email change request not found or expired")
+		}
+		return nil, err
+	}
+
+	return &change, nil
+}
+
+// Confirm marks the change confirmed and updates the user's email in one transaction, so
+// a change is applied at most once, never after it was cancelled, and never marked
+// confirmed without taking effect. The user must still have the old address.
+func (r *EmailChangeRepository) Confirm(change *domain.EmailChange) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE email_changes SET confirmed_at = NOW()
+		WHERE id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+	`, change.ID)
+	if err != nil {
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrEmailChangeNotPending
+	}
+
+	// Only active users take part in email uniqueness, which the database enforces here
+	result, err = tx.Exec(ctx, `
+		UPDATE users SET email = $3, email_verified = TRUE, updated_at = NOW()
+		WHERE id = $1 AND email = $2 AND is_deleted = FALSE
+	`, change.UserID, change.OldEmail, change.NewEmail)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("email already in use")
+		}
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		return domain.ErrEmailChangeNotPending
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *EmailChangeRepository) MarkCancelled(id uuid.UUID) error {
+	query := `
+		UPDATE email_changes SET cancelled_at = NOW()
+		WHERE id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+	`
+
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return domain.ErrEmailChangeNotPending
+	}
+
+	return nil
+}
+
+func (r *EmailChangeRepository) CancelPendingByUserID(userID uuid.UUID) error {
+	query := `
+		UPDATE email_changes SET cancelled_at = NOW()
+		WHERE user_id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+	`
+
+	_, err := r.db.Exec(context.Background(), query, userID)
+	return err
+}
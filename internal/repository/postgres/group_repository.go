@@ -21,26 +21,26 @@ func NewGroupRepository(db *pgxpool.Pool) domain.GroupRepository {
 
 func (r *GroupRepository) Create(group *domain.Group) error {
 	query := `
-		INSERT INTO groups (id, name, description, is_active, is_deleted, is_system, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO groups (id, name, description, is_active, is_deleted, is_system, passwordless_enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 
 	_, err := r.db.Exec(context.Background(), query,
 		group.ID, group.Name, group.Description,
-		group.IsActive, group.IsDeleted, group.IsSystem,
+		group.IsActive, group.IsDeleted, group.IsSystem, group.PasswordlessEnabled,
 		group.CreatedAt, group.UpdatedAt)
 	return err
 }
 
 func (r *GroupRepository) GetByID(id uuid.UUID) (*domain.Group, error) {
 	query := `
-		SELECT id, name, description, is_active, is_deleted, is_system, created_at, updated_at
+		SELECT id, name, description, is_active, is_deleted, is_system, passwordless_enabled, created_at, updated_at
 		FROM groups WHERE id = $1 AND is_deleted = FALSE
 	`
 
 	var group domain.Group
 	err := r.db.QueryRow(context.Background(), query, id).Scan(
-		&group.ID, &group.Name, &group.Description, &group.IsActive, &group.IsDeleted, &group.IsSystem, &group.CreatedAt, &group.UpdatedAt,
+		&group.ID, &group.Name, &group.Description, &group.IsActive, &group.IsDeleted, &group.IsSystem, &group.PasswordlessEnabled, &group.CreatedAt, &group.UpdatedAt,
 	)
 
 	if err != nil {
@@ -56,11 +56,11 @@ func (r *GroupRepository) GetByID(id uuid.UUID) (*domain.Group, error) {
 func (r *GroupRepository) Update(group *domain.Group) error {
 	query := `
 		UPDATE groups 
-		SET name = $2, description = $3, is_active = $4, updated_at = NOW()
+		SET name = $2, description = $3, is_active = $4, passwordless_enabled = $5, updated_at = NOW()
 		WHERE id = $1
 	`
 
-	result, err := r.db.Exec(context.Background(), query, group.ID, group.Name, group.Description, group.IsActive)
+	result, err := r.db.Exec(context.Background(), query, group.ID, group.Name, group.Description, group.IsActive, group.PasswordlessEnabled)
 	if err != nil {
 		return err
 	}
@@ -93,7 +93,7 @@ func (r *GroupRepository) Delete(id uuid.UUID) error {
 
 func (r *GroupRepository) List(limit, offset int) ([]*domain.Group, error) {
 	query := `
-		SELECT id, name, description, is_active, is_deleted, is_system, created_at, updated_at
+		SELECT id, name, description, is_active, is_deleted, is_system, passwordless_enabled, created_at, updated_at
 		FROM groups 
 		WHERE is_deleted = FALSE
 		ORDER BY created_at DESC
@@ -110,7 +110,7 @@ func (r *GroupRepository) List(limit, offset int) ([]*domain.Group, error) {
 	for rows.Next() {
 		var group domain.Group
 		err := rows.Scan(
-			&group.ID, &group.Name, &group.Description, &group.IsActive, &group.IsDeleted, &group.IsSystem, &group.CreatedAt, &group.UpdatedAt,
+			&group.ID, &group.Name, &group.Description, &group.IsActive, &group.IsDeleted, &group.IsSystem, &group.PasswordlessEnabled, &group.CreatedAt, &group.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -155,15 +155,61 @@ func (r *GroupRepository) RemoveMember(groupID, userID uuid.UUID) error {
 	return nil
 }
 
-func (r *GroupRepository) GetMembers(groupID uuid.UUID) ([]*domain.User, error) {
+// userGroupsCTE resolves user_group_tree(group_id): the active groups user $1 belongs to
+// directly and every active group containing one of them. UNION stops the recursion at
+// groups that were already visited.
+const userGroupsCTE = `
+		user_group_tree(group_id) AS (
+			SELECT g.id
+			FROM user_groups ug
+			INNER JOIN groups g ON g.id = ug.group_id
+			WHERE ug.user_id = $1
+			  AND g.is_deleted = FALSE
+			  AND g.is_active = TRUE
+			UNION
+			SELECT gp.parent_group_id
+			FROM group_parents gp
+			INNER JOIN user_group_tree t ON gp.group_id = t.group_id
+			INNER JOIN groups pg ON pg.id = gp.parent_group_id
+			WHERE pg.is_deleted = FALSE
+			  AND pg.is_active = TRUE
+		)`
+
+// subgroupsCTE resolves group_tree(group_id): group $1 and every active group nested in it
+const subgroupsCTE = `
+		group_tree(group_id) AS (
+			SELECT $1::uuid
+			UNION
+			SELECT gp.group_id
+			FROM group_parents gp
+			INNER JOIN group_tree t ON gp.parent_group_id = t.group_id
+			INNER JOIN groups cg ON cg.id = gp.group_id
+			WHERE cg.is_deleted = FALSE
+			  AND cg.is_active = TRUE
+		)`
+
+// GetMembers returns the users in the group; effective scope includes members of the
+// groups nested in it
+func (r *GroupRepository) GetMembers(groupID uuid.UUID, scope domain.MembershipScope) ([]*domain.User, error) {
 	query := `
-		SELECT u.id, u.email, u.password_hash, u.first_name, u.last_name, u.status, u.email_verified, u.is_deleted, u.is_system, u.created_at, u.updated_at
+		SELECT u.id, u.email, u.password_hash, u.first_name, u.last_name, u.status, u.email_verified, u.is_deleted, u.is_system, u.password_changed_at, u.created_at, u.updated_at
 		FROM users u
 		INNER JOIN user_groups ug ON u.id = ug.user_id
 		WHERE ug.group_id = $1
 		  AND u.is_deleted = FALSE
 		ORDER BY u.created_at ASC
 	`
+	if scope == domain.MembershipEffective {
+		query = `
+		WITH RECURSIVE` + subgroupsCTE + `
+		SELECT DISTINCT u.id, u.email, u.password_hash, u.first_name, u.last_name, u.status, u.email_verified, u.is_deleted, u.is_system, u.password_changed_at, u.created_at, u.updated_at
+		FROM users u
+		INNER JOIN user_groups ug ON u.id = ug.user_id
+		INNER JOIN group_tree t ON ug.group_id = t.group_id
+		WHERE u.is_deleted = FALSE
+		ORDER BY u.created_at ASC
+	`
+	}
 
 	rows, err := r.db.Query(context.Background(), query, groupID)
 	if err != nil {
@@ -176,7 +222,7 @@ func (r *GroupRepository) GetMembers(groupID uuid.UUID) ([]*domain.User, error)
 		var user domain.User
 		err := rows.Scan(
 			&user.ID, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
-			&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.CreatedAt, &user.UpdatedAt,
+			&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.PasswordChangedAt, &user.CreatedAt, &user.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -187,9 +233,11 @@ func (r *GroupRepository) GetMembers(groupID uuid.UUID) ([]*domain.User, error)
 	return users, nil
 }
 
-func (r *GroupRepository) GetUserGroups(userID uuid.UUID) ([]*domain.Group, error) {
+// GetUserGroups returns the active groups of the user; effective scope includes the
+// groups containing them
+func (r *GroupRepository) GetUserGroups(userID uuid.UUID, scope domain.MembershipScope) ([]*domain.Group, error) {
 	query := `
-		SELECT g.id, g.name, g.description, g.is_active, g.is_deleted, g.is_system, g.created_at, g.updated_at
+		SELECT g.id, g.name, g.description, g.is_active, g.is_deleted, g.is_system, g.passwordless_enabled, g.created_at, g.updated_at
 		FROM groups g
 		INNER JOIN user_groups ug ON g.id = ug.group_id
 		WHERE ug.user_id = $1
@@ -197,8 +245,120 @@ func (r *GroupRepository) GetUserGroups(userID uuid.UUID) ([]*domain.Group, erro
 		  AND g.is_active = TRUE
 		ORDER BY g.created_at ASC
 	`
+	if scope == domain.MembershipEffective {
+		query = `
+		WITH RECURSIVE` + userGroupsCTE + `
+		SELECT g.id, g.name, g.description, g.is_active, g.is_deleted, g.is_system, g.passwordless_enabled, g.created_at, g.updated_at
+		FROM groups g
+		INNER JOIN user_group_tree t ON g.id = t.group_id
+		ORDER BY g.created_at ASC
+	`
+	}
+
+	return r.queryGroups(query, userID)
+}
+
+// AddSubgroup nests subgroupID in groupID. Edges are added under a transaction-level
+// advisory lock so concurrent additions cannot form a cycle together.
+func (r *GroupRepository) AddSubgroup(groupID, subgroupID uuid.UUID) error {
+	if groupID == subgroupID {
+		return domain.ErrGroupCycle
+	}
 
-	rows, err := r.db.Query(context.Background(), query, userID)
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('group_parents'))`); err != nil {
+		return err
+	}
+
+	var found int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM groups WHERE id IN ($1, $2) AND is_deleted = FALSE`, groupID, subgroupID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != 2 {
+		return fmt.Errorf("group not found")
+	}
+
+	// The edge closes a cycle when the subgroup already contains the group
+	var cycle bool
+	err = tx.QueryRow(ctx, `
+		WITH RECURSIVE ancestors(group_id) AS (
+			SELECT $1::uuid
+			UNION
+			SELECT gp.parent_group_id
+			FROM group_parents gp
+			INNER JOIN ancestors a ON gp.group_id = a.group_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE group_id = $2)
+	`, groupID, subgroupID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return domain.ErrGroupCycle
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO group_parents (group_id, parent_group_id)
+		VALUES ($1, $2)
+		ON CONFLICT (group_id, parent_group_id) DO NOTHING
+	`, subgroupID, groupID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *GroupRepository) RemoveSubgroup(groupID, subgroupID uuid.UUID) error {
+	query := `DELETE FROM group_parents WHERE group_id = $1 AND parent_group_id = $2`
+
+	result, err := r.db.Exec(context.Background(), query, subgroupID, groupID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("subgroup not found in group")
+	}
+
+	return nil
+}
+
+// GetSubgroups returns the groups nested directly in the group, or with effective scope
+// every active group nested in it at any depth
+func (r *GroupRepository) GetSubgroups(groupID uuid.UUID, scope domain.MembershipScope) ([]*domain.Group, error) {
+	query := `
+		SELECT g.id, g.name, g.description, g.is_active, g.is_deleted, g.is_system, g.passwordless_enabled, g.created_at, g.updated_at
+		FROM groups g
+		INNER JOIN group_parents gp ON g.id = gp.group_id
+		WHERE gp.parent_group_id = $1
+		  AND g.is_deleted = FALSE
+		ORDER BY g.name ASC
+	`
+	if scope == domain.MembershipEffective {
+		query = `
+		WITH RECURSIVE` + subgroupsCTE + `
+		SELECT g.id, g.name, g.description, g.is_active, g.is_deleted, g.is_system, g.passwordless_enabled, g.created_at, g.updated_at
+		FROM groups g
+		INNER JOIN group_tree t ON g.id = t.group_id
+		WHERE g.id <> $1
+		ORDER BY g.name ASC
+	`
+	}
+
+	return r.queryGroups(query, groupID)
+}
+
+// queryGroups runs a query selecting the group columns and collects the rows
+func (r *GroupRepository) queryGroups(query string, args ...interface{}) ([]*domain.Group, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -208,7 +368,7 @@ func (r *GroupRepository) GetUserGroups(userID uuid.UUID) ([]*domain.Group, erro
 	for rows.Next() {
 		var group domain.Group
 		err := rows.Scan(
-			&group.ID, &group.Name, &group.Description, &group.IsActive, &group.IsDeleted, &group.IsSystem, &group.CreatedAt, &group.UpdatedAt,
+			&group.ID, &group.Name, &group.Description, &group.IsActive, &group.IsDeleted, &group.IsSystem, &group.PasswordlessEnabled, &group.CreatedAt, &group.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -216,5 +376,5 @@ func (r *GroupRepository) GetUserGroups(userID uuid.UUID) ([]*domain.Group, erro
 		groups = append(groups, &group)
 	}
 
-	return groups, nil
+	return groups, rows.Err()
 }
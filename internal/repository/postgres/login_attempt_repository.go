@@ -0,0 +1,89 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type LoginAttemptRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginAttemptRepository(db *pgxpool.Pool) domain.LoginAttemptStore {
+	return &LoginAttemptRepository{db: db}
+}
+
+func (r *LoginAttemptRepository) Get(key string) (*domain.LoginAttempts, error) {
+	query := `SELECT key, failures, locked_until, last_failure_at FROM login_attempts WHERE key = $1`
+
+	var attempts domain.LoginAttempts
+	err := r.db.QueryRow(context.Background(), query, key).Scan(
+		&attempts.Key, &attempts.Failures, &attempts.LockedUntil, &attempts.LastFailureAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return &domain.LoginAttempts{Key: key}, nil
+		}
+		return nil, err
+	}
+
+	return &attempts, nil
+}
+
+func (r *LoginAttemptRepository) RecordFailure(key string, resetAfter time.Duration) (*domain.LoginAttempts, error) {
+	// Failures older than resetAfter no longer count toward the streak
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failure_at)
+		VALUES ($1, 1, NOW())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failure_at IS NULL
+					OR login_attempts.last_failure_at < NOW() - make_interval(secs => $2)
+				THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failure_at = NOW()
+		RETURNING key, failures, locked_until, last_failure_at
+	`
+
+	var attempts domain.LoginAttempts
+	err := r.db.QueryRow(context.Background(), query, key, resetAfter.Seconds()).Scan(
+		&attempts.Key, &attempts.Failures, &attempts.LockedUntil, &attempts.LastFailureAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempts, nil
+}
+
+func (r *LoginAttemptRepository) SetLockedUntil(key string, until time.Time) error {
+	query := `UPDATE login_attempts SET locked_until = $2 WHERE key = $1`
+
+	_, err := r.db.Exec(context.Background(), query, key, until)
+	return err
+}
+
+func (r *LoginAttemptRepository) Reset(key string) error {
+	query := `DELETE FROM login_attempts WHERE key = $1`
+
+	_, err := r.db.Exec(context.Background(), query, key)
+	return err
+}
+
+func (r *LoginAttemptRepository) PurgeExpired(resetAfter time.Duration) error {
+	query := `
+		DELETE FROM login_attempts
+		WHERE last_failure_at < NOW() - make_interval(secs => $1)
+		AND (locked_until IS NULL OR locked_until < NOW())
+	`
+
+	_, err := r.db.Exec(context.Background(), query, resetAfter.Seconds())
+	return err
+}
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type PasswordHistoryRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordHistoryRepository(db *pgxpool.Pool) domain.PasswordHistoryRepository {
+	return &PasswordHistoryRepository{db: db}
+}
+
+func (r *PasswordHistoryRepository) Add(userID uuid.UUID, passwordHash string) error {
+	query := `
+		INSERT INTO password_history (id, user_id, password_hash)
+		VALUES ($1, $2, $3)
+	`
+
+	_, err := r.db.Exec(context.Background(), query, uuid.New(), userID, passwordHash)
+	return err
+}
+
+func (r *PasswordHistoryRepository) GetRecent(userID uuid.UUID, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := r.db.Query(context.Background(), query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, hash)
+	}
+
+	return hashes, nil
+}
+
+func (r *PasswordHistoryRepository) Prune(userID uuid.UUID, keep int) error {
+	query := `
+		DELETE FROM password_history
+		WHERE user_id = $1
+		  AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC
+			LIMIT $2
+		  )
+	`
+
+	_, err := r.db.Exec(context.Background(), query, userID, keep)
+	return err
+}
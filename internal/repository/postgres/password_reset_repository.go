@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type PasswordResetTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordResetTokenRepository(db *pgxpool.Pool) domain.PasswordResetTokenRepository {
+	return &PasswordResetTokenRepository{db: db}
+}
+
+func (r *PasswordResetTokenRepository) Create(token *domain.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.db.Exec(context.Background(), query, token.ID, token.UserID, token.TokenHash, token.ExpiresAt, token.CreatedAt)
+	return err
+}
+
+// Consume marks the token used in a transaction that stays open while apply runs. The
+// updated row stays locked until then, so a concurrent call for the same token waits and
+// finds it used, and a failing apply rolls the consumption back.
+func (r *PasswordResetTokenRepository) Consume(tokenHash string, apply func(userID uuid.UUID) error) error {
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE password_reset_tokens SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`
+
+	var userID uuid.UUID
+	if err := tx.QueryRow(ctx, query, tokenHash).Scan(&userID); err != nil {
+		if err == pgx.ErrNoRows {
+			return domain.ErrInvalidResetToken
+		}
+		return err
+	}
+
+	if err := apply(userID); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PasswordResetTokenRepository) DeleteByUserID(userID uuid.UUID) error {
+	query := `DELETE FROM password_reset_tokens WHERE user_id = $1`
+
+	_, err := r.db.Exec(context.Background(), query, userID)
+	return err
+}
@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type PasswordlessChallengeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordlessChallengeRepository(db *pgxpool.Pool) domain.PasswordlessChallengeRepository {
+	return &PasswordlessChallengeRepository{db: db}
+}
+
+func (r *PasswordlessChallengeRepository) Create(challenge *domain.PasswordlessChallenge) error {
+	query := `
+		INSERT INTO passwordless_challenges (id, user_id, provider, token_hash, code_hash, binding_hash, attempts, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(context.Background(), query,
+		challenge.ID, challenge.UserID, challenge.Provider,
+		challenge.TokenHash, challenge.CodeHash, challenge.BindingHash,
+		challenge.Attempts, challenge.ExpiresAt, challenge.CreatedAt)
+	return err
+}
+
+func (r *PasswordlessChallengeRepository) GetByID(id uuid.UUID) (*domain.PasswordlessChallenge, error) {
+	query := `
+		SELECT id, user_id, provider, token_hash, code_hash, binding_hash, attempts, expires_at, consumed_at, created_at
+		FROM passwordless_challenges
+		WHERE id = $1 AND consumed_at IS NULL AND expires_at > NOW()
+	`
+
+	return r.scanOne(query, id)
+}
+
+func (r *PasswordlessChallengeRepository) GetByTokenHash(tokenHash string) (*domain.PasswordlessChallenge, error) {
+	query := `
+		SELECT id, user_id, provider, token_hash, code_hash, binding_hash, attempts, expires_at, consumed_at, created_at
+		FROM passwordless_challenges
+		WHERE token_hash = $1 AND consumed_at IS NULL AND expires_at > NOW()
+	`
+
+	return r.scanOne(query, tokenHash)
+}
+
+func (r *PasswordlessChallengeRepository) scanOne(query string, arg interface{}) (*domain.PasswordlessChallenge, error) {
+	var challenge domain.PasswordlessChallenge
+	err := r.db.QueryRow(context.Background(), query, arg).Scan(
+		&challenge.ID, &challenge.UserID, &challenge.Provider,
+		&challenge.TokenHash, &challenge.CodeHash, &challenge.BindingHash,
+		&challenge.Attempts, &challenge.ExpiresAt, &challenge.ConsumedAt, &challenge.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("challenge not found or expired")
+		}
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+func (r *PasswordlessChallengeRepository) IncrementAttempts(id uuid.UUID) (int, error) {
+	query := `
+		UPDATE passwordless_challenges SET attempts = attempts + 1
+		WHERE id = $1 AND consumed_at IS NULL
+		RETURNING attempts
+	`
+
+	var attempts int
+	if err := r.db.QueryRow(context.Background(), query, id).Scan(&attempts); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, fmt.Errorf("challenge not found or expired")
+		}
+		return 0, err
+	}
+
+	return attempts, nil
+}
+
+func (r *PasswordlessChallengeRepository) Consume(id uuid.UUID) error {
+	query := `
+		UPDATE passwordless_challenges SET consumed_at = NOW()
+		WHERE id = $1 AND consumed_at IS NULL AND expires_at > NOW()
+	`
+
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("challenge already used or expired")
+	}
+
+	return nil
+}
+
+func (r *PasswordlessChallengeRepository) DeleteByUserID(userID uuid.UUID) error {
+	query := `DELETE FROM passwordless_challenges WHERE user_id = $1`
+
+	_, err := r.db.Exec(context.Background(), query, userID)
+	return err
+}
+
+func (r *PasswordlessChallengeRepository) DeleteExpired() error {
+	query := `DELETE FROM passwordless_challenges WHERE expires_at < NOW() OR consumed_at IS NOT NULL`
+
+	_, err := r.db.Exec(context.Background(), query)
+	return err
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
@@ -150,14 +151,15 @@ func (r *PermissionRepository) Count() (int, error) {
 	return count, err
 }
 
-func (r *PermissionRepository) AssignToRole(roleID, permissionID uuid.UUID) error {
+// AssignToRole links the permission to the role, replacing the condition of an existing link
+func (r *PermissionRepository) AssignToRole(roleID, permissionID uuid.UUID, condition string) error {
 	query := `
-		INSERT INTO role_permissions (role_id, permission_id)
-		VALUES ($1, $2)
-		ON CONFLICT (role_id, permission_id) DO NOTHING
+		INSERT INTO role_permissions (role_id, permission_id, condition)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (role_id, permission_id) DO UPDATE SET condition = EXCLUDED.condition
 	`
 
-	_, err := r.db.Exec(context.Background(), query, roleID, permissionID)
+	_, err := r.db.Exec(context.Background(), query, roleID, permissionID, condition)
 	return err
 }
 
@@ -178,7 +180,8 @@ func (r *PermissionRepository) RemoveFromRole(roleID, permissionID uuid.UUID) er
 
 func (r *PermissionRepository) GetRolePermissions(roleID uuid.UUID) ([]*domain.Permission, error) {
 	query := `
-		SELECT p.id, p.resource, p.action, p.description, p.is_active, p.is_deleted, p.is_system, p.created_at, p.updated_at
+		SELECT p.id, p.resource, p.action, p.description, p.is_active, p.is_deleted, p.is_system, p.created_at, p.updated_at,
+		       rp.condition
 		FROM permissions p
 		INNER JOIN role_permissions rp ON p.id = rp.permission_id
 		WHERE rp.role_id = $1
@@ -198,6 +201,7 @@ func (r *PermissionRepository) GetRolePermissions(roleID uuid.UUID) ([]*domain.P
 		var permission domain.Permission
 		err := rows.Scan(
 			&permission.ID, &permission.Resource, &permission.Action, &permission.Description, &permission.IsActive, &permission.IsDeleted, &permission.IsSystem, &permission.CreatedAt, &permission.UpdatedAt,
+			&permission.Condition,
 		)
 		if err != nil {
 			return nil, err
@@ -208,57 +212,663 @@ func (r *PermissionRepository) GetRolePermissions(roleID uuid.UUID) ([]*domain.P
 	return permissions, nil
 }
 
+// permissionMatchesCondition is permissionMatches for the requested resource $2 and action $3
+var permissionMatchesCondition = permissionMatches("$2", "$3")
+
+// permissionMatches is the SQL form of domain.PermissionMatches for permission p granted
+// on the requested resource and action parameters: wildcards ("*"), hierarchical
+// resources ("billing" covers "billing.invoices") and trailing ".*" resources
+func permissionMatches(resource, action string) string {
+	return strings.NewReplacer("$resource", resource, "$action", action).Replace(`(p.action = $action OR p.action = '*')
+		  AND (p.resource = $resource
+		       OR p.resource = '*'
+		       OR left($resource, length(p.resource) + 1) = (p.resource || '.')
+		       OR (right(p.resource, 2) = '.*' AND left($resource, length(p.resource) - 1) = left(p.resource, -1)))`)
+}
+
+// userGrantedRolesCTE defines granted_roles(role_id, condition): the active roles of user
+// $1 assigned directly, through their groups and the groups containing them and, when $4
+// is set, on instance $4 of resource $2, followed by the roles those roles inherit. The
+// condition is that of the assignment the role derives from.
+const userGrantedRolesCTE = userGroupsCTE + `,
+		direct_roles(role_id, condition) AS (
+			SELECT ur.role_id, ur.condition
+			FROM user_roles ur
+			INNER JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = $1
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT gr.role_id, gr.condition
+			FROM user_group_tree t
+			INNER JOIN group_roles gr ON gr.group_id = t.group_id
+			INNER JOIN roles r ON r.id = gr.role_id
+			WHERE r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT rg.role_id, rg.condition
+			FROM resource_grants rg
+			INNER JOIN roles r ON r.id = rg.role_id
+			WHERE $4 <> ''
+			  AND rg.resource_type = $2
+			  AND rg.resource_id = $4
+			  AND ((rg.subject_type = 'user' AND rg.subject_id = $1)
+			    OR (rg.subject_type = 'group' AND rg.subject_id IN (SELECT group_id FROM user_group_tree)))
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+		),
+		granted_roles(role_id, condition) AS (
+			SELECT role_id, condition FROM direct_roles
+			UNION
+			SELECT rp.parent_role_id, g.condition
+			FROM role_parents rp
+			INNER JOIN granted_roles g ON rp.role_id = g.role_id
+			INNER JOIN roles pr ON pr.id = rp.parent_role_id
+			WHERE pr.is_deleted = FALSE
+			  AND pr.is_active = TRUE
+		)`
+
+// CheckUserPermission checks the permissions of the roles assigned to the user directly
+// or through their groups and the groups containing them, including the roles those
+// roles inherit from. Only unconditional grants count; see GetUserPermissionGrants. A
+// deny rule covering the permission overrides every grant.
 func (r *PermissionRepository) CheckUserPermission(userID uuid.UUID, resource, action string) (bool, error) {
+	return r.CheckUserResourcePermission(userID, resource, "", action)
+}
+
+// CheckUserResourcePermission is CheckUserPermission on a resource instance: when
+// resourceID is set, the roles granted on that instance to the user or their groups
+// count as well
+func (r *PermissionRepository) CheckUserResourcePermission(userID uuid.UUID, resource, resourceID, action string) (bool, error) {
+	denyRule, err := r.GetUserDenyRule(userID, resource, resourceID, action)
+	if err != nil {
+		return false, err
+	}
+	if denyRule != nil {
+		return false, nil
+	}
+
+	query := `
+		WITH RECURSIVE` + userGrantedRolesCTE + `
+		SELECT EXISTS (
+			SELECT 1
+			FROM permissions p
+			INNER JOIN role_permissions rp ON p.id = rp.permission_id
+			INNER JOIN granted_roles g ON rp.role_id = g.role_id
+			WHERE ` + permissionMatchesCondition + `
+			  AND p.is_deleted = FALSE
+			  AND p.is_active = TRUE
+			  AND g.condition = ''
+			  AND rp.condition = ''
+		)
+	`
+
+	var hasPermission bool
+	if err := r.db.QueryRow(context.Background(), query, userID, resource, action, resourceID).Scan(&hasPermission); err != nil {
+		return false, err
+	}
+
+	return hasPermission, nil
+}
+
+// GetUserPermissionGrants returns the distinct conditions of the paths granting the user
+// the permission, as CheckUserResourcePermission does; none means no grant
+func (r *PermissionRepository) GetUserPermissionGrants(userID uuid.UUID, resource, resourceID, action string) ([]domain.GrantConditions, error) {
 	query := `
-		SELECT COUNT(*) > 0
+		WITH RECURSIVE` + userGrantedRolesCTE + `
+		SELECT DISTINCT g.condition, rp.condition
 		FROM permissions p
 		INNER JOIN role_permissions rp ON p.id = rp.permission_id
-		INNER JOIN roles r ON rp.role_id = r.id
-		INNER JOIN user_roles ur ON r.id = ur.role_id
-		WHERE ur.user_id = $1 
-		  AND p.resource = $2 
-		  AND p.action = $3
+		INNER JOIN granted_roles g ON rp.role_id = g.role_id
+		WHERE ` + permissionMatchesCondition + `
 		  AND p.is_deleted = FALSE
 		  AND p.is_active = TRUE
-		  AND r.is_deleted = FALSE
-		  AND r.is_active = TRUE
-		
-		UNION ALL
-		
-		SELECT COUNT(*) > 0
-		FROM permissions p
-		INNER JOIN role_permissions rp ON p.id = rp.permission_id
-		INNER JOIN roles r ON rp.role_id = r.id
-		INNER JOIN group_roles gr ON r.id = gr.role_id
-		INNER JOIN groups g ON gr.group_id = g.id
-		INNER JOIN user_groups ug ON g.id = ug.group_id
-		WHERE ug.user_id = $1 
-		  AND p.resource = $2 
-		  AND p.action = $3
+	`
+
+	rows, err := r.db.Query(context.Background(), query, userID, resource, action, resourceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []domain.GrantConditions
+	for rows.Next() {
+		var grant domain.GrantConditions
+		if err := rows.Scan(&grant.Assignment, &grant.Permission); err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, rows.Err()
+}
+
+// GetUserDenyRule returns the oldest deny rule covering the permission that applies to
+// the user directly, to one of their groups or to one of their granted roles, or nil if
+// none does. Rules on roles apply whatever the conditions of the role's assignment.
+func (r *PermissionRepository) GetUserDenyRule(userID uuid.UUID, resource, resourceID, action string) (*domain.DenyRule, error) {
+	query := `
+		WITH RECURSIVE` + userGrantedRolesCTE + `
+		SELECT ` + denyRuleColumns + `
+		FROM deny_rules d
+		INNER JOIN permissions p ON p.id = d.permission_id
+		WHERE ` + permissionMatchesCondition + `
 		  AND p.is_deleted = FALSE
 		  AND p.is_active = TRUE
-		  AND r.is_deleted = FALSE
-		  AND r.is_active = TRUE
-		  AND g.is_deleted = FALSE
-		  AND g.is_active = TRUE
+		  AND (d.resource_id = '' OR d.resource_id = $4)
+		  AND ((d.subject_type = 'user' AND d.subject_id = $1)
+		    OR (d.subject_type = 'group' AND d.subject_id IN (SELECT group_id FROM user_group_tree))
+		    OR (d.subject_type = 'role' AND d.subject_id IN (SELECT role_id FROM granted_roles)))
+		ORDER BY d.created_at ASC
+		LIMIT 1
 	`
 
-	rows, err := r.db.Query(context.Background(), query, userID, resource, action)
+	rule, err := scanDenyRule(r.db.QueryRow(context.Background(), query, userID, resource, action, resourceID))
 	if err != nil {
-		return false, err
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// maxPermissionPaths bounds GetUserPermissionPaths, as diamond-shaped group and role
+// hierarchies multiply the paths
+const maxPermissionPaths = 1000
+
+// pathNodeJSON builds a domain.PathNode from the group or role with the alias
+func pathNodeJSON(alias string) string {
+	return fmt.Sprintf("jsonb_build_object('id', %[1]s.id, 'name', %[1]s.name, 'is_active', %[1]s.is_active, 'is_deleted', %[1]s.is_deleted)", alias)
+}
+
+// GetUserPermissionPaths lists every path along which the user could be granted the
+// permission, following the same assignments, group nesting and role inheritance as
+// CheckUserResourcePermission but keeping inactive and deleted groups, roles and
+// permissions on the paths instead of filtering them out
+func (r *PermissionRepository) GetUserPermissionPaths(userID uuid.UUID, resource, resourceID, action string) ([]*domain.PermissionPath, error) {
+	query := `
+		WITH RECURSIVE group_paths(group_id, groups) AS (
+			SELECT g.id, jsonb_build_array(` + pathNodeJSON("g") + `)
+			FROM user_groups ug
+			INNER JOIN groups g ON g.id = ug.group_id
+			WHERE ug.user_id = $1
+			UNION ALL
+			SELECT pg.id, t.groups || jsonb_build_array(` + pathNodeJSON("pg") + `)
+			FROM group_paths t
+			INNER JOIN group_parents gp ON gp.group_id = t.group_id
+			INNER JOIN groups pg ON pg.id = gp.parent_group_id
+			WHERE NOT t.groups @> jsonb_build_array(jsonb_build_object('id', pg.id))
+		),
+		assignments(via, grant_id, groups, role_id, condition) AS (
+			SELECT 'user'::text, NULL::uuid, '[]'::jsonb, ur.role_id, ur.condition
+			FROM user_roles ur
+			WHERE ur.user_id = $1
+
+			UNION ALL
+
+			SELECT 'group'::text, NULL::uuid, t.groups, gr.role_id, gr.condition
+			FROM group_paths t
+			INNER JOIN group_roles gr ON gr.group_id = t.group_id
+
+			UNION ALL
+
+			SELECT 'resource_grant'::text, rg.id, '[]'::jsonb, rg.role_id, rg.condition
+			FROM resource_grants rg
+			WHERE $4 <> ''
+			  AND rg.resource_type = $2
+			  AND rg.resource_id = $4
+			  AND rg.subject_type = 'user'
+			  AND rg.subject_id = $1
+
+			UNION ALL
+
+			SELECT 'resource_grant'::text, rg.id, t.groups, rg.role_id, rg.condition
+			FROM group_paths t
+			INNER JOIN resource_grants rg ON rg.subject_type = 'group' AND rg.subject_id = t.group_id
+			WHERE $4 <> ''
+			  AND rg.resource_type = $2
+			  AND rg.resource_id = $4
+		),
+		role_paths(via, grant_id, groups, role_id, roles, condition) AS (
+			SELECT a.via, a.grant_id, a.groups, r.id, jsonb_build_array(` + pathNodeJSON("r") + `), a.condition
+			FROM assignments a
+			INNER JOIN roles r ON r.id = a.role_id
+			UNION ALL
+			SELECT t.via, t.grant_id, t.groups, pr.id, t.roles || jsonb_build_array(` + pathNodeJSON("pr") + `), t.condition
+			FROM role_paths t
+			INNER JOIN role_parents rp ON rp.role_id = t.role_id
+			INNER JOIN roles pr ON pr.id = rp.parent_role_id
+			WHERE NOT t.roles @> jsonb_build_array(jsonb_build_object('id', pr.id))
+		)
+		SELECT t.via, t.grant_id, t.groups, t.roles,
+		       p.id, p.resource || ':' || p.action, p.is_active, p.is_deleted,
+		       t.condition, rp.condition
+		FROM role_paths t
+		INNER JOIN role_permissions rp ON rp.role_id = t.role_id
+		INNER JOIN permissions p ON p.id = rp.permission_id
+		WHERE ` + permissionMatchesCondition + `
+		ORDER BY array_position(ARRAY['user', 'group', 'resource_grant'], t.via), jsonb_array_length(t.groups), jsonb_array_length(t.roles)
+		LIMIT $5
+	`
+
+	rows, err := r.db.Query(context.Background(), query, userID, resource, action, resourceID, maxPermissionPaths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []*domain.PermissionPath
+	for rows.Next() {
+		var path domain.PermissionPath
+		err := rows.Scan(
+			&path.Via, &path.ResourceGrantID, &path.Groups, &path.Roles,
+			&path.Permission.ID, &path.Permission.Name, &path.Permission.IsActive, &path.Permission.IsDeleted,
+			&path.AssignmentCondition, &path.PermissionCondition,
+		)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, &path)
+	}
+
+	return paths, rows.Err()
+}
+
+// userEffectivePermissionsCTE defines effective_permissions: for every active permission
+// user $1 holds globally, one row per source, i.e. per active role assigned to the user
+// or to one of their groups and per role it inherits holding the permission
+const userEffectivePermissionsCTE = userGroupsCTE + `,
+		assigned_roles(via, group_id, assigned_role_id, condition) AS (
+			SELECT 'user'::text, NULL::uuid, ur.role_id, ur.condition
+			FROM user_roles ur
+			INNER JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = $1
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT 'group'::text, t.group_id, gr.role_id, gr.condition
+			FROM user_group_tree t
+			INNER JOIN group_roles gr ON gr.group_id = t.group_id
+			INNER JOIN roles r ON r.id = gr.role_id
+			WHERE r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+		),
+		role_sources(via, group_id, assigned_role_id, role_id, condition) AS (
+			SELECT via, group_id, assigned_role_id, assigned_role_id, condition FROM assigned_roles
+			UNION
+			SELECT s.via, s.group_id, s.assigned_role_id, rp.parent_role_id, s.condition
+			FROM role_sources s
+			INNER JOIN role_parents rp ON rp.role_id = s.role_id
+			INNER JOIN roles pr ON pr.id = rp.parent_role_id
+			WHERE pr.is_deleted = FALSE
+			  AND pr.is_active = TRUE
+		),
+		effective_permissions AS (
+			SELECT p.id AS permission_id, p.resource, p.action, p.description,
+			       s.via, s.group_id, s.assigned_role_id, s.role_id,
+			       s.condition AS assignment_condition, rp.condition AS permission_condition
+			FROM role_sources s
+			INNER JOIN role_permissions rp ON rp.role_id = s.role_id
+			INNER JOIN permissions p ON p.id = rp.permission_id
+			WHERE p.is_deleted = FALSE
+			  AND p.is_active = TRUE
+		)`
+
+// GetUserEffectivePermissions returns a page of the permissions the user holds globally,
+// ordered by resource and action, each with all of its sources and the oldest global deny
+// rule of the user covering the whole permission, as GetUserDenyRule finds it
+func (r *PermissionRepository) GetUserEffectivePermissions(userID uuid.UUID, limit, offset int) ([]*domain.EffectivePermission, error) {
+	query := `
+		WITH RECURSIVE` + userEffectivePermissionsCTE + `,
+		permission_page AS (
+			SELECT DISTINCT permission_id, resource, action
+			FROM effective_permissions
+			ORDER BY resource, action
+			LIMIT $2 OFFSET $3
+		)
+		SELECT e.permission_id, e.resource, e.action, e.description,
+		       e.via, e.group_id, COALESCE(g.name, ''), e.assigned_role_id, ar.name, e.role_id, r.name,
+		       e.assignment_condition, e.permission_condition,
+		       CASE WHEN deny.id IS NULL THEN NULL ELSE to_jsonb(deny) END
+		FROM permission_page pp
+		LEFT JOIN LATERAL (
+			SELECT ` + denyRuleColumns + `
+			FROM deny_rules d
+			INNER JOIN permissions p ON p.id = d.permission_id
+			WHERE ` + permissionMatches("pp.resource", "pp.action") + `
+			  AND p.is_deleted = FALSE
+			  AND p.is_active = TRUE
+			  AND d.resource_id = ''
+			  AND ((d.subject_type = 'user' AND d.subject_id = $1)
+			    OR (d.subject_type = 'group' AND d.subject_id IN (SELECT group_id FROM user_group_tree))
+			    OR (d.subject_type = 'role' AND d.subject_id IN (SELECT role_id FROM role_sources)))
+			ORDER BY d.created_at ASC
+			LIMIT 1
+		) deny ON TRUE
+		INNER JOIN effective_permissions e ON e.permission_id = pp.permission_id
+		INNER JOIN roles ar ON ar.id = e.assigned_role_id
+		INNER JOIN roles r ON r.id = e.role_id
+		LEFT JOIN groups g ON g.id = e.group_id
+		ORDER BY e.resource, e.action, e.permission_id, e.via DESC, g.name, ar.name, r.name
+	`
+
+	rows, err := r.db.Query(context.Background(), query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []*domain.EffectivePermission
+	for rows.Next() {
+		var permission domain.EffectivePermission
+		var source domain.PermissionSource
+		err := rows.Scan(
+			&permission.PermissionID, &permission.Resource, &permission.Action, &permission.Description,
+			&source.Via, &source.GroupID, &source.GroupName, &source.AssignedRoleID, &source.AssignedRoleName, &source.RoleID, &source.RoleName,
+			&source.AssignmentCondition, &source.PermissionCondition,
+			&permission.DeniedBy,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Rows of a permission are adjacent; collect their sources on one entry
+		if n := len(permissions); n > 0 && permissions[n-1].PermissionID == permission.PermissionID {
+			permissions[n-1].Sources = append(permissions[n-1].Sources, source)
+			continue
+		}
+		permission.Sources = []domain.PermissionSource{source}
+		permissions = append(permissions, &permission)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (r *PermissionRepository) CountUserEffectivePermissions(userID uuid.UUID) (int, error) {
+	query := `
+		WITH RECURSIVE` + userEffectivePermissionsCTE + `
+		SELECT COUNT(DISTINCT permission_id) FROM effective_permissions
+	`
+
+	var count int
+	err := r.db.QueryRow(context.Background(), query, userID).Scan(&count)
+	return count, err
+}
+
+// permissionHoldersCTE defines permission_holders(user_id, conditional): the users granted
+// resource $1 and action $2 globally, found by walking CheckUserPermission's paths
+// backwards from the matching permissions: to the active roles holding them, the active
+// roles inheriting those, the users and active groups assigned them and the members of
+// those groups and their active subgroups. Deny rules are walked the same way from their
+// subjects, and users reached by one are left out.
+var permissionHoldersCTE = `
+		holder_roles(role_id, conditional, deny) AS (
+			SELECT rp.role_id, rp.condition <> '', FALSE
+			FROM permissions p
+			INNER JOIN role_permissions rp ON rp.permission_id = p.id
+			INNER JOIN roles r ON r.id = rp.role_id
+			WHERE ` + permissionMatches("$1", "$2") + `
+			  AND p.is_deleted = FALSE
+			  AND p.is_active = TRUE
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT d.subject_id, FALSE, TRUE
+			FROM deny_rules d
+			INNER JOIN permissions p ON p.id = d.permission_id
+			INNER JOIN roles r ON r.id = d.subject_id
+			WHERE d.subject_type = 'role'
+			  AND d.resource_id = ''
+			  AND ` + permissionMatches("$1", "$2") + `
+			  AND p.is_deleted = FALSE
+			  AND p.is_active = TRUE
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT rp.role_id, h.conditional, h.deny
+			FROM holder_roles h
+			INNER JOIN role_parents rp ON rp.parent_role_id = h.role_id
+			INNER JOIN roles cr ON cr.id = rp.role_id
+			WHERE cr.is_deleted = FALSE
+			  AND cr.is_active = TRUE
+		),
+		holder_groups(group_id, conditional, deny) AS (
+			SELECT gr.group_id, h.conditional OR gr.condition <> '', h.deny
+			FROM holder_roles h
+			INNER JOIN group_roles gr ON gr.role_id = h.role_id
+			INNER JOIN groups g ON g.id = gr.group_id
+			WHERE g.is_deleted = FALSE
+			  AND g.is_active = TRUE
+
+			UNION
+
+			SELECT d.subject_id, FALSE, TRUE
+			FROM deny_rules d
+			INNER JOIN permissions p ON p.id = d.permission_id
+			INNER JOIN groups g ON g.id = d.subject_id
+			WHERE d.subject_type = 'group'
+			  AND d.resource_id = ''
+			  AND ` + permissionMatches("$1", "$2") + `
+			  AND p.is_deleted = FALSE
+			  AND p.is_active = TRUE
+			  AND g.is_deleted = FALSE
+			  AND g.is_active = TRUE
+
+			UNION
+
+			SELECT gp.group_id, h.conditional, h.deny
+			FROM holder_groups h
+			INNER JOIN group_parents gp ON gp.parent_group_id = h.group_id
+			INNER JOIN groups cg ON cg.id = gp.group_id
+			WHERE cg.is_deleted = FALSE
+			  AND cg.is_active = TRUE
+		),
+		holder_users(user_id, conditional, deny) AS (
+			SELECT ur.user_id, h.conditional OR ur.condition <> '', h.deny
+			FROM holder_roles h
+			INNER JOIN user_roles ur ON ur.role_id = h.role_id
+
+			UNION
+
+			SELECT ug.user_id, h.conditional, h.deny
+			FROM holder_groups h
+			INNER JOIN user_groups ug ON ug.group_id = h.group_id
+
+			UNION
+
+			SELECT d.subject_id, FALSE, TRUE
+			FROM deny_rules d
+			INNER JOIN permissions p ON p.id = d.permission_id
+			WHERE d.subject_type = 'user'
+			  AND d.resource_id = ''
+			  AND ` + permissionMatches("$1", "$2") + `
+			  AND p.is_deleted = FALSE
+			  AND p.is_active = TRUE
+		),
+		permission_holders(user_id, conditional) AS (
+			SELECT user_id, bool_and(conditional)
+			FROM holder_users
+			GROUP BY user_id
+			HAVING NOT bool_or(deny)
+		)`
+
+// GetPermissionHolders returns a page of the users who can perform the action on the
+// resource, ordered by email. Deleted users are left out.
+func (r *PermissionRepository) GetPermissionHolders(resource, action string, limit, offset int) ([]*domain.PermissionHolder, error) {
+	query := `
+		WITH RECURSIVE` + permissionHoldersCTE + `
+		SELECT u.id, u.email, u.first_name, u.last_name, u.status, h.conditional
+		FROM permission_holders h
+		INNER JOIN users u ON u.id = h.user_id
+		WHERE u.is_deleted = FALSE
+		ORDER BY u.email
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.Query(context.Background(), query, resource, action, limit, offset)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
+	var holders []*domain.PermissionHolder
 	for rows.Next() {
-		var hasPermission bool
-		err := rows.Scan(&hasPermission)
+		var holder domain.PermissionHolder
+		err := rows.Scan(&holder.UserID, &holder.Email, &holder.FirstName, &holder.LastName, &holder.Status, &holder.Conditional)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
-		if hasPermission {
-			return true, nil
+		holders = append(holders, &holder)
+	}
+
+	return holders, rows.Err()
+}
+
+func (r *PermissionRepository) CountPermissionHolders(resource, action string) (int, error) {
+	query := `
+		WITH RECURSIVE` + permissionHoldersCTE + `
+		SELECT COUNT(*)
+		FROM permission_holders h
+		INNER JOIN users u ON u.id = h.user_id
+		WHERE u.is_deleted = FALSE
+	`
+
+	var count int
+	err := r.db.QueryRow(context.Background(), query, resource, action).Scan(&count)
+	return count, err
+}
+
+// GetPermissionGrantsBatch decides many checks in one query. Each check is resolved as by
+// GetUserDenyRule and GetUserPermissionGrants, with the checks unnested from arrays and
+// the user's groups and roles resolved per check.
+func (r *PermissionRepository) GetPermissionGrantsBatch(checks []domain.CheckPermissionRequest) ([]domain.PermissionCheckGrants, error) {
+	userIDs := make([]uuid.UUID, len(checks))
+	resources := make([]string, len(checks))
+	actions := make([]string, len(checks))
+	resourceIDs := make([]string, len(checks))
+	for i, check := range checks {
+		userIDs[i] = check.UserID
+		resources[i] = check.Resource
+		actions[i] = check.Action
+		resourceIDs[i] = check.ResourceID
+	}
+
+	query := `
+		WITH RECURSIVE checks(user_id, resource, action, resource_id, idx) AS (
+			SELECT * FROM unnest($1::uuid[], $2::text[], $3::text[], $4::text[]) WITH ORDINALITY
+		),
+		check_group_tree(idx, group_id) AS (
+			SELECT c.idx, g.id
+			FROM checks c
+			INNER JOIN user_groups ug ON ug.user_id = c.user_id
+			INNER JOIN groups g ON g.id = ug.group_id
+			WHERE g.is_deleted = FALSE
+			  AND g.is_active = TRUE
+			UNION
+			SELECT t.idx, gp.parent_group_id
+			FROM check_group_tree t
+			INNER JOIN group_parents gp ON gp.group_id = t.group_id
+			INNER JOIN groups pg ON pg.id = gp.parent_group_id
+			WHERE pg.is_deleted = FALSE
+			  AND pg.is_active = TRUE
+		),
+		check_direct_roles(idx, role_id, condition) AS (
+			SELECT c.idx, ur.role_id, ur.condition
+			FROM checks c
+			INNER JOIN user_roles ur ON ur.user_id = c.user_id
+			INNER JOIN roles r ON r.id = ur.role_id
+			WHERE r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT t.idx, gr.role_id, gr.condition
+			FROM check_group_tree t
+			INNER JOIN group_roles gr ON gr.group_id = t.group_id
+			INNER JOIN roles r ON r.id = gr.role_id
+			WHERE r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT c.idx, rg.role_id, rg.condition
+			FROM checks c
+			INNER JOIN resource_grants rg ON rg.resource_type = c.resource AND rg.resource_id = c.resource_id
+			INNER JOIN roles r ON r.id = rg.role_id
+			WHERE c.resource_id <> ''
+			  AND ((rg.subject_type = 'user' AND rg.subject_id = c.user_id)
+			    OR (rg.subject_type = 'group' AND rg.subject_id IN (SELECT group_id FROM check_group_tree t WHERE t.idx = c.idx)))
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+		),
+		check_granted_roles(idx, role_id, condition) AS (
+			SELECT idx, role_id, condition FROM check_direct_roles
+			UNION
+			SELECT g.idx, rp.parent_role_id, g.condition
+			FROM role_parents rp
+			INNER JOIN check_granted_roles g ON rp.role_id = g.role_id
+			INNER JOIN roles pr ON pr.id = rp.parent_role_id
+			WHERE pr.is_deleted = FALSE
+			  AND pr.is_active = TRUE
+		)
+		SELECT
+			(SELECT d.id
+			 FROM deny_rules d
+			 INNER JOIN permissions p ON p.id = d.permission_id
+			 WHERE ` + permissionMatches("c.resource", "c.action") + `
+			   AND p.is_deleted = FALSE
+			   AND p.is_active = TRUE
+			   AND (d.resource_id = '' OR d.resource_id = c.resource_id)
+			   AND ((d.subject_type = 'user' AND d.subject_id = c.user_id)
+			     OR (d.subject_type = 'group' AND d.subject_id IN (SELECT group_id FROM check_group_tree t WHERE t.idx = c.idx))
+			     OR (d.subject_type = 'role' AND d.subject_id IN (SELECT role_id FROM check_granted_roles g WHERE g.idx = c.idx)))
+			 ORDER BY d.created_at ASC
+			 LIMIT 1),
+			(SELECT COALESCE(jsonb_agg(DISTINCT jsonb_build_object('assignment', g.condition, 'permission', rp.condition)), '[]'::jsonb)
+			 FROM check_granted_roles g
+			 INNER JOIN role_permissions rp ON rp.role_id = g.role_id
+			 INNER JOIN permissions p ON p.id = rp.permission_id
+			 WHERE g.idx = c.idx
+			   AND ` + permissionMatches("c.resource", "c.action") + `
+			   AND p.is_deleted = FALSE
+			   AND p.is_active = TRUE)
+		FROM checks c
+		ORDER BY c.idx
+	`
+
+	rows, err := r.db.Query(context.Background(), query, userIDs, resources, actions, resourceIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]domain.PermissionCheckGrants, 0, len(checks))
+	for rows.Next() {
+		var result domain.PermissionCheckGrants
+		if err := rows.Scan(&result.DenyRuleID, &result.Grants); err != nil {
+			return nil, err
 		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(results) != len(checks) {
+		return nil, fmt.Errorf("expected %d check results, got %d", len(checks), len(results))
 	}
 
-	return false, nil
+	return results, nil
 }
@@ -0,0 +1,189 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// TestDenyOverridesGrants checks that a deny rule on users:* wins over a grant of
+// users:delete in every query deciding permissions: the check, the batch check, the deny
+// lookup used to explain a decision, and the permission holders listing
+func TestDenyOverridesGrants(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewPermissionRepository(db)
+	deleteUsers := testPermission(t, db, "users", "delete")
+	allUsers := testPermission(t, db, "users", domain.PermissionWildcard)
+
+	tests := []struct {
+		name string
+		// grant gives the user users:delete and returns the resource ID it applies to
+		grant func(t *testing.T, user *domain.User) string
+	}{
+		{
+			name: "role of a parent group",
+			grant: func(t *testing.T, user *domain.User) string {
+				parent, child := createTestGroup(t, db), createTestGroup(t, db)
+				groups := NewGroupRepository(db)
+				if err := groups.AddSubgroup(parent.ID, child.ID); err != nil {
+					t.Fatal(err)
+				}
+				if err := groups.AddMember(child.ID, user.ID); err != nil {
+					t.Fatal(err)
+				}
+				if err := NewRoleRepository(db).AssignToGroup(parent.ID, createTestRole(t, db, deleteUsers).ID, ""); err != nil {
+					t.Fatal(err)
+				}
+				return ""
+			},
+		},
+		{
+			name: "inherited role",
+			grant: func(t *testing.T, user *domain.User) string {
+				roles := NewRoleRepository(db)
+				child := createTestRole(t, db)
+				if err := roles.AddParent(child.ID, createTestRole(t, db, deleteUsers).ID); err != nil {
+					t.Fatal(err)
+				}
+				if err := roles.AssignToUser(user.ID, child.ID, ""); err != nil {
+					t.Fatal(err)
+				}
+				return ""
+			},
+		},
+		{
+			name: "resource grant",
+			grant: func(t *testing.T, user *domain.User) string {
+				grant := &domain.ResourceGrant{
+					ID:           uuid.New(),
+					RoleID:       createTestRole(t, db, deleteUsers).ID,
+					SubjectType:  "user",
+					SubjectID:    user.ID,
+					ResourceType: "users",
+					ResourceID:   "user-1",
+					CreatedAt:    time.Now(),
+				}
+				if err := NewResourceGrantRepository(db).Create(grant); err != nil {
+					t.Fatal(err)
+				}
+				return grant.ResourceID
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := createTestUser(t, db)
+			resourceID := tt.grant(t, user)
+
+			allowed, err := repo.CheckUserResourcePermission(user.ID, "users", resourceID, "delete")
+			if err != nil || !allowed {
+				t.Fatalf("before the deny rule: CheckUserResourcePermission() = %v, %v, want granted", allowed, err)
+			}
+
+			rule := &domain.DenyRule{
+				ID:           uuid.New(),
+				SubjectType:  "user",
+				SubjectID:    user.ID,
+				PermissionID: allUsers.ID,
+				CreatedAt:    time.Now(),
+			}
+			if err := NewDenyRuleRepository(db).Create(rule); err != nil {
+				t.Fatal(err)
+			}
+
+			assertDenied(t, repo, user, resourceID, rule)
+		})
+	}
+}
+
+func assertDenied(t *testing.T, repo domain.PermissionRepository, user *domain.User, resourceID string, rule *domain.DenyRule) {
+	t.Helper()
+
+	if allowed, err := repo.CheckUserResourcePermission(user.ID, "users", resourceID, "delete"); err != nil || allowed {
+		t.Errorf("CheckUserResourcePermission() = %v, %v, want denied", allowed, err)
+	}
+
+	results, err := repo.GetPermissionGrantsBatch([]domain.CheckPermissionRequest{
+		{UserID: user.ID, Resource: "users", Action: "delete", ResourceID: resourceID},
+	})
+	if err != nil {
+		t.Fatalf("GetPermissionGrantsBatch() error = %v", err)
+	}
+	if results[0].DenyRuleID == nil || *results[0].DenyRuleID != rule.ID {
+		t.Errorf("GetPermissionGrantsBatch() deny rule = %v, want %s", results[0].DenyRuleID, rule.ID)
+	}
+
+	denyRule, err := repo.GetUserDenyRule(user.ID, "users", resourceID, "delete")
+	if err != nil || denyRule == nil || denyRule.ID != rule.ID {
+		t.Errorf("GetUserDenyRule() = %v, %v, want %s", denyRule, err, rule.ID)
+	}
+
+	// Holders and effective permissions are resolved from global grants only
+	if resourceID == "" {
+		permissions, err := repo.GetUserEffectivePermissions(user.ID, 100, 0)
+		if err != nil {
+			t.Fatalf("GetUserEffectivePermissions() error = %v", err)
+		}
+		for _, permission := range permissions {
+			if permission.Resource == "users" && permission.Action == "delete" &&
+				(permission.DeniedBy == nil || permission.DeniedBy.ID != rule.ID) {
+				t.Errorf("GetUserEffectivePermissions() users:delete denied by %v, want %s", permission.DeniedBy, rule.ID)
+			}
+		}
+
+		holders, err := repo.GetPermissionHolders("users", "delete", 1000, 0)
+		if err != nil {
+			t.Fatalf("GetPermissionHolders() error = %v", err)
+		}
+		for _, holder := range holders {
+			if holder.UserID == user.ID {
+				t.Errorf("GetPermissionHolders() lists the denied user")
+			}
+		}
+	}
+}
+
+// TestPermissionMatchesSQL runs the matcher table shared with domain.PermissionMatches
+// and the Go client against the SQL form of the matcher
+func TestPermissionMatchesSQL(t *testing.T) {
+	db := newTestDB(t)
+
+	data, err := os.ReadFile("../../domain/testdata/permission_matches.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []struct {
+		GrantedResource string `json:"granted_resource"`
+		GrantedAction   string `json:"granted_action"`
+		Resource        string `json:"resource"`
+		Action          string `json:"action"`
+		Want            bool   `json:"want"`
+	}
+	if err := json.Unmarshal(data, &tests); err != nil {
+		t.Fatal(err)
+	}
+
+	query := `
+		SELECT ` + permissionMatches("$3::text", "$4::text") + `
+		FROM (SELECT $1::text AS resource, $2::text AS action) p
+	`
+	for _, tt := range tests {
+		var got bool
+		if err := db.QueryRow(context.Background(), query, tt.GrantedResource, tt.GrantedAction, tt.Resource, tt.Action).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.Want {
+			t.Errorf("SQL permissionMatches(%q, %q, %q, %q) = %v, want %v",
+				tt.GrantedResource, tt.GrantedAction, tt.Resource, tt.Action, got, tt.Want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepostgres "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/lib/pq"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// newTestDB connects to the scratch database in TEST_DATABASE_URL after applying the
+// migrations to it, and skips the test when the variable is unset. Tests create their own
+// users, groups and roles, so they can share the database.
+func newTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	driver, err := migratepostgres.WithInstance(sqlDB, &migratepostgres.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.NewWithDatabaseInstance("file://../../../migrations/postgres", "postgres", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		t.Fatalf("failed to apply migrations: %v", err)
+	}
+
+	db, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+
+	return db
+}
+
+func createTestUser(t *testing.T, db *pgxpool.Pool) *domain.User {
+	t.Helper()
+
+	now := time.Now()
+	user := &domain.User{
+		ID:        uuid.New(),
+		Email:     uuid.NewString() + "@example.com",
+		Status:    domain.UserStatusActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := NewUserRepository(db).Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func createTestGroup(t *testing.T, db *pgxpool.Pool) *domain.Group {
+	t.Helper()
+
+	now := time.Now()
+	group := &domain.Group{ID: uuid.New(), Name: "group-" + uuid.NewString(), IsActive: true, CreatedAt: now, UpdatedAt: now}
+	if err := NewGroupRepository(db).Create(group); err != nil {
+		t.Fatal(err)
+	}
+	return group
+}
+
+// createTestRole creates a role holding the permissions
+func createTestRole(t *testing.T, db *pgxpool.Pool, permissions ...*domain.Permission) *domain.Role {
+	t.Helper()
+
+	now := time.Now()
+	role := &domain.Role{ID: uuid.New(), Name: "role-" + uuid.NewString(), IsActive: true, CreatedAt: now, UpdatedAt: now}
+	if err := NewRoleRepository(db).Create(role); err != nil {
+		t.Fatal(err)
+	}
+	for _, permission := range permissions {
+		if err := NewPermissionRepository(db).AssignToRole(role.ID, permission.ID, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return role
+}
+
+// testPermission returns the permission, creating it if the migrations did not seed it
+func testPermission(t *testing.T, db *pgxpool.Pool, resource, action string) *domain.Permission {
+	t.Helper()
+
+	repo := NewPermissionRepository(db)
+	if permission, err := repo.GetByResourceAndAction(resource, action); err == nil {
+		return permission
+	}
+
+	now := time.Now()
+	permission := &domain.Permission{ID: uuid.New(), Resource: resource, Action: action, IsActive: true, CreatedAt: now, UpdatedAt: now}
+	if err := repo.Create(permission); err != nil {
+		t.Fatal(err)
+	}
+	return permission
+}
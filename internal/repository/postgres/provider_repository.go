@@ -0,0 +1,143 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type ProviderRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewProviderRepository(db *pgxpool.Pool) domain.ProviderRepository {
+	return &ProviderRepository{db: db}
+}
+
+func (r *ProviderRepository) GetByName(name string) (*domain.Provider, error) {
+	query := `
+		SELECT id, name, type, config, enabled, is_system, passwordless_enabled,
+		       domains, login_priority, fallback_on, created_at, updated_at
+		FROM providers WHERE name = $1
+	`
+
+	var provider domain.Provider
+	err := r.db.QueryRow(context.Background(), query, name).Scan(
+		&provider.ID, &provider.Name, &provider.Type, &provider.Config, &provider.Enabled,
+		&provider.IsSystem, &provider.PasswordlessEnabled,
+		&provider.Domains, &provider.LoginPriority, &provider.FallbackOn, &provider.CreatedAt, &provider.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("provider not found")
+		}
+		return nil, err
+	}
+
+	return &provider, nil
+}
+
+func (r *ProviderRepository) List() ([]*domain.Provider, error) {
+	query := `
+		SELECT id, name, type, config, enabled, is_system, passwordless_enabled,
+		       domains, login_priority, fallback_on, created_at, updated_at
+		FROM providers
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var providers []*domain.Provider
+	for rows.Next() {
+		var provider domain.Provider
+		err := rows.Scan(
+			&provider.ID, &provider.Name, &provider.Type, &provider.Config, &provider.Enabled,
+			&provider.IsSystem, &provider.PasswordlessEnabled,
+			&provider.Domains, &provider.LoginPriority, &provider.FallbackOn, &provider.CreatedAt, &provider.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, &provider)
+	}
+
+	return providers, nil
+}
+
+// Create inserts the provider and sets its CreatedAt and UpdatedAt from the database
+func (r *ProviderRepository) Create(provider *domain.Provider) error {
+	query := `
+		INSERT INTO providers (id, name, type, config, enabled, is_system, passwordless_enabled,
+		                       domains, login_priority, fallback_on)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING created_at, updated_at
+	`
+
+	return r.db.QueryRow(context.Background(), query,
+		provider.ID, provider.Name, provider.Type, provider.Config, provider.Enabled,
+		provider.IsSystem, provider.PasswordlessEnabled,
+		provider.Domains, provider.LoginPriority, provider.FallbackOn,
+	).Scan(&provider.CreatedAt, &provider.UpdatedAt)
+}
+
+// Update stores the provider's config, enabled flag and login routing and refreshes its UpdatedAt
+func (r *ProviderRepository) Update(provider *domain.Provider) error {
+	query := `
+		UPDATE providers
+		SET config = $2, enabled = $3, domains = $4, login_priority = $5, fallback_on = $6, updated_at = NOW()
+		WHERE name = $1
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(context.Background(), query,
+		provider.Name, provider.Config, provider.Enabled,
+		provider.Domains, provider.LoginPriority, provider.FallbackOn,
+	).Scan(&provider.UpdatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("provider not found")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *ProviderRepository) Delete(name string) error {
+	query := `DELETE FROM providers WHERE name = $1 AND is_system = FALSE`
+
+	result, err := r.db.Exec(context.Background(), query, name)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("provider not found or is a system provider")
+	}
+
+	return nil
+}
+
+func (r *ProviderRepository) SetPasswordlessEnabled(name string, enabled bool) error {
+	query := `UPDATE providers SET passwordless_enabled = $2, updated_at = NOW() WHERE name = $1`
+
+	result, err := r.db.Exec(context.Background(), query, name, enabled)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("provider not found")
+	}
+
+	return nil
+}
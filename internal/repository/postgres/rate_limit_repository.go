@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type RateLimitRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRateLimitRepository(db *pgxpool.Pool) domain.RateLimiter {
+	return &RateLimitRepository{db: db}
+}
+
+func (r *RateLimitRepository) Allow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+	// Start a new window when the current one has expired, otherwise count the request in it
+	query := `
+		INSERT INTO rate_limits (key, count, window_start, expires_at)
+		VALUES ($1, 1, NOW(), NOW() + make_interval(secs => $2))
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_limits.expires_at <= NOW() THEN 1 ELSE rate_limits.count + 1 END,
+			window_start = CASE WHEN rate_limits.expires_at <= NOW() THEN NOW() ELSE rate_limits.window_start END,
+			expires_at = CASE WHEN rate_limits.expires_at <= NOW() THEN NOW() + make_interval(secs => $2) ELSE rate_limits.expires_at END
+		RETURNING count, expires_at
+	`
+
+	var count int
+	var expiresAt time.Time
+	if err := r.db.QueryRow(context.Background(), query, key, window.Seconds()).Scan(&count, &expiresAt); err != nil {
+		return false, 0, err
+	}
+
+	if count > limit {
+		return false, time.Until(expiresAt), nil
+	}
+
+	return true, 0, nil
+}
+
+func (r *RateLimitRepository) PurgeExpired() error {
+	query := `DELETE FROM rate_limits WHERE expires_at < NOW()`
+
+	_, err := r.db.Exec(context.Background(), query)
+	return err
+}
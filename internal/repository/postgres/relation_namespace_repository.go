@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type RelationNamespaceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRelationNamespaceRepository(db *pgxpool.Pool) domain.RelationNamespaceRepository {
+	return &RelationNamespaceRepository{db: db}
+}
+
+// Upsert creates or replaces the namespace schema and sets its CreatedAt and UpdatedAt
+// from the database
+func (r *RelationNamespaceRepository) Upsert(config *domain.NamespaceConfig) error {
+	query := `
+		INSERT INTO relation_namespaces (name, relations)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET relations = EXCLUDED.relations
+		RETURNING created_at, updated_at
+	`
+
+	return r.db.QueryRow(context.Background(), query, config.Name, config.Relations).
+		Scan(&config.CreatedAt, &config.UpdatedAt)
+}
+
+func (r *RelationNamespaceRepository) GetByName(name string) (*domain.NamespaceConfig, error) {
+	query := `
+		SELECT name, relations, created_at, updated_at
+		FROM relation_namespaces WHERE name = $1
+	`
+
+	var config domain.NamespaceConfig
+	err := r.db.QueryRow(context.Background(), query, name).Scan(
+		&config.Name, &config.Relations, &config.CreatedAt, &config.UpdatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("namespace not found")
+		}
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (r *RelationNamespaceRepository) List() ([]*domain.NamespaceConfig, error) {
+	query := `
+		SELECT name, relations, created_at, updated_at
+		FROM relation_namespaces
+		ORDER BY name ASC
+	`
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var configs []*domain.NamespaceConfig
+	for rows.Next() {
+		var config domain.NamespaceConfig
+		if err := rows.Scan(&config.Name, &config.Relations, &config.CreatedAt, &config.UpdatedAt); err != nil {
+			return nil, err
+		}
+		configs = append(configs, &config)
+	}
+
+	return configs, rows.Err()
+}
+
+func (r *RelationNamespaceRepository) Delete(name string) error {
+	query := `DELETE FROM relation_namespaces WHERE name = $1`
+
+	result, err := r.db.Exec(context.Background(), query, name)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("namespace not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// relationTupleVisible restricts relation tuples aliased t to those visible at revision $1
+const relationTupleVisible = `t.created_revision <= $1 AND (t.deleted_revision IS NULL OR t.deleted_revision > $1)`
+
+type RelationTupleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRelationTupleRepository(db *pgxpool.Pool) domain.RelationTupleRepository {
+	return &RelationTupleRepository{db: db}
+}
+
+// Write applies deletes and then writes as a new revision. Writers are serialized with a
+// transaction-level advisory lock so that revisions become visible in order and a
+// snapshot never changes once its token has been handed out.
+func (r *RelationTupleRepository) Write(writes, deletes []*domain.RelationTuple) (int64, error) {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('relation_tuples'))`); err != nil {
+		return 0, err
+	}
+
+	var revision int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO relation_tuple_revisions (revision)
+		SELECT COALESCE(MAX(revision), 0) + 1 FROM relation_tuple_revisions
+		RETURNING revision
+	`).Scan(&revision)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, tuple := range deletes {
+		_, err := tx.Exec(ctx, `
+			UPDATE relation_tuples SET deleted_revision = $1
+			WHERE namespace = $2 AND object_id = $3 AND relation = $4
+			  AND subject_namespace = $5 AND subject_id = $6 AND subject_relation = $7
+			  AND deleted_revision IS NULL
+		`, revision, tuple.Namespace, tuple.ObjectID, tuple.Relation,
+			tuple.Subject.Namespace, tuple.Subject.ObjectID, tuple.Subject.Relation)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, tuple := range writes {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO relation_tuples (namespace, object_id, relation, subject_namespace, subject_id, subject_relation, created_revision)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (namespace, object_id, relation, subject_namespace, subject_id, subject_relation)
+			WHERE deleted_revision IS NULL DO NOTHING
+		`, tuple.Namespace, tuple.ObjectID, tuple.Relation,
+			tuple.Subject.Namespace, tuple.Subject.ObjectID, tuple.Subject.Relation, revision)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return revision, nil
+}
+
+func (r *RelationTupleRepository) CurrentRevision() (int64, error) {
+	query := `SELECT COALESCE(MAX(revision), 0) FROM relation_tuple_revisions`
+
+	var revision int64
+	err := r.db.QueryRow(context.Background(), query).Scan(&revision)
+	return revision, err
+}
+
+// OldestRevision returns the oldest revision left by PurgeHistory. Until the first purge
+// every revision is kept, including the empty revision 0.
+func (r *RelationTupleRepository) OldestRevision() (int64, error) {
+	query := `
+		SELECT CASE WHEN MIN(revision) > 1 THEN MIN(revision) ELSE 0 END
+		FROM relation_tuple_revisions
+	`
+
+	var revision int64
+	err := r.db.QueryRow(context.Background(), query).Scan(&revision)
+	return revision, err
+}
+
+// PurgeHistory picks the newest revision written more than retention ago as the new
+// oldest revision, then drops the tuples deleted at or before it and the revisions before
+// it. Snapshots from that revision on are unaffected, and the latest revision is never
+// dropped so revision numbers keep increasing. It takes the writers' lock so that no
+// write commits a revision in between.
+func (r *RelationTupleRepository) PurgeHistory(retention time.Duration) error {
+	ctx := context.Background()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('relation_tuples'))`); err != nil {
+		return err
+	}
+
+	var oldest *int64
+	err = tx.QueryRow(ctx, `
+		SELECT MAX(revision) FROM relation_tuple_revisions
+		WHERE created_at < NOW() - make_interval(secs => $1)
+	`, retention.Seconds()).Scan(&oldest)
+	if err != nil {
+		return err
+	}
+	if oldest == nil {
+		return nil
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM relation_tuples WHERE deleted_revision <= $1`, *oldest); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM relation_tuple_revisions WHERE revision < $1`, *oldest); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *RelationTupleRepository) Read(filter domain.RelationTupleFilter, revision int64) ([]*domain.RelationTuple, error) {
+	query := `
+		SELECT t.namespace, t.object_id, t.relation, t.subject_namespace, t.subject_id, t.subject_relation
+		FROM relation_tuples t
+		WHERE ` + relationTupleVisible + `
+		  AND t.namespace = $2
+		  AND ($3 = '' OR t.object_id = $3)
+		  AND ($4 = '' OR t.relation = $4)
+		  AND ($5 = '' OR (t.subject_namespace = $5 AND t.subject_id = $6 AND t.subject_relation = $7))
+		ORDER BY t.object_id, t.relation, t.subject_namespace, t.subject_id, t.subject_relation
+	`
+
+	var subject domain.RelationSubject
+	if filter.Subject != nil {
+		subject = *filter.Subject
+	}
+
+	rows, err := r.db.Query(context.Background(), query, revision,
+		filter.Namespace, filter.ObjectID, filter.Relation,
+		subject.Namespace, subject.ObjectID, subject.Relation)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tuples []*domain.RelationTuple
+	for rows.Next() {
+		var tuple domain.RelationTuple
+		err := rows.Scan(
+			&tuple.Namespace, &tuple.ObjectID, &tuple.Relation,
+			&tuple.Subject.Namespace, &tuple.Subject.ObjectID, &tuple.Subject.Relation,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tuples = append(tuples, &tuple)
+	}
+
+	return tuples, rows.Err()
+}
+
+// HasTuples reports whether live tuples use the namespace, or one of its relations when
+// relation is set, either as object or as subject userset
+func (r *RelationTupleRepository) HasTuples(namespace, relation string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM relation_tuples
+			WHERE deleted_revision IS NULL
+			  AND ((namespace = $1 AND ($2 = '' OR relation = $2))
+			    OR (subject_namespace = $1 AND $2 <> '' AND subject_relation = $2))
+		)
+	`
+
+	var exists bool
+	err := r.db.QueryRow(context.Background(), query, namespace, relation).Scan(&exists)
+	return exists, err
+}
+
+// ReachableObjects walks tuples from the subject to their objects, and on from those
+// objects as subjects, collecting the objects in the namespace. Every object the subject
+// has a relation to is reachable this way, as rewrites only follow tuples.
+func (r *RelationTupleRepository) ReachableObjects(subject domain.RelationSubject, namespace string, revision int64) ([]string, error) {
+	query := `
+		WITH RECURSIVE reachable(namespace, object_id) AS (
+			SELECT t.namespace::text, t.object_id::text
+			FROM relation_tuples t
+			WHERE ` + relationTupleVisible + `
+			  AND t.subject_namespace = $2 AND t.subject_id = $3 AND t.subject_relation = $4
+
+			UNION
+
+			-- A userset subject such as group:eng#member is related to its own object
+			SELECT $2::text, $3::text WHERE $4 <> ''
+
+			UNION
+
+			SELECT t.namespace::text, t.object_id::text
+			FROM reachable rc
+			INNER JOIN relation_tuples t ON t.subject_namespace = rc.namespace AND t.subject_id = rc.object_id
+			WHERE ` + relationTupleVisible + `
+		)
+		SELECT object_id FROM reachable WHERE namespace = $5 ORDER BY object_id
+	`
+
+	rows, err := r.db.Query(context.Background(), query, revision,
+		subject.Namespace, subject.ObjectID, subject.Relation, namespace)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var objectIDs []string
+	for rows.Next() {
+		var objectID string
+		if err := rows.Scan(&objectID); err != nil {
+			return nil, err
+		}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	return objectIDs, rows.Err()
+}
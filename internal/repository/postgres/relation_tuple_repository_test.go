@@ -0,0 +1,79 @@
+//go:build integration
+
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// TestPurgeHistory checks that purging drops tuples deleted before the retention window
+// while snapshots taken inside it still see the tuples they saw when they were taken
+func TestPurgeHistory(t *testing.T) {
+	db := newTestDB(t)
+	repo := NewRelationTupleRepository(db)
+	ctx := context.Background()
+
+	newTuple := func() *domain.RelationTuple {
+		return &domain.RelationTuple{
+			Namespace: "doc",
+			ObjectID:  uuid.NewString(),
+			Relation:  "viewer",
+			Subject:   domain.RelationSubject{Namespace: "user", ObjectID: uuid.NewString()},
+		}
+	}
+	write := func(writes, deletes []*domain.RelationTuple) int64 {
+		revision, err := repo.Write(writes, deletes)
+		if err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		return revision
+	}
+	visible := func(tuple *domain.RelationTuple, revision int64) bool {
+		tuples, err := repo.Read(domain.RelationTupleFilter{Namespace: tuple.Namespace, ObjectID: tuple.ObjectID}, revision)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		return len(tuples) == 1
+	}
+
+	expired, kept := newTuple(), newTuple()
+	write([]*domain.RelationTuple{expired, kept}, nil)
+	write(nil, []*domain.RelationTuple{expired})
+	horizon := write(nil, nil)
+
+	// Everything so far falls out of the retention window
+	if _, err := db.Exec(ctx, `UPDATE relation_tuple_revisions SET created_at = NOW() - INTERVAL '2 hours' WHERE revision <= $1`, horizon); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := write(nil, []*domain.RelationTuple{kept})
+
+	if err := repo.PurgeHistory(time.Hour); err != nil {
+		t.Fatalf("PurgeHistory() error = %v", err)
+	}
+
+	oldest, err := repo.OldestRevision()
+	if err != nil || oldest != horizon {
+		t.Errorf("OldestRevision() = %d, %v, want %d", oldest, err, horizon)
+	}
+	current, err := repo.CurrentRevision()
+	if err != nil || current != snapshot {
+		t.Errorf("CurrentRevision() = %d, %v, want %d", current, err, snapshot)
+	}
+
+	var remaining int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM relation_tuples WHERE object_id = $1`, expired.ObjectID).Scan(&remaining); err != nil {
+		t.Fatal(err)
+	}
+	if remaining != 0 {
+		t.Errorf("tuple deleted before the window was kept")
+	}
+	if !visible(kept, horizon) || visible(kept, snapshot) {
+		t.Errorf("tuple deleted inside the window is not visible exactly until its deletion")
+	}
+}
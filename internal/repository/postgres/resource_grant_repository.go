@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// resourceGrantFilterCondition applies a domain.ResourceGrantFilter passed as $1-$4
+const resourceGrantFilterCondition = `
+	($1 = '' OR g.subject_type = $1)
+	AND ($2::uuid IS NULL OR g.subject_id = $2)
+	AND ($3 = '' OR g.resource_type = $3)
+	AND ($4 = '' OR g.resource_id = $4)`
+
+type ResourceGrantRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewResourceGrantRepository(db *pgxpool.Pool) domain.ResourceGrantRepository {
+	return &ResourceGrantRepository{db: db}
+}
+
+func (r *ResourceGrantRepository) Create(grant *domain.ResourceGrant) error {
+	query := `
+		INSERT INTO resource_grants (id, role_id, subject_type, subject_id, resource_type, resource_id, condition, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := r.db.Exec(context.Background(), query,
+		grant.ID, grant.RoleID, grant.SubjectType, grant.SubjectID,
+		grant.ResourceType, grant.ResourceID, grant.Condition, grant.CreatedAt)
+	return err
+}
+
+func (r *ResourceGrantRepository) GetByID(id uuid.UUID) (*domain.ResourceGrant, error) {
+	query := `
+		SELECT g.id, g.role_id, ro.name, g.subject_type, g.subject_id, g.resource_type, g.resource_id, g.condition, g.created_at
+		FROM resource_grants g
+		INNER JOIN roles ro ON ro.id = g.role_id
+		WHERE g.id = $1
+	`
+
+	var grant domain.ResourceGrant
+	err := r.db.QueryRow(context.Background(), query, id).Scan(
+		&grant.ID, &grant.RoleID, &grant.RoleName, &grant.SubjectType, &grant.SubjectID, &grant.ResourceType, &grant.ResourceID, &grant.Condition, &grant.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("resource grant not found")
+		}
+		return nil, err
+	}
+
+	return &grant, nil
+}
+
+func (r *ResourceGrantRepository) Delete(id uuid.UUID) error {
+	query := `DELETE FROM resource_grants WHERE id = $1`
+
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("resource grant not found")
+	}
+
+	return nil
+}
+
+func (r *ResourceGrantRepository) List(filter domain.ResourceGrantFilter, limit, offset int) ([]*domain.ResourceGrant, error) {
+	query := `
+		SELECT g.id, g.role_id, ro.name, g.subject_type, g.subject_id, g.resource_type, g.resource_id, g.condition, g.created_at
+		FROM resource_grants g
+		INNER JOIN roles ro ON ro.id = g.role_id
+		WHERE ` + resourceGrantFilterCondition + `
+		ORDER BY g.created_at DESC
+		LIMIT $5 OFFSET $6
+	`
+
+	rows, err := r.db.Query(context.Background(), query,
+		filter.SubjectType, filter.SubjectID, filter.ResourceType, filter.ResourceID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []*domain.ResourceGrant
+	for rows.Next() {
+		var grant domain.ResourceGrant
+		err := rows.Scan(
+			&grant.ID, &grant.RoleID, &grant.RoleName, &grant.SubjectType, &grant.SubjectID, &grant.ResourceType, &grant.ResourceID, &grant.Condition, &grant.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, &grant)
+	}
+
+	return grants, rows.Err()
+}
+
+func (r *ResourceGrantRepository) Count(filter domain.ResourceGrantFilter) (int, error) {
+	query := `SELECT COUNT(*) FROM resource_grants g WHERE ` + resourceGrantFilterCondition
+
+	var count int
+	err := r.db.QueryRow(context.Background(), query,
+		filter.SubjectType, filter.SubjectID, filter.ResourceType, filter.ResourceID).Scan(&count)
+	return count, err
+}
@@ -150,14 +150,15 @@ func (r *RoleRepository) Count() (int, error) {
 	return count, err
 }
 
-func (r *RoleRepository) AssignToUser(userID, roleID uuid.UUID) error {
+// AssignToUser assigns the role to the user, replacing the condition of an existing assignment
+func (r *RoleRepository) AssignToUser(userID, roleID uuid.UUID, condition string) error {
 	query := `
-		INSERT INTO user_roles (user_id, role_id)
-		VALUES ($1, $2)
-		ON CONFLICT (user_id, role_id) DO NOTHING
+		INSERT INTO user_roles (user_id, role_id, condition)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, role_id) DO UPDATE SET condition = EXCLUDED.condition
 	`
 
-	_, err := r.db.Exec(context.Background(), query, userID, roleID)
+	_, err := r.db.Exec(context.Background(), query, userID, roleID, condition)
 	return err
 }
 
@@ -176,14 +177,15 @@ func (r *RoleRepository) RemoveFromUser(userID, roleID uuid.UUID) error {
 	return nil
 }
 
-func (r *RoleRepository) AssignToGroup(groupID, roleID uuid.UUID) error {
+// AssignToGroup assigns the role to the group, replacing the condition of an existing assignment
+func (r *RoleRepository) AssignToGroup(groupID, roleID uuid.UUID, condition string) error {
 	query := `
-		INSERT INTO group_roles (group_id, role_id)
-		VALUES ($1, $2)
-		ON CONFLICT (group_id, role_id) DO NOTHING
+		INSERT INTO group_roles (group_id, role_id, condition)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (group_id, role_id) DO UPDATE SET condition = EXCLUDED.condition
 	`
 
-	_, err := r.db.Exec(context.Background(), query, groupID, roleID)
+	_, err := r.db.Exec(context.Background(), query, groupID, roleID, condition)
 	return err
 }
 
@@ -202,15 +204,39 @@ func (r *RoleRepository) RemoveFromGroup(groupID, roleID uuid.UUID) error {
 	return nil
 }
 
+// inheritedRolesCTE extends direct_roles(role_id) with every active role inherited
+// through role_parents as granted_roles(role_id). UNION stops the recursion at roles that
+// were already visited.
+const inheritedRolesCTE = `
+		granted_roles(role_id) AS (
+			SELECT role_id FROM direct_roles
+			UNION
+			SELECT rp.parent_role_id
+			FROM role_parents rp
+			INNER JOIN granted_roles g ON rp.role_id = g.role_id
+			INNER JOIN roles pr ON pr.id = rp.parent_role_id
+			WHERE pr.is_deleted = FALSE
+			  AND pr.is_active = TRUE
+		)`
+
+// GetUserRoles returns the roles assigned to the user followed by the roles they inherit,
+// which are marked Inherited
 func (r *RoleRepository) GetUserRoles(userID uuid.UUID) ([]*domain.Role, error) {
 	query := `
-		SELECT r.id, r.name, r.description, r.is_active, r.is_deleted, r.is_system, r.created_at, r.updated_at
+		WITH RECURSIVE direct_roles AS (
+			SELECT ur.role_id
+			FROM user_roles ur
+			INNER JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = $1
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+		),` + inheritedRolesCTE + `
+		SELECT r.id, r.name, r.description, r.is_active, r.is_deleted, r.is_system, r.created_at, r.updated_at,
+		       ur.role_id IS NULL AS inherited, COALESCE(ur.condition, '')
 		FROM roles r
-		INNER JOIN user_roles ur ON r.id = ur.role_id
-		WHERE ur.user_id = $1
-		  AND r.is_deleted = FALSE
-		  AND r.is_active = TRUE
-		ORDER BY r.created_at ASC
+		INNER JOIN granted_roles g ON r.id = g.role_id
+		LEFT JOIN user_roles ur ON ur.role_id = r.id AND ur.user_id = $1
+		ORDER BY inherited ASC, r.created_at ASC
 	`
 
 	rows, err := r.db.Query(context.Background(), query, userID)
@@ -224,6 +250,7 @@ func (r *RoleRepository) GetUserRoles(userID uuid.UUID) ([]*domain.Role, error)
 		var role domain.Role
 		err := rows.Scan(
 			&role.ID, &role.Name, &role.Description, &role.IsActive, &role.IsDeleted, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt,
+			&role.Inherited, &role.Condition,
 		)
 		if err != nil {
 			return nil, err
@@ -234,10 +261,59 @@ func (r *RoleRepository) GetUserRoles(userID uuid.UUID) ([]*domain.Role, error)
 	return roles, nil
 }
 
-func (r *RoleRepository) GetGroupRoles(groupID uuid.UUID) ([]*domain.Role, error) {
+// GetUserEffectiveRoles returns the distinct roles the user holds directly, through their
+// groups and the groups containing them, and by inheritance from any of those, whatever
+// the conditions of the assignments
+func (r *RoleRepository) GetUserEffectiveRoles(userID uuid.UUID) ([]*domain.Role, error) {
 	query := `
+		WITH RECURSIVE` + userGroupsCTE + `,
+		direct_roles(role_id) AS (
+			SELECT ur.role_id
+			FROM user_roles ur
+			INNER JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = $1
+			  AND r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+
+			UNION
+
+			SELECT gr.role_id
+			FROM user_group_tree t
+			INNER JOIN group_roles gr ON gr.group_id = t.group_id
+			INNER JOIN roles r ON r.id = gr.role_id
+			WHERE r.is_deleted = FALSE
+			  AND r.is_active = TRUE
+		),` + inheritedRolesCTE + `
 		SELECT r.id, r.name, r.description, r.is_active, r.is_deleted, r.is_system, r.created_at, r.updated_at
 		FROM roles r
+		INNER JOIN granted_roles g ON r.id = g.role_id
+		ORDER BY r.created_at ASC
+	`
+
+	rows, err := r.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*domain.Role
+	for rows.Next() {
+		var role domain.Role
+		err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.IsActive, &role.IsDeleted, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, rows.Err()
+}
+
+func (r *RoleRepository) GetGroupRoles(groupID uuid.UUID) ([]*domain.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.is_active, r.is_deleted, r.is_system, r.created_at, r.updated_at,
+		       gr.condition
+		FROM roles r
 		INNER JOIN group_roles gr ON r.id = gr.role_id
 		WHERE gr.group_id = $1
 		  AND r.is_deleted = FALSE
@@ -256,6 +332,7 @@ func (r *RoleRepository) GetGroupRoles(groupID uuid.UUID) ([]*domain.Role, error
 		var role domain.Role
 		err := rows.Scan(
 			&role.ID, &role.Name, &role.Description, &role.IsActive, &role.IsDeleted, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt,
+			&role.Condition,
 		)
 		if err != nil {
 			return nil, err
@@ -265,3 +342,193 @@ func (r *RoleRepository) GetGroupRoles(groupID uuid.UUID) ([]*domain.Role, error
 
 	return roles, nil
 }
+
+// AddParent makes roleID inherit the permissions of parentRoleID. Edges are added under
+// a transaction-level advisory lock so concurrent additions cannot form a cycle together.
+func (r *RoleRepository) AddParent(roleID, parentRoleID uuid.UUID) error {
+	if roleID == parentRoleID {
+		return domain.ErrRoleCycle
+	}
+
+	ctx := context.Background()
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('role_parents'))`); err != nil {
+		return err
+	}
+
+	var found int
+	err = tx.QueryRow(ctx, `SELECT COUNT(*) FROM roles WHERE id IN ($1, $2) AND is_deleted = FALSE`, roleID, parentRoleID).Scan(&found)
+	if err != nil {
+		return err
+	}
+	if found != 2 {
+		return fmt.Errorf("role not found")
+	}
+
+	// The edge closes a cycle when the role is already an ancestor of the new parent
+	var cycle bool
+	err = tx.QueryRow(ctx, `
+		WITH RECURSIVE ancestors(role_id) AS (
+			SELECT $1::uuid
+			UNION
+			SELECT rp.parent_role_id
+			FROM role_parents rp
+			INNER JOIN ancestors a ON rp.role_id = a.role_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE role_id = $2)
+	`, parentRoleID, roleID).Scan(&cycle)
+	if err != nil {
+		return err
+	}
+	if cycle {
+		return domain.ErrRoleCycle
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO role_parents (role_id, parent_role_id)
+		VALUES ($1, $2)
+		ON CONFLICT (role_id, parent_role_id) DO NOTHING
+	`, roleID, parentRoleID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *RoleRepository) RemoveParent(roleID, parentRoleID uuid.UUID) error {
+	query := `DELETE FROM role_parents WHERE role_id = $1 AND parent_role_id = $2`
+
+	result, err := r.db.Exec(context.Background(), query, roleID, parentRoleID)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("role does not inherit from parent role")
+	}
+
+	return nil
+}
+
+// GetParents returns the roles roleID inherits from directly
+func (r *RoleRepository) GetParents(roleID uuid.UUID) ([]*domain.Role, error) {
+	query := `
+		SELECT r.id, r.name, r.description, r.is_active, r.is_deleted, r.is_system, r.created_at, r.updated_at
+		FROM roles r
+		INNER JOIN role_parents rp ON r.id = rp.parent_role_id
+		WHERE rp.role_id = $1
+		  AND r.is_deleted = FALSE
+		ORDER BY r.name ASC
+	`
+
+	return r.queryRoles(query, roleID)
+}
+
+// GetHierarchy returns the inheritance graph of a role. Inactive roles are included so
+// administrators see the whole graph; they grant nothing while inactive.
+func (r *RoleRepository) GetHierarchy(roleID uuid.UUID) (*domain.RoleHierarchy, error) {
+	role, err := r.GetByID(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors, err := r.queryRoles(`
+		WITH RECURSIVE related(role_id) AS (
+			SELECT parent_role_id FROM role_parents WHERE role_id = $1
+			UNION
+			SELECT rp.parent_role_id
+			FROM role_parents rp
+			INNER JOIN related rel ON rp.role_id = rel.role_id
+		)
+		SELECT r.id, r.name, r.description, r.is_active, r.is_deleted, r.is_system, r.created_at, r.updated_at
+		FROM roles r
+		INNER JOIN related rel ON r.id = rel.role_id
+		WHERE r.is_deleted = FALSE
+		ORDER BY r.name ASC
+	`, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	descendants, err := r.queryRoles(`
+		WITH RECURSIVE related(role_id) AS (
+			SELECT role_id FROM role_parents WHERE parent_role_id = $1
+			UNION
+			SELECT rp.role_id
+			FROM role_parents rp
+			INNER JOIN related rel ON rp.parent_role_id = rel.role_id
+		)
+		SELECT r.id, r.name, r.description, r.is_active, r.is_deleted, r.is_system, r.created_at, r.updated_at
+		FROM roles r
+		INNER JOIN related rel ON r.id = rel.role_id
+		WHERE r.is_deleted = FALSE
+		ORDER BY r.name ASC
+	`, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []uuid.UUID{roleID}
+	for _, related := range append(ancestors, descendants...) {
+		nodes = append(nodes, related.ID)
+	}
+
+	rows, err := r.db.Query(context.Background(), `
+		SELECT role_id, parent_role_id
+		FROM role_parents
+		WHERE role_id = ANY($1) AND parent_role_id = ANY($1)
+		ORDER BY role_id, parent_role_id
+	`, nodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := []domain.RoleEdge{}
+	for rows.Next() {
+		var edge domain.RoleEdge
+		if err := rows.Scan(&edge.RoleID, &edge.ParentRoleID); err != nil {
+			return nil, err
+		}
+		edges = append(edges, edge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &domain.RoleHierarchy{
+		Role:        role,
+		Ancestors:   ancestors,
+		Descendants: descendants,
+		Edges:       edges,
+	}, nil
+}
+
+// queryRoles runs a query selecting the role columns and collects the rows
+func (r *RoleRepository) queryRoles(query string, args ...interface{}) ([]*domain.Role, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []*domain.Role{}
+	for rows.Next() {
+		var role domain.Role
+		err := rows.Scan(
+			&role.ID, &role.Name, &role.Description, &role.IsActive, &role.IsDeleted, &role.IsSystem, &role.CreatedAt, &role.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, &role)
+	}
+
+	return roles, rows.Err()
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type SAMLAssertionRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSAMLAssertionRepository(db *pgxpool.Pool) domain.SAMLAssertionRepository {
+	return &SAMLAssertionRepository{db: db}
+}
+
+// Consume inserts the assertion ID; the primary key makes concurrent consumers on
+// different instances race for a single row
+func (r *SAMLAssertionRepository) Consume(provider, assertionID string, expiresAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO saml_assertions (provider, assertion_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (provider, assertion_id) DO NOTHING
+	`
+
+	result, err := r.db.Exec(context.Background(), query, provider, assertionID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+func (r *SAMLAssertionRepository) DeleteExpired() error {
+	query := `DELETE FROM saml_assertions WHERE expires_at < NOW()`
+
+	_, err := r.db.Exec(context.Background(), query)
+	return err
+}
@@ -0,0 +1,194 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type SCIMSyncStateRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSCIMSyncStateRepository(db *pgxpool.Pool) domain.SCIMSyncStateRepository {
+	return &SCIMSyncStateRepository{db: db}
+}
+
+const scimSyncStateColumns = `target_id, resource_type, local_id, display, remote_id, operation, status, attempts,
+		       last_error, last_request, revision, next_attempt_at, synced_at, updated_at`
+
+func scanSCIMSyncState(row pgx.Row) (*domain.SCIMSyncState, error) {
+	var state domain.SCIMSyncState
+	err := row.Scan(
+		&state.TargetID, &state.ResourceType, &state.LocalID, &state.Display, &state.RemoteID,
+		&state.Operation, &state.Status, &state.Attempts, &state.LastError, &state.LastRequest,
+		&state.Revision, &state.NextAttemptAt, &state.SyncedAt, &state.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("scim sync state not found")
+		}
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *SCIMSyncStateRepository) queryStates(query string, args ...interface{}) ([]*domain.SCIMSyncState, error) {
+	rows, err := r.db.Query(context.Background(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := []*domain.SCIMSyncState{}
+	for rows.Next() {
+		state, err := scanSCIMSyncState(rows)
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+
+	return states, rows.Err()
+}
+
+func (r *SCIMSyncStateRepository) Enqueue(targetID uuid.UUID, resourceType string, localID uuid.UUID, display, operation string) error {
+	query := `
+		INSERT INTO scim_sync_state (target_id, resource_type, local_id, display, operation, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (target_id, resource_type, local_id) DO UPDATE
+		SET display = CASE WHEN EXCLUDED.display = '' THEN scim_sync_state.display ELSE EXCLUDED.display END,
+		    operation = EXCLUDED.operation, status = EXCLUDED.status, attempts = 0, last_error = '',
+		    revision = scim_sync_state.revision + 1, next_attempt_at = NOW(), updated_at = NOW()
+	`
+
+	_, err := r.db.Exec(context.Background(), query, targetID, resourceType, localID, display, operation, domain.SCIMSyncPending)
+	return err
+}
+
+func (r *SCIMSyncStateRepository) EnqueueDelete(resourceType string, localID uuid.UUID) error {
+	query := `
+		UPDATE scim_sync_state
+		SET operation = $3, status = $4, attempts = 0, last_error = '',
+		    revision = revision + 1, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE resource_type = $1 AND local_id = $2
+	`
+
+	_, err := r.db.Exec(context.Background(), query, resourceType, localID, domain.SCIMOperationDelete, domain.SCIMSyncPending)
+	return err
+}
+
+func (r *SCIMSyncStateRepository) Get(targetID uuid.UUID, resourceType string, localID uuid.UUID) (*domain.SCIMSyncState, error) {
+	query := `
+		SELECT ` + scimSyncStateColumns + `
+		FROM scim_sync_state WHERE target_id = $1 AND resource_type = $2 AND local_id = $3
+	`
+
+	return scanSCIMSyncState(r.db.QueryRow(context.Background(), query, targetID, resourceType, localID))
+}
+
+func (r *SCIMSyncStateRepository) ClaimDue(limit int, lease time.Duration) ([]*domain.SCIMSyncState, error) {
+	query := `
+		UPDATE scim_sync_state SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		WHERE (target_id, resource_type, local_id) IN (
+			SELECT s.target_id, s.resource_type, s.local_id
+			FROM scim_sync_state s
+			JOIN scim_targets t ON t.id = s.target_id AND t.enabled
+			WHERE s.status = $3 AND s.next_attempt_at <= NOW()
+			ORDER BY s.next_attempt_at ASC
+			LIMIT $1
+			FOR UPDATE OF s SKIP LOCKED
+		)
+		RETURNING ` + scimSyncStateColumns
+
+	return r.queryStates(query, limit, lease.Seconds(), domain.SCIMSyncPending)
+}
+
+func (r *SCIMSyncStateRepository) List(targetID uuid.UUID, status string, limit, offset int) ([]*domain.SCIMSyncState, error) {
+	query := `
+		SELECT ` + scimSyncStateColumns + `
+		FROM scim_sync_state
+		WHERE target_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY updated_at DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	return r.queryStates(query, targetID, status, limit, offset)
+}
+
+func (r *SCIMSyncStateRepository) ListLocalIDs(targetID uuid.UUID, resourceType string) ([]uuid.UUID, error) {
+	query := `SELECT local_id FROM scim_sync_state WHERE target_id = $1 AND resource_type = $2`
+
+	rows, err := r.db.Query(context.Background(), query, targetID, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+func (r *SCIMSyncStateRepository) SetRemoteID(targetID uuid.UUID, resourceType string, localID uuid.UUID, remoteID string) error {
+	query := `
+		UPDATE scim_sync_state SET remote_id = $4
+		WHERE target_id = $1 AND resource_type = $2 AND local_id = $3
+	`
+
+	_, err := r.db.Exec(context.Background(), query, targetID, resourceType, localID, remoteID)
+	return err
+}
+
+func (r *SCIMSyncStateRepository) Update(state *domain.SCIMSyncState) error {
+	query := `
+		UPDATE scim_sync_state
+		SET status = $5, attempts = $6, last_error = $7, last_request = $8,
+		    next_attempt_at = $9, synced_at = $10, updated_at = NOW()
+		WHERE target_id = $1 AND resource_type = $2 AND local_id = $3 AND revision = $4
+	`
+
+	_, err := r.db.Exec(context.Background(), query,
+		state.TargetID, state.ResourceType, state.LocalID, state.Revision,
+		state.Status, state.Attempts, state.LastError, state.LastRequest, state.NextAttemptAt, state.SyncedAt,
+	)
+	return err
+}
+
+func (r *SCIMSyncStateRepository) Delete(state *domain.SCIMSyncState) error {
+	query := `
+		DELETE FROM scim_sync_state
+		WHERE target_id = $1 AND resource_type = $2 AND local_id = $3 AND revision = $4
+	`
+
+	_, err := r.db.Exec(context.Background(), query, state.TargetID, state.ResourceType, state.LocalID, state.Revision)
+	return err
+}
+
+func (r *SCIMSyncStateRepository) RetryFailed(targetID uuid.UUID) (int, error) {
+	query := `
+		UPDATE scim_sync_state
+		SET status = $2, attempts = 0, revision = revision + 1, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE target_id = $1 AND status = $3
+	`
+
+	result, err := r.db.Exec(context.Background(), query, targetID, domain.SCIMSyncPending, domain.SCIMSyncFailed)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(result.RowsAffected()), nil
+}
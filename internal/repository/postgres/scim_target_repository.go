@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type SCIMTargetRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSCIMTargetRepository(db *pgxpool.Pool) domain.SCIMTargetRepository {
+	return &SCIMTargetRepository{db: db}
+}
+
+const scimTargetColumns = `id, name, base_url, token, enabled, dry_run, sync_groups, last_reconciled_at, created_at, updated_at`
+
+func scanSCIMTarget(row pgx.Row) (*domain.SCIMTarget, error) {
+	var target domain.SCIMTarget
+	err := row.Scan(
+		&target.ID, &target.Name, &target.BaseURL, &target.Token, &target.Enabled, &target.DryRun,
+		&target.SyncGroups, &target.LastReconciledAt, &target.CreatedAt, &target.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("scim target not found")
+		}
+		return nil, err
+	}
+	return &target, nil
+}
+
+func (r *SCIMTargetRepository) Create(target *domain.SCIMTarget) error {
+	query := `
+		INSERT INTO scim_targets (id, name, base_url, token, enabled, dry_run, sync_groups, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+
+	_, err := r.db.Exec(context.Background(), query,
+		target.ID, target.Name, target.BaseURL, target.Token, target.Enabled, target.DryRun,
+		target.SyncGroups, target.CreatedAt, target.UpdatedAt,
+	)
+	return err
+}
+
+func (r *SCIMTargetRepository) GetByID(id uuid.UUID) (*domain.SCIMTarget, error) {
+	query := `SELECT ` + scimTargetColumns + ` FROM scim_targets WHERE id = $1`
+
+	return scanSCIMTarget(r.db.QueryRow(context.Background(), query, id))
+}
+
+func (r *SCIMTargetRepository) GetByName(name string) (*domain.SCIMTarget, error) {
+	query := `SELECT ` + scimTargetColumns + ` FROM scim_targets WHERE name = $1`
+
+	return scanSCIMTarget(r.db.QueryRow(context.Background(), query, name))
+}
+
+func (r *SCIMTargetRepository) List() ([]*domain.SCIMTarget, error) {
+	query := `SELECT ` + scimTargetColumns + ` FROM scim_targets ORDER BY name ASC`
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []*domain.SCIMTarget{}
+	for rows.Next() {
+		target, err := scanSCIMTarget(rows)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, rows.Err()
+}
+
+func (r *SCIMTargetRepository) Update(target *domain.SCIMTarget) error {
+	query := `
+		UPDATE scim_targets
+		SET base_url = $2, token = $3, enabled = $4, dry_run = $5, sync_groups = $6, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Exec(context.Background(), query,
+		target.ID, target.BaseURL, target.Token, target.Enabled, target.DryRun, target.SyncGroups,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("scim target not found")
+	}
+
+	return nil
+}
+
+func (r *SCIMTargetRepository) UpdateLastReconciled(id uuid.UUID) error {
+	query := `UPDATE scim_targets SET last_reconciled_at = NOW() WHERE id = $1`
+
+	_, err := r.db.Exec(context.Background(), query, id)
+	return err
+}
+
+func (r *SCIMTargetRepository) Delete(id uuid.UUID) error {
+	query := `DELETE FROM scim_targets WHERE id = $1`
+
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("scim target not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type SCIMTokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewSCIMTokenRepository(db *pgxpool.Pool) domain.SCIMTokenRepository {
+	return &SCIMTokenRepository{db: db}
+}
+
+func (r *SCIMTokenRepository) Create(token *domain.SCIMToken) error {
+	query := `
+		INSERT INTO scim_tokens (id, name, token_hash, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	_, err := r.db.Exec(context.Background(), query, token.ID, token.Name, token.TokenHash, token.CreatedAt)
+	return err
+}
+
+func (r *SCIMTokenRepository) GetByTokenHash(tokenHash string) (*domain.SCIMToken, error) {
+	query := `
+		SELECT id, name, token_hash, last_used_at, created_at
+		FROM scim_tokens WHERE token_hash = $1
+	`
+
+	var token domain.SCIMToken
+	err := r.db.QueryRow(context.Background(), query, tokenHash).Scan(
+		&token.ID, &token.Name, &token.TokenHash, &token.LastUsedAt, &token.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, fmt.Errorf("scim token not found")
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+func (r *SCIMTokenRepository) List() ([]*domain.SCIMToken, error) {
+	query := `
+		SELECT id, name, token_hash, last_used_at, created_at
+		FROM scim_tokens
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*domain.SCIMToken{}
+	for rows.Next() {
+		var token domain.SCIMToken
+		if err := rows.Scan(&token.ID, &token.Name, &token.TokenHash, &token.LastUsedAt, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, &token)
+	}
+
+	return tokens, rows.Err()
+}
+
+func (r *SCIMTokenRepository) UpdateLastUsed(id uuid.UUID) error {
+	query := `UPDATE scim_tokens SET last_used_at = NOW() WHERE id = $1`
+
+	_, err := r.db.Exec(context.Background(), query, id)
+	return err
+}
+
+func (r *SCIMTokenRepository) Delete(id uuid.UUID) error {
+	query := `DELETE FROM scim_tokens WHERE id = $1`
+
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("scim token not found")
+	}
+
+	return nil
+}
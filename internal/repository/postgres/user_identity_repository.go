@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+type UserIdentityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewUserIdentityRepository(db *pgxpool.Pool) domain.UserIdentityRepository {
+	return &UserIdentityRepository{db: db}
+}
+
+func (r *UserIdentityRepository) Create(identity *domain.UserIdentity) error {
+	query := `
+		INSERT INTO user_identities (id, user_id, provider, subject, email, last_login_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.Exec(context.Background(), query,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject,
+		identity.Email, identity.LastLoginAt, identity.CreatedAt)
+	return err
+}
+
+func (r *UserIdentityRepository) GetByID(id uuid.UUID) (*domain.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, last_login_at, created_at
+		FROM user_identities WHERE id = $1
+	`
+
+	return r.scanOne(query, id)
+}
+
+func (r *UserIdentityRepository) GetByProviderSubject(provider, subject string) (*domain.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, last_login_at, created_at
+		FROM user_identities WHERE provider = $1 AND subject = $2
+	`
+
+	return r.scanOne(query, provider, subject)
+}
+
+func (r *UserIdentityRepository) GetByUserID(userID uuid.UUID) ([]*domain.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, last_login_at, created_at
+		FROM user_identities WHERE user_id = $1
+		ORDER BY created_at ASC
+	`
+
+	rows, err := r.db.Query(context.Background(), query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []*domain.UserIdentity
+	for rows.Next() {
+		var identity domain.UserIdentity
+		err := rows.Scan(
+			&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+			&identity.Email, &identity.LastLoginAt, &identity.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, &identity)
+	}
+
+	return identities, nil
+}
+
+func (r *UserIdentityRepository) UpdateLastLogin(id uuid.UUID) error {
+	query := `UPDATE user_identities SET last_login_at = NOW() WHERE id = $1`
+
+	_, err := r.db.Exec(context.Background(), query, id)
+	return err
+}
+
+func (r *UserIdentityRepository) Delete(id uuid.UUID) error {
+	query := `DELETE FROM user_identities WHERE id = $1`
+
+	result, err := r.db.Exec(context.Background(), query, id)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("identity not found")
+	}
+
+	return nil
+}
+
+func (r *UserIdentityRepository) scanOne(query string, args ...interface{}) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	err := r.db.QueryRow(context.Background(), query, args...).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject,
+		&identity.Email, &identity.LastLoginAt, &identity.CreatedAt,
+	)
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, domain.ErrIdentityNotFound
+		}
+		return nil, err
+	}
+
+	return &identity, nil
+}
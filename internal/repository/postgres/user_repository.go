@@ -2,10 +2,12 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/aras-services/aras-auth/internal/domain"
@@ -21,8 +23,8 @@ func NewUserRepository(db *pgxpool.Pool) domain.UserRepository {
 
 func (r *UserRepository) Create(user *domain.User) error {
 	query := `
-		INSERT INTO users (id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO users (id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, password_changed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11)
 	`
 
 	_, err := r.db.Exec(context.Background(), query,
@@ -34,19 +36,19 @@ func (r *UserRepository) Create(user *domain.User) error {
 
 func (r *UserRepository) GetByID(id uuid.UUID) (*domain.User, error) {
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, created_at, updated_at
+		SELECT id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, password_changed_at, created_at, updated_at
 		FROM users WHERE id = $1 AND is_deleted = FALSE
 	`
 
 	var user domain.User
 	err := r.db.QueryRow(context.Background(), query, id).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
-		&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.CreatedAt, &user.UpdatedAt,
+		&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.PasswordChangedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -56,19 +58,19 @@ func (r *UserRepository) GetByID(id uuid.UUID) (*domain.User, error) {
 
 func (r *UserRepository) GetByEmail(email string) (*domain.User, error) {
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, created_at, updated_at
+		SELECT id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, password_changed_at, created_at, updated_at
 		FROM users WHERE email = $1 AND is_deleted = FALSE
 	`
 
 	var user domain.User
 	err := r.db.QueryRow(context.Background(), query, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
-		&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.CreatedAt, &user.UpdatedAt,
+		&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.PasswordChangedAt, &user.CreatedAt, &user.UpdatedAt,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, domain.ErrUserNotFound
 		}
 		return nil, err
 	}
@@ -118,7 +120,7 @@ func (r *UserRepository) Delete(id uuid.UUID) error {
 
 func (r *UserRepository) List(limit, offset int) ([]*domain.User, error) {
 	query := `
-		SELECT id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, created_at, updated_at
+		SELECT id, email, password_hash, first_name, last_name, status, email_verified, is_deleted, is_system, password_changed_at, created_at, updated_at
 		FROM users 
 		WHERE is_deleted = FALSE
 		ORDER BY created_at DESC
@@ -136,7 +138,7 @@ func (r *UserRepository) List(limit, offset int) ([]*domain.User, error) {
 		var user domain.User
 		err := rows.Scan(
 			&user.ID, &user.Email, &user.PasswordHash, &user.FirstName, &user.LastName,
-			&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.CreatedAt, &user.UpdatedAt,
+			&user.Status, &user.EmailVerified, &user.IsDeleted, &user.IsSystem, &user.PasswordChangedAt, &user.CreatedAt, &user.UpdatedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -156,7 +158,24 @@ func (r *UserRepository) Count() (int, error) {
 }
 
 func (r *UserRepository) UpdatePassword(id uuid.UUID, passwordHash string) error {
-	query := `UPDATE users SET password_hash = $2, updated_at = NOW() WHERE id = $1`
+	query := `UPDATE users SET password_hash = $2, password_changed_at = NOW(), updated_at = NOW() WHERE id = $1`
+
+	result, err := r.db.Exec(context.Background(), query, id, passwordHash)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// UpdatePasswordHash replaces the stored hash without treating it as a password change
+// (used when rehashing the same password with newer parameters)
+func (r *UserRepository) UpdatePasswordHash(id uuid.UUID, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2 WHERE id = $1`
 
 	result, err := r.db.Exec(context.Background(), query, id, passwordHash)
 	if err != nil {
@@ -184,3 +203,51 @@ func (r *UserRepository) UpdateEmailVerified(id uuid.UUID, verified bool) error
 
 	return nil
 }
+
+// UpdateEmail changes a user's email and marks it verified. Only active users take part
+// in email uniqueness, so addresses of soft-deleted users can be reused.
+func (r *UserRepository) UpdateEmail(id uuid.UUID, email string) error {
+	query := `UPDATE users SET email = $2, email_verified = TRUE, updated_at = NOW() WHERE id = $1 AND is_deleted = FALSE`
+
+	result, err := r.db.Exec(context.Background(), query, id, email)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("email already in use")
+		}
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+func (r *UserRepository) UpdateAccount(user *domain.User, passwordHash string) error {
+	query := `
+		UPDATE users
+		SET email = $2, first_name = $3, last_name = $4, status = $5, email_verified = $6,
+		    password_hash = CASE WHEN $7::text = '' THEN password_hash ELSE $7::text END,
+		    password_changed_at = CASE WHEN $7::text = '' THEN password_changed_at ELSE NOW() END,
+		    updated_at = NOW()
+		WHERE id = $1 AND is_deleted = FALSE
+	`
+
+	result, err := r.db.Exec(context.Background(), query,
+		user.ID, user.Email, user.FirstName, user.LastName, user.Status, user.EmailVerified, passwordHash)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return fmt.Errorf("email already in use")
+		}
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
@@ -27,6 +27,10 @@ func (s *JWTService) GenerateAccessToken(userID uuid.UUID, email string) (string
 	return s.jwtService.GenerateAccessToken(userID, email)
 }
 
+func (s *JWTService) GenerateScopedAccessToken(userID uuid.UUID, email, scope string) (string, error) {
+	return s.jwtService.GenerateScopedAccessToken(userID, email, scope)
+}
+
 func (s *JWTService) GenerateRefreshToken(userID uuid.UUID) (string, error) {
 	// Generate JWT refresh token
 	tokenString, err := s.jwtService.GenerateRefreshToken(userID)
@@ -60,6 +64,7 @@ func (s *JWTService) ValidateAccessToken(token string) (*domain.TokenClaims, err
 	return &domain.TokenClaims{
 		UserID:    claims.UserID,
 		Email:     claims.Email,
+		Scope:     claims.Scope,
 		ExpiresAt: claims.ExpiresAt.Unix(),
 		IssuedAt:  claims.IssuedAt.Unix(),
 		Issuer:    claims.Issuer,
@@ -107,6 +112,14 @@ func (s *JWTService) IntrospectToken(token string) (*domain.TokenIntrospection,
 		}, nil
 	}
 
+	// Scoped tokens (password_change, identity_link) only authorize their own flow and
+	// must not be accepted by other services
+	if claims.Scope != "" {
+		return &domain.TokenIntrospection{
+			Active: false,
+		}, nil
+	}
+
 	return &domain.TokenIntrospection{
 		Active:    true,
 		UserID:    claims.UserID,
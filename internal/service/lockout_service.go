@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
+)
+
+type LockoutService struct {
+	store  domain.LoginAttemptStore
+	policy domain.LockoutPolicy
+}
+
+func NewLockoutService(store domain.LoginAttemptStore, policy domain.LockoutPolicy) domain.LockoutService {
+	return &LockoutService{
+		store:  store,
+		policy: policy,
+	}
+}
+
+func (s *LockoutService) Check(ctx context.Context, identifier string) error {
+	attempts, err := s.store.Get(lockoutKey(identifier))
+	if err != nil {
+		return fmt.Errorf("failed to load login attempts: %w", err)
+	}
+
+	if attempts.LockedUntil != nil {
+		if remaining := time.Until(*attempts.LockedUntil); remaining > 0 {
+			return &domain.RetryAfterError{RetryAfter: remaining}
+		}
+	}
+
+	return nil
+}
+
+func (s *LockoutService) RecordFailure(ctx context.Context, identifier string) error {
+	key := lockoutKey(identifier)
+
+	attempts, err := s.store.RecordFailure(key, s.policy.ResetAfter)
+	if err != nil {
+		return fmt.Errorf("failed to record login failure: %w", err)
+	}
+
+	if delay := s.delay(attempts.Failures); delay > 0 {
+		return s.store.SetLockedUntil(key, time.Now().Add(delay))
+	}
+
+	return nil
+}
+
+func (s *LockoutService) RecordSuccess(ctx context.Context, identifier string) error {
+	return s.store.Reset(lockoutKey(identifier))
+}
+
+func (s *LockoutService) Status(ctx context.Context, identifier string) (*domain.LoginAttempts, error) {
+	return s.store.Get(lockoutKey(identifier))
+}
+
+func (s *LockoutService) Unlock(ctx context.Context, identifier string) error {
+	return s.store.Reset(lockoutKey(identifier))
+}
+
+// delay returns how long further attempts are blocked after the given number of consecutive failures
+func (s *LockoutService) delay(failures int) time.Duration {
+	if s.policy.MaxAttempts > 0 && failures >= s.policy.MaxAttempts {
+		return s.policy.LockoutDuration
+	}
+
+	excess := failures - s.policy.FreeAttempts
+	if excess <= 0 || s.policy.BaseDelay <= 0 {
+		return 0
+	}
+
+	delay := s.policy.BaseDelay
+	for i := 1; i < excess; i++ {
+		delay *= 2
+		if s.policy.MaxDelay > 0 && delay >= s.policy.MaxDelay {
+			return s.policy.MaxDelay
+		}
+	}
+
+	if s.policy.MaxDelay > 0 && delay > s.policy.MaxDelay {
+		return s.policy.MaxDelay
+	}
+	return delay
+}
+
+// lockoutKey derives the store key from the submitted login name. Hashing keeps raw
+// emails, including ones that do not belong to any account, out of the store.
+func lockoutKey(identifier string) string {
+	return "login:" + securetoken.Hash(strings.ToLower(strings.TrimSpace(identifier)))
+}
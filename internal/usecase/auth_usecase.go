@@ -2,35 +2,74 @@ package usecase
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/aras-services/aras-auth/internal/domain"
 	"github.com/aras-services/aras-auth/pkg/password"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
 )
 
 type AuthUseCase struct {
 	providerRegistry domain.ProviderRegistry
+	providerRepo     domain.ProviderRepository
 	tokenService     domain.TokenService
 	userRepo         domain.UserRepository
+	roleRepo         domain.RoleRepository
+	lockoutService   domain.LockoutService
+	resetTokenRepo   domain.PasswordResetTokenRepository
+	emailSender      domain.EmailSender
+	passwordPolicy   domain.PasswordPolicy
+	publicURL        string
 }
 
-func NewAuthUseCase(providerRegistry domain.ProviderRegistry, tokenService domain.TokenService, userRepo domain.UserRepository) *AuthUseCase {
+func NewAuthUseCase(
+	providerRegistry domain.ProviderRegistry,
+	providerRepo domain.ProviderRepository,
+	tokenService domain.TokenService,
+	userRepo domain.UserRepository,
+	roleRepo domain.RoleRepository,
+	lockoutService domain.LockoutService,
+	resetTokenRepo domain.PasswordResetTokenRepository,
+	emailSender domain.EmailSender,
+	passwordPolicy domain.PasswordPolicy,
+	publicURL string,
+) *AuthUseCase {
 	return &AuthUseCase{
 		providerRegistry: providerRegistry,
+		providerRepo:     providerRepo,
 		tokenService:     tokenService,
 		userRepo:         userRepo,
+		roleRepo:         roleRepo,
+		lockoutService:   lockoutService,
+		resetTokenRepo:   resetTokenRepo,
+		emailSender:      emailSender,
+		passwordPolicy:   passwordPolicy,
+		publicURL:        strings.TrimRight(publicURL, "/"),
 	}
 }
 
 type LoginResponse struct {
 	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
 	ExpiresIn    int64        `json:"expires_in"`
 	TokenType    string       `json:"token_type"`
 	User         *domain.User `json:"user"`
+	// PasswordExpired is set when the password must be rotated; the access token is then
+	// restricted to /auth/change-password and no refresh token is issued
+	PasswordExpired bool `json:"password_expired,omitempty"`
+	// Reauthenticated is set when the sign-in only re-authenticated a signed-in user; the
+	// access token is then a reauth_token for changing the email address and no refresh
+	// token is issued
+	Reauthenticated bool `json:"reauthenticated,omitempty"`
 }
 
 type RegisterResponse struct {
@@ -58,15 +97,16 @@ func (uc *AuthUseCase) Register(ctx context.Context, req *domain.CreateUserReque
 
 	// Create user
 	user := &domain.User{
-		ID:            uuid.New(),
-		Email:         req.Email,
-		PasswordHash:  hashedPassword,
-		FirstName:     req.FirstName,
-		LastName:      req.LastName,
-		Status:        domain.UserStatusPending,
-		EmailVerified: false,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		ID:                uuid.New(),
+		Email:             req.Email,
+		PasswordHash:      hashedPassword,
+		FirstName:         req.FirstName,
+		LastName:          req.LastName,
+		Status:            domain.UserStatusPending,
+		EmailVerified:     false,
+		PasswordChangedAt: time.Now(),
+		CreatedAt:         time.Now(),
+		UpdatedAt:         time.Now(),
 	}
 
 	// Get default provider and create user
@@ -86,18 +126,290 @@ func (uc *AuthUseCase) Register(ctx context.Context, req *domain.CreateUserReque
 }
 
 func (uc *AuthUseCase) Login(ctx context.Context, req *domain.LoginRequest) (*LoginResponse, error) {
-	// Get default provider
+	chain, err := uc.selectLoginProviders(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Browser sign-in providers cannot check a password; the client must redirect instead
+	if len(chain) == 1 {
+		if _, ok := chain[0].provider.(loginStarter); ok {
+			return nil, fmt.Errorf("identity provider %s requires browser sign-in via /auth/providers/%s/start",
+				chain[0].provider.GetProviderName(), chain[0].provider.GetProviderName())
+		}
+		if _, ok := chain[0].provider.(domain.CertificateProvider); ok {
+			return nil, fmt.Errorf("identity provider %s requires a client certificate via /auth/cert",
+				chain[0].provider.GetProviderName())
+		}
+	}
+
+	// Throttled accounts are rejected before credentials are checked. Unknown emails are
+	// tracked exactly like real ones so lockout state does not reveal account existence.
+	if err := uc.lockoutService.Check(ctx, req.Email); err != nil {
+		return nil, err
+	}
+
+	// Authenticate user
+	user, err := uc.authenticateChain(ctx, chain, req.Email, req.Password)
+	if err != nil {
+		if err := uc.lockoutService.RecordFailure(ctx, req.Email); err != nil {
+			fmt.Printf("Warning: failed to record login failure: %v\n", err)
+		}
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	if err := uc.lockoutService.RecordSuccess(ctx, req.Email); err != nil {
+		fmt.Printf("Warning: failed to reset login failures: %v\n", err)
+	}
+
+	// Expired passwords only get a token restricted to changing the password
+	expired, err := uc.isPasswordExpired(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate password policy: %w", err)
+	}
+	if expired {
+		accessToken, err := uc.tokenService.GenerateScopedAccessToken(user.ID, user.Email, domain.TokenScopePasswordChange)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate access token: %w", err)
+		}
+
+		return &LoginResponse{
+			AccessToken:     accessToken,
+			ExpiresIn:       900, // 15 minutes
+			TokenType:       "Bearer",
+			User:            user,
+			PasswordExpired: true,
+		}, nil
+	}
+
+	return uc.IssueTokens(ctx, user)
+}
+
+// loginCandidate is a provider to try at login and the failures after which the next
+// candidate is tried
+type loginCandidate struct {
+	provider   domain.IdentityProvider
+	fallbackOn []string
+}
+
+// selectLoginProviders picks the providers to authenticate against, in order: the provider
+// named in the request, the provider owning the email domain (home-realm discovery), the
+// login chain of providers with a login priority, and finally the default provider.
+func (uc *AuthUseCase) selectLoginProviders(req *domain.LoginRequest) ([]loginCandidate, error) {
+	if req.Provider != "" {
+		provider, err := uc.providerRegistry.GetProvider(req.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("identity provider not available")
+		}
+		return []loginCandidate{{provider: provider}}, nil
+	}
+
+	stored, err := uc.providerRepo.List()
+	if err != nil {
+		fmt.Printf("Warning: failed to load provider routing, using default provider: %v\n", err)
+	}
+
+	if routed := routeByDomain(stored, req.Email); routed != nil {
+		provider, err := uc.providerRegistry.GetProvider(routed.Name)
+		if err != nil {
+			return nil, fmt.Errorf("identity provider not available")
+		}
+		return []loginCandidate{{provider: provider}}, nil
+	}
+
+	var chained []*domain.Provider
+	for _, row := range stored {
+		if row.Enabled && row.LoginPriority != nil {
+			chained = append(chained, row)
+		}
+	}
+	sort.SliceStable(chained, func(i, j int) bool {
+		return *chained[i].LoginPriority < *chained[j].LoginPriority
+	})
+
+	var chain []loginCandidate
+	for _, row := range chained {
+		// Rows that failed to load are skipped rather than failing every login
+		provider, err := uc.providerRegistry.GetProvider(row.Name)
+		if err != nil {
+			continue
+		}
+		chain = append(chain, loginCandidate{provider: provider, fallbackOn: row.FallbackOn})
+	}
+	if len(chain) > 0 {
+		return chain, nil
+	}
+
 	provider := uc.providerRegistry.GetDefaultProvider()
 	if provider == nil {
 		return nil, fmt.Errorf("no identity provider available")
 	}
+	return []loginCandidate{{provider: provider}}, nil
+}
+
+// authenticateChain tries each candidate in turn. It moves on only when the failure is
+// listed in the candidate's fallbackOn, so e.g. a wrong directory password is not retried
+// against a local account with the same email.
+func (uc *AuthUseCase) authenticateChain(ctx context.Context, chain []loginCandidate, email, pwd string) (*domain.User, error) {
+	var lastErr error
+	for _, candidate := range chain {
+		user, err := candidate.provider.Authenticate(ctx, email, pwd)
+		if err == nil {
+			return user, nil
+		}
+		lastErr = err
+
+		failure := providerFailure(err)
+		if failure == domain.ProviderFailureUnavailable {
+			fmt.Printf("Warning: identity provider %s unavailable: %v\n", candidate.provider.GetProviderName(), err)
+		}
+
+		if failure == "" || !slices.Contains(candidate.fallbackOn, failure) {
+			break
+		}
+	}
 
-	// Authenticate user
-	user, err := provider.Authenticate(ctx, req.Email, req.Password)
+	return nil, lastErr
+}
+
+// providerFailure classifies an Authenticate error as one of the ProviderFailure* kinds,
+// or "" for failures a chain never falls back on (e.g. an inactive account)
+func providerFailure(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrUserNotFound):
+		return domain.ProviderFailureNotFound
+	case errors.Is(err, domain.ErrInvalidCredentials):
+		return domain.ProviderFailureInvalidCredentials
+	case errors.Is(err, domain.ErrProviderUnavailable):
+		return domain.ProviderFailureUnavailable
+	default:
+		return ""
+	}
+}
+
+// routeByDomain returns the enabled provider whose domains include the email's domain
+func routeByDomain(stored []*domain.Provider, email string) *domain.Provider {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return nil
+	}
+	emailDomain := strings.ToLower(email[at+1:])
+
+	for _, row := range stored {
+		if !row.Enabled {
+			continue
+		}
+		for _, routed := range row.Domains {
+			if routed == emailDomain {
+				return row
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListLoginProviders returns the enabled providers for login pages. When email belongs to
+// a domain routed to a provider, only that provider is returned (home-realm discovery).
+func (uc *AuthUseCase) ListLoginProviders(ctx context.Context, email string) ([]*domain.ProviderInfo, error) {
+	stored, err := uc.providerRepo.List()
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+
+	if email != "" {
+		if routed := routeByDomain(stored, email); routed != nil {
+			stored = []*domain.Provider{routed}
+		}
 	}
 
+	providers := []*domain.ProviderInfo{}
+	for _, row := range stored {
+		provider, err := uc.providerRegistry.GetProvider(row.Name)
+		if err != nil {
+			continue
+		}
+
+		info := &domain.ProviderInfo{Name: row.Name, Type: row.Type}
+		if _, ok := provider.(loginStarter); ok {
+			info.LoginURL = fmt.Sprintf("%s/api/v1/auth/providers/%s/start", uc.publicURL, url.PathEscape(row.Name))
+		}
+		providers = append(providers, info)
+	}
+
+	return providers, nil
+}
+
+// CertificateLogin signs in with the client certificate of a mutual TLS connection. The
+// certificate is checked by the named mtls provider or, when providerName is empty, by
+// each enabled one until one trusts its chain.
+func (uc *AuthUseCase) CertificateLogin(ctx context.Context, providerName string, state *tls.ConnectionState) (*LoginResponse, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("client certificate required")
+	}
+
+	providers, err := uc.certificateProviders(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	// A provider trusting the chain decides; one that does not passes to the next
+	for _, provider := range providers {
+		var user *domain.User
+		user, err = provider.AuthenticateCertificate(ctx, state)
+		if err == nil {
+			return uc.IssueTokens(ctx, user)
+		}
+		if !errors.Is(err, domain.ErrUntrustedCertificate) {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// certificateProviders returns the named mtls provider, or every enabled one in the
+// order they are stored
+func (uc *AuthUseCase) certificateProviders(providerName string) ([]domain.CertificateProvider, error) {
+	if providerName != "" {
+		provider, err := uc.providerRegistry.GetProvider(providerName)
+		if err != nil || !provider.IsEnabled() {
+			return nil, fmt.Errorf("identity provider not available")
+		}
+		certProvider, ok := provider.(domain.CertificateProvider)
+		if !ok {
+			return nil, fmt.Errorf("identity provider %s does not support client certificates", providerName)
+		}
+		return []domain.CertificateProvider{certProvider}, nil
+	}
+
+	stored, err := uc.providerRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+	var providers []domain.CertificateProvider
+	for _, row := range stored {
+		if !row.Enabled || row.Type != domain.ProviderTypeMTLS {
+			continue
+		}
+		provider, err := uc.providerRegistry.GetProvider(row.Name)
+		if err != nil {
+			continue
+		}
+		if certProvider, ok := provider.(domain.CertificateProvider); ok {
+			providers = append(providers, certProvider)
+		}
+	}
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("no client certificate provider available")
+	}
+
+	return providers, nil
+}
+
+// IssueTokens creates the access and refresh tokens for an authenticated user. It is
+// shared by every login method so they all return the same LoginResponse.
+func (uc *AuthUseCase) IssueTokens(ctx context.Context, user *domain.User) (*LoginResponse, error) {
 	// Generate tokens
 	accessToken, err := uc.tokenService.GenerateAccessToken(user.ID, user.Email)
 	if err != nil {
@@ -136,6 +448,15 @@ func (uc *AuthUseCase) RefreshToken(ctx context.Context, refreshToken string) (*
 		return nil, fmt.Errorf("user account is not active")
 	}
 
+	// Sessions cannot be extended past password expiry
+	expired, err := uc.isPasswordExpired(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate password policy: %w", err)
+	}
+	if expired {
+		return nil, fmt.Errorf("password has expired and must be changed")
+	}
+
 	// Generate new access token
 	accessToken, err := uc.tokenService.GenerateAccessToken(user.ID, user.Email)
 	if err != nil {
@@ -197,20 +518,75 @@ func (uc *AuthUseCase) ForgotPassword(ctx context.Context, req *domain.ResetPass
 		return nil
 	}
 
-	// TODO: Send password reset email
-	// For now, just log the request
-	fmt.Printf("Password reset requested for user: %s\n", user.Email)
+	// Externally authenticated accounts have no local password to reset
+	if !password.IsUsable(user.PasswordHash) {
+		return nil
+	}
+
+	// Only the most recent reset link stays valid
+	if err := uc.resetTokenRepo.DeleteByUserID(user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate previous reset tokens: %w", err)
+	}
+
+	token, err := securetoken.Generate(securetoken.DefaultLength)
+	if err != nil {
+		return err
+	}
+
+	resetToken := &domain.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: securetoken.Hash(token),
+		ExpiresAt: time.Now().Add(uc.passwordPolicy.ResetTokenExpiry),
+		CreatedAt: time.Now(),
+	}
+	if err := uc.resetTokenRepo.Create(resetToken); err != nil {
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", uc.publicURL, url.QueryEscape(token))
+	body := fmt.Sprintf("We received a request to reset your password.\n\n"+
+		"Use the link below within %s to choose a new password:\n%s\n\n"+
+		"If you did not request this, you can safely ignore this email.\n",
+		uc.passwordPolicy.ResetTokenExpiry, link)
+
+	// Delivery failures are logged rather than returned so responses don't reveal account existence
+	if err := uc.emailSender.SendEmail(ctx, user.Email, "Reset your password", body); err != nil {
+		fmt.Printf("Warning: failed to send password reset email to %s: %v\n", user.Email, err)
+	}
 
 	return nil
 }
 
 func (uc *AuthUseCase) ResetPassword(ctx context.Context, req *domain.ConfirmResetPasswordRequest) error {
-	// TODO: Validate reset token
-	// For now, this is a placeholder implementation
+	provider := uc.providerRegistry.GetDefaultProvider()
+	if provider == nil {
+		return fmt.Errorf("no identity provider available")
+	}
+
+	// Reject weak passwords before the token is spent
+	if !password.IsValidPassword(req.NewPassword) {
+		return fmt.Errorf("password does not meet requirements")
+	}
+
+	// The password changes while the token is held, so concurrent requests with the same
+	// token cannot both succeed, and a password rejected by the provider's strength and
+	// reuse history checks leaves the link usable for another attempt
+	var userID uuid.UUID
+	err := uc.resetTokenRepo.Consume(securetoken.Hash(req.Token), func(id uuid.UUID) error {
+		userID = id
+		return provider.ChangePassword(ctx, id, req.NewPassword)
+	})
+	if err != nil {
+		return err
+	}
+
+	// A reset implies the old credentials may be compromised; end existing sessions
+	if err := uc.tokenService.RevokeAllUserTokens(userID); err != nil {
+		fmt.Printf("Warning: failed to revoke sessions after password reset: %v\n", err)
+	}
 
-	// Find user by token (in a real implementation, you'd store tokens in DB)
-	// For now, return an error
-	return fmt.Errorf("password reset not implemented yet")
+	return nil
 }
 
 func (uc *AuthUseCase) VerifyEmail(ctx context.Context, userID uuid.UUID) error {
@@ -218,6 +594,38 @@ func (uc *AuthUseCase) VerifyEmail(ctx context.Context, userID uuid.UUID) error
 	return uc.userRepo.UpdateEmailVerified(userID, true)
 }
 
+// isPasswordExpired applies the password max-age policy, limited to users holding
+// one of the expiring roles, directly, through a group or by inheritance, when such roles
+// are configured
+func (uc *AuthUseCase) isPasswordExpired(user *domain.User) (bool, error) {
+	if uc.passwordPolicy.MaxAge <= 0 || user.PasswordChangedAt.IsZero() || !password.IsUsable(user.PasswordHash) {
+		return false, nil
+	}
+
+	if time.Since(user.PasswordChangedAt) < uc.passwordPolicy.MaxAge {
+		return false, nil
+	}
+
+	if len(uc.passwordPolicy.ExpiringRoles) == 0 {
+		return true, nil
+	}
+
+	roles, err := uc.roleRepo.GetUserEffectiveRoles(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, role := range roles {
+		for _, name := range uc.passwordPolicy.ExpiringRoles {
+			if role.Name == name {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
 func (uc *AuthUseCase) IntrospectToken(ctx context.Context, token string) (*domain.TokenIntrospection, error) {
 	return uc.tokenService.IntrospectToken(token)
 }
@@ -0,0 +1,314 @@
+package usecase
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/password"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
+)
+
+// fakeResetTokenRepository spends a token only when apply succeeds, like the Postgres
+// repository's transaction
+type fakeResetTokenRepository struct {
+	domain.PasswordResetTokenRepository
+	tokens map[string]uuid.UUID
+}
+
+func (r *fakeResetTokenRepository) Consume(tokenHash string, apply func(userID uuid.UUID) error) error {
+	userID, ok := r.tokens[tokenHash]
+	if !ok {
+		return domain.ErrInvalidResetToken
+	}
+	if err := apply(userID); err != nil {
+		return err
+	}
+	delete(r.tokens, tokenHash)
+	return nil
+}
+
+// fakePasswordProvider rejects the passwords in rejected, as the history check would
+type fakePasswordProvider struct {
+	domain.IdentityProvider
+	rejected  map[string]bool
+	passwords map[uuid.UUID]string
+}
+
+func (p *fakePasswordProvider) ChangePassword(ctx context.Context, userID uuid.UUID, newPassword string) error {
+	if p.rejected[newPassword] {
+		return errors.New("password was used recently")
+	}
+	p.passwords[userID] = newPassword
+	return nil
+}
+
+type fakeProviderRegistry struct {
+	domain.ProviderRegistry
+	providers []domain.IdentityProvider
+}
+
+func (r *fakeProviderRegistry) GetDefaultProvider() domain.IdentityProvider {
+	return r.providers[0]
+}
+
+func (r *fakeProviderRegistry) GetEnabledProviders() []domain.IdentityProvider {
+	return r.providers
+}
+
+type fakeTokenService struct {
+	domain.TokenService
+	revoked []uuid.UUID
+	// claims are the valid access tokens
+	claims map[string]*domain.TokenClaims
+}
+
+func (s *fakeTokenService) ValidateAccessToken(token string) (*domain.TokenClaims, error) {
+	claims, ok := s.claims[token]
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func (s *fakeTokenService) RevokeAllUserTokens(userID uuid.UUID) error {
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+func TestResetPassword(t *testing.T) {
+	const reused = "Reused-Password-1!"
+	userID := uuid.New()
+	tokens := &fakeResetTokenRepository{tokens: map[string]uuid.UUID{securetoken.Hash("reset-token"): userID}}
+	provider := &fakePasswordProvider{rejected: map[string]bool{reused: true}, passwords: make(map[uuid.UUID]string)}
+	tokenService := &fakeTokenService{}
+	uc := &AuthUseCase{
+		providerRegistry: &fakeProviderRegistry{providers: []domain.IdentityProvider{provider}},
+		tokenService:     tokenService,
+		resetTokenRepo:   tokens,
+	}
+	ctx := context.Background()
+
+	steps := []struct {
+		name     string
+		token    string
+		password string
+		wantErr  bool
+	}{
+		{name: "weak password", token: "reset-token", password: "short", wantErr: true},
+		{name: "password rejected by history", token: "reset-token", password: reused, wantErr: true},
+		{name: "unknown token", token: "other-token", password: "Fresh-Password-2!", wantErr: true},
+		{name: "retry with an acceptable password", token: "reset-token", password: "Fresh-Password-2!"},
+		{name: "token is spent", token: "reset-token", password: "Fresh-Password-3!", wantErr: true},
+	}
+
+	for _, step := range steps {
+		err := uc.ResetPassword(ctx, &domain.ConfirmResetPasswordRequest{Token: step.token, NewPassword: step.password})
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: ResetPassword() error = %v, wantErr %v", step.name, err, step.wantErr)
+		}
+	}
+
+	if got := provider.passwords[userID]; got != "Fresh-Password-2!" {
+		t.Errorf("password = %q, want the retried password", got)
+	}
+	if len(tokenService.revoked) != 1 || tokenService.revoked[0] != userID {
+		t.Errorf("revoked sessions of %v, want only after the successful reset", tokenService.revoked)
+	}
+}
+
+// fakeEffectiveRoleRepository holds the roles each user has by any path; GetUserRoles is
+// left unimplemented so a lookup of direct roles only fails the test
+type fakeEffectiveRoleRepository struct {
+	domain.RoleRepository
+	roles map[uuid.UUID][]*domain.Role
+}
+
+func (r *fakeEffectiveRoleRepository) GetUserEffectiveRoles(userID uuid.UUID) ([]*domain.Role, error) {
+	return r.roles[userID], nil
+}
+
+func TestIsPasswordExpired(t *testing.T) {
+	viaGroup := uuid.New()
+	unaffected := uuid.New()
+	roles := &fakeEffectiveRoleRepository{roles: map[uuid.UUID][]*domain.Role{
+		viaGroup:   {{Name: "viewer"}, {Name: "admin"}},
+		unaffected: {{Name: "viewer"}},
+	}}
+
+	tests := []struct {
+		name          string
+		userID        uuid.UUID
+		changedAt     time.Time
+		passwordHash  string
+		expiringRoles []string
+		want          bool
+	}{
+		{name: "recent password", userID: viaGroup, changedAt: time.Now(), passwordHash: "hash", expiringRoles: []string{"admin"}},
+		{name: "expiring role held through a group", userID: viaGroup, changedAt: time.Now().Add(-48 * time.Hour), passwordHash: "hash", expiringRoles: []string{"admin"}, want: true},
+		{name: "no expiring role", userID: unaffected, changedAt: time.Now().Add(-48 * time.Hour), passwordHash: "hash", expiringRoles: []string{"admin"}},
+		{name: "no roles configured", userID: unaffected, changedAt: time.Now().Add(-48 * time.Hour), passwordHash: "hash", want: true},
+		{name: "external account", userID: viaGroup, changedAt: time.Now().Add(-48 * time.Hour), passwordHash: password.Unusable, expiringRoles: []string{"admin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &AuthUseCase{
+				roleRepo:       roles,
+				passwordPolicy: domain.PasswordPolicy{MaxAge: 24 * time.Hour, ExpiringRoles: tt.expiringRoles},
+			}
+
+			got, err := uc.isPasswordExpired(&domain.User{ID: tt.userID, PasswordHash: tt.passwordHash, PasswordChangedAt: tt.changedAt})
+			if err != nil {
+				t.Fatalf("isPasswordExpired() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isPasswordExpired() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeCertificateProvider trusts a certificate chain when trusted is set and then signs
+// in user, or fails with mapErr as an unmapped identity would
+type fakeCertificateProvider struct {
+	domain.IdentityProvider
+	trusted bool
+	user    *domain.User
+	mapErr  error
+	calls   int
+}
+
+func (p *fakeCertificateProvider) AuthenticateCertificate(ctx context.Context, state *tls.ConnectionState) (*domain.User, error) {
+	p.calls++
+	if !p.trusted {
+		return nil, fmt.Errorf("%w by provider: unknown authority", domain.ErrUntrustedCertificate)
+	}
+	if p.mapErr != nil {
+		return nil, p.mapErr
+	}
+	return p.user, nil
+}
+
+func (p *fakeCertificateProvider) IsEnabled() bool {
+	return true
+}
+
+type fakeNamedProviderRegistry struct {
+	domain.ProviderRegistry
+	providers map[string]domain.IdentityProvider
+}
+
+func (r *fakeNamedProviderRegistry) GetDefaultProvider() domain.IdentityProvider {
+	return r.providers["local"]
+}
+
+func (r *fakeNamedProviderRegistry) GetProvider(name string) (domain.IdentityProvider, error) {
+	provider, ok := r.providers[name]
+	if !ok {
+		return nil, domain.ErrProviderUnavailable
+	}
+	return provider, nil
+}
+
+type fakeProviderRepository struct {
+	domain.ProviderRepository
+	rows []*domain.Provider
+}
+
+func (r *fakeProviderRepository) List() ([]*domain.Provider, error) {
+	return r.rows, nil
+}
+
+func (s *fakeTokenService) GenerateAccessToken(userID uuid.UUID, email string) (string, error) {
+	return "access-" + email, nil
+}
+
+func (s *fakeTokenService) GenerateRefreshToken(userID uuid.UUID) (string, error) {
+	return "refresh-" + userID.String(), nil
+}
+
+func TestCertificateLoginTriesEachProvider(t *testing.T) {
+	alice := &domain.User{ID: uuid.New(), Email: "alice@example.com", Status: domain.UserStatusActive}
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{}}}
+
+	tests := []struct {
+		name     string
+		provider string
+		// first and second are the enabled mtls providers, in stored order
+		first, second *fakeCertificateProvider
+		wantErr       string
+		wantCalls     [2]int
+	}{
+		{
+			name:      "certificate of the second CA",
+			first:     &fakeCertificateProvider{},
+			second:    &fakeCertificateProvider{trusted: true, user: alice},
+			wantCalls: [2]int{1, 1},
+		},
+		{
+			name:      "first trusting provider decides",
+			first:     &fakeCertificateProvider{trusted: true, mapErr: errors.New("no user is mapped")},
+			second:    &fakeCertificateProvider{trusted: true, user: alice},
+			wantErr:   "no user is mapped",
+			wantCalls: [2]int{1, 0},
+		},
+		{
+			name:      "no provider trusts the chain",
+			first:     &fakeCertificateProvider{},
+			second:    &fakeCertificateProvider{},
+			wantErr:   domain.ErrUntrustedCertificate.Error(),
+			wantCalls: [2]int{1, 1},
+		},
+		{
+			name:      "named provider only",
+			provider:  "corp-ca",
+			first:     &fakeCertificateProvider{},
+			second:    &fakeCertificateProvider{trusted: true, user: alice},
+			wantErr:   domain.ErrUntrustedCertificate.Error(),
+			wantCalls: [2]int{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &AuthUseCase{
+				tokenService: &fakeTokenService{},
+				providerRegistry: &fakeNamedProviderRegistry{providers: map[string]domain.IdentityProvider{
+					"corp-ca": tt.first, "partner-ca": tt.second,
+				}},
+				providerRepo: &fakeProviderRepository{rows: []*domain.Provider{
+					{Name: "local", Type: domain.ProviderTypeLocal, Enabled: true},
+					{Name: "corp-ca", Type: domain.ProviderTypeMTLS, Enabled: true},
+					{Name: "partner-ca", Type: domain.ProviderTypeMTLS, Enabled: true},
+				}},
+			}
+
+			response, err := uc.CertificateLogin(context.Background(), tt.provider, state)
+
+			if got := [2]int{tt.first.calls, tt.second.calls}; got != tt.wantCalls {
+				t.Errorf("AuthenticateCertificate() calls = %v, want %v", got, tt.wantCalls)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("CertificateLogin() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CertificateLogin() error = %v", err)
+			}
+			if response.User != alice {
+				t.Errorf("CertificateLogin() user = %v, want alice", response.User)
+			}
+		})
+	}
+}
@@ -11,14 +11,32 @@ import (
 )
 
 type AuthzUseCase struct {
-	roleRepo       domain.RoleRepository
-	permissionRepo domain.PermissionRepository
+	roleRepo          domain.RoleRepository
+	permissionRepo    domain.PermissionRepository
+	resourceGrantRepo domain.ResourceGrantRepository
+	denyRuleRepo      domain.DenyRuleRepository
+	userRepo          domain.UserRepository
+	groupRepo         domain.GroupRepository
+	conditions        domain.ConditionEvaluator
 }
 
-func NewAuthzUseCase(roleRepo domain.RoleRepository, permissionRepo domain.PermissionRepository) *AuthzUseCase {
+func NewAuthzUseCase(
+	roleRepo domain.RoleRepository,
+	permissionRepo domain.PermissionRepository,
+	resourceGrantRepo domain.ResourceGrantRepository,
+	denyRuleRepo domain.DenyRuleRepository,
+	userRepo domain.UserRepository,
+	groupRepo domain.GroupRepository,
+	conditions domain.ConditionEvaluator,
+) *AuthzUseCase {
 	return &AuthzUseCase{
-		roleRepo:       roleRepo,
-		permissionRepo: permissionRepo,
+		roleRepo:          roleRepo,
+		permissionRepo:    permissionRepo,
+		resourceGrantRepo: resourceGrantRepo,
+		denyRuleRepo:      denyRuleRepo,
+		userRepo:          userRepo,
+		groupRepo:         groupRepo,
+		conditions:        conditions,
 	}
 }
 
@@ -36,8 +54,53 @@ type ListPermissionsResponse struct {
 	Limit       int                  `json:"limit"`
 }
 
+type ListResourceGrantsResponse struct {
+	Grants []*domain.ResourceGrant `json:"grants"`
+	Total  int                     `json:"total"`
+	Page   int                     `json:"page"`
+	Limit  int                     `json:"limit"`
+}
+
+type ListDenyRulesResponse struct {
+	Rules []*domain.DenyRule `json:"rules"`
+	Total int                `json:"total"`
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+}
+
+type ListEffectivePermissionsResponse struct {
+	Permissions []*domain.EffectivePermission `json:"permissions"`
+	Total       int                           `json:"total"`
+	Page        int                           `json:"page"`
+	Limit       int                           `json:"limit"`
+}
+
+type ListPermissionHoldersResponse struct {
+	Users []*domain.PermissionHolder `json:"users"`
+	Total int                        `json:"total"`
+	Page  int                        `json:"page"`
+	Limit int                        `json:"limit"`
+}
+
+// BatchCheckResult is the decision of one check of a batch, with the check it answers
+type BatchCheckResult struct {
+	UserID        uuid.UUID `json:"user_id"`
+	Resource      string    `json:"resource"`
+	Action        string    `json:"action"`
+	ResourceID    string    `json:"resource_id,omitempty"`
+	HasPermission bool      `json:"has_permission"`
+	// DenyRuleID is the deny rule that overrode the user's grants, if any
+	DenyRuleID *uuid.UUID `json:"deny_rule_id,omitempty"`
+}
+
+type BatchCheckPermissionResponse struct {
+	Results []BatchCheckResult `json:"results"`
+}
+
 type CheckPermissionResponse struct {
 	HasPermission bool `json:"has_permission"`
+	// DeniedBy is the deny rule that overrode the user's grants, if any
+	DeniedBy *domain.DenyRule `json:"denied_by,omitempty"`
 }
 
 // Role management
@@ -135,7 +198,10 @@ func (uc *AuthzUseCase) DeleteRole(ctx context.Context, roleID uuid.UUID) error
 }
 
 func (uc *AuthzUseCase) AssignRoleToUser(ctx context.Context, userID uuid.UUID, req *domain.AssignRoleRequest) error {
-	return uc.roleRepo.AssignToUser(userID, req.RoleID)
+	if err := uc.validateCondition(req.Condition); err != nil {
+		return err
+	}
+	return uc.roleRepo.AssignToUser(userID, req.RoleID, req.Condition)
 }
 
 func (uc *AuthzUseCase) RemoveRoleFromUser(ctx context.Context, userID, roleID uuid.UUID) error {
@@ -143,7 +209,10 @@ func (uc *AuthzUseCase) RemoveRoleFromUser(ctx context.Context, userID, roleID u
 }
 
 func (uc *AuthzUseCase) AssignRoleToGroup(ctx context.Context, groupID uuid.UUID, req *domain.AssignRoleRequest) error {
-	return uc.roleRepo.AssignToGroup(groupID, req.RoleID)
+	if err := uc.validateCondition(req.Condition); err != nil {
+		return err
+	}
+	return uc.roleRepo.AssignToGroup(groupID, req.RoleID, req.Condition)
 }
 
 func (uc *AuthzUseCase) RemoveRoleFromGroup(ctx context.Context, groupID, roleID uuid.UUID) error {
@@ -158,8 +227,29 @@ func (uc *AuthzUseCase) GetGroupRoles(ctx context.Context, groupID uuid.UUID) ([
 	return uc.roleRepo.GetGroupRoles(groupID)
 }
 
+// Role inheritance
+func (uc *AuthzUseCase) AddRoleParent(ctx context.Context, roleID uuid.UUID, req *domain.AddRoleParentRequest) error {
+	return uc.roleRepo.AddParent(roleID, req.ParentRoleID)
+}
+
+func (uc *AuthzUseCase) RemoveRoleParent(ctx context.Context, roleID, parentRoleID uuid.UUID) error {
+	return uc.roleRepo.RemoveParent(roleID, parentRoleID)
+}
+
+func (uc *AuthzUseCase) GetRoleParents(ctx context.Context, roleID uuid.UUID) ([]*domain.Role, error) {
+	return uc.roleRepo.GetParents(roleID)
+}
+
+func (uc *AuthzUseCase) GetRoleHierarchy(ctx context.Context, roleID uuid.UUID) (*domain.RoleHierarchy, error) {
+	return uc.roleRepo.GetHierarchy(roleID)
+}
+
 // Permission management
 func (uc *AuthzUseCase) CreatePermission(ctx context.Context, req *domain.CreatePermissionRequest) (*domain.Permission, error) {
+	if err := domain.ValidatePermissionPattern(req.Resource, req.Action); err != nil {
+		return nil, err
+	}
+
 	isActive := true // default
 	if req.IsActive != nil {
 		isActive = *req.IsActive
@@ -237,6 +327,10 @@ func (uc *AuthzUseCase) UpdatePermission(ctx context.Context, permissionID uuid.
 		permission.IsActive = *req.IsActive
 	}
 
+	if err := domain.ValidatePermissionPattern(permission.Resource, permission.Action); err != nil {
+		return nil, err
+	}
+
 	// Save updated permission
 	if err := uc.permissionRepo.Update(permission); err != nil {
 		return nil, fmt.Errorf("failed to update permission: %w", err)
@@ -257,7 +351,10 @@ func (uc *AuthzUseCase) DeletePermission(ctx context.Context, permissionID uuid.
 }
 
 func (uc *AuthzUseCase) AssignPermissionToRole(ctx context.Context, roleID uuid.UUID, req *domain.AssignPermissionRequest) error {
-	return uc.permissionRepo.AssignToRole(roleID, req.PermissionID)
+	if err := uc.validateCondition(req.Condition); err != nil {
+		return err
+	}
+	return uc.permissionRepo.AssignToRole(roleID, req.PermissionID, req.Condition)
 }
 
 func (uc *AuthzUseCase) RemovePermissionFromRole(ctx context.Context, roleID, permissionID uuid.UUID) error {
@@ -268,14 +365,418 @@ func (uc *AuthzUseCase) GetRolePermissions(ctx context.Context, roleID uuid.UUID
 	return uc.permissionRepo.GetRolePermissions(roleID)
 }
 
+// Resource-scoped grants
+func (uc *AuthzUseCase) CreateResourceGrant(ctx context.Context, req *domain.CreateResourceGrantRequest) (*domain.ResourceGrant, error) {
+	if err := uc.validateCondition(req.Condition); err != nil {
+		return nil, err
+	}
+
+	role, err := uc.roleRepo.GetByID(req.RoleID)
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	// Subjects are polymorphic, so their existence cannot be left to a foreign key
+	switch req.SubjectType {
+	case domain.SubjectTypeUser:
+		if _, err := uc.userRepo.GetByID(req.SubjectID); err != nil {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+	case domain.SubjectTypeGroup:
+		if _, err := uc.groupRepo.GetByID(req.SubjectID); err != nil {
+			return nil, fmt.Errorf("group not found: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported subject type %q", req.SubjectType)
+	}
+
+	grant := &domain.ResourceGrant{
+		ID:           uuid.New(),
+		RoleID:       role.ID,
+		RoleName:     role.Name,
+		SubjectType:  req.SubjectType,
+		SubjectID:    req.SubjectID,
+		ResourceType: req.ResourceType,
+		ResourceID:   req.ResourceID,
+		Condition:    req.Condition,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := uc.resourceGrantRepo.Create(grant); err != nil {
+		return nil, fmt.Errorf("failed to create resource grant: %w", err)
+	}
+
+	return grant, nil
+}
+
+func (uc *AuthzUseCase) GetResourceGrant(ctx context.Context, grantID uuid.UUID) (*domain.ResourceGrant, error) {
+	return uc.resourceGrantRepo.GetByID(grantID)
+}
+
+func (uc *AuthzUseCase) DeleteResourceGrant(ctx context.Context, grantID uuid.UUID) error {
+	return uc.resourceGrantRepo.Delete(grantID)
+}
+
+func (uc *AuthzUseCase) ListResourceGrants(ctx context.Context, filter domain.ResourceGrantFilter, page, limit int) (*ListResourceGrantsResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	grants, err := uc.resourceGrantRepo.List(filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource grants: %w", err)
+	}
+
+	total, err := uc.resourceGrantRepo.Count(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count resource grants: %w", err)
+	}
+
+	return &ListResourceGrantsResponse{
+		Grants: grants,
+		Total:  total,
+		Page:   page,
+		Limit:  limit,
+	}, nil
+}
+
+// GetUserEffectivePermissions lists the permissions the user holds globally through their
+// roles and groups, deduplicated, with the sources of each
+func (uc *AuthzUseCase) GetUserEffectivePermissions(ctx context.Context, userID uuid.UUID, page, limit int) (*ListEffectivePermissionsResponse, error) {
+	if _, err := uc.userRepo.GetByID(userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	permissions, err := uc.permissionRepo.GetUserEffectivePermissions(userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list effective permissions: %w", err)
+	}
+
+	for _, permission := range permissions {
+		permission.Conditional = true
+		for _, source := range permission.Sources {
+			if source.Unconditional() {
+				permission.Conditional = false
+				break
+			}
+		}
+	}
+
+	total, err := uc.permissionRepo.CountUserEffectivePermissions(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count effective permissions: %w", err)
+	}
+
+	return &ListEffectivePermissionsResponse{
+		Permissions: permissions,
+		Total:       total,
+		Page:        page,
+		Limit:       limit,
+	}, nil
+}
+
+// ListPermissionHolders lists the users who can perform the action on the resource,
+// for access reviews
+func (uc *AuthzUseCase) ListPermissionHolders(ctx context.Context, resource, action string, page, limit int) (*ListPermissionHoldersResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	holders, err := uc.permissionRepo.GetPermissionHolders(resource, action, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permission holders: %w", err)
+	}
+
+	total, err := uc.permissionRepo.CountPermissionHolders(resource, action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count permission holders: %w", err)
+	}
+
+	return &ListPermissionHoldersResponse{
+		Users: holders,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+// Deny rules
+func (uc *AuthzUseCase) CreateDenyRule(ctx context.Context, req *domain.CreateDenyRuleRequest) (*domain.DenyRule, error) {
+	permission, err := uc.permissionRepo.GetByID(req.PermissionID)
+	if err != nil {
+		return nil, fmt.Errorf("permission not found: %w", err)
+	}
+
+	// Subjects are polymorphic, so their existence cannot be left to a foreign key
+	switch req.SubjectType {
+	case domain.SubjectTypeUser:
+		if _, err := uc.userRepo.GetByID(req.SubjectID); err != nil {
+			return nil, fmt.Errorf("user not found: %w", err)
+		}
+	case domain.SubjectTypeGroup:
+		if _, err := uc.groupRepo.GetByID(req.SubjectID); err != nil {
+			return nil, fmt.Errorf("group not found: %w", err)
+		}
+	case domain.SubjectTypeRole:
+		if _, err := uc.roleRepo.GetByID(req.SubjectID); err != nil {
+			return nil, fmt.Errorf("role not found: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported subject type %q", req.SubjectType)
+	}
+
+	rule := &domain.DenyRule{
+		ID:           uuid.New(),
+		SubjectType:  req.SubjectType,
+		SubjectID:    req.SubjectID,
+		PermissionID: permission.ID,
+		Resource:     permission.Resource,
+		Action:       permission.Action,
+		ResourceID:   req.ResourceID,
+		Description:  req.Description,
+		CreatedAt:    time.Now(),
+	}
+
+	if err := uc.denyRuleRepo.Create(rule); err != nil {
+		return nil, fmt.Errorf("failed to create deny rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+func (uc *AuthzUseCase) GetDenyRule(ctx context.Context, ruleID uuid.UUID) (*domain.DenyRule, error) {
+	return uc.denyRuleRepo.GetByID(ruleID)
+}
+
+func (uc *AuthzUseCase) DeleteDenyRule(ctx context.Context, ruleID uuid.UUID) error {
+	return uc.denyRuleRepo.Delete(ruleID)
+}
+
+func (uc *AuthzUseCase) ListDenyRules(ctx context.Context, filter domain.DenyRuleFilter, page, limit int) (*ListDenyRulesResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset := (page - 1) * limit
+
+	rules, err := uc.denyRuleRepo.List(filter, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deny rules: %w", err)
+	}
+
+	total, err := uc.denyRuleRepo.Count(filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count deny rules: %w", err)
+	}
+
+	return &ListDenyRulesResponse{
+		Rules: rules,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+// Conditions
+func (uc *AuthzUseCase) validateCondition(condition string) error {
+	if condition == "" {
+		return nil
+	}
+	return uc.conditions.Validate(condition)
+}
+
 // Authorization checks
+
+// CheckPermission allows the user if any role granting the permission is assigned
+// unconditionally, or if the conditions along one of the grants hold in req.Context,
+// unless a deny rule covering the permission applies to the user
 func (uc *AuthzUseCase) CheckPermission(ctx context.Context, req *domain.CheckPermissionRequest) (*CheckPermissionResponse, error) {
-	hasPermission, err := uc.permissionRepo.CheckUserPermission(req.UserID, req.Resource, req.Action)
+	denyRule, err := uc.permissionRepo.GetUserDenyRule(req.UserID, req.Resource, req.ResourceID, req.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permission: %w", err)
+	}
+	if denyRule != nil {
+		return &CheckPermissionResponse{HasPermission: false, DeniedBy: denyRule}, nil
+	}
+
+	grants, err := uc.permissionRepo.GetUserPermissionGrants(req.UserID, req.Resource, req.ResourceID, req.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permission: %w", err)
+	}
+
+	if len(grants) == 0 {
+		return &CheckPermissionResponse{HasPermission: false}, nil
+	}
+
+	accessContext, err := uc.accessContext(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check permission: %w", err)
 	}
 
 	return &CheckPermissionResponse{
-		HasPermission: hasPermission,
+		HasPermission: domain.ConditionsHold(uc.conditions, grants, accessContext),
 	}, nil
 }
+
+// accessContext completes the caller's context with the attributes the service knows,
+// which take precedence over caller-supplied ones
+func (uc *AuthzUseCase) accessContext(req *domain.CheckPermissionRequest) (*domain.AccessContext, error) {
+	user, err := uc.userRepo.GetByID(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return newAccessContext(req, user), nil
+}
+
+func newAccessContext(req *domain.CheckPermissionRequest, user *domain.User) *domain.AccessContext {
+	accessContext := &domain.AccessContext{
+		User:     map[string]interface{}{},
+		Resource: map[string]interface{}{},
+		Action:   req.Action,
+	}
+	if req.Context != nil {
+		accessContext.IP = req.Context.IP
+		accessContext.Time = req.Context.Time
+		for key, value := range req.Context.User {
+			accessContext.User[key] = value
+		}
+		for key, value := range req.Context.Resource {
+			accessContext.Resource[key] = value
+		}
+	}
+
+	accessContext.User["id"] = user.ID.String()
+	accessContext.User["email"] = user.Email
+
+	accessContext.Resource["type"] = req.Resource
+	accessContext.Resource["id"] = req.ResourceID
+
+	return accessContext
+}
+
+// CheckPermissionBatch decides many checks with one query for the grants and deny rules
+// of all of them, returning the results in the order of req.Checks. Users are loaded
+// only for checks that depend on conditions.
+func (uc *AuthzUseCase) CheckPermissionBatch(ctx context.Context, req *domain.BatchCheckPermissionRequest) (*BatchCheckPermissionResponse, error) {
+	batchGrants, err := uc.permissionRepo.GetPermissionGrantsBatch(req.Checks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+
+	users := map[uuid.UUID]*domain.User{}
+	results := make([]BatchCheckResult, len(req.Checks))
+	for i := range req.Checks {
+		check := &req.Checks[i]
+		checkGrants := batchGrants[i]
+
+		results[i] = BatchCheckResult{
+			UserID:     check.UserID,
+			Resource:   check.Resource,
+			Action:     check.Action,
+			ResourceID: check.ResourceID,
+			DenyRuleID: checkGrants.DenyRuleID,
+		}
+		if checkGrants.DenyRuleID != nil || len(checkGrants.Grants) == 0 {
+			continue
+		}
+
+		user, ok := users[check.UserID]
+		if !ok {
+			if user, err = uc.userRepo.GetByID(check.UserID); err != nil {
+				return nil, fmt.Errorf("failed to check permissions: %w", err)
+			}
+			users[check.UserID] = user
+		}
+
+		results[i].HasPermission = domain.ConditionsHold(uc.conditions, checkGrants.Grants, newAccessContext(check, user))
+	}
+
+	return &BatchCheckPermissionResponse{Results: results}, nil
+}
+
+// ExplainPermission decides the permission like CheckPermission and returns the paths
+// granting it along with the near misses: paths broken by an inactive or deleted group,
+// role or permission, or by a condition that does not hold in req.Context
+func (uc *AuthzUseCase) ExplainPermission(ctx context.Context, req *domain.CheckPermissionRequest) (*domain.PermissionExplanation, error) {
+	accessContext, err := uc.accessContext(req)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	denyRule, err := uc.permissionRepo.GetUserDenyRule(req.UserID, req.Resource, req.ResourceID, req.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain permission: %w", err)
+	}
+
+	paths, err := uc.permissionRepo.GetUserPermissionPaths(req.UserID, req.Resource, req.ResourceID, req.Action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain permission: %w", err)
+	}
+
+	explanation := &domain.PermissionExplanation{
+		UserID:     req.UserID,
+		Resource:   req.Resource,
+		Action:     req.Action,
+		ResourceID: req.ResourceID,
+		DeniedBy:   denyRule,
+		Grants:     []*domain.PermissionPath{},
+		NearMisses: []*domain.PermissionPath{},
+	}
+
+	for _, path := range paths {
+		path.Reasons = path.Blockers()
+		if len(path.Reasons) == 0 {
+			path.Reasons = append(path.Reasons, uc.failedConditions(path, accessContext)...)
+		}
+
+		if len(path.Reasons) == 0 {
+			explanation.Grants = append(explanation.Grants, path)
+		} else {
+			explanation.NearMisses = append(explanation.NearMisses, path)
+		}
+	}
+
+	explanation.HasPermission = denyRule == nil && len(explanation.Grants) > 0
+
+	return explanation, nil
+}
+
+// failedConditions explains which conditions on the path do not hold in the context
+func (uc *AuthzUseCase) failedConditions(path *domain.PermissionPath, accessContext *domain.AccessContext) []string {
+	var reasons []string
+	for _, condition := range []struct{ kind, expression string }{
+		{"assignment", path.AssignmentCondition},
+		{"permission", path.PermissionCondition},
+	} {
+		ok, err := uc.conditions.Evaluate(condition.expression, accessContext)
+		switch {
+		case err != nil:
+			reasons = append(reasons, fmt.Sprintf("%s condition could not be evaluated: %v", condition.kind, err))
+		case !ok:
+			reasons = append(reasons, fmt.Sprintf("%s condition does not hold", condition.kind))
+		}
+	}
+	return reasons
+}
@@ -0,0 +1,225 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/password"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
+)
+
+type EmailChangeUseCase struct {
+	providerRegistry domain.ProviderRegistry
+	tokenService     domain.TokenService
+	userRepo         domain.UserRepository
+	identityRepo     domain.UserIdentityRepository
+	emailChangeRepo  domain.EmailChangeRepository
+	emailSender      domain.EmailSender
+	expiry           time.Duration
+	publicURL        string
+}
+
+func NewEmailChangeUseCase(
+	providerRegistry domain.ProviderRegistry,
+	tokenService domain.TokenService,
+	userRepo domain.UserRepository,
+	identityRepo domain.UserIdentityRepository,
+	emailChangeRepo domain.EmailChangeRepository,
+	emailSender domain.EmailSender,
+	expiry time.Duration,
+	publicURL string,
+) *EmailChangeUseCase {
+	return &EmailChangeUseCase{
+		providerRegistry: providerRegistry,
+		tokenService:     tokenService,
+		userRepo:         userRepo,
+		identityRepo:     identityRepo,
+		emailChangeRepo:  emailChangeRepo,
+		emailSender:      emailSender,
+		expiry:           expiry,
+		publicURL:        strings.TrimRight(publicURL, "/"),
+	}
+}
+
+// RequestChange starts an email change. The new address gets a confirmation link and the
+// old address a notification with a cancel link; nothing changes until confirmation.
+func (uc *EmailChangeUseCase) RequestChange(ctx context.Context, userID uuid.UUID, req *domain.ChangeEmailRequest) (*domain.EmailChange, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, fmt.Errorf("new email must differ from the current email")
+	}
+
+	// Re-authenticate so a hijacked session alone cannot move the account to another mailbox
+	if err := uc.reauthenticate(ctx, user, req); err != nil {
+		return nil, err
+	}
+
+	if _, err := uc.userRepo.GetByEmail(newEmail); err == nil {
+		return nil, fmt.Errorf("email already in use")
+	}
+
+	// Only the most recent request stays valid
+	if err := uc.emailChangeRepo.CancelPendingByUserID(userID); err != nil {
+		return nil, fmt.Errorf("failed to cancel previous email change: %w", err)
+	}
+
+	token, err := securetoken.Generate(securetoken.DefaultLength)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelToken, err := securetoken.Generate(securetoken.DefaultLength)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &domain.EmailChange{
+		ID:              uuid.New(),
+		UserID:          userID,
+		OldEmail:        user.Email,
+		NewEmail:        newEmail,
+		TokenHash:       securetoken.Hash(token),
+		CancelTokenHash: securetoken.Hash(cancelToken),
+		RevokeSessions:  req.RevokeSessions,
+		ExpiresAt:       time.Now().Add(uc.expiry),
+		CreatedAt:       time.Now(),
+	}
+	if err := uc.emailChangeRepo.Create(change); err != nil {
+		return nil, fmt.Errorf("failed to create email change request: %w", err)
+	}
+
+	confirmLink := fmt.Sprintf("%s/confirm-email-change?token=%s", uc.publicURL, url.QueryEscape(token))
+	confirmBody := fmt.Sprintf("A request was made to use this address for your account.\n\n"+
+		"Confirm the change within %s:\n%s\n\n"+
+		"If you did not request this, you can safely ignore this email.\n",
+		uc.expiry, confirmLink)
+	if err := uc.emailSender.SendEmail(ctx, change.NewEmail, "Confirm your new email address", confirmBody); err != nil {
+		return nil, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	cancelLink := fmt.Sprintf("%s/cancel-email-change?token=%s", uc.publicURL, url.QueryEscape(cancelToken))
+	noticeBody := fmt.Sprintf("A request was made to change the email address of your account to %s.\n\n"+
+		"If you did not request this, cancel it now and change your password:\n%s\n",
+		change.NewEmail, cancelLink)
+	if err := uc.emailSender.SendEmail(ctx, change.OldEmail, "Your email address is being changed", noticeBody); err != nil {
+		fmt.Printf("Warning: failed to send email change notice to %s: %v\n", change.OldEmail, err)
+	}
+
+	return change, nil
+}
+
+// reauthenticate checks the current password: against the local password when the user
+// has one, otherwise through the directory and webhook providers linked to the account.
+// Users signing in through OIDC or SAML only send the reauth_token of a fresh sign-in
+// instead (see FederationUseCase.StartReauthentication).
+func (uc *EmailChangeUseCase) reauthenticate(ctx context.Context, user *domain.User, req *domain.ChangeEmailRequest) error {
+	if req.ReauthToken != "" {
+		claims, err := uc.tokenService.ValidateAccessToken(req.ReauthToken)
+		if err != nil || claims.Scope != domain.TokenScopeReauthentication || claims.UserID != user.ID {
+			return fmt.Errorf("invalid or expired re-authentication")
+		}
+		return nil
+	}
+
+	if password.IsUsable(user.PasswordHash) {
+		provider := uc.providerRegistry.GetDefaultProvider()
+		if provider == nil {
+			return fmt.Errorf("no identity provider available")
+		}
+
+		valid, err := provider.VerifyPassword(ctx, user.ID, req.CurrentPassword)
+		if err != nil || !valid {
+			return fmt.Errorf("current password is incorrect")
+		}
+		return nil
+	}
+
+	identities, err := uc.identityRepo.GetByUserID(user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %w", err)
+	}
+	for _, identity := range identities {
+		provider, err := uc.providerRegistry.GetProvider(identity.Provider)
+		if err != nil || !provider.IsEnabled() {
+			continue
+		}
+		if _, ok := provider.(loginStarter); ok {
+			continue
+		}
+		if _, ok := provider.(domain.CertificateProvider); ok {
+			continue
+		}
+
+		// The user signs in with their email, so the password must sign in this user
+		authenticated, err := provider.Authenticate(ctx, user.Email, req.CurrentPassword)
+		if err == nil && authenticated.ID == user.ID {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("current password is incorrect")
+}
+
+// GetPendingChange returns the user's pending email change, if any
+func (uc *EmailChangeUseCase) GetPendingChange(ctx context.Context, userID uuid.UUID) (*domain.EmailChange, error) {
+	return uc.emailChangeRepo.GetPendingByUserID(userID)
+}
+
+// CancelOwnChange lets a signed-in user withdraw their pending email change
+func (uc *EmailChangeUseCase) CancelOwnChange(ctx context.Context, userID uuid.UUID) error {
+	return uc.emailChangeRepo.CancelPendingByUserID(userID)
+}
+
+// Confirm applies a pending email change using the token sent to the new address
+func (uc *EmailChangeUseCase) Confirm(ctx context.Context, req *domain.EmailChangeTokenRequest) error {
+	change, err := uc.emailChangeRepo.GetByTokenHash(securetoken.Hash(req.Token))
+	if err != nil {
+		return fmt.Errorf("invalid or expired confirmation link")
+	}
+
+	// The account must still have the address the request was made from
+	user, err := uc.userRepo.GetByID(change.UserID)
+	if err != nil || user.Email != change.OldEmail {
+		uc.emailChangeRepo.MarkCancelled(change.ID)
+		return fmt.Errorf("invalid or expired confirmation link")
+	}
+
+	// The change is marked confirmed and applied in one transaction, so a failed update
+	// (e.g. the new address was taken meanwhile) leaves it pending and cancellable
+	if err := uc.emailChangeRepo.Confirm(change); err != nil {
+		if errors.Is(err, domain.ErrEmailChangeNotPending) {
+			return fmt.Errorf("invalid or expired confirmation link")
+		}
+		return err
+	}
+
+	if change.RevokeSessions {
+		if err := uc.tokenService.RevokeAllUserTokens(change.UserID); err != nil {
+			fmt.Printf("Warning: failed to revoke sessions after email change: %v\n", err)
+		}
+	}
+
+	return nil
+}
+
+// Cancel withdraws a pending email change using the token sent to the old address
+func (uc *EmailChangeUseCase) Cancel(ctx context.Context, req *domain.EmailChangeTokenRequest) error {
+	change, err := uc.emailChangeRepo.GetByCancelTokenHash(securetoken.Hash(req.Token))
+	if err != nil {
+		return fmt.Errorf("invalid or expired cancellation link")
+	}
+
+	return uc.emailChangeRepo.MarkCancelled(change.ID)
+}
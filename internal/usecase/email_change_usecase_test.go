@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/password"
+)
+
+// fakeLocalProvider accepts the password in passwords for each user
+type fakeLocalProvider struct {
+	domain.IdentityProvider
+	passwords map[uuid.UUID]string
+}
+
+func (p *fakeLocalProvider) VerifyPassword(ctx context.Context, userID uuid.UUID, pwd string) (bool, error) {
+	return p.passwords[userID] == pwd, nil
+}
+
+// fakeDirectoryProvider signs in user with the password, like LDAP or a webhook
+type fakeDirectoryProvider struct {
+	domain.IdentityProvider
+	user     *domain.User
+	password string
+}
+
+func (p *fakeDirectoryProvider) Authenticate(ctx context.Context, username, pwd string) (*domain.User, error) {
+	if username != p.user.Email || pwd != p.password {
+		return nil, domain.ErrInvalidCredentials
+	}
+	return p.user, nil
+}
+
+func (p *fakeDirectoryProvider) IsEnabled() bool {
+	return true
+}
+
+// fakeBrowserProvider signs in through a browser redirect and must never be asked for a
+// password
+type fakeBrowserProvider struct {
+	fakeDirectoryProvider
+}
+
+func (p *fakeBrowserProvider) BeginLogin(ctx context.Context, login *domain.FederatedLogin) (string, error) {
+	return "https://idp.example.com/authorize", nil
+}
+
+type fakeIdentityRepository struct {
+	domain.UserIdentityRepository
+	identities map[uuid.UUID][]*domain.UserIdentity
+}
+
+func (r *fakeIdentityRepository) GetByUserID(userID uuid.UUID) ([]*domain.UserIdentity, error) {
+	return r.identities[userID], nil
+}
+
+func TestEmailChangeReauthenticate(t *testing.T) {
+	local := &domain.User{ID: uuid.New(), Email: "alice@example.com", PasswordHash: "$argon2id$hash"}
+	directory := &domain.User{ID: uuid.New(), Email: "bob@example.com", PasswordHash: password.Unusable}
+	federated := &domain.User{ID: uuid.New(), Email: "carol@example.com", PasswordHash: password.Unusable}
+
+	uc := &EmailChangeUseCase{
+		providerRegistry: &fakeNamedProviderRegistry{providers: map[string]domain.IdentityProvider{
+			"local": &fakeLocalProvider{passwords: map[uuid.UUID]string{local.ID: "local-password"}},
+			"corp":  &fakeDirectoryProvider{user: directory, password: "directory-password"},
+			"okta":  &fakeBrowserProvider{fakeDirectoryProvider{user: federated, password: "sso-password"}},
+		}},
+		identityRepo: &fakeIdentityRepository{identities: map[uuid.UUID][]*domain.UserIdentity{
+			directory.ID: {{Provider: "corp"}},
+			federated.ID: {{Provider: "okta"}},
+		}},
+		tokenService: &fakeTokenService{claims: map[string]*domain.TokenClaims{
+			"reauth-carol":  {UserID: federated.ID, Scope: domain.TokenScopeReauthentication},
+			"reauth-alice":  {UserID: local.ID, Scope: domain.TokenScopeReauthentication},
+			"session-carol": {UserID: federated.ID},
+			"login-carol":   {UserID: federated.ID, Scope: domain.TokenScopeReauthenticationLogin},
+		}},
+	}
+
+	tests := []struct {
+		name    string
+		user    *domain.User
+		req     domain.ChangeEmailRequest
+		wantErr bool
+	}{
+		{name: "local password", user: local, req: domain.ChangeEmailRequest{CurrentPassword: "local-password"}},
+		{name: "wrong local password", user: local, req: domain.ChangeEmailRequest{CurrentPassword: "directory-password"}, wantErr: true},
+		{name: "directory password", user: directory, req: domain.ChangeEmailRequest{CurrentPassword: "directory-password"}},
+		{name: "wrong directory password", user: directory, req: domain.ChangeEmailRequest{CurrentPassword: "local-password"}, wantErr: true},
+		{name: "password of a browser sign-in provider", user: federated, req: domain.ChangeEmailRequest{CurrentPassword: "sso-password"}, wantErr: true},
+		{name: "re-authentication token", user: federated, req: domain.ChangeEmailRequest{ReauthToken: "reauth-carol"}},
+		{name: "re-authentication token of another user", user: federated, req: domain.ChangeEmailRequest{ReauthToken: "reauth-alice"}, wantErr: true},
+		{name: "session token", user: federated, req: domain.ChangeEmailRequest{ReauthToken: "session-carol"}, wantErr: true},
+		{name: "unfinished re-authentication", user: federated, req: domain.ChangeEmailRequest{ReauthToken: "login-carol"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := uc.reauthenticate(context.Background(), tt.user, &tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("reauthenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
+)
+
+type FederationUseCase struct {
+	authUseCase      *AuthUseCase
+	providerRegistry domain.ProviderRegistry
+	tokenService     domain.TokenService
+}
+
+func NewFederationUseCase(authUseCase *AuthUseCase, providerRegistry domain.ProviderRegistry, tokenService domain.TokenService) *FederationUseCase {
+	return &FederationUseCase{
+		authUseCase:      authUseCase,
+		providerRegistry: providerRegistry,
+		tokenService:     tokenService,
+	}
+}
+
+var errInvalidFederatedLogin = fmt.Errorf("invalid or expired sign-in request")
+
+// Start begins a browser sign-in with an external identity provider. It returns the URL
+// to redirect to and the login secrets the caller must keep in the browser until the
+// callback; they are never stored server-side.
+func (uc *FederationUseCase) Start(ctx context.Context, providerName string) (string, *domain.FederatedLogin, error) {
+	provider, err := uc.getLoginStarter(providerName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	login := &domain.FederatedLogin{Provider: providerName}
+	for _, secret := range []*string{&login.State, &login.Nonce, &login.CodeVerifier} {
+		if *secret, err = securetoken.Generate(securetoken.DefaultLength); err != nil {
+			return "", nil, err
+		}
+	}
+
+	authURL, err := provider.BeginLogin(ctx, login)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return authURL, login, nil
+}
+
+// StartLink begins a browser sign-in that links the external identity to the signed-in
+// user instead of signing in as whoever the identity belongs to. The login carries a
+// token restricted to linking so the callback can tell which user to link.
+func (uc *FederationUseCase) StartLink(ctx context.Context, providerName string, userID uuid.UUID, email string) (string, *domain.FederatedLogin, error) {
+	authURL, login, err := uc.Start(ctx, providerName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	login.LinkToken, err = uc.tokenService.GenerateScopedAccessToken(userID, email, domain.TokenScopeIdentityLink)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate link token: %w", err)
+	}
+
+	return authURL, login, nil
+}
+
+// StartReauthentication begins a browser sign-in through which a signed-in user confirms
+// their identity again. The callback then returns a token restricted to the changes that
+// need re-authentication instead of a new session.
+func (uc *FederationUseCase) StartReauthentication(ctx context.Context, providerName string, userID uuid.UUID, email string) (string, *domain.FederatedLogin, error) {
+	authURL, login, err := uc.Start(ctx, providerName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	login.LinkToken, err = uc.tokenService.GenerateScopedAccessToken(userID, email, domain.TokenScopeReauthenticationLogin)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate re-authentication token: %w", err)
+	}
+
+	return authURL, login, nil
+}
+
+// Callback completes a browser sign-in. login is the value kept by the browser since
+// Start; state and code are the parameters the issuer redirected back with.
+func (uc *FederationUseCase) Callback(ctx context.Context, providerName string, login *domain.FederatedLogin, state, code string) (*LoginResponse, error) {
+	// The state must match the browser's own login so a callback cannot be replayed
+	// into another browser (login CSRF)
+	if login == nil || login.Provider != providerName || state == "" ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(login.State)) != 1 {
+		return nil, errInvalidFederatedLogin
+	}
+
+	if code == "" {
+		return nil, fmt.Errorf("authorization code is required")
+	}
+
+	provider, err := uc.getFederatedProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err = uc.linkContext(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := provider.CompleteLogin(ctx, login, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.finishLogin(ctx, login, user)
+}
+
+// ConsumeAssertion completes a SAML sign-in at the assertion consumer service. login is
+// the value kept by the browser since Start, if any; it is only bound to the response when
+// it belongs to this provider and relayState matches, otherwise the response is treated
+// as IdP-initiated.
+func (uc *FederationUseCase) ConsumeAssertion(ctx context.Context, providerName string, login *domain.FederatedLogin, relayState, samlResponse string) (*LoginResponse, error) {
+	if samlResponse == "" {
+		return nil, fmt.Errorf("SAMLResponse is required")
+	}
+
+	provider, err := uc.getSAMLProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	if login == nil || login.Provider != providerName || relayState == "" ||
+		subtle.ConstantTimeCompare([]byte(relayState), []byte(login.State)) != 1 {
+		login = nil
+	}
+
+	ctx, err = uc.linkContext(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := provider.ConsumeAssertion(ctx, login, samlResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.finishLogin(ctx, login, user)
+}
+
+// Metadata returns the SAML service provider metadata for providerName
+func (uc *FederationUseCase) Metadata(ctx context.Context, providerName string) ([]byte, error) {
+	provider, err := uc.getSAMLProvider(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Metadata(ctx)
+}
+
+// linkContext marks ctx with the user a linking sign-in was started by. A
+// re-authentication resolves the user like any sign-in; finishLogin then checks it.
+func (uc *FederationUseCase) linkContext(ctx context.Context, login *domain.FederatedLogin) (context.Context, error) {
+	if login == nil || login.LinkToken == "" {
+		return ctx, nil
+	}
+
+	claims, err := uc.tokenService.ValidateAccessToken(login.LinkToken)
+	if err != nil {
+		return nil, errInvalidFederatedLogin
+	}
+
+	switch claims.Scope {
+	case domain.TokenScopeIdentityLink:
+		return domain.WithIdentityLink(ctx, claims.UserID), nil
+	case domain.TokenScopeReauthenticationLogin:
+		return ctx, nil
+	}
+	return nil, errInvalidFederatedLogin
+}
+
+// finishLogin issues the tokens of a completed sign-in. A re-authentication must have
+// signed in the user who started it, and only gets a re-authentication token.
+func (uc *FederationUseCase) finishLogin(ctx context.Context, login *domain.FederatedLogin, user *domain.User) (*LoginResponse, error) {
+	if login == nil || login.LinkToken == "" {
+		return uc.authUseCase.IssueTokens(ctx, user)
+	}
+
+	claims, err := uc.tokenService.ValidateAccessToken(login.LinkToken)
+	if err != nil {
+		return nil, errInvalidFederatedLogin
+	}
+	if claims.Scope != domain.TokenScopeReauthenticationLogin {
+		return uc.authUseCase.IssueTokens(ctx, user)
+	}
+	if user.ID != claims.UserID {
+		return nil, fmt.Errorf("signed in as another user than the one re-authenticating")
+	}
+
+	token, err := uc.tokenService.GenerateScopedAccessToken(user.ID, user.Email, domain.TokenScopeReauthentication)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate re-authentication token: %w", err)
+	}
+
+	return &LoginResponse{
+		AccessToken:     token,
+		ExpiresIn:       900, // 15 minutes
+		TokenType:       "Bearer",
+		User:            user,
+		Reauthenticated: true,
+	}, nil
+}
+
+// loginStarter is implemented by every provider that signs in through a browser redirect
+type loginStarter interface {
+	BeginLogin(ctx context.Context, login *domain.FederatedLogin) (string, error)
+}
+
+func (uc *FederationUseCase) getLoginStarter(name string) (loginStarter, error) {
+	provider, err := uc.providerRegistry.GetProvider(name)
+	if err != nil {
+		return nil, fmt.Errorf("identity provider not available")
+	}
+
+	starter, ok := provider.(loginStarter)
+	if !ok {
+		return nil, fmt.Errorf("identity provider %s does not support browser sign-in", name)
+	}
+
+	return starter, nil
+}
+
+func (uc *FederationUseCase) getSAMLProvider(name string) (domain.SAMLProvider, error) {
+	provider, err := uc.providerRegistry.GetProvider(name)
+	if err != nil {
+		return nil, fmt.Errorf("identity provider not available")
+	}
+
+	samlProvider, ok := provider.(domain.SAMLProvider)
+	if !ok {
+		return nil, fmt.Errorf("identity provider %s is not a SAML provider", name)
+	}
+
+	return samlProvider, nil
+}
+
+func (uc *FederationUseCase) getFederatedProvider(name string) (domain.FederatedProvider, error) {
+	provider, err := uc.providerRegistry.GetProvider(name)
+	if err != nil {
+		return nil, fmt.Errorf("identity provider not available")
+	}
+
+	federated, ok := provider.(domain.FederatedProvider)
+	if !ok {
+		return nil, fmt.Errorf("identity provider %s does not support browser sign-in", name)
+	}
+
+	return federated, nil
+}
@@ -34,14 +34,15 @@ func (uc *GroupUseCase) CreateGroup(ctx context.Context, req *domain.CreateGroup
 	}
 
 	group := &domain.Group{
-		ID:          uuid.New(),
-		Name:        req.Name,
-		Description: req.Description,
-		IsActive:    isActive,
-		IsDeleted:   false,
-		IsSystem:    false,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:                  uuid.New(),
+		Name:                req.Name,
+		Description:         req.Description,
+		IsActive:            isActive,
+		IsDeleted:           false,
+		IsSystem:            false,
+		PasswordlessEnabled: req.PasswordlessEnabled,
+		CreatedAt:           time.Now(),
+		UpdatedAt:           time.Now(),
 	}
 
 	if err := uc.groupRepo.Create(group); err != nil {
@@ -100,6 +101,9 @@ func (uc *GroupUseCase) UpdateGroup(ctx context.Context, groupID uuid.UUID, req
 	if req.IsActive != nil {
 		group.IsActive = *req.IsActive
 	}
+	if req.PasswordlessEnabled != nil {
+		group.PasswordlessEnabled = *req.PasswordlessEnabled
+	}
 
 	// Save updated group
 	if err := uc.groupRepo.Update(group); err != nil {
@@ -117,21 +121,46 @@ func (uc *GroupUseCase) DeleteGroup(ctx context.Context, groupID uuid.UUID) erro
 	}
 
 	// Delete group
-	return uc.groupRepo.Delete(groupID)
+	if err := uc.groupRepo.Delete(groupID); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (uc *GroupUseCase) AddMember(ctx context.Context, groupID uuid.UUID, req *domain.AddMemberRequest) error {
-	return uc.groupRepo.AddMember(groupID, req.UserID)
+	if err := uc.groupRepo.AddMember(groupID, req.UserID); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (uc *GroupUseCase) RemoveMember(ctx context.Context, groupID, userID uuid.UUID) error {
-	return uc.groupRepo.RemoveMember(groupID, userID)
+	if err := uc.groupRepo.RemoveMember(groupID, userID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (uc *GroupUseCase) GetMembers(ctx context.Context, groupID uuid.UUID, scope domain.MembershipScope) ([]*domain.User, error) {
+	return uc.groupRepo.GetMembers(groupID, scope)
+}
+
+func (uc *GroupUseCase) GetUserGroups(ctx context.Context, userID uuid.UUID, scope domain.MembershipScope) ([]*domain.Group, error) {
+	return uc.groupRepo.GetUserGroups(userID, scope)
+}
+
+// Nested groups
+func (uc *GroupUseCase) AddSubgroup(ctx context.Context, groupID uuid.UUID, req *domain.AddSubgroupRequest) error {
+	return uc.groupRepo.AddSubgroup(groupID, req.GroupID)
 }
 
-func (uc *GroupUseCase) GetMembers(ctx context.Context, groupID uuid.UUID) ([]*domain.User, error) {
-	return uc.groupRepo.GetMembers(groupID)
+func (uc *GroupUseCase) RemoveSubgroup(ctx context.Context, groupID, subgroupID uuid.UUID) error {
+	return uc.groupRepo.RemoveSubgroup(groupID, subgroupID)
 }
 
-func (uc *GroupUseCase) GetUserGroups(ctx context.Context, userID uuid.UUID) ([]*domain.Group, error) {
-	return uc.groupRepo.GetUserGroups(userID)
+func (uc *GroupUseCase) GetSubgroups(ctx context.Context, groupID uuid.UUID, scope domain.MembershipScope) ([]*domain.Group, error) {
+	return uc.groupRepo.GetSubgroups(groupID, scope)
 }
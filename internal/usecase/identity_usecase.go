@@ -0,0 +1,152 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/password"
+)
+
+// IdentityUseCase manages the external identities linked to a user's account
+type IdentityUseCase struct {
+	identityRepo      domain.UserIdentityRepository
+	userRepo          domain.UserRepository
+	providerRepo      domain.ProviderRepository
+	providerRegistry  domain.ProviderRegistry
+	lockoutService    domain.LockoutService
+	federationUseCase *FederationUseCase
+}
+
+func NewIdentityUseCase(
+	identityRepo domain.UserIdentityRepository,
+	userRepo domain.UserRepository,
+	providerRepo domain.ProviderRepository,
+	providerRegistry domain.ProviderRegistry,
+	lockoutService domain.LockoutService,
+	federationUseCase *FederationUseCase,
+) *IdentityUseCase {
+	return &IdentityUseCase{
+		identityRepo:      identityRepo,
+		userRepo:          userRepo,
+		providerRepo:      providerRepo,
+		providerRegistry:  providerRegistry,
+		lockoutService:    lockoutService,
+		federationUseCase: federationUseCase,
+	}
+}
+
+func (uc *IdentityUseCase) ListIdentities(ctx context.Context, userID uuid.UUID) ([]*domain.UserIdentity, error) {
+	identities, err := uc.identityRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// LinkWithPassword links a directory account to the user after checking its credentials.
+// Failed attempts count towards the lockout of the directory username.
+func (uc *IdentityUseCase) LinkWithPassword(ctx context.Context, userID uuid.UUID, req *domain.LinkIdentityRequest) (*domain.UserIdentity, error) {
+	stored, err := uc.providerRepo.GetByName(req.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("identity provider not available")
+	}
+	if stored.Type == domain.ProviderTypeLocal {
+		return nil, fmt.Errorf("local accounts cannot be linked")
+	}
+
+	identityProvider, err := uc.providerRegistry.GetProvider(req.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("identity provider not available")
+	}
+	if _, ok := identityProvider.(loginStarter); ok {
+		return nil, fmt.Errorf("identity provider %s requires browser sign-in via /users/me/identities/%s/start", req.Provider, req.Provider)
+	}
+
+	if err := uc.lockoutService.Check(ctx, req.Username); err != nil {
+		return nil, err
+	}
+
+	if _, err := identityProvider.Authenticate(domain.WithIdentityLink(ctx, userID), req.Username, req.Password); err != nil {
+		if err := uc.lockoutService.RecordFailure(ctx, req.Username); err != nil {
+			fmt.Printf("Warning: failed to record login failure: %v\n", err)
+		}
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	if err := uc.lockoutService.RecordSuccess(ctx, req.Username); err != nil {
+		fmt.Printf("Warning: failed to reset login failures: %v\n", err)
+	}
+
+	identities, err := uc.identityRepo.GetByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, identity := range identities {
+		if identity.Provider == req.Provider {
+			return identity, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to link identity")
+}
+
+// StartLink begins linking an identity at a browser sign-in provider; see FederationUseCase.StartLink
+func (uc *IdentityUseCase) StartLink(ctx context.Context, providerName string, userID uuid.UUID) (string, *domain.FederatedLogin, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return uc.federationUseCase.StartLink(ctx, providerName, user.ID, user.Email)
+}
+
+// StartReauthentication begins a browser sign-in through an OIDC or SAML identity linked
+// to the user; see FederationUseCase.StartReauthentication
+func (uc *IdentityUseCase) StartReauthentication(ctx context.Context, providerName string, userID uuid.UUID) (string, *domain.FederatedLogin, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return "", nil, err
+	}
+
+	identities, err := uc.identityRepo.GetByUserID(user.ID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	for _, identity := range identities {
+		if identity.Provider == providerName {
+			return uc.federationUseCase.StartReauthentication(ctx, providerName, user.ID, user.Email)
+		}
+	}
+
+	return "", nil, fmt.Errorf("no %s account is linked", providerName)
+}
+
+// Unlink removes one of the user's identities. The last way to sign in cannot be removed:
+// a user without a usable password keeps at least one identity.
+func (uc *IdentityUseCase) Unlink(ctx context.Context, userID, identityID uuid.UUID) error {
+	identity, err := uc.identityRepo.GetByID(identityID)
+	if err != nil || identity.UserID != userID {
+		return fmt.Errorf("identity not found")
+	}
+
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if !password.IsUsable(user.PasswordHash) {
+		identities, err := uc.identityRepo.GetByUserID(userID)
+		if err != nil {
+			return err
+		}
+		if len(identities) <= 1 {
+			return fmt.Errorf("cannot unlink the only sign-in method; set a password first")
+		}
+	}
+
+	return uc.identityRepo.Delete(identity.ID)
+}
@@ -0,0 +1,262 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
+)
+
+type PasswordlessUseCase struct {
+	authUseCase      *AuthUseCase
+	providerRegistry domain.ProviderRegistry
+	userRepo         domain.UserRepository
+	groupRepo        domain.GroupRepository
+	providerRepo     domain.ProviderRepository
+	challengeRepo    domain.PasswordlessChallengeRepository
+	emailSender      domain.EmailSender
+	policy           domain.PasswordlessPolicy
+	publicURL        string
+}
+
+func NewPasswordlessUseCase(
+	authUseCase *AuthUseCase,
+	providerRegistry domain.ProviderRegistry,
+	userRepo domain.UserRepository,
+	groupRepo domain.GroupRepository,
+	providerRepo domain.ProviderRepository,
+	challengeRepo domain.PasswordlessChallengeRepository,
+	emailSender domain.EmailSender,
+	policy domain.PasswordlessPolicy,
+	publicURL string,
+) *PasswordlessUseCase {
+	return &PasswordlessUseCase{
+		authUseCase:      authUseCase,
+		providerRegistry: providerRegistry,
+		userRepo:         userRepo,
+		groupRepo:        groupRepo,
+		providerRepo:     providerRepo,
+		challengeRepo:    challengeRepo,
+		emailSender:      emailSender,
+		policy:           policy,
+		publicURL:        strings.TrimRight(publicURL, "/"),
+	}
+}
+
+type StartPasswordlessResponse struct {
+	ChallengeID uuid.UUID `json:"challenge_id"`
+	ExpiresIn   int64     `json:"expires_in"`
+}
+
+var errInvalidPasswordlessChallenge = fmt.Errorf("invalid or expired sign-in code")
+
+// Start emails a sign-in link and code to the user. The response is identical whether or
+// not the email belongs to an account that may use passwordless sign-in. binding is a
+// browser-held secret; only the browser presenting it again can redeem the challenge.
+func (uc *PasswordlessUseCase) Start(ctx context.Context, req *domain.StartPasswordlessRequest, binding string) (*StartPasswordlessResponse, error) {
+	response := &StartPasswordlessResponse{
+		ChallengeID: uuid.New(),
+		ExpiresIn:   int64(uc.policy.Expiry.Seconds()),
+	}
+
+	providerName := req.Provider
+	if providerName == "" {
+		defaultProvider := uc.providerRegistry.GetDefaultProvider()
+		if defaultProvider == nil {
+			return nil, fmt.Errorf("no identity provider available")
+		}
+		providerName = defaultProvider.GetProviderName()
+	}
+
+	// Looking an address up at an external provider reaches its directory for anyone who
+	// asks, so only local accounts can start a passwordless sign-in
+	if !uc.isLocal(providerName) {
+		return nil, fmt.Errorf("passwordless sign-in is only available for local accounts")
+	}
+
+	provider, err := uc.providerRegistry.GetProvider(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("identity provider not available")
+	}
+
+	user, err := provider.GetUserByEmail(ctx, req.Email)
+	if err != nil || user.Status != domain.UserStatusActive {
+		return response, nil
+	}
+
+	enabled, err := uc.isEnabled(user, providerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate passwordless policy: %w", err)
+	}
+	if !enabled {
+		return response, nil
+	}
+
+	token, err := securetoken.Generate(securetoken.DefaultLength)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := generateCode()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the most recent challenge stays valid
+	if err := uc.challengeRepo.DeleteByUserID(user.ID); err != nil {
+		return nil, fmt.Errorf("failed to invalidate previous challenges: %w", err)
+	}
+
+	challenge := &domain.PasswordlessChallenge{
+		ID:          response.ChallengeID,
+		UserID:      user.ID,
+		Provider:    providerName,
+		TokenHash:   securetoken.Hash(token),
+		CodeHash:    hashCode(response.ChallengeID, code),
+		BindingHash: securetoken.Hash(binding),
+		ExpiresAt:   time.Now().Add(uc.policy.Expiry),
+		CreatedAt:   time.Now(),
+	}
+	if err := uc.challengeRepo.Create(challenge); err != nil {
+		return nil, fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	link := fmt.Sprintf("%s/passwordless?token=%s", uc.publicURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Use the link below to sign in:\n%s\n\n"+
+		"Or enter this code: %s\n\n"+
+		"The link and code expire in %s and only work in the browser where you requested them.\n"+
+		"If you did not request this, you can safely ignore this email.\n",
+		link, code, uc.policy.Expiry)
+
+	// Delivery failures are logged rather than returned so responses don't reveal account existence
+	if err := uc.emailSender.SendEmail(ctx, user.Email, "Your sign-in link", body); err != nil {
+		fmt.Printf("Warning: failed to send passwordless email to %s: %v\n", user.Email, err)
+	}
+
+	return response, nil
+}
+
+// Complete redeems an emailed link token or code and issues the normal login tokens
+func (uc *PasswordlessUseCase) Complete(ctx context.Context, req *domain.CompletePasswordlessRequest, binding string) (*LoginResponse, error) {
+	if binding == "" {
+		return nil, errInvalidPasswordlessChallenge
+	}
+
+	var challenge *domain.PasswordlessChallenge
+	var err error
+	switch {
+	case req.Token != "":
+		challenge, err = uc.challengeRepo.GetByTokenHash(securetoken.Hash(req.Token))
+		if err != nil || !bindingMatches(challenge, binding) {
+			return nil, errInvalidPasswordlessChallenge
+		}
+	case req.ChallengeID != nil && req.Code != "":
+		challenge, err = uc.challengeRepo.GetByID(*req.ChallengeID)
+		if err != nil || !bindingMatches(challenge, binding) {
+			return nil, errInvalidPasswordlessChallenge
+		}
+
+		// Count the guess before comparing so concurrent guesses cannot exceed the limit
+		attempts, err := uc.challengeRepo.IncrementAttempts(challenge.ID)
+		if err != nil {
+			return nil, errInvalidPasswordlessChallenge
+		}
+		if attempts > uc.policy.MaxAttempts {
+			uc.challengeRepo.Consume(challenge.ID)
+			return nil, errInvalidPasswordlessChallenge
+		}
+
+		if subtle.ConstantTimeCompare([]byte(hashCode(challenge.ID, req.Code)), []byte(challenge.CodeHash)) != 1 {
+			return nil, errInvalidPasswordlessChallenge
+		}
+	default:
+		return nil, fmt.Errorf("either token or challenge_id and code are required")
+	}
+
+	if err := uc.challengeRepo.Consume(challenge.ID); err != nil {
+		return nil, errInvalidPasswordlessChallenge
+	}
+
+	user, err := uc.userRepo.GetByID(challenge.UserID)
+	if err != nil || user.Status != domain.UserStatusActive {
+		return nil, errInvalidPasswordlessChallenge
+	}
+
+	// Access may have been revoked since the challenge was issued
+	enabled, err := uc.isEnabled(user, challenge.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate passwordless policy: %w", err)
+	}
+	if !enabled {
+		return nil, errInvalidPasswordlessChallenge
+	}
+
+	// Redeeming the challenge proves control of the mailbox
+	if !user.EmailVerified {
+		if err := uc.userRepo.UpdateEmailVerified(user.ID, true); err != nil {
+			fmt.Printf("Warning: failed to mark email verified for user %s: %v\n", user.ID, err)
+		} else {
+			user.EmailVerified = true
+		}
+	}
+
+	return uc.authUseCase.IssueTokens(ctx, user)
+}
+
+// isLocal reports whether providerName is a local provider. The built-in local provider
+// has no row in the providers table.
+func (uc *PasswordlessUseCase) isLocal(providerName string) bool {
+	stored, err := uc.providerRepo.GetByName(providerName)
+	if err != nil {
+		return providerName == domain.ProviderTypeLocal
+	}
+	return stored.Type == domain.ProviderTypeLocal
+}
+
+// isEnabled reports whether passwordless sign-in is enabled for the provider or any of the
+// user's groups, including the groups containing them
+func (uc *PasswordlessUseCase) isEnabled(user *domain.User, providerName string) (bool, error) {
+	if provider, err := uc.providerRepo.GetByName(providerName); err == nil && provider.PasswordlessEnabled {
+		return true, nil
+	}
+
+	groups, err := uc.groupRepo.GetUserGroups(user.ID, domain.MembershipEffective)
+	if err != nil {
+		return false, err
+	}
+
+	for _, group := range groups {
+		if group.PasswordlessEnabled {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func bindingMatches(challenge *domain.PasswordlessChallenge, binding string) bool {
+	return subtle.ConstantTimeCompare([]byte(securetoken.Hash(binding)), []byte(challenge.BindingHash)) == 1
+}
+
+// generateCode returns a uniformly random six-digit code
+func generateCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// hashCode binds a code to its challenge so equal codes hash differently across challenges
+func hashCode(challengeID uuid.UUID, code string) string {
+	return securetoken.Hash(challengeID.String() + ":" + code)
+}
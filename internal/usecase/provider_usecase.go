@@ -0,0 +1,291 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+// providerNamePattern keeps provider names usable as URL path segments
+var providerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+type ProviderUseCase struct {
+	providerRepo     domain.ProviderRepository
+	providerFactory  domain.ProviderFactory
+	providerRegistry domain.ProviderRegistry
+
+	// loaded records the UpdatedAt of every provider row applied to the registry so a
+	// reload only rebuilds providers that changed since
+	mu     sync.Mutex
+	loaded map[string]time.Time
+}
+
+func NewProviderUseCase(providerRepo domain.ProviderRepository, providerFactory domain.ProviderFactory, providerRegistry domain.ProviderRegistry) *ProviderUseCase {
+	return &ProviderUseCase{
+		providerRepo:     providerRepo,
+		providerFactory:  providerFactory,
+		providerRegistry: providerRegistry,
+		loaded:           make(map[string]time.Time),
+	}
+}
+
+// ListProviders returns every provider with its secret config values redacted
+func (uc *ProviderUseCase) ListProviders(ctx context.Context) ([]*domain.Provider, error) {
+	providers, err := uc.providerRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+
+	redacted := make([]*domain.Provider, len(providers))
+	for i, stored := range providers {
+		redacted[i] = stored.Redacted()
+	}
+
+	return redacted, nil
+}
+
+// GetProvider returns a provider with its secret config values redacted
+func (uc *ProviderUseCase) GetProvider(ctx context.Context, name string) (*domain.Provider, error) {
+	stored, err := uc.providerRepo.GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return stored.Redacted(), nil
+}
+
+// CreateProvider stores a new provider and registers it immediately
+func (uc *ProviderUseCase) CreateProvider(ctx context.Context, req *domain.CreateProviderRequest) (*domain.Provider, error) {
+	if !providerNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("provider name may only contain letters, digits, '.', '_' and '-'")
+	}
+
+	if _, err := uc.providerRepo.GetByName(req.Name); err == nil {
+		return nil, fmt.Errorf("provider %s already exists", req.Name)
+	}
+
+	stored := &domain.Provider{
+		ID:            uuid.New(),
+		Name:          req.Name,
+		Type:          req.Type,
+		Config:        req.Config,
+		Enabled:       req.Enabled == nil || *req.Enabled,
+		Domains:       []string{},
+		LoginPriority: req.LoginPriority,
+		FallbackOn:    []string{domain.ProviderFailureNotFound},
+	}
+	if len(stored.Config) == 0 {
+		stored.Config = json.RawMessage("{}")
+	}
+	if req.Domains != nil {
+		stored.Domains = normalizeDomains(req.Domains)
+	}
+	if req.FallbackOn != nil {
+		stored.FallbackOn = req.FallbackOn
+	}
+	if err := uc.validateLoginRouting(stored); err != nil {
+		return nil, err
+	}
+
+	// Build the provider first so an invalid config is rejected before it is stored
+	identityProvider, err := uc.providerFactory.Create(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.providerRepo.Create(stored); err != nil {
+		return nil, fmt.Errorf("failed to create provider: %w", err)
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if err := uc.providerRegistry.ReplaceProvider(identityProvider); err != nil {
+		return nil, err
+	}
+	uc.loaded[stored.Name] = stored.UpdatedAt
+
+	return stored.Redacted(), nil
+}
+
+// UpdateProvider changes a provider's config, enabled flag or login routing and swaps the
+// running provider for one built from the new configuration. Secret config values sent
+// back as domain.ProviderSecretPlaceholder keep their stored value.
+func (uc *ProviderUseCase) UpdateProvider(ctx context.Context, name string, req *domain.UpdateProviderRequest) (*domain.Provider, error) {
+	stored, err := uc.providerRepo.GetByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(req.Config) > 0 {
+		config, err := stored.RestoreSecrets(req.Config)
+		if err != nil {
+			return nil, err
+		}
+		stored.Config = config
+	}
+	if req.Enabled != nil {
+		stored.Enabled = *req.Enabled
+	}
+	if req.Domains != nil {
+		stored.Domains = normalizeDomains(req.Domains)
+	}
+	if req.LoginPriority != nil {
+		stored.LoginPriority = req.LoginPriority
+		if *req.LoginPriority == 0 {
+			stored.LoginPriority = nil
+		}
+	}
+	if req.FallbackOn != nil {
+		stored.FallbackOn = req.FallbackOn
+	}
+	if err := uc.validateLoginRouting(stored); err != nil {
+		return nil, err
+	}
+
+	identityProvider, err := uc.providerFactory.Create(stored)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.providerRepo.Update(stored); err != nil {
+		return nil, fmt.Errorf("failed to update provider: %w", err)
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	if err := uc.providerRegistry.ReplaceProvider(identityProvider); err != nil {
+		return nil, err
+	}
+	uc.loaded[stored.Name] = stored.UpdatedAt
+
+	return stored.Redacted(), nil
+}
+
+// DeleteProvider removes a provider and unregisters it. System providers cannot be deleted.
+func (uc *ProviderUseCase) DeleteProvider(ctx context.Context, name string) error {
+	stored, err := uc.providerRepo.GetByName(name)
+	if err != nil {
+		return err
+	}
+
+	if stored.IsSystem {
+		return fmt.Errorf("system provider %s cannot be deleted", name)
+	}
+
+	if err := uc.providerRepo.Delete(name); err != nil {
+		return err
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+	uc.providerRegistry.UnregisterProvider(name)
+	delete(uc.loaded, name)
+
+	return nil
+}
+
+// validateLoginRouting checks that only password providers join the login chain and that
+// no email domain is claimed by two providers
+func (uc *ProviderUseCase) validateLoginRouting(stored *domain.Provider) error {
+	if stored.LoginPriority != nil && stored.Type != domain.ProviderTypeLocal && stored.Type != domain.ProviderTypeLDAP {
+		return fmt.Errorf("%s providers sign in through the browser and cannot be part of the login chain", stored.Type)
+	}
+
+	if len(stored.Domains) == 0 {
+		return nil
+	}
+
+	providers, err := uc.providerRepo.List()
+	if err != nil {
+		return fmt.Errorf("failed to list providers: %w", err)
+	}
+
+	for _, other := range providers {
+		if other.Name == stored.Name {
+			continue
+		}
+		for _, claimed := range other.Domains {
+			for _, emailDomain := range stored.Domains {
+				if claimed == emailDomain {
+					return fmt.Errorf("domain %s is already routed to provider %s", emailDomain, other.Name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// normalizeDomains lower-cases email domains and drops duplicates and a leading '@'
+func normalizeDomains(domains []string) []string {
+	normalized := []string{}
+	seen := make(map[string]bool)
+	for _, emailDomain := range domains {
+		emailDomain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(emailDomain), "@"))
+		if emailDomain != "" && !seen[emailDomain] {
+			seen[emailDomain] = true
+			normalized = append(normalized, emailDomain)
+		}
+	}
+	return normalized
+}
+
+func (uc *ProviderUseCase) SetPasswordlessEnabled(ctx context.Context, name string, enabled bool) (*domain.Provider, error) {
+	if err := uc.providerRepo.SetPasswordlessEnabled(name, enabled); err != nil {
+		return nil, err
+	}
+
+	return uc.GetProvider(ctx, name)
+}
+
+// ReloadProviders synchronizes the registry with the providers table: new and changed
+// rows are (re)built, rows that disappeared are unregistered. It picks up changes made
+// by other instances or directly in the database. A row whose config is invalid is
+// logged and left unregistered so it cannot serve logins with a stale configuration.
+func (uc *ProviderUseCase) ReloadProviders(ctx context.Context) error {
+	providers, err := uc.providerRepo.List()
+	if err != nil {
+		return fmt.Errorf("failed to load providers: %w", err)
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	present := make(map[string]bool)
+	for _, stored := range providers {
+		present[stored.Name] = true
+
+		if updatedAt, ok := uc.loaded[stored.Name]; ok && updatedAt.Equal(stored.UpdatedAt) {
+			continue
+		}
+		uc.loaded[stored.Name] = stored.UpdatedAt
+
+		identityProvider, err := uc.providerFactory.Create(stored)
+		if err != nil {
+			fmt.Printf("Warning: skipping misconfigured identity provider %s: %v\n", stored.Name, err)
+			uc.providerRegistry.UnregisterProvider(stored.Name)
+			continue
+		}
+
+		if err := uc.providerRegistry.ReplaceProvider(identityProvider); err != nil {
+			fmt.Printf("Warning: failed to register identity provider %s: %v\n", stored.Name, err)
+		}
+	}
+
+	for name := range uc.loaded {
+		if !present[name] {
+			uc.providerRegistry.UnregisterProvider(name)
+			delete(uc.loaded, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,536 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+)
+
+const (
+	// maxRelationDepth bounds the usersets followed by one check or expand
+	maxRelationDepth = 25
+	// defaultLookupLimit is the number of objects returned by LookupResources by default
+	defaultLookupLimit = 100
+)
+
+// RelationUseCase implements relation-based access control over relation tuples:
+// Zanzibar's check, expand and lookup against namespace schemas, at revisions selected
+// with consistency tokens
+type RelationUseCase struct {
+	namespaceRepo domain.RelationNamespaceRepository
+	tupleRepo     domain.RelationTupleRepository
+}
+
+func NewRelationUseCase(namespaceRepo domain.RelationNamespaceRepository, tupleRepo domain.RelationTupleRepository) *RelationUseCase {
+	return &RelationUseCase{
+		namespaceRepo: namespaceRepo,
+		tupleRepo:     tupleRepo,
+	}
+}
+
+// Namespace schemas
+func (uc *RelationUseCase) ListNamespaces(ctx context.Context) ([]*domain.NamespaceConfig, error) {
+	return uc.namespaceRepo.List()
+}
+
+func (uc *RelationUseCase) GetNamespace(ctx context.Context, name string) (*domain.NamespaceConfig, error) {
+	return uc.namespaceRepo.GetByName(name)
+}
+
+// UpsertNamespace creates or replaces a namespace schema. Relations that still have
+// tuples cannot be removed.
+func (uc *RelationUseCase) UpsertNamespace(ctx context.Context, name string, req *domain.UpsertNamespaceRequest) (*domain.NamespaceConfig, error) {
+	config := &domain.NamespaceConfig{
+		Name:      name,
+		Relations: req.Relations,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if existing, err := uc.namespaceRepo.GetByName(name); err == nil {
+		for relation := range existing.Relations {
+			if config.HasRelation(relation) {
+				continue
+			}
+			inUse, err := uc.tupleRepo.HasTuples(name, relation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check relation tuples: %w", err)
+			}
+			if inUse {
+				return nil, fmt.Errorf("relation %s still has relation tuples", relation)
+			}
+		}
+	}
+
+	if err := uc.namespaceRepo.Upsert(config); err != nil {
+		return nil, fmt.Errorf("failed to save namespace: %w", err)
+	}
+
+	return config, nil
+}
+
+func (uc *RelationUseCase) DeleteNamespace(ctx context.Context, name string) error {
+	inUse, err := uc.tupleRepo.HasTuples(name, "")
+	if err != nil {
+		return fmt.Errorf("failed to check relation tuples: %w", err)
+	}
+	if inUse {
+		return fmt.Errorf("namespace %s still has relation tuples", name)
+	}
+
+	return uc.namespaceRepo.Delete(name)
+}
+
+// Relation tuples
+
+// WriteTuples writes and deletes tuples in one revision and returns its consistency token.
+// Written tuples must use relations defined by their namespaces.
+func (uc *RelationUseCase) WriteTuples(ctx context.Context, req *domain.WriteRelationTuplesRequest) (*domain.WriteRelationTuplesResponse, error) {
+	if len(req.Writes) == 0 && len(req.Deletes) == 0 {
+		return nil, fmt.Errorf("no relation tuples to write or delete")
+	}
+
+	evaluator, err := uc.newEvaluator(0)
+	if err != nil {
+		return nil, err
+	}
+
+	writes := make([]*domain.RelationTuple, 0, len(req.Writes))
+	for _, raw := range req.Writes {
+		tuple, err := domain.ParseRelationTuple(raw)
+		if err != nil {
+			return nil, err
+		}
+		if err := evaluator.requireRelation(tuple.Namespace, tuple.Relation); err != nil {
+			return nil, err
+		}
+		if tuple.Subject.Relation != "" {
+			if err := evaluator.requireRelation(tuple.Subject.Namespace, tuple.Subject.Relation); err != nil {
+				return nil, err
+			}
+		}
+		writes = append(writes, tuple)
+	}
+
+	deletes := make([]*domain.RelationTuple, 0, len(req.Deletes))
+	for _, raw := range req.Deletes {
+		tuple, err := domain.ParseRelationTuple(raw)
+		if err != nil {
+			return nil, err
+		}
+		deletes = append(deletes, tuple)
+	}
+
+	revision, err := uc.tupleRepo.Write(writes, deletes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write relation tuples: %w", err)
+	}
+
+	return &domain.WriteRelationTuplesResponse{
+		ConsistencyToken: domain.EncodeConsistencyToken(revision),
+	}, nil
+}
+
+// ReadTuples lists the tuples matching the filter
+func (uc *RelationUseCase) ReadTuples(ctx context.Context, filter domain.RelationTupleFilter, consistency *domain.Consistency) (*domain.ReadRelationTuplesResponse, error) {
+	revision, err := uc.resolveRevision(consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	tuples, err := uc.tupleRepo.Read(filter, revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relation tuples: %w", err)
+	}
+	if tuples == nil {
+		tuples = []*domain.RelationTuple{}
+	}
+
+	return &domain.ReadRelationTuplesResponse{
+		Tuples:           tuples,
+		ConsistencyToken: domain.EncodeConsistencyToken(revision),
+	}, nil
+}
+
+// Check reports whether the subject has the relation to the object
+func (uc *RelationUseCase) Check(ctx context.Context, req *domain.CheckRelationRequest) (*domain.CheckRelationResponse, error) {
+	namespace, objectID, err := domain.ParseRelationObject(req.Object)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := domain.ParseRelationSubject(req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := uc.resolveRevision(req.Consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, err := uc.newEvaluator(revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluator.requireRelation(namespace, req.Relation); err != nil {
+		return nil, err
+	}
+
+	allowed, _, err := evaluator.check(namespace, objectID, req.Relation, subject, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.CheckRelationResponse{
+		Allowed:          allowed,
+		ConsistencyToken: domain.EncodeConsistencyToken(revision),
+	}, nil
+}
+
+// Expand returns the userset tree of object#relation
+func (uc *RelationUseCase) Expand(ctx context.Context, req *domain.ExpandRelationRequest) (*domain.ExpandRelationResponse, error) {
+	namespace, objectID, err := domain.ParseRelationObject(req.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := uc.resolveRevision(req.Consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, err := uc.newEvaluator(revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluator.requireRelation(namespace, req.Relation); err != nil {
+		return nil, err
+	}
+
+	tree, err := evaluator.expand(namespace, objectID, req.Relation, map[string]bool{}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ExpandRelationResponse{
+		Tree:             tree,
+		ConsistencyToken: domain.EncodeConsistencyToken(revision),
+	}, nil
+}
+
+// LookupResources lists the objects of a namespace the subject has the relation to.
+// Candidates are the objects reachable from the subject through tuples, each confirmed
+// with a check.
+func (uc *RelationUseCase) LookupResources(ctx context.Context, req *domain.LookupResourcesRequest) (*domain.LookupResourcesResponse, error) {
+	subject, err := domain.ParseRelationSubject(req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit < 1 {
+		limit = defaultLookupLimit
+	}
+
+	revision, err := uc.resolveRevision(req.Consistency)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluator, err := uc.newEvaluator(revision)
+	if err != nil {
+		return nil, err
+	}
+	if err := evaluator.requireRelation(req.Namespace, req.Relation); err != nil {
+		return nil, err
+	}
+
+	candidates, err := uc.tupleRepo.ReachableObjects(subject, req.Namespace, revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up resources: %w", err)
+	}
+
+	objectIDs := []string{}
+	for _, objectID := range candidates {
+		allowed, _, err := evaluator.check(req.Namespace, objectID, req.Relation, subject, 0)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			objectIDs = append(objectIDs, objectID)
+			if len(objectIDs) == limit {
+				break
+			}
+		}
+	}
+
+	return &domain.LookupResourcesResponse{
+		ObjectIDs:        objectIDs,
+		ConsistencyToken: domain.EncodeConsistencyToken(revision),
+	}, nil
+}
+
+// resolveRevision picks the revision a read is evaluated at, the latest unless an exact
+// snapshot is requested
+func (uc *RelationUseCase) resolveRevision(consistency *domain.Consistency) (int64, error) {
+	current, err := uc.tupleRepo.CurrentRevision()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read relation store revision: %w", err)
+	}
+	if consistency == nil {
+		return current, nil
+	}
+
+	if consistency.AtLeastAsFresh != "" && consistency.AtExactSnapshot != "" {
+		return 0, fmt.Errorf("at_least_as_fresh and at_exact_snapshot are mutually exclusive")
+	}
+
+	token := consistency.AtLeastAsFresh
+	if token == "" {
+		token = consistency.AtExactSnapshot
+	}
+	if token == "" {
+		return current, nil
+	}
+
+	revision, err := domain.DecodeConsistencyToken(token)
+	if err != nil {
+		return 0, err
+	}
+	if revision > current {
+		return 0, domain.ErrStaleConsistencyToken
+	}
+	if consistency.AtExactSnapshot != "" {
+		oldest, err := uc.tupleRepo.OldestRevision()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read relation store revision: %w", err)
+		}
+		if revision < oldest {
+			return 0, domain.ErrSnapshotExpired
+		}
+		return revision, nil
+	}
+	return current, nil
+}
+
+func (uc *RelationUseCase) newEvaluator(revision int64) (*relationEvaluator, error) {
+	configs, err := uc.namespaceRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load namespaces: %w", err)
+	}
+
+	namespaces := make(map[string]*domain.NamespaceConfig, len(configs))
+	for _, config := range configs {
+		namespaces[config.Name] = config
+	}
+
+	return &relationEvaluator{
+		uc:         uc,
+		revision:   revision,
+		namespaces: namespaces,
+		tuples:     map[string][]*domain.RelationTuple{},
+		results:    map[string]bool{},
+		inProgress: map[string]bool{},
+	}, nil
+}
+
+// relationEvaluator evaluates relations at one revision with the namespace configs
+// loaded when it was created, caching tuple reads and settled check results for the
+// duration of a request
+type relationEvaluator struct {
+	uc         *RelationUseCase
+	revision   int64
+	namespaces map[string]*domain.NamespaceConfig
+	tuples     map[string][]*domain.RelationTuple
+	results    map[string]bool
+	inProgress map[string]bool
+}
+
+// namespace returns the config of the namespace, or nil when it is not defined
+func (e *relationEvaluator) namespace(name string) *domain.NamespaceConfig {
+	return e.namespaces[name]
+}
+
+func (e *relationEvaluator) requireRelation(namespace, relation string) error {
+	config := e.namespace(namespace)
+	if config == nil {
+		return fmt.Errorf("namespace %s not found", namespace)
+	}
+	if !config.HasRelation(relation) {
+		return fmt.Errorf("relation %s is not defined in namespace %s", relation, namespace)
+	}
+	return nil
+}
+
+func (e *relationEvaluator) read(namespace, objectID, relation string) ([]*domain.RelationTuple, error) {
+	key := namespace + ":" + objectID + "#" + relation
+	if tuples, ok := e.tuples[key]; ok {
+		return tuples, nil
+	}
+
+	tuples, err := e.uc.tupleRepo.Read(domain.RelationTupleFilter{
+		Namespace: namespace,
+		ObjectID:  objectID,
+		Relation:  relation,
+	}, e.revision)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relation tuples: %w", err)
+	}
+
+	e.tuples[key] = tuples
+	return tuples, nil
+}
+
+// check reports whether subject has the relation to the object. A relation that is
+// already being evaluated further up counts as false there; cyclic reports that this
+// happened, in which case a negative result is not cached as it may be incomplete.
+func (e *relationEvaluator) check(namespace, objectID, relation string, subject domain.RelationSubject, depth int) (allowed, cyclic bool, err error) {
+	key := namespace + ":" + objectID + "#" + relation + "@" + subject.String()
+	if allowed, ok := e.results[key]; ok {
+		return allowed, false, nil
+	}
+	if e.inProgress[key] {
+		return false, true, nil
+	}
+	if depth > maxRelationDepth {
+		return false, false, domain.ErrRelationDepthExceeded
+	}
+
+	// A userset contains itself
+	if subject == (domain.RelationSubject{Namespace: namespace, ObjectID: objectID, Relation: relation}) {
+		return true, false, nil
+	}
+
+	config := e.namespace(namespace)
+	if config == nil || !config.HasRelation(relation) {
+		return false, false, nil
+	}
+
+	e.inProgress[key] = true
+	allowed, cyclic, err = e.evaluate(config, objectID, relation, subject, depth)
+	delete(e.inProgress, key)
+	if err != nil {
+		return false, false, err
+	}
+
+	if allowed || !cyclic {
+		e.results[key] = allowed
+	}
+	return allowed, cyclic, nil
+}
+
+func (e *relationEvaluator) evaluate(config *domain.NamespaceConfig, objectID, relation string, subject domain.RelationSubject, depth int) (bool, bool, error) {
+	cyclic := false
+	follow := func(namespace, objectID, relation string) (bool, error) {
+		allowed, c, err := e.check(namespace, objectID, relation, subject, depth+1)
+		cyclic = cyclic || c
+		return allowed, err
+	}
+
+	// Direct tuples, then the members of usersets in them
+	tuples, err := e.read(config.Name, objectID, relation)
+	if err != nil {
+		return false, false, err
+	}
+	for _, tuple := range tuples {
+		if tuple.Subject == subject {
+			return true, false, nil
+		}
+	}
+	for _, tuple := range tuples {
+		if tuple.Subject.Relation == "" {
+			continue
+		}
+		if allowed, err := follow(tuple.Subject.Namespace, tuple.Subject.ObjectID, tuple.Subject.Relation); err != nil || allowed {
+			return allowed, false, err
+		}
+	}
+
+	for _, rewrite := range config.Relations[relation].Union {
+		if rewrite.ComputedUserset != "" {
+			if allowed, err := follow(config.Name, objectID, rewrite.ComputedUserset); err != nil || allowed {
+				return allowed, false, err
+			}
+			continue
+		}
+
+		related, err := e.read(config.Name, objectID, rewrite.TupleToUserset.Tupleset)
+		if err != nil {
+			return false, false, err
+		}
+		for _, tuple := range related {
+			if allowed, err := follow(tuple.Subject.Namespace, tuple.Subject.ObjectID, rewrite.TupleToUserset.ComputedUserset); err != nil || allowed {
+				return allowed, false, err
+			}
+		}
+	}
+
+	return false, cyclic, nil
+}
+
+// expand builds the userset tree of object#relation, following rewrites but leaving
+// usersets among the direct subjects for the caller to expand. Branches that would
+// revisit a node on the current path are left out.
+func (e *relationEvaluator) expand(namespace, objectID, relation string, path map[string]bool, depth int) (*domain.UsersetTree, error) {
+	if depth > maxRelationDepth {
+		return nil, domain.ErrRelationDepthExceeded
+	}
+
+	key := namespace + ":" + objectID + "#" + relation
+	tree := &domain.UsersetTree{
+		Object:   namespace + ":" + objectID,
+		Relation: relation,
+		Subjects: []domain.RelationSubject{},
+	}
+
+	config := e.namespace(namespace)
+	if config == nil || !config.HasRelation(relation) {
+		return tree, nil
+	}
+
+	path[key] = true
+	defer delete(path, key)
+
+	tuples, err := e.read(namespace, objectID, relation)
+	if err != nil {
+		return nil, err
+	}
+	for _, tuple := range tuples {
+		tree.Subjects = append(tree.Subjects, tuple.Subject)
+	}
+
+	addChild := func(namespace, objectID, relation string) error {
+		if path[namespace+":"+objectID+"#"+relation] {
+			return nil
+		}
+		child, err := e.expand(namespace, objectID, relation, path, depth+1)
+		if err != nil {
+			return err
+		}
+		tree.Children = append(tree.Children, child)
+		return nil
+	}
+
+	for _, rewrite := range config.Relations[relation].Union {
+		if rewrite.ComputedUserset != "" {
+			if err := addChild(namespace, objectID, rewrite.ComputedUserset); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		related, err := e.read(namespace, objectID, rewrite.TupleToUserset.Tupleset)
+		if err != nil {
+			return nil, err
+		}
+		for _, tuple := range related {
+			if err := addChild(tuple.Subject.Namespace, tuple.Subject.ObjectID, rewrite.TupleToUserset.ComputedUserset); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tree, nil
+}
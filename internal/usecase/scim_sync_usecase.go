@@ -0,0 +1,797 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/scim"
+)
+
+// scimTargetNamePattern keeps target names readable in logs and URLs
+var scimTargetNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// scimClaimLease is how long a claimed state is hidden from other workers while it is
+// being delivered
+const scimClaimLease = 5 * time.Minute
+
+// SCIMSyncUseCase pushes users and groups to downstream SCIM targets. Changes are
+// recorded per target in the sync state table and delivered by Run with retries;
+// reconciliation re-enqueues every resource to repair drift and missed changes.
+type SCIMSyncUseCase struct {
+	targetRepo domain.SCIMTargetRepository
+	stateRepo  domain.SCIMSyncStateRepository
+	userRepo   domain.UserRepository
+	groupRepo  domain.GroupRepository
+	httpClient *http.Client
+	policy     domain.SCIMSyncPolicy
+
+	wake chan struct{}
+}
+
+func NewSCIMSyncUseCase(
+	targetRepo domain.SCIMTargetRepository,
+	stateRepo domain.SCIMSyncStateRepository,
+	userRepo domain.UserRepository,
+	groupRepo domain.GroupRepository,
+	httpClient *http.Client,
+	policy domain.SCIMSyncPolicy,
+) *SCIMSyncUseCase {
+	if policy.PollInterval <= 0 {
+		policy.PollInterval = 30 * time.Second
+	}
+	if policy.BatchSize < 1 {
+		policy.BatchSize = 50
+	}
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	return &SCIMSyncUseCase{
+		targetRepo: targetRepo,
+		stateRepo:  stateRepo,
+		userRepo:   userRepo,
+		groupRepo:  groupRepo,
+		httpClient: httpClient,
+		policy:     policy,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+type ListSCIMSyncStateResponse struct {
+	States []*domain.SCIMSyncState `json:"states"`
+	Page   int                     `json:"page"`
+	Limit  int                     `json:"limit"`
+}
+
+func (uc *SCIMSyncUseCase) ListTargets(ctx context.Context) ([]*domain.SCIMTarget, error) {
+	targets, err := uc.targetRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scim targets: %w", err)
+	}
+
+	return targets, nil
+}
+
+func (uc *SCIMSyncUseCase) GetTarget(ctx context.Context, id uuid.UUID) (*domain.SCIMTarget, error) {
+	return uc.targetRepo.GetByID(id)
+}
+
+// CreateTarget stores a new target and, when enabled, reconciles it so existing users
+// and groups are pushed
+func (uc *SCIMSyncUseCase) CreateTarget(ctx context.Context, req *domain.CreateSCIMTargetRequest) (*domain.SCIMTarget, error) {
+	if !scimTargetNamePattern.MatchString(req.Name) {
+		return nil, fmt.Errorf("target name may only contain letters, digits, '.', '_' and '-'")
+	}
+
+	if _, err := uc.targetRepo.GetByName(req.Name); err == nil {
+		return nil, fmt.Errorf("scim target %s already exists", req.Name)
+	}
+
+	target := &domain.SCIMTarget{
+		ID:         uuid.New(),
+		Name:       req.Name,
+		BaseURL:    req.BaseURL,
+		Token:      req.Token,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		DryRun:     req.DryRun,
+		SyncGroups: req.SyncGroups == nil || *req.SyncGroups,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	if err := uc.targetRepo.Create(target); err != nil {
+		return nil, fmt.Errorf("failed to create scim target: %w", err)
+	}
+
+	if target.Enabled {
+		if err := uc.reconcile(target); err != nil {
+			return nil, fmt.Errorf("scim target created but reconciliation failed: %w", err)
+		}
+	}
+
+	return target, nil
+}
+
+// UpdateTarget changes a target. Enabling it, leaving dry-run mode or starting to sync
+// groups triggers a reconciliation.
+func (uc *SCIMSyncUseCase) UpdateTarget(ctx context.Context, id uuid.UUID, req *domain.UpdateSCIMTargetRequest) (*domain.SCIMTarget, error) {
+	target, err := uc.targetRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	wasEnabled, wasDryRun, syncedGroups := target.Enabled, target.DryRun, target.SyncGroups
+
+	if req.BaseURL != nil {
+		target.BaseURL = *req.BaseURL
+	}
+	if req.Token != nil {
+		target.Token = *req.Token
+	}
+	if req.Enabled != nil {
+		target.Enabled = *req.Enabled
+	}
+	if req.DryRun != nil {
+		target.DryRun = *req.DryRun
+	}
+	if req.SyncGroups != nil {
+		target.SyncGroups = *req.SyncGroups
+	}
+
+	if err := uc.targetRepo.Update(target); err != nil {
+		return nil, fmt.Errorf("failed to update scim target: %w", err)
+	}
+
+	if target.Enabled && (!wasEnabled || (wasDryRun && !target.DryRun) || (!syncedGroups && target.SyncGroups)) {
+		if err := uc.reconcile(target); err != nil {
+			return nil, fmt.Errorf("scim target updated but reconciliation failed: %w", err)
+		}
+	}
+
+	return target, nil
+}
+
+// DeleteTarget removes a target and its sync state; resources already pushed to it are left in place
+func (uc *SCIMSyncUseCase) DeleteTarget(ctx context.Context, id uuid.UUID) error {
+	return uc.targetRepo.Delete(id)
+}
+
+func (uc *SCIMSyncUseCase) ListSyncState(ctx context.Context, targetID uuid.UUID, status string, page, limit int) (*ListSCIMSyncStateResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	if _, err := uc.targetRepo.GetByID(targetID); err != nil {
+		return nil, err
+	}
+
+	states, err := uc.stateRepo.List(targetID, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scim sync state: %w", err)
+	}
+
+	return &ListSCIMSyncStateResponse{
+		States: states,
+		Page:   page,
+		Limit:  limit,
+	}, nil
+}
+
+// Reconcile re-enqueues every user and group for a target and schedules deletion of
+// resources that no longer exist locally
+func (uc *SCIMSyncUseCase) Reconcile(ctx context.Context, targetID uuid.UUID) error {
+	target, err := uc.targetRepo.GetByID(targetID)
+	if err != nil {
+		return err
+	}
+	if !target.Enabled {
+		return fmt.Errorf("scim target %s is disabled", target.Name)
+	}
+
+	return uc.reconcile(target)
+}
+
+// ReconcileAll reconciles every enabled target
+func (uc *SCIMSyncUseCase) ReconcileAll(ctx context.Context) error {
+	targets, err := uc.targetRepo.List()
+	if err != nil {
+		return fmt.Errorf("failed to list scim targets: %w", err)
+	}
+
+	for _, target := range targets {
+		if !target.Enabled {
+			continue
+		}
+		if err := uc.reconcile(target); err != nil {
+			return fmt.Errorf("failed to reconcile scim target %s: %w", target.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// RetryFailed schedules every failed state of a target for immediate delivery
+func (uc *SCIMSyncUseCase) RetryFailed(ctx context.Context, targetID uuid.UUID) (int, error) {
+	if _, err := uc.targetRepo.GetByID(targetID); err != nil {
+		return 0, err
+	}
+
+	count, err := uc.stateRepo.RetryFailed(targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retry scim sync state: %w", err)
+	}
+
+	uc.notify()
+	return count, nil
+}
+
+func (uc *SCIMSyncUseCase) reconcile(target *domain.SCIMTarget) error {
+	known, err := uc.stateRepo.ListLocalIDs(target.ID, domain.SCIMResourceUser)
+	if err != nil {
+		return err
+	}
+	seen := make(map[uuid.UUID]bool)
+	for offset := 0; ; offset += scimScanBatch {
+		users, err := uc.userRepo.List(scimScanBatch, offset)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if user.IsSystem {
+				continue
+			}
+			seen[user.ID] = true
+			if err := uc.stateRepo.Enqueue(target.ID, domain.SCIMResourceUser, user.ID, user.Email, domain.SCIMOperationUpsert); err != nil {
+				return err
+			}
+		}
+		if len(users) < scimScanBatch {
+			break
+		}
+	}
+	if err := uc.enqueueRemoved(target, domain.SCIMResourceUser, known, seen); err != nil {
+		return err
+	}
+
+	known, err = uc.stateRepo.ListLocalIDs(target.ID, domain.SCIMResourceGroup)
+	if err != nil {
+		return err
+	}
+	seen = make(map[uuid.UUID]bool)
+	if target.SyncGroups {
+		for offset := 0; ; offset += scimScanBatch {
+			groups, err := uc.groupRepo.List(scimScanBatch, offset)
+			if err != nil {
+				return err
+			}
+			for _, group := range groups {
+				if group.IsSystem {
+					continue
+				}
+				seen[group.ID] = true
+				if err := uc.stateRepo.Enqueue(target.ID, domain.SCIMResourceGroup, group.ID, group.Name, domain.SCIMOperationUpsert); err != nil {
+					return err
+				}
+			}
+			if len(groups) < scimScanBatch {
+				break
+			}
+		}
+	}
+	if err := uc.enqueueRemoved(target, domain.SCIMResourceGroup, known, seen); err != nil {
+		return err
+	}
+
+	if err := uc.targetRepo.UpdateLastReconciled(target.ID); err != nil {
+		fmt.Printf("Warning: failed to record reconciliation of scim target %s: %v\n", target.Name, err)
+	}
+
+	uc.notify()
+	return nil
+}
+
+func (uc *SCIMSyncUseCase) enqueueRemoved(target *domain.SCIMTarget, resourceType string, known []uuid.UUID, seen map[uuid.UUID]bool) error {
+	for _, localID := range known {
+		if seen[localID] {
+			continue
+		}
+		if err := uc.stateRepo.Enqueue(target.ID, resourceType, localID, "", domain.SCIMOperationDelete); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UserChanged implements domain.ChangeListener
+func (uc *SCIMSyncUseCase) UserChanged(ctx context.Context, userID uuid.UUID) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil || user.IsSystem {
+		return
+	}
+	uc.enqueue(domain.SCIMResourceUser, user.ID, user.Email, false)
+}
+
+// UserDeleted implements domain.ChangeListener
+func (uc *SCIMSyncUseCase) UserDeleted(ctx context.Context, userID uuid.UUID) {
+	if err := uc.stateRepo.EnqueueDelete(domain.SCIMResourceUser, userID); err != nil {
+		fmt.Printf("Warning: failed to enqueue scim deletion of user %s: %v\n", userID, err)
+		return
+	}
+	uc.notify()
+}
+
+// GroupChanged implements domain.ChangeListener
+func (uc *SCIMSyncUseCase) GroupChanged(ctx context.Context, groupID uuid.UUID) {
+	group, err := uc.groupRepo.GetByID(groupID)
+	if err != nil || group.IsSystem {
+		return
+	}
+	uc.enqueue(domain.SCIMResourceGroup, group.ID, group.Name, true)
+}
+
+// GroupDeleted implements domain.ChangeListener
+func (uc *SCIMSyncUseCase) GroupDeleted(ctx context.Context, groupID uuid.UUID) {
+	if err := uc.stateRepo.EnqueueDelete(domain.SCIMResourceGroup, groupID); err != nil {
+		fmt.Printf("Warning: failed to enqueue scim deletion of group %s: %v\n", groupID, err)
+		return
+	}
+	uc.notify()
+}
+
+// enqueue schedules an upsert on every enabled target; failures are left to reconciliation
+func (uc *SCIMSyncUseCase) enqueue(resourceType string, localID uuid.UUID, display string, isGroup bool) {
+	targets, err := uc.targetRepo.List()
+	if err != nil {
+		fmt.Printf("Warning: failed to list scim targets: %v\n", err)
+		return
+	}
+
+	for _, target := range targets {
+		if !target.Enabled || (isGroup && !target.SyncGroups) {
+			continue
+		}
+		if err := uc.stateRepo.Enqueue(target.ID, resourceType, localID, display, domain.SCIMOperationUpsert); err != nil {
+			fmt.Printf("Warning: failed to enqueue scim sync of %s %s to %s: %v\n", resourceType, localID, target.Name, err)
+		}
+	}
+
+	uc.notify()
+}
+
+// notify wakes Run without blocking
+func (uc *SCIMSyncUseCase) notify() {
+	select {
+	case uc.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due changes until ctx is cancelled. It wakes on every enqueued change
+// and otherwise polls at the policy interval to pick up retries and other instances' work.
+func (uc *SCIMSyncUseCase) Run(ctx context.Context) {
+	ticker := time.NewTicker(uc.policy.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			delivered, err := uc.ProcessDue(ctx)
+			if err != nil {
+				fmt.Printf("Warning: failed to deliver scim changes: %v\n", err)
+				break
+			}
+			if delivered < uc.policy.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-uc.wake:
+		}
+	}
+}
+
+// ProcessDue delivers one batch of due changes and returns how many were attempted
+func (uc *SCIMSyncUseCase) ProcessDue(ctx context.Context) (int, error) {
+	states, err := uc.stateRepo.ClaimDue(uc.policy.BatchSize, scimClaimLease)
+	if err != nil {
+		return 0, err
+	}
+
+	targets := make(map[uuid.UUID]*domain.SCIMTarget)
+	for _, state := range states {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		target, ok := targets[state.TargetID]
+		if !ok {
+			if target, err = uc.targetRepo.GetByID(state.TargetID); err != nil {
+				return 0, err
+			}
+			targets[state.TargetID] = target
+		}
+
+		uc.deliver(ctx, target, state)
+	}
+
+	return len(states), nil
+}
+
+// deliver sends one state to its target and records the outcome
+func (uc *SCIMSyncUseCase) deliver(ctx context.Context, target *domain.SCIMTarget, state *domain.SCIMSyncState) {
+	sync := &scimSync{
+		uc:     uc,
+		ctx:    ctx,
+		target: target,
+		client: scim.NewClient(target.BaseURL, target.Token, uc.httpClient),
+	}
+
+	var err error
+	removed := false
+	switch state.ResourceType {
+	case domain.SCIMResourceUser:
+		removed, err = sync.user(state)
+	case domain.SCIMResourceGroup:
+		removed, err = sync.group(state)
+	default:
+		err = fmt.Errorf("unknown resource type %s", state.ResourceType)
+	}
+
+	state.LastRequest = sync.plan
+	if err == nil && removed && !target.DryRun {
+		if err := uc.stateRepo.Delete(state); err != nil {
+			fmt.Printf("Warning: failed to remove scim sync state: %v\n", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		state.Status = domain.SCIMSyncSynced
+		if target.DryRun {
+			state.Status = domain.SCIMSyncDryRun
+		}
+		state.Attempts = 0
+		state.LastError = ""
+		state.NextAttemptAt = now
+		state.SyncedAt = &now
+	default:
+		state.Attempts++
+		state.LastError = err.Error()
+		state.Status = domain.SCIMSyncPending
+		state.NextAttemptAt = now.Add(uc.retryDelay(state.Attempts))
+		if state.Attempts >= uc.policy.MaxAttempts || !retryable(err) {
+			state.Status = domain.SCIMSyncFailed
+		}
+	}
+
+	if err := uc.stateRepo.Update(state); err != nil {
+		fmt.Printf("Warning: failed to record scim sync state: %v\n", err)
+	}
+}
+
+// retryDelay doubles the base delay per failed attempt up to the maximum
+func (uc *SCIMSyncUseCase) retryDelay(attempts int) time.Duration {
+	delay := uc.policy.RetryBaseDelay
+	for i := 1; i < attempts && delay < uc.policy.RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if uc.policy.RetryMaxDelay > 0 && delay > uc.policy.RetryMaxDelay {
+		delay = uc.policy.RetryMaxDelay
+	}
+	return delay
+}
+
+// retryable reports whether a failed delivery may succeed later. Client errors other than
+// timeouts, conflicts on concurrent changes and rate limiting are permanent.
+func retryable(err error) bool {
+	var scimErr *scim.Error
+	if !errors.As(err, &scimErr) {
+		return true
+	}
+
+	status := scimErr.HTTPStatus()
+	switch {
+	case status == http.StatusRequestTimeout, status == http.StatusTooManyRequests, status == http.StatusPreconditionFailed:
+		return true
+	case status >= 400 && status < 500:
+		return false
+	}
+	return true
+}
+
+func isSCIMNotFound(err error) bool {
+	var scimErr *scim.Error
+	return errors.As(err, &scimErr) && scimErr.HTTPStatus() == http.StatusNotFound
+}
+
+// scimSync delivers the states of one target. In dry-run mode lookups are still made
+// but changes are only described in plan.
+type scimSync struct {
+	uc     *SCIMSyncUseCase
+	ctx    context.Context
+	target *domain.SCIMTarget
+	client *scim.Client
+	plan   string
+}
+
+func (s *scimSync) record(method, path string) {
+	if s.plan != "" {
+		s.plan += "; "
+	}
+	s.plan += method + " " + path
+}
+
+// user delivers a user state and reports whether the user was removed from the target
+func (s *scimSync) user(state *domain.SCIMSyncState) (bool, error) {
+	if state.Operation == domain.SCIMOperationUpsert {
+		user, err := s.uc.userRepo.GetByID(state.LocalID)
+		if err == nil && !user.IsSystem {
+			remoteID, err := s.pushUser(user, state.RemoteID)
+			if err != nil {
+				return false, err
+			}
+			if remoteID != state.RemoteID && remoteID != "" {
+				state.RemoteID = remoteID
+				if err := s.uc.stateRepo.SetRemoteID(s.target.ID, domain.SCIMResourceUser, user.ID, remoteID); err != nil {
+					return false, err
+				}
+			}
+			return false, nil
+		}
+	}
+
+	// Deleted locally, or the upsert found no user: remove it from the target
+	remoteID := state.RemoteID
+	if remoteID == "" && state.Display != "" {
+		existing, err := s.client.FindUser(s.ctx, state.Display)
+		if err != nil {
+			return false, err
+		}
+		if existing != nil {
+			remoteID = existing.ID
+		}
+	}
+	if remoteID == "" {
+		return true, nil
+	}
+
+	s.record(http.MethodDelete, "/Users/"+remoteID)
+	if s.target.DryRun {
+		return true, nil
+	}
+	if err := s.client.DeleteUser(s.ctx, remoteID); err != nil && !isSCIMNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// pushUser creates or replaces a user on the target and returns its remote ID. When the
+// user is new to the target its groups are enqueued so their memberships include it.
+func (s *scimSync) pushUser(user *domain.User, remoteID string) (string, error) {
+	resource := s.scimUser(user)
+
+	if remoteID != "" {
+		s.record(http.MethodPut, "/Users/"+remoteID)
+		if s.target.DryRun {
+			return remoteID, nil
+		}
+		_, err := s.client.ReplaceUser(s.ctx, remoteID, resource)
+		if err == nil {
+			return remoteID, nil
+		}
+		if !isSCIMNotFound(err) {
+			return "", err
+		}
+		// Deleted on the target: create it again below
+	}
+
+	existing, err := s.client.FindUser(s.ctx, user.Email)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		s.record(http.MethodPut, "/Users/"+existing.ID)
+		if s.target.DryRun {
+			return existing.ID, nil
+		}
+		if _, err := s.client.ReplaceUser(s.ctx, existing.ID, resource); err != nil {
+			return "", err
+		}
+		return existing.ID, nil
+	}
+
+	s.record(http.MethodPost, "/Users")
+	if s.target.DryRun {
+		return "", nil
+	}
+	created, err := s.client.CreateUser(s.ctx, resource)
+	if err != nil {
+		return "", err
+	}
+
+	if s.target.SyncGroups {
+		groups, err := s.uc.groupRepo.GetUserGroups(user.ID, domain.MembershipDirect)
+		if err != nil {
+			fmt.Printf("Warning: failed to load groups of user %s: %v\n", user.ID, err)
+		}
+		for _, group := range groups {
+			if group.IsSystem {
+				continue
+			}
+			if err := s.uc.stateRepo.Enqueue(s.target.ID, domain.SCIMResourceGroup, group.ID, group.Name, domain.SCIMOperationUpsert); err != nil {
+				fmt.Printf("Warning: failed to enqueue scim sync of group %s: %v\n", group.ID, err)
+			}
+		}
+	}
+
+	return created.ID, nil
+}
+
+func (s *scimSync) scimUser(user *domain.User) *scim.User {
+	active := user.Status == domain.UserStatusActive
+	resource := &scim.User{
+		Schemas:    []string{scim.SchemaUser},
+		ExternalID: user.ID.String(),
+		UserName:   user.Email,
+		Emails:     []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:     &active,
+	}
+	if user.FirstName != "" || user.LastName != "" {
+		resource.DisplayName = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		resource.Name = &scim.Name{Formatted: resource.DisplayName, GivenName: user.FirstName, FamilyName: user.LastName}
+	}
+	return resource
+}
+
+// group delivers a group state and reports whether the group was removed from the target.
+// Inactive groups are removed since membership in them grants nothing.
+func (s *scimSync) group(state *domain.SCIMSyncState) (bool, error) {
+	if state.Operation == domain.SCIMOperationUpsert && s.target.SyncGroups {
+		group, err := s.uc.groupRepo.GetByID(state.LocalID)
+		if err == nil && group.IsActive && !group.IsSystem {
+			remoteID, err := s.pushGroup(group, state.RemoteID)
+			if err != nil {
+				return false, err
+			}
+			if remoteID != state.RemoteID && remoteID != "" {
+				state.RemoteID = remoteID
+				if err := s.uc.stateRepo.SetRemoteID(s.target.ID, domain.SCIMResourceGroup, group.ID, remoteID); err != nil {
+					return false, err
+				}
+			}
+			return false, nil
+		}
+	}
+
+	remoteID := state.RemoteID
+	if remoteID == "" && state.Display != "" {
+		existing, err := s.client.FindGroup(s.ctx, state.Display)
+		if err != nil {
+			return false, err
+		}
+		if existing != nil {
+			remoteID = existing.ID
+		}
+	}
+	if remoteID == "" {
+		return true, nil
+	}
+
+	s.record(http.MethodDelete, "/Groups/"+remoteID)
+	if s.target.DryRun {
+		return true, nil
+	}
+	if err := s.client.DeleteGroup(s.ctx, remoteID); err != nil && !isSCIMNotFound(err) {
+		return false, err
+	}
+	return true, nil
+}
+
+// pushGroup creates or replaces a group with its full member list. Members not yet on
+// the target are pushed first.
+func (s *scimSync) pushGroup(group *domain.Group, remoteID string) (string, error) {
+	members, err := s.uc.groupRepo.GetMembers(group.ID, domain.MembershipDirect)
+	if err != nil {
+		return "", err
+	}
+
+	resource := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ExternalID:  group.ID.String(),
+		DisplayName: group.Name,
+		Members:     []scim.Member{},
+	}
+	for _, member := range members {
+		if member.IsSystem {
+			continue
+		}
+		memberID, err := s.memberID(member)
+		if err != nil {
+			return "", err
+		}
+		if memberID != "" {
+			resource.Members = append(resource.Members, scim.Member{Value: memberID, Display: member.Email})
+		}
+	}
+
+	if remoteID != "" {
+		s.record(http.MethodPut, "/Groups/"+remoteID)
+		if s.target.DryRun {
+			return remoteID, nil
+		}
+		_, err := s.client.ReplaceGroup(s.ctx, remoteID, resource)
+		if err == nil {
+			return remoteID, nil
+		}
+		if !isSCIMNotFound(err) {
+			return "", err
+		}
+	}
+
+	existing, err := s.client.FindGroup(s.ctx, group.Name)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		s.record(http.MethodPut, "/Groups/"+existing.ID)
+		if s.target.DryRun {
+			return existing.ID, nil
+		}
+		if _, err := s.client.ReplaceGroup(s.ctx, existing.ID, resource); err != nil {
+			return "", err
+		}
+		return existing.ID, nil
+	}
+
+	s.record(http.MethodPost, "/Groups")
+	if s.target.DryRun {
+		return "", nil
+	}
+	created, err := s.client.CreateGroup(s.ctx, resource)
+	if err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+// memberID returns the remote ID of a group member, pushing the user when it is not on
+// the target yet. In dry-run mode such members are only planned and have no ID.
+func (s *scimSync) memberID(user *domain.User) (string, error) {
+	state, err := s.uc.stateRepo.Get(s.target.ID, domain.SCIMResourceUser, user.ID)
+	if err == nil && state.RemoteID != "" && state.Operation == domain.SCIMOperationUpsert {
+		return state.RemoteID, nil
+	}
+
+	if err != nil {
+		// Track the user so later changes and reconciliation keep it in sync
+		if err := s.uc.stateRepo.Enqueue(s.target.ID, domain.SCIMResourceUser, user.ID, user.Email, domain.SCIMOperationUpsert); err != nil {
+			return "", err
+		}
+	}
+
+	remoteID, err := s.pushUser(user, "")
+	if err != nil {
+		return "", err
+	}
+	if remoteID != "" {
+		if err := s.uc.stateRepo.SetRemoteID(s.target.ID, domain.SCIMResourceUser, user.ID, remoteID); err != nil {
+			return "", err
+		}
+	}
+	return remoteID, nil
+}
@@ -0,0 +1,538 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/scim"
+)
+
+const testSCIMToken = "target-token"
+
+// fakeSCIMTarget is a SCIM service provider keeping users and groups in memory. It
+// records every request as "METHOD /path" and can be told to fail with a status.
+type fakeSCIMTarget struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	users    map[string]*scim.User
+	groups   map[string]*scim.Group
+	requests []string
+	failWith int
+	nextID   int
+}
+
+func newFakeSCIMTarget(t *testing.T) *fakeSCIMTarget {
+	t.Helper()
+
+	target := &fakeSCIMTarget{
+		users:  make(map[string]*scim.User),
+		groups: make(map[string]*scim.Group),
+	}
+	target.server = httptest.NewServer(http.HandlerFunc(target.serve))
+	t.Cleanup(target.server.Close)
+
+	return target
+}
+
+func (f *fakeSCIMTarget) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+
+	if r.Header.Get("Authorization") != "Bearer "+testSCIMToken {
+		writeSCIM(w, http.StatusUnauthorized, scim.NewError(http.StatusUnauthorized, "", "invalid token"))
+		return
+	}
+	if f.failWith != 0 {
+		writeSCIM(w, f.failWith, scim.NewError(f.failWith, "", "failure requested by test"))
+		return
+	}
+
+	resource, id, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch resource {
+	case "Users":
+		f.serveUsers(w, r, id)
+	case "Groups":
+		f.serveGroups(w, r, id)
+	default:
+		writeSCIM(w, http.StatusNotFound, scim.NewError(http.StatusNotFound, "", "unknown endpoint"))
+	}
+}
+
+func (f *fakeSCIMTarget) serveUsers(w http.ResponseWriter, r *http.Request, id string) {
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		filter, err := scim.ParseFilter(r.URL.Query().Get("filter"))
+		userName, ok := scim.EqualityValue(filter, "userName")
+		if err != nil || !ok {
+			writeSCIM(w, http.StatusBadRequest, scim.Errorf(scim.ErrorInvalidFilter, "unsupported filter"))
+			return
+		}
+		resources := []*scim.User{}
+		for _, user := range f.users {
+			if strings.EqualFold(user.UserName, userName) {
+				resources = append(resources, user)
+			}
+		}
+		writeSCIM(w, http.StatusOK, map[string]interface{}{"totalResults": len(resources), "Resources": resources})
+
+	case r.Method == http.MethodPost && id == "":
+		var user scim.User
+		json.NewDecoder(r.Body).Decode(&user)
+		f.nextID++
+		user.ID = fmt.Sprintf("u-%d", f.nextID)
+		f.users[user.ID] = &user
+		writeSCIM(w, http.StatusCreated, &user)
+
+	case r.Method == http.MethodPut && f.users[id] != nil:
+		var user scim.User
+		json.NewDecoder(r.Body).Decode(&user)
+		user.ID = id
+		f.users[id] = &user
+		writeSCIM(w, http.StatusOK, &user)
+
+	case r.Method == http.MethodDelete && f.users[id] != nil:
+		delete(f.users, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeSCIM(w, http.StatusNotFound, scim.NewError(http.StatusNotFound, "", "user not found"))
+	}
+}
+
+func (f *fakeSCIMTarget) serveGroups(w http.ResponseWriter, r *http.Request, id string) {
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		filter, err := scim.ParseFilter(r.URL.Query().Get("filter"))
+		displayName, ok := scim.EqualityValue(filter, "displayName")
+		if err != nil || !ok {
+			writeSCIM(w, http.StatusBadRequest, scim.Errorf(scim.ErrorInvalidFilter, "unsupported filter"))
+			return
+		}
+		resources := []*scim.Group{}
+		for _, group := range f.groups {
+			if group.DisplayName == displayName {
+				resources = append(resources, group)
+			}
+		}
+		writeSCIM(w, http.StatusOK, map[string]interface{}{"totalResults": len(resources), "Resources": resources})
+
+	case r.Method == http.MethodPost && id == "":
+		var group scim.Group
+		json.NewDecoder(r.Body).Decode(&group)
+		f.nextID++
+		group.ID = fmt.Sprintf("g-%d", f.nextID)
+		f.groups[group.ID] = &group
+		writeSCIM(w, http.StatusCreated, &group)
+
+	case r.Method == http.MethodPut && f.groups[id] != nil:
+		var group scim.Group
+		json.NewDecoder(r.Body).Decode(&group)
+		group.ID = id
+		f.groups[id] = &group
+		writeSCIM(w, http.StatusOK, &group)
+
+	case r.Method == http.MethodDelete && f.groups[id] != nil:
+		delete(f.groups, id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeSCIM(w, http.StatusNotFound, scim.NewError(http.StatusNotFound, "", "group not found"))
+	}
+}
+
+// takeRequests returns the requests received since the last call
+func (f *fakeSCIMTarget) takeRequests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func writeSCIM(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// fakeSCIMTargetRepository serves a fixed set of targets
+type fakeSCIMTargetRepository struct {
+	domain.SCIMTargetRepository
+	targets []*domain.SCIMTarget
+}
+
+func (r *fakeSCIMTargetRepository) GetByID(id uuid.UUID) (*domain.SCIMTarget, error) {
+	for _, target := range r.targets {
+		if target.ID == id {
+			return target, nil
+		}
+	}
+	return nil, fmt.Errorf("scim target not found")
+}
+
+func (r *fakeSCIMTargetRepository) List() ([]*domain.SCIMTarget, error) {
+	return r.targets, nil
+}
+
+type syncStateKey struct {
+	targetID     uuid.UUID
+	resourceType string
+	localID      uuid.UUID
+}
+
+// fakeSCIMSyncStateRepository keeps sync states in memory with the revision semantics of
+// the Postgres repository
+type fakeSCIMSyncStateRepository struct {
+	domain.SCIMSyncStateRepository
+	states map[syncStateKey]*domain.SCIMSyncState
+}
+
+func newFakeSCIMSyncStateRepository() *fakeSCIMSyncStateRepository {
+	return &fakeSCIMSyncStateRepository{states: make(map[syncStateKey]*domain.SCIMSyncState)}
+}
+
+func (r *fakeSCIMSyncStateRepository) Enqueue(targetID uuid.UUID, resourceType string, localID uuid.UUID, display, operation string) error {
+	key := syncStateKey{targetID, resourceType, localID}
+	state, ok := r.states[key]
+	if !ok {
+		state = &domain.SCIMSyncState{TargetID: targetID, ResourceType: resourceType, LocalID: localID}
+		r.states[key] = state
+	}
+	state.Display = display
+	state.Operation = operation
+	state.Status = domain.SCIMSyncPending
+	state.NextAttemptAt = time.Now()
+	state.Revision++
+	return nil
+}
+
+func (r *fakeSCIMSyncStateRepository) EnqueueDelete(resourceType string, localID uuid.UUID) error {
+	for key, state := range r.states {
+		if key.resourceType == resourceType && key.localID == localID {
+			state.Operation = domain.SCIMOperationDelete
+			state.Status = domain.SCIMSyncPending
+			state.NextAttemptAt = time.Now()
+			state.Revision++
+		}
+	}
+	return nil
+}
+
+func (r *fakeSCIMSyncStateRepository) Get(targetID uuid.UUID, resourceType string, localID uuid.UUID) (*domain.SCIMSyncState, error) {
+	state, ok := r.states[syncStateKey{targetID, resourceType, localID}]
+	if !ok {
+		return nil, fmt.Errorf("scim sync state not found")
+	}
+	copied := *state
+	return &copied, nil
+}
+
+func (r *fakeSCIMSyncStateRepository) ClaimDue(limit int, lease time.Duration) ([]*domain.SCIMSyncState, error) {
+	var due []*domain.SCIMSyncState
+	for _, state := range r.states {
+		if len(due) == limit {
+			break
+		}
+		if state.Status == domain.SCIMSyncPending && !state.NextAttemptAt.After(time.Now()) {
+			state.NextAttemptAt = time.Now().Add(lease)
+			copied := *state
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+func (r *fakeSCIMSyncStateRepository) SetRemoteID(targetID uuid.UUID, resourceType string, localID uuid.UUID, remoteID string) error {
+	if state, ok := r.states[syncStateKey{targetID, resourceType, localID}]; ok {
+		state.RemoteID = remoteID
+	}
+	return nil
+}
+
+func (r *fakeSCIMSyncStateRepository) Update(state *domain.SCIMSyncState) error {
+	key := syncStateKey{state.TargetID, state.ResourceType, state.LocalID}
+	if stored, ok := r.states[key]; ok && stored.Revision == state.Revision {
+		copied := *state
+		r.states[key] = &copied
+	}
+	return nil
+}
+
+func (r *fakeSCIMSyncStateRepository) Delete(state *domain.SCIMSyncState) error {
+	key := syncStateKey{state.TargetID, state.ResourceType, state.LocalID}
+	if stored, ok := r.states[key]; ok && stored.Revision == state.Revision {
+		delete(r.states, key)
+	}
+	return nil
+}
+
+type fakeSyncUserRepository struct {
+	domain.UserRepository
+	users map[uuid.UUID]*domain.User
+}
+
+func (r *fakeSyncUserRepository) GetByID(id uuid.UUID) (*domain.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, domain.ErrUserNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+type fakeSyncGroupRepository struct {
+	domain.GroupRepository
+	groups  map[uuid.UUID]*domain.Group
+	members map[uuid.UUID][]*domain.User
+}
+
+func (r *fakeSyncGroupRepository) GetByID(id uuid.UUID) (*domain.Group, error) {
+	group, ok := r.groups[id]
+	if !ok {
+		return nil, fmt.Errorf("group not found")
+	}
+	return group, nil
+}
+
+func (r *fakeSyncGroupRepository) GetMembers(groupID uuid.UUID, scope domain.MembershipScope) ([]*domain.User, error) {
+	return r.members[groupID], nil
+}
+
+func (r *fakeSyncGroupRepository) GetUserGroups(userID uuid.UUID, scope domain.MembershipScope) ([]*domain.Group, error) {
+	var groups []*domain.Group
+	for groupID, members := range r.members {
+		for _, member := range members {
+			if member.ID == userID {
+				groups = append(groups, r.groups[groupID])
+			}
+		}
+	}
+	return groups, nil
+}
+
+type syncFixture struct {
+	uc     *SCIMSyncUseCase
+	target *domain.SCIMTarget
+	remote *fakeSCIMTarget
+	states *fakeSCIMSyncStateRepository
+	users  *fakeSyncUserRepository
+	groups *fakeSyncGroupRepository
+}
+
+func newSyncFixture(t *testing.T) *syncFixture {
+	t.Helper()
+
+	remote := newFakeSCIMTarget(t)
+	target := &domain.SCIMTarget{
+		ID:         uuid.New(),
+		Name:       "app",
+		BaseURL:    remote.server.URL,
+		Token:      testSCIMToken,
+		Enabled:    true,
+		SyncGroups: true,
+	}
+	f := &syncFixture{
+		target: target,
+		remote: remote,
+		states: newFakeSCIMSyncStateRepository(),
+		users:  &fakeSyncUserRepository{users: make(map[uuid.UUID]*domain.User)},
+		groups: &fakeSyncGroupRepository{groups: make(map[uuid.UUID]*domain.Group), members: make(map[uuid.UUID][]*domain.User)},
+	}
+	f.uc = NewSCIMSyncUseCase(
+		&fakeSCIMTargetRepository{targets: []*domain.SCIMTarget{target}},
+		f.states,
+		f.users,
+		f.groups,
+		remote.server.Client(),
+		domain.SCIMSyncPolicy{MaxAttempts: 3, RetryBaseDelay: time.Minute, RetryMaxDelay: time.Hour},
+	)
+
+	return f
+}
+
+func (f *syncFixture) addUser(email string) *domain.User {
+	user := &domain.User{ID: uuid.New(), Email: email, FirstName: "Test", LastName: "User", Status: domain.UserStatusActive}
+	f.users.users[user.ID] = user
+	return user
+}
+
+func (f *syncFixture) process(t *testing.T) {
+	t.Helper()
+	if _, err := f.uc.ProcessDue(context.Background()); err != nil {
+		t.Fatalf("ProcessDue() error = %v", err)
+	}
+}
+
+func (f *syncFixture) state(t *testing.T, resourceType string, localID uuid.UUID) *domain.SCIMSyncState {
+	t.Helper()
+	state, err := f.states.Get(f.target.ID, resourceType, localID)
+	if err != nil {
+		t.Fatalf("no %s sync state for %s", resourceType, localID)
+	}
+	return state
+}
+
+func assertRequests(t *testing.T, got []string, want ...string) {
+	t.Helper()
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
+
+func TestSCIMSyncUserLifecycle(t *testing.T) {
+	f := newSyncFixture(t)
+	ctx := context.Background()
+	user := f.addUser("alice@example.com")
+
+	f.uc.UserChanged(ctx, user.ID)
+	f.process(t)
+	assertRequests(t, f.remote.takeRequests(), "GET /Users", "POST /Users")
+
+	state := f.state(t, domain.SCIMResourceUser, user.ID)
+	if state.Status != domain.SCIMSyncSynced || state.RemoteID != "u-1" {
+		t.Fatalf("state after create = %s/%q, want synced/u-1", state.Status, state.RemoteID)
+	}
+
+	user.FirstName = "Alicia"
+	f.uc.UserChanged(ctx, user.ID)
+	f.process(t)
+	assertRequests(t, f.remote.takeRequests(), "PUT /Users/u-1")
+	if got := f.remote.users["u-1"].Name.GivenName; got != "Alicia" {
+		t.Errorf("remote givenName = %q, want Alicia", got)
+	}
+
+	delete(f.users.users, user.ID)
+	f.uc.UserDeleted(ctx, user.ID)
+	f.process(t)
+	assertRequests(t, f.remote.takeRequests(), "DELETE /Users/u-1")
+	if _, err := f.states.Get(f.target.ID, domain.SCIMResourceUser, user.ID); err == nil {
+		t.Errorf("sync state kept after the user was removed from the target")
+	}
+}
+
+func TestSCIMSyncAdoptsExistingRemoteUser(t *testing.T) {
+	f := newSyncFixture(t)
+	f.remote.users["legacy-7"] = &scim.User{ID: "legacy-7", UserName: "bob@example.com"}
+	user := f.addUser("bob@example.com")
+
+	f.uc.UserChanged(context.Background(), user.ID)
+	f.process(t)
+
+	assertRequests(t, f.remote.takeRequests(), "GET /Users", "PUT /Users/legacy-7")
+	if state := f.state(t, domain.SCIMResourceUser, user.ID); state.RemoteID != "legacy-7" {
+		t.Errorf("remote ID = %q, want legacy-7", state.RemoteID)
+	}
+}
+
+func TestSCIMSyncGroupPushesMembersFirst(t *testing.T) {
+	f := newSyncFixture(t)
+	member := f.addUser("carol@example.com")
+	system := f.addUser("system@example.com")
+	system.IsSystem = true
+	group := &domain.Group{ID: uuid.New(), Name: "engineering", IsActive: true}
+	f.groups.groups[group.ID] = group
+	f.groups.members[group.ID] = []*domain.User{member, system}
+
+	f.uc.GroupChanged(context.Background(), group.ID)
+	f.process(t)
+
+	assertRequests(t, f.remote.takeRequests(), "GET /Users", "POST /Users", "GET /Groups", "POST /Groups")
+	remoteGroup := f.remote.groups["g-2"]
+	if remoteGroup == nil || len(remoteGroup.Members) != 1 || remoteGroup.Members[0].Value != "u-1" {
+		t.Fatalf("remote group = %+v, want one member u-1", remoteGroup)
+	}
+	if state := f.state(t, domain.SCIMResourceUser, member.ID); state.RemoteID != "u-1" {
+		t.Errorf("member remote ID = %q, want u-1", state.RemoteID)
+	}
+}
+
+func TestSCIMSyncSkipsSystemUsers(t *testing.T) {
+	f := newSyncFixture(t)
+	user := f.addUser("admin@example.com")
+	user.IsSystem = true
+
+	f.uc.UserChanged(context.Background(), user.ID)
+	f.process(t)
+
+	assertRequests(t, f.remote.takeRequests())
+}
+
+func TestSCIMSyncDryRun(t *testing.T) {
+	f := newSyncFixture(t)
+	f.target.DryRun = true
+	user := f.addUser("dave@example.com")
+
+	f.uc.UserChanged(context.Background(), user.ID)
+	f.process(t)
+
+	assertRequests(t, f.remote.takeRequests(), "GET /Users")
+	state := f.state(t, domain.SCIMResourceUser, user.ID)
+	if state.Status != domain.SCIMSyncDryRun || state.LastRequest != "POST /Users" {
+		t.Errorf("state = %s/%q, want dry_run/\"POST /Users\"", state.Status, state.LastRequest)
+	}
+	if len(f.remote.users) != 0 {
+		t.Errorf("dry run created %d remote users", len(f.remote.users))
+	}
+}
+
+func TestSCIMSyncFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		token      string
+		wantStatus string
+	}{
+		{name: "server error is retried", status: http.StatusServiceUnavailable, wantStatus: domain.SCIMSyncPending},
+		{name: "rate limit is retried", status: http.StatusTooManyRequests, wantStatus: domain.SCIMSyncPending},
+		{name: "bad request fails", status: http.StatusBadRequest, wantStatus: domain.SCIMSyncFailed},
+		{name: "conflict fails", status: http.StatusConflict, wantStatus: domain.SCIMSyncFailed},
+		{name: "rejected token fails", token: "wrong-token", wantStatus: domain.SCIMSyncFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSyncFixture(t)
+			f.remote.failWith = tt.status
+			if tt.token != "" {
+				f.target.Token = tt.token
+			}
+			user := f.addUser("erin@example.com")
+
+			f.uc.UserChanged(context.Background(), user.ID)
+			f.process(t)
+
+			state := f.state(t, domain.SCIMResourceUser, user.ID)
+			if state.Status != tt.wantStatus || state.Attempts != 1 || state.LastError == "" {
+				t.Fatalf("state = %s after %d attempts (%q), want %s after 1", state.Status, state.Attempts, state.LastError, tt.wantStatus)
+			}
+			if tt.wantStatus == domain.SCIMSyncPending && !state.NextAttemptAt.After(time.Now()) {
+				t.Errorf("retry is not delayed: next attempt at %s", state.NextAttemptAt)
+			}
+		})
+	}
+}
+
+func TestSCIMSyncRetryDelay(t *testing.T) {
+	uc := &SCIMSyncUseCase{policy: domain.SCIMSyncPolicy{RetryBaseDelay: time.Second, RetryMaxDelay: 10 * time.Second}}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, delay := range want {
+		if got := uc.retryDelay(i + 1); got != delay {
+			t.Errorf("retryDelay(%d) = %s, want %s", i+1, got, delay)
+		}
+	}
+}
@@ -0,0 +1,968 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/password"
+	"github.com/aras-services/aras-auth/pkg/scim"
+	"github.com/aras-services/aras-auth/pkg/securetoken"
+)
+
+const (
+	// scimDefaultCount and scimMaxResults bound the page size of SCIM queries
+	scimDefaultCount = 100
+	scimMaxResults   = 200
+	// scimScanBatch is the page size used when a filter is evaluated over all resources
+	scimScanBatch = 500
+)
+
+// SCIMUseCase serves SCIM 2.0 provisioning of users and groups on top of the user and
+// group repositories, and manages the bearer tokens SCIM clients authenticate with
+type SCIMUseCase struct {
+	userRepo         domain.UserRepository
+	groupRepo        domain.GroupRepository
+	tokenRepo        domain.SCIMTokenRepository
+	tokenService     domain.TokenService
+	providerRegistry domain.ProviderRegistry
+	baseURL          string
+}
+
+func NewSCIMUseCase(
+	userRepo domain.UserRepository,
+	groupRepo domain.GroupRepository,
+	tokenRepo domain.SCIMTokenRepository,
+	tokenService domain.TokenService,
+	providerRegistry domain.ProviderRegistry,
+	publicURL string,
+) *SCIMUseCase {
+	return &SCIMUseCase{
+		userRepo:         userRepo,
+		groupRepo:        groupRepo,
+		tokenRepo:        tokenRepo,
+		tokenService:     tokenService,
+		providerRegistry: providerRegistry,
+		baseURL:          strings.TrimRight(publicURL, "/") + "/scim/v2",
+	}
+}
+
+// SCIMListQuery holds the query parameters of a SCIM list request
+type SCIMListQuery struct {
+	Filter             string
+	StartIndex         int // 1-based
+	Count              int // negative when not requested
+	Attributes         []string
+	ExcludedAttributes []string
+}
+
+var errSCIMNotFound = scim.NewError(http.StatusNotFound, "", "resource not found")
+
+// CreateToken issues a SCIM bearer token. The plaintext token is only returned here.
+func (uc *SCIMUseCase) CreateToken(ctx context.Context, req *domain.CreateSCIMTokenRequest) (*domain.CreateSCIMTokenResponse, error) {
+	plaintext, err := securetoken.Generate(securetoken.DefaultLength)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &domain.SCIMToken{
+		ID:        uuid.New(),
+		Name:      req.Name,
+		TokenHash: securetoken.Hash(plaintext),
+		CreatedAt: time.Now(),
+	}
+	if err := uc.tokenRepo.Create(token); err != nil {
+		return nil, fmt.Errorf("failed to create scim token: %w", err)
+	}
+
+	return &domain.CreateSCIMTokenResponse{SCIMToken: token, Token: plaintext}, nil
+}
+
+func (uc *SCIMUseCase) ListTokens(ctx context.Context) ([]*domain.SCIMToken, error) {
+	return uc.tokenRepo.List()
+}
+
+func (uc *SCIMUseCase) DeleteToken(ctx context.Context, id uuid.UUID) error {
+	return uc.tokenRepo.Delete(id)
+}
+
+// Authenticate resolves a SCIM bearer token
+func (uc *SCIMUseCase) Authenticate(ctx context.Context, plaintext string) (*domain.SCIMToken, error) {
+	token, err := uc.tokenRepo.GetByTokenHash(securetoken.Hash(plaintext))
+	if err != nil {
+		return nil, fmt.Errorf("invalid scim token")
+	}
+
+	if err := uc.tokenRepo.UpdateLastUsed(token.ID); err != nil {
+		fmt.Printf("Warning: failed to update scim token %s: %v\n", token.ID, err)
+	}
+
+	return token, nil
+}
+
+func (uc *SCIMUseCase) ServiceProviderConfig() *scim.ServiceProviderConfig {
+	return &scim.ServiceProviderConfig{
+		Schemas:        []string{scim.SchemaServiceProviderConfig},
+		Patch:          scim.Supported{Supported: true},
+		Bulk:           scim.BulkSupport{Supported: false},
+		Filter:         scim.FilterSupport{Supported: true, MaxResults: scimMaxResults},
+		ChangePassword: scim.Supported{Supported: true},
+		Sort:           scim.Supported{Supported: false},
+		ETag:           scim.Supported{Supported: true},
+		AuthenticationSchemes: []scim.AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "Bearer Token",
+			Description: "SCIM token issued through /api/v1/admin/scim/tokens",
+			Primary:     true,
+		}},
+		Meta: &scim.Meta{ResourceType: "ServiceProviderConfig", Location: uc.baseURL + "/ServiceProviderConfig"},
+	}
+}
+
+func (uc *SCIMUseCase) Schemas() []scim.Schema {
+	schemas := []scim.Schema{scim.UserSchema(), scim.GroupSchema()}
+	for i := range schemas {
+		schemas[i].Meta = &scim.Meta{ResourceType: "Schema", Location: uc.baseURL + "/Schemas/" + schemas[i].ID}
+	}
+	return schemas
+}
+
+func (uc *SCIMUseCase) ResourceTypes() []scim.ResourceType {
+	return []scim.ResourceType{
+		{
+			Schemas:     []string{scim.SchemaResourceType},
+			ID:          "User",
+			Name:        "User",
+			Endpoint:    "/Users",
+			Description: "User Account",
+			Schema:      scim.SchemaUser,
+			Meta:        &scim.Meta{ResourceType: "ResourceType", Location: uc.baseURL + "/ResourceTypes/User"},
+		},
+		{
+			Schemas:     []string{scim.SchemaResourceType},
+			ID:          "Group",
+			Name:        "Group",
+			Endpoint:    "/Groups",
+			Description: "Group",
+			Schema:      scim.SchemaGroup,
+			Meta:        &scim.Meta{ResourceType: "ResourceType", Location: uc.baseURL + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+// ListUsers answers a user query. A `userName eq` filter is looked up directly; other
+// filters are evaluated over every user.
+func (uc *SCIMUseCase) ListUsers(ctx context.Context, query *SCIMListQuery) (*scim.ListResponse, error) {
+	filter, err := parseSCIMFilter(query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	withGroups := !excludesAttribute(query, "groups")
+
+	if filter != nil {
+		if email, ok := scim.EqualityValue(filter, "userName"); ok {
+			var users []*domain.User
+			if user, err := uc.userRepo.GetByEmail(email); err == nil {
+				users = append(users, user)
+			}
+			return uc.userPage(query, users, len(users), 0, withGroups)
+		}
+
+		var matched []*domain.User
+		err := uc.scanUsers(func(user *domain.User) error {
+			resource, err := uc.renderUser(user, withGroups)
+			if err != nil {
+				return err
+			}
+			object, err := scim.ToMap(resource)
+			if err != nil {
+				return err
+			}
+			if filter.Matches(object) {
+				matched = append(matched, user)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return uc.userPage(query, matched, len(matched), 0, withGroups)
+	}
+
+	total, err := uc.userRepo.Count()
+	if err != nil {
+		return nil, err
+	}
+	startIndex, count := pageBounds(query)
+	users, err := uc.userRepo.List(count, startIndex-1)
+	if err != nil {
+		return nil, err
+	}
+	return uc.userPage(query, users, total, startIndex-1, withGroups)
+}
+
+// userPage renders the page of users selected by the query. offset is the index of the
+// first element of users within the whole result.
+func (uc *SCIMUseCase) userPage(query *SCIMListQuery, users []*domain.User, total, offset int, withGroups bool) (*scim.ListResponse, error) {
+	startIndex, count := pageBounds(query)
+
+	from := startIndex - 1 - offset
+	if from > len(users) {
+		from = len(users)
+	}
+	to := from + count
+	if to > len(users) {
+		to = len(users)
+	}
+
+	response := newListResponse(total, startIndex)
+	for _, user := range users[from:to] {
+		resource, err := uc.renderUser(user, withGroups)
+		if err != nil {
+			return nil, err
+		}
+		object, err := scim.ToMap(resource)
+		if err != nil {
+			return nil, err
+		}
+		response.Resources = append(response.Resources, scim.Project(object, query.Attributes, query.ExcludedAttributes))
+	}
+	response.ItemsPerPage = len(response.Resources)
+
+	return response, nil
+}
+
+func (uc *SCIMUseCase) scanUsers(visit func(user *domain.User) error) error {
+	for offset := 0; ; offset += scimScanBatch {
+		users, err := uc.userRepo.List(scimScanBatch, offset)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := visit(user); err != nil {
+				return err
+			}
+		}
+		if len(users) < scimScanBatch {
+			return nil
+		}
+	}
+}
+
+func (uc *SCIMUseCase) GetUser(ctx context.Context, id string) (*scim.User, error) {
+	user, err := uc.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.renderUser(user, true)
+}
+
+func (uc *SCIMUseCase) CreateUser(ctx context.Context, resource *scim.User) (*scim.User, error) {
+	email, err := scimUserEmail(resource)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.userRepo.GetByEmail(email); err == nil {
+		return nil, scim.NewError(http.StatusConflict, scim.ErrorUniqueness, fmt.Sprintf("user %s already exists", email))
+	}
+
+	// The provisioning system vouches for the address, and sets a password only when
+	// users should sign in locally
+	now := time.Now()
+	user := &domain.User{
+		ID:                uuid.New(),
+		Email:             email,
+		PasswordHash:      password.Unusable,
+		Status:            domain.UserStatusActive,
+		EmailVerified:     true,
+		PasswordChangedAt: now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	applySCIMUser(user, resource)
+
+	if resource.Password != "" {
+		if user.PasswordHash, err = hashSCIMPassword(resource.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := uc.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return uc.GetUser(ctx, user.ID.String())
+}
+
+// ReplaceUser applies a full user representation (PUT). version is the If-Match header.
+func (uc *SCIMUseCase) ReplaceUser(ctx context.Context, id string, resource *scim.User, version string) (*scim.User, error) {
+	user, err := uc.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSCIMVersion(userVersion(user), version); err != nil {
+		return nil, err
+	}
+
+	if err := uc.updateUser(ctx, user, resource); err != nil {
+		return nil, err
+	}
+
+	return uc.GetUser(ctx, id)
+}
+
+// PatchUser applies PATCH operations to a user
+func (uc *SCIMUseCase) PatchUser(ctx context.Context, id string, patch *scim.PatchRequest, version string) (*scim.User, error) {
+	user, err := uc.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSCIMVersion(userVersion(user), version); err != nil {
+		return nil, err
+	}
+
+	current, err := uc.renderUser(user, false)
+	if err != nil {
+		return nil, err
+	}
+	object, err := scim.ToMap(current)
+	if err != nil {
+		return nil, err
+	}
+	ensureMultiValued(object, "emails")
+
+	if err := scim.ApplyPatch(object, patch.Operations); err != nil {
+		return nil, err
+	}
+
+	// Some clients send booleans as strings, e.g. "active": "False"
+	for key, value := range object {
+		if s, ok := value.(string); ok && strings.EqualFold(key, "active") {
+			if active, err := strconv.ParseBool(s); err == nil {
+				object[key] = active
+			}
+		}
+	}
+
+	var patched scim.User
+	if err := scim.FromMap(object, &patched); err != nil {
+		return nil, err
+	}
+
+	if err := uc.updateUser(ctx, user, &patched); err != nil {
+		return nil, err
+	}
+
+	return uc.GetUser(ctx, id)
+}
+
+// DeleteUser soft-deletes a user and revokes its sessions
+func (uc *SCIMUseCase) DeleteUser(ctx context.Context, id, version string) error {
+	user, err := uc.getUser(id)
+	if err != nil {
+		return err
+	}
+	if err := checkSCIMVersion(userVersion(user), version); err != nil {
+		return err
+	}
+	if user.IsSystem {
+		return scim.Errorf(scim.ErrorMutability, "system users cannot be deleted")
+	}
+
+	if err := uc.userRepo.Delete(user.ID); err != nil {
+		return err
+	}
+	if err := uc.tokenService.RevokeAllUserTokens(user.ID); err != nil {
+		fmt.Printf("Warning: failed to revoke tokens of deleted user %s: %v\n", user.ID, err)
+	}
+
+	return nil
+}
+
+// updateUser applies a user representation and persists the changes. System users cannot
+// be changed. Deactivated users lose their sessions.
+func (uc *SCIMUseCase) updateUser(ctx context.Context, user *domain.User, resource *scim.User) error {
+	if user.IsSystem {
+		return scim.NewError(http.StatusForbidden, scim.ErrorMutability, "system users cannot be modified")
+	}
+
+	email, err := scimUserEmail(resource)
+	if err != nil {
+		return err
+	}
+
+	emailChanged := !strings.EqualFold(email, user.Email)
+	if emailChanged {
+		if existing, err := uc.userRepo.GetByEmail(email); err == nil && existing.ID != user.ID {
+			return scim.NewError(http.StatusConflict, scim.ErrorUniqueness, fmt.Sprintf("user %s already exists", email))
+		}
+	}
+
+	// The password is checked before anything is written so that a rejected password
+	// leaves the user unchanged
+	var provider domain.PasswordProvider
+	var passwordHash string
+	if resource.Password != "" {
+		if provider, passwordHash, err = uc.hashPassword(ctx, user.ID, resource.Password); err != nil {
+			return err
+		}
+	}
+
+	// The provisioning system vouches for a new address, as on creation
+	if emailChanged {
+		user.Email = email
+		user.EmailVerified = true
+	}
+
+	wasActive := user.Status == domain.UserStatusActive
+	applySCIMUser(user, resource)
+	if err := uc.userRepo.UpdateAccount(user, passwordHash); err != nil {
+		return err
+	}
+
+	if passwordHash != "" {
+		if err := provider.RecordPasswordHistory(ctx, user.ID, passwordHash); err != nil {
+			fmt.Printf("Warning: failed to record password history of user %s: %v\n", user.ID, err)
+		}
+	}
+
+	if wasActive && user.Status != domain.UserStatusActive {
+		if err := uc.tokenService.RevokeAllUserTokens(user.ID); err != nil {
+			fmt.Printf("Warning: failed to revoke tokens of deactivated user %s: %v\n", user.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// hashPassword checks and hashes a password through the default provider, like
+// ChangePassword and ResetPassword, so the password policy and reuse history apply to
+// SCIM clients too. The caller stores the hash along with the other changes.
+func (uc *SCIMUseCase) hashPassword(ctx context.Context, userID uuid.UUID, newPassword string) (domain.PasswordProvider, string, error) {
+	provider, ok := uc.providerRegistry.GetDefaultProvider().(domain.PasswordProvider)
+	if !ok {
+		return nil, "", fmt.Errorf("no identity provider available for passwords")
+	}
+
+	passwordHash, err := provider.HashNewPassword(ctx, userID, newPassword)
+	if err != nil {
+		return nil, "", scim.Errorf(scim.ErrorInvalidValue, "%s", err.Error())
+	}
+
+	return provider, passwordHash, nil
+}
+
+// applySCIMUser copies the mutable attributes of a representation onto user. The email
+// is handled by the caller.
+func applySCIMUser(user *domain.User, resource *scim.User) {
+	user.FirstName, user.LastName = "", ""
+	if resource.Name != nil {
+		user.FirstName = resource.Name.GivenName
+		user.LastName = resource.Name.FamilyName
+	}
+
+	if resource.Active != nil {
+		switch {
+		case *resource.Active:
+			user.Status = domain.UserStatusActive
+		case user.Status == domain.UserStatusActive:
+			user.Status = domain.UserStatusInactive
+		}
+	}
+}
+
+func (uc *SCIMUseCase) getUser(id string) (*domain.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errSCIMNotFound
+	}
+
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, errSCIMNotFound
+	}
+
+	return user, nil
+}
+
+func (uc *SCIMUseCase) renderUser(user *domain.User, withGroups bool) (*scim.User, error) {
+	active := user.Status == domain.UserStatusActive
+	created, modified := user.CreatedAt, user.UpdatedAt
+
+	resource := &scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          user.ID.String(),
+		UserName:    user.Email,
+		DisplayName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+		Emails:      []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      &created,
+			LastModified: &modified,
+			Location:     uc.baseURL + "/Users/" + user.ID.String(),
+			Version:      userVersion(user),
+		},
+	}
+	if user.FirstName != "" || user.LastName != "" {
+		resource.Name = &scim.Name{
+			Formatted:  resource.DisplayName,
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+		}
+	}
+
+	if withGroups {
+		groups, err := uc.groupRepo.GetUserGroups(user.ID, domain.MembershipDirect)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			resource.Groups = append(resource.Groups, scim.GroupRef{
+				Value:   group.ID.String(),
+				Ref:     uc.baseURL + "/Groups/" + group.ID.String(),
+				Display: group.Name,
+			})
+		}
+	}
+
+	return resource, nil
+}
+
+// ListGroups answers a group query; filters are evaluated over every group
+func (uc *SCIMUseCase) ListGroups(ctx context.Context, query *SCIMListQuery) (*scim.ListResponse, error) {
+	filter, err := parseSCIMFilter(query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	withMembers := !excludesAttribute(query, "members")
+	startIndex, count := pageBounds(query)
+
+	if filter == nil {
+		total, err := uc.groupRepo.Count()
+		if err != nil {
+			return nil, err
+		}
+		groups, err := uc.groupRepo.List(count, startIndex-1)
+		if err != nil {
+			return nil, err
+		}
+		return uc.groupPage(query, groups, total, startIndex-1, withMembers)
+	}
+
+	var matched []*domain.Group
+	for offset := 0; ; offset += scimScanBatch {
+		groups, err := uc.groupRepo.List(scimScanBatch, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			resource, err := uc.renderGroup(group, withMembers)
+			if err != nil {
+				return nil, err
+			}
+			object, err := scim.ToMap(resource)
+			if err != nil {
+				return nil, err
+			}
+			if filter.Matches(object) {
+				matched = append(matched, group)
+			}
+		}
+		if len(groups) < scimScanBatch {
+			break
+		}
+	}
+
+	return uc.groupPage(query, matched, len(matched), 0, withMembers)
+}
+
+func (uc *SCIMUseCase) groupPage(query *SCIMListQuery, groups []*domain.Group, total, offset int, withMembers bool) (*scim.ListResponse, error) {
+	startIndex, count := pageBounds(query)
+
+	from := startIndex - 1 - offset
+	if from > len(groups) {
+		from = len(groups)
+	}
+	to := from + count
+	if to > len(groups) {
+		to = len(groups)
+	}
+
+	response := newListResponse(total, startIndex)
+	for _, group := range groups[from:to] {
+		resource, err := uc.renderGroup(group, withMembers)
+		if err != nil {
+			return nil, err
+		}
+		object, err := scim.ToMap(resource)
+		if err != nil {
+			return nil, err
+		}
+		response.Resources = append(response.Resources, scim.Project(object, query.Attributes, query.ExcludedAttributes))
+	}
+	response.ItemsPerPage = len(response.Resources)
+
+	return response, nil
+}
+
+func (uc *SCIMUseCase) GetGroup(ctx context.Context, id string) (*scim.Group, error) {
+	group, err := uc.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.renderGroup(group, true)
+}
+
+func (uc *SCIMUseCase) CreateGroup(ctx context.Context, resource *scim.Group) (*scim.Group, error) {
+	if strings.TrimSpace(resource.DisplayName) == "" {
+		return nil, scim.Errorf(scim.ErrorInvalidValue, "displayName is required")
+	}
+	memberIDs, err := uc.resolveMembers(resource.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	group := &domain.Group{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(resource.DisplayName),
+		IsActive:  true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := uc.groupRepo.Create(group); err != nil {
+		return nil, scim.NewError(http.StatusConflict, scim.ErrorUniqueness, fmt.Sprintf("failed to create group: %v", err))
+	}
+
+	if err := uc.syncMembers(group.ID, nil, memberIDs); err != nil {
+		return nil, err
+	}
+
+	return uc.GetGroup(ctx, group.ID.String())
+}
+
+// ReplaceGroup applies a full group representation (PUT), including its member list
+func (uc *SCIMUseCase) ReplaceGroup(ctx context.Context, id string, resource *scim.Group, version string) (*scim.Group, error) {
+	group, err := uc.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := uc.renderGroup(group, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSCIMVersion(current.Meta.Version, version); err != nil {
+		return nil, err
+	}
+
+	if err := uc.updateGroup(group, current, resource); err != nil {
+		return nil, err
+	}
+
+	return uc.GetGroup(ctx, id)
+}
+
+// PatchGroup applies PATCH operations to a group, typically member additions and removals
+func (uc *SCIMUseCase) PatchGroup(ctx context.Context, id string, patch *scim.PatchRequest, version string) (*scim.Group, error) {
+	group, err := uc.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	current, err := uc.renderGroup(group, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSCIMVersion(current.Meta.Version, version); err != nil {
+		return nil, err
+	}
+
+	object, err := scim.ToMap(current)
+	if err != nil {
+		return nil, err
+	}
+	ensureMultiValued(object, "members")
+
+	if err := scim.ApplyPatch(object, patch.Operations); err != nil {
+		return nil, err
+	}
+
+	var patched scim.Group
+	if err := scim.FromMap(object, &patched); err != nil {
+		return nil, err
+	}
+
+	if err := uc.updateGroup(group, current, &patched); err != nil {
+		return nil, err
+	}
+
+	return uc.GetGroup(ctx, id)
+}
+
+func (uc *SCIMUseCase) DeleteGroup(ctx context.Context, id, version string) error {
+	group, err := uc.getGroup(id)
+	if err != nil {
+		return err
+	}
+	current, err := uc.renderGroup(group, true)
+	if err != nil {
+		return err
+	}
+	if err := checkSCIMVersion(current.Meta.Version, version); err != nil {
+		return err
+	}
+	if group.IsSystem {
+		return scim.Errorf(scim.ErrorMutability, "system groups cannot be deleted")
+	}
+
+	return uc.groupRepo.Delete(group.ID)
+}
+
+func (uc *SCIMUseCase) updateGroup(group *domain.Group, current, resource *scim.Group) error {
+	name := strings.TrimSpace(resource.DisplayName)
+	if name == "" {
+		return scim.Errorf(scim.ErrorInvalidValue, "displayName is required")
+	}
+
+	memberIDs, err := uc.resolveMembers(resource.Members)
+	if err != nil {
+		return err
+	}
+
+	if name != group.Name {
+		group.Name = name
+		if err := uc.groupRepo.Update(group); err != nil {
+			return scim.NewError(http.StatusConflict, scim.ErrorUniqueness, fmt.Sprintf("failed to update group: %v", err))
+		}
+	}
+
+	var currentIDs []uuid.UUID
+	for _, member := range current.Members {
+		currentIDs = append(currentIDs, uuid.MustParse(member.Value))
+	}
+
+	return uc.syncMembers(group.ID, currentIDs, memberIDs)
+}
+
+// resolveMembers validates member references; only users can be members
+func (uc *SCIMUseCase) resolveMembers(members []scim.Member) ([]uuid.UUID, error) {
+	var memberIDs []uuid.UUID
+	for _, member := range members {
+		if member.Type != "" && !strings.EqualFold(member.Type, "User") {
+			return nil, scim.Errorf(scim.ErrorInvalidValue, "members must be users")
+		}
+
+		userID, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, scim.Errorf(scim.ErrorInvalidValue, "invalid member %q", member.Value)
+		}
+		if _, err := uc.userRepo.GetByID(userID); err != nil {
+			return nil, scim.Errorf(scim.ErrorInvalidValue, "member %s not found", member.Value)
+		}
+		memberIDs = append(memberIDs, userID)
+	}
+	return memberIDs, nil
+}
+
+func (uc *SCIMUseCase) syncMembers(groupID uuid.UUID, current, wanted []uuid.UUID) error {
+	isWanted := make(map[uuid.UUID]bool)
+	for _, userID := range wanted {
+		isWanted[userID] = true
+	}
+	isMember := make(map[uuid.UUID]bool)
+	for _, userID := range current {
+		isMember[userID] = true
+		if !isWanted[userID] {
+			if err := uc.groupRepo.RemoveMember(groupID, userID); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, userID := range wanted {
+		if !isMember[userID] {
+			if err := uc.groupRepo.AddMember(groupID, userID); err != nil {
+				return err
+			}
+			isMember[userID] = true
+		}
+	}
+
+	return nil
+}
+
+func (uc *SCIMUseCase) getGroup(id string) (*domain.Group, error) {
+	groupID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, errSCIMNotFound
+	}
+
+	group, err := uc.groupRepo.GetByID(groupID)
+	if err != nil {
+		return nil, errSCIMNotFound
+	}
+
+	return group, nil
+}
+
+// renderGroup builds the SCIM representation of a group. Membership changes do not touch
+// the group row, so the version also covers the member list and is only set with members.
+func (uc *SCIMUseCase) renderGroup(group *domain.Group, withMembers bool) (*scim.Group, error) {
+	created, modified := group.CreatedAt, group.UpdatedAt
+
+	resource := &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          group.ID.String(),
+		DisplayName: group.Name,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      &created,
+			LastModified: &modified,
+			Location:     uc.baseURL + "/Groups/" + group.ID.String(),
+		},
+	}
+
+	if withMembers {
+		members, err := uc.groupRepo.GetMembers(group.ID, domain.MembershipDirect)
+		if err != nil {
+			return nil, err
+		}
+		memberIDs := make([]string, 0, len(members))
+		for _, member := range members {
+			resource.Members = append(resource.Members, scim.Member{
+				Value:   member.ID.String(),
+				Ref:     uc.baseURL + "/Users/" + member.ID.String(),
+				Display: member.Email,
+				Type:    "User",
+			})
+			memberIDs = append(memberIDs, member.ID.String())
+		}
+		sort.Strings(memberIDs)
+
+		sum := sha256.Sum256([]byte(group.UpdatedAt.UTC().Format(time.RFC3339Nano) + "|" + strings.Join(memberIDs, ",")))
+		resource.Meta.Version = fmt.Sprintf(`W/"%x"`, sum[:8])
+	}
+
+	return resource, nil
+}
+
+func userVersion(user *domain.User) string {
+	return fmt.Sprintf(`W/"%x"`, user.UpdatedAt.UnixNano())
+}
+
+// checkSCIMVersion enforces an If-Match precondition against the current version
+func checkSCIMVersion(current, ifMatch string) error {
+	ifMatch = strings.TrimSpace(ifMatch)
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(candidate) == current {
+			return nil
+		}
+	}
+
+	return scim.NewError(http.StatusPreconditionFailed, "", "resource has been modified")
+}
+
+func parseSCIMFilter(filter string) (scim.Filter, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+
+	parsed, err := scim.ParseFilter(filter)
+	if err != nil {
+		var scimErr *scim.Error
+		if errors.As(err, &scimErr) {
+			return nil, scimErr
+		}
+		return nil, scim.Errorf(scim.ErrorInvalidFilter, "%v", err)
+	}
+	return parsed, nil
+}
+
+func pageBounds(query *SCIMListQuery) (startIndex, count int) {
+	startIndex, count = query.StartIndex, query.Count
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = scimDefaultCount
+	}
+	if count > scimMaxResults {
+		count = scimMaxResults
+	}
+	return startIndex, count
+}
+
+func newListResponse(total, startIndex int) *scim.ListResponse {
+	return &scim.ListResponse{
+		Schemas:      []string{scim.MessageListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		Resources:    []interface{}{},
+	}
+}
+
+func excludesAttribute(query *SCIMListQuery, name string) bool {
+	for _, excluded := range query.ExcludedAttributes {
+		if strings.EqualFold(excluded, name) {
+			return true
+		}
+	}
+	if len(query.Attributes) == 0 {
+		return false
+	}
+	for _, attribute := range query.Attributes {
+		if strings.EqualFold(scim.ParseAttributePath(attribute)[0], name) {
+			return false
+		}
+	}
+	return true
+}
+
+// ensureMultiValued makes an absent multi-valued attribute an empty list so that PATCH
+// add operations append to it
+func ensureMultiValued(object map[string]interface{}, name string) {
+	if scim.Lookup(object, name) == nil {
+		object[name] = []interface{}{}
+	}
+}
+
+// scimUserEmail returns the email address of a user representation: userName when it is
+// an address, otherwise the primary email
+func scimUserEmail(resource *scim.User) (string, error) {
+	for _, candidate := range []string{resource.UserName, resource.PrimaryEmail()} {
+		candidate = strings.TrimSpace(candidate)
+		if address, err := mail.ParseAddress(candidate); err == nil && address.Address == candidate {
+			return candidate, nil
+		}
+	}
+
+	return "", scim.Errorf(scim.ErrorInvalidValue, "userName or a primary email must be an email address")
+}
+
+func hashSCIMPassword(plaintext string) (string, error) {
+	if !password.IsValidPassword(plaintext) {
+		return "", scim.Errorf(scim.ErrorInvalidValue, "password does not meet requirements")
+	}
+
+	passwordHash, err := password.HashPassword(plaintext)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return passwordHash, nil
+}
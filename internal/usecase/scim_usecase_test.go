@@ -0,0 +1,109 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/aras-services/aras-auth/internal/domain"
+	"github.com/aras-services/aras-auth/pkg/scim"
+)
+
+// fakeAccountUserRepository records UpdateAccount calls; the separate UpdateEmail,
+// UpdatePassword and Update writes are left unimplemented so using them fails the test
+type fakeAccountUserRepository struct {
+	domain.UserRepository
+	updates []domain.User
+	hashes  []string
+}
+
+func (r *fakeAccountUserRepository) GetByEmail(email string) (*domain.User, error) {
+	return nil, domain.ErrUserNotFound
+}
+
+func (r *fakeAccountUserRepository) UpdateAccount(user *domain.User, passwordHash string) error {
+	r.updates = append(r.updates, *user)
+	r.hashes = append(r.hashes, passwordHash)
+	return nil
+}
+
+// fakeHashingProvider hashes passwords by prefixing them, rejecting those in rejected
+type fakeHashingProvider struct {
+	domain.IdentityProvider
+	rejected map[string]bool
+	recorded []string
+}
+
+func (p *fakeHashingProvider) HashNewPassword(ctx context.Context, userID uuid.UUID, newPassword string) (string, error) {
+	if p.rejected[newPassword] {
+		return "", errors.New("password was used recently")
+	}
+	return "hash:" + newPassword, nil
+}
+
+func (p *fakeHashingProvider) RecordPasswordHistory(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	p.recorded = append(p.recorded, passwordHash)
+	return nil
+}
+
+func TestUpdateUserWritesOnce(t *testing.T) {
+	const reused = "Reused-Password-1!"
+
+	tests := []struct {
+		name         string
+		email        string
+		password     string
+		wantErr      bool
+		wantHash     string
+		wantVerified bool
+	}{
+		{name: "profile only", email: "alice@example.com"},
+		{name: "new email", email: "alice@corp.example.com", wantVerified: true},
+		{name: "new email and password", email: "alice@corp.example.com", password: "Fresh-Password-2!", wantHash: "hash:Fresh-Password-2!", wantVerified: true},
+		{name: "rejected password", email: "alice@corp.example.com", password: reused, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeAccountUserRepository{}
+			provider := &fakeHashingProvider{rejected: map[string]bool{reused: true}}
+			uc := &SCIMUseCase{
+				userRepo:         users,
+				tokenService:     &fakeTokenService{},
+				providerRegistry: &fakeProviderRegistry{providers: []domain.IdentityProvider{provider}},
+			}
+			user := &domain.User{ID: uuid.New(), Email: "alice@example.com", Status: domain.UserStatusActive}
+			resource := &scim.User{UserName: tt.email, Password: tt.password, Name: &scim.Name{GivenName: "Alice"}}
+
+			err := uc.updateUser(context.Background(), user, resource)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("updateUser() error = nil, want the password rejected")
+				}
+				if len(users.updates) != 0 {
+					t.Errorf("updateUser() wrote %d times after rejecting the password", len(users.updates))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("updateUser() error = %v", err)
+			}
+
+			if len(users.updates) != 1 {
+				t.Fatalf("UpdateAccount() called %d times, want once", len(users.updates))
+			}
+			written := users.updates[0]
+			if written.Email != tt.email || written.FirstName != "Alice" || written.EmailVerified != tt.wantVerified {
+				t.Errorf("UpdateAccount() user = %+v, want email %s, first name Alice, verified %v", written, tt.email, tt.wantVerified)
+			}
+			if users.hashes[0] != tt.wantHash {
+				t.Errorf("UpdateAccount() password hash = %q, want %q", users.hashes[0], tt.wantHash)
+			}
+			if tt.wantHash != "" && (len(provider.recorded) != 1 || provider.recorded[0] != tt.wantHash) {
+				t.Errorf("recorded history %v, want the new hash", provider.recorded)
+			}
+		})
+	}
+}
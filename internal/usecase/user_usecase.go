@@ -10,12 +10,14 @@ import (
 )
 
 type UserUseCase struct {
-	userRepo domain.UserRepository
+	userRepo       domain.UserRepository
+	lockoutService domain.LockoutService
 }
 
-func NewUserUseCase(userRepo domain.UserRepository) *UserUseCase {
+func NewUserUseCase(userRepo domain.UserRepository, lockoutService domain.LockoutService) *UserUseCase {
 	return &UserUseCase{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		lockoutService: lockoutService,
 	}
 }
 
@@ -96,10 +98,32 @@ func (uc *UserUseCase) DeleteUser(ctx context.Context, userID uuid.UUID) error {
 	}
 
 	// Delete user
-	return uc.userRepo.Delete(userID)
+	if err := uc.userRepo.Delete(userID); err != nil {
+		return err
+	}
+
+	return nil
 }
 
 func (uc *UserUseCase) GetCurrentUser(ctx context.Context, userID uuid.UUID) (*domain.User, error) {
 	return uc.userRepo.GetByID(userID)
 }
 
+func (uc *UserUseCase) GetLockoutStatus(ctx context.Context, userID uuid.UUID) (*domain.LoginAttempts, error) {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	return uc.lockoutService.Status(ctx, user.Email)
+}
+
+// UnlockUser clears failed-login counters and any active lockout for a user
+func (uc *UserUseCase) UnlockUser(ctx context.Context, userID uuid.UUID) error {
+	user, err := uc.userRepo.GetByID(userID)
+	if err != nil {
+		return fmt.Errorf("user not found: %w", err)
+	}
+
+	return uc.lockoutService.Unlock(ctx, user.Email)
+}
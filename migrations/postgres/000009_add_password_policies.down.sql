@@ -0,0 +1,4 @@
+-- Rollback script
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS password_history;
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
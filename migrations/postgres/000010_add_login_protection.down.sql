@@ -0,0 +1,3 @@
+-- Rollback script
+DROP TABLE IF EXISTS rate_limits;
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,5 @@
+-- Rollback script
+DELETE FROM permissions WHERE resource = 'providers';
+DROP TABLE IF EXISTS passwordless_challenges;
+ALTER TABLE groups DROP COLUMN IF EXISTS passwordless_enabled;
+ALTER TABLE providers DROP COLUMN IF EXISTS passwordless_enabled;
@@ -0,0 +1,35 @@
+-- Allow passwordless sign-in per provider and per group
+ALTER TABLE providers
+    ADD COLUMN passwordless_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE groups
+    ADD COLUMN passwordless_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create passwordless_challenges table (emailed sign-in links and codes)
+CREATE TABLE IF NOT EXISTS passwordless_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(100) NOT NULL,
+    token_hash VARCHAR(255) NOT NULL UNIQUE,
+    code_hash VARCHAR(255) NOT NULL,
+    binding_hash VARCHAR(255) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_passwordless_challenges_user_id ON passwordless_challenges(user_id);
+CREATE INDEX IF NOT EXISTS idx_passwordless_challenges_expires_at ON passwordless_challenges(expires_at);
+
+-- Provider administration permissions
+INSERT INTO permissions (resource, action, description, is_system) VALUES
+('providers', 'read', 'View identity provider configuration', TRUE),
+('providers', 'update', 'Update identity provider configuration', TRUE)
+ON CONFLICT (resource, action) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.resource = 'providers'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,4 @@
+-- Rollback script
+DROP TABLE IF EXISTS email_changes;
+DROP INDEX IF EXISTS idx_users_email_active;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
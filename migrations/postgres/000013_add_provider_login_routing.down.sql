@@ -0,0 +1,5 @@
+-- Rollback script
+ALTER TABLE providers
+    DROP COLUMN IF EXISTS fallback_on,
+    DROP COLUMN IF EXISTS login_priority,
+    DROP COLUMN IF EXISTS domains;
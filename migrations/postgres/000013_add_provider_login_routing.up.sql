@@ -0,0 +1,5 @@
+-- Login routing: home-realm discovery by email domain and an ordered fallback chain
+ALTER TABLE providers
+    ADD COLUMN domains TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN login_priority INTEGER,
+    ADD COLUMN fallback_on TEXT[] NOT NULL DEFAULT '{not_found}';
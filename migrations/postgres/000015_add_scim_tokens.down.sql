@@ -0,0 +1,3 @@
+-- Rollback script
+DELETE FROM permissions WHERE resource = 'scim' AND action = 'update';
+DROP TABLE IF EXISTS scim_tokens;
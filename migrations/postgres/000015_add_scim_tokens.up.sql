@@ -0,0 +1,19 @@
+-- Create scim_tokens table (bearer credentials of SCIM provisioning clients)
+CREATE TABLE IF NOT EXISTS scim_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    token_hash VARCHAR(255) NOT NULL UNIQUE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Permission to manage SCIM credentials, granted to administrators
+INSERT INTO permissions (resource, action, description) VALUES
+('scim', 'update', 'Manage SCIM provisioning credentials')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.resource = 'scim' AND p.action = 'update'
+ON CONFLICT DO NOTHING;
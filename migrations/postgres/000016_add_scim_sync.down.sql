@@ -0,0 +1,3 @@
+-- Rollback script
+DROP TABLE IF EXISTS scim_sync_state;
+DROP TABLE IF EXISTS scim_targets;
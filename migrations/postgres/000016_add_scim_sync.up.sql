@@ -0,0 +1,35 @@
+-- Create scim_targets table (downstream applications users and groups are pushed to over SCIM)
+CREATE TABLE IF NOT EXISTS scim_targets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL UNIQUE,
+    base_url VARCHAR(2048) NOT NULL,
+    token TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    sync_groups BOOLEAN NOT NULL DEFAULT TRUE,
+    last_reconciled_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create scim_sync_state table (per target outbox and mapping of local to remote resources)
+CREATE TABLE IF NOT EXISTS scim_sync_state (
+    target_id UUID NOT NULL REFERENCES scim_targets(id) ON DELETE CASCADE,
+    resource_type VARCHAR(10) NOT NULL, -- 'User', 'Group'
+    local_id UUID NOT NULL,
+    display VARCHAR(255) NOT NULL DEFAULT '',
+    remote_id VARCHAR(255) NOT NULL DEFAULT '',
+    operation VARCHAR(10) NOT NULL, -- 'upsert', 'delete'
+    status VARCHAR(20) NOT NULL, -- 'pending', 'synced', 'failed', 'dry_run'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    last_request TEXT NOT NULL DEFAULT '',
+    revision INTEGER NOT NULL DEFAULT 1,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    synced_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (target_id, resource_type, local_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scim_sync_state_due ON scim_sync_state(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_scim_sync_state_local_id ON scim_sync_state(resource_type, local_id);
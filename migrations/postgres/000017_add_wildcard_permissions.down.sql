@@ -0,0 +1,2 @@
+-- Rollback script
+DELETE FROM permissions WHERE resource = '*' AND action = '*';
@@ -0,0 +1,11 @@
+-- Wildcard permission covering every resource and action. Administrators hold it so new
+-- resources and actions do not need to be granted to them one by one.
+INSERT INTO permissions (resource, action, description, is_system) VALUES
+('*', '*', 'All permissions on all resources', TRUE)
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.resource = '*' AND p.action = '*'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,10 @@
+-- Create role_parents table (a role inherits the permissions of its parent roles)
+CREATE TABLE IF NOT EXISTS role_parents (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    parent_role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (role_id, parent_role_id),
+    CHECK (role_id <> parent_role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_parents_parent_role_id ON role_parents(parent_role_id);
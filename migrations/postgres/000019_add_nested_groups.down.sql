@@ -0,0 +1,2 @@
+-- Rollback script
+DROP TABLE IF EXISTS group_parents;
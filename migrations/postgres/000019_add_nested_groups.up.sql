@@ -0,0 +1,10 @@
+-- Create group_parents table (members of a group are effective members of its parent groups)
+CREATE TABLE IF NOT EXISTS group_parents (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    parent_group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (group_id, parent_group_id),
+    CHECK (group_id <> parent_group_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_parents_parent_group_id ON group_parents(parent_group_id);
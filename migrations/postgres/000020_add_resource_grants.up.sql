@@ -0,0 +1,15 @@
+-- Create resource_grants table (a role granted to a user or group on one resource instance)
+CREATE TABLE IF NOT EXISTS resource_grants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    subject_type VARCHAR(20) NOT NULL CHECK (subject_type IN ('user', 'group')),
+    subject_id UUID NOT NULL,
+    resource_type VARCHAR(100) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (role_id, subject_type, subject_id, resource_type, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_resource_grants_resource ON resource_grants(resource_type, resource_id);
+CREATE INDEX IF NOT EXISTS idx_resource_grants_subject ON resource_grants(subject_type, subject_id);
+
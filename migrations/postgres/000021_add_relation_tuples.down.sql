@@ -0,0 +1,5 @@
+-- Rollback script
+DELETE FROM permissions WHERE resource = 'relations' AND action IN ('read', 'update');
+DROP TABLE IF EXISTS relation_tuples;
+DROP TABLE IF EXISTS relation_tuple_revisions;
+DROP TABLE IF EXISTS relation_namespaces;
@@ -0,0 +1,49 @@
+-- Create relation_namespaces table (ReBAC namespace schemas)
+CREATE TABLE IF NOT EXISTS relation_namespaces (
+    name VARCHAR(64) PRIMARY KEY,
+    relations JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_relation_namespaces_updated_at
+    BEFORE UPDATE ON relation_namespaces
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Create relation_tuple_revisions table (one row per write, the source of consistency tokens)
+CREATE TABLE IF NOT EXISTS relation_tuple_revisions (
+    revision BIGINT PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create relation_tuples table (object#relation@subject, visible from created_revision
+-- until deleted_revision)
+CREATE TABLE IF NOT EXISTS relation_tuples (
+    id BIGSERIAL PRIMARY KEY,
+    namespace VARCHAR(64) NOT NULL,
+    object_id VARCHAR(255) NOT NULL,
+    relation VARCHAR(64) NOT NULL,
+    subject_namespace VARCHAR(64) NOT NULL,
+    subject_id VARCHAR(255) NOT NULL,
+    subject_relation VARCHAR(64) NOT NULL DEFAULT '',
+    created_revision BIGINT NOT NULL,
+    deleted_revision BIGINT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_relation_tuples_live ON relation_tuples(namespace, object_id, relation, subject_namespace, subject_id, subject_relation)
+    WHERE deleted_revision IS NULL;
+CREATE INDEX IF NOT EXISTS idx_relation_tuples_object ON relation_tuples(namespace, object_id, relation);
+CREATE INDEX IF NOT EXISTS idx_relation_tuples_subject ON relation_tuples(subject_namespace, subject_id);
+
+-- Permissions to query and to manage relation tuples, granted to administrators
+INSERT INTO permissions (resource, action, description) VALUES
+('relations', 'read', 'Check, expand and look up relation tuples'),
+('relations', 'update', 'Write relation tuples and namespace schemas')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.resource = 'relations' AND p.action IN ('read', 'update')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,5 @@
+-- Rollback script
+ALTER TABLE role_permissions DROP COLUMN IF EXISTS condition;
+ALTER TABLE resource_grants DROP COLUMN IF EXISTS condition;
+ALTER TABLE group_roles DROP COLUMN IF EXISTS condition;
+ALTER TABLE user_roles DROP COLUMN IF EXISTS condition;
@@ -0,0 +1,6 @@
+-- Conditions (CEL expressions) restricting role assignments and role-permission links;
+-- an empty condition always holds
+ALTER TABLE user_roles ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT '';
+ALTER TABLE group_roles ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT '';
+ALTER TABLE resource_grants ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT '';
+ALTER TABLE role_permissions ADD COLUMN IF NOT EXISTS condition TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- Create deny_rules table (a permission explicitly denied to a user, group or role).
+-- Deny rules override every grant of the permission.
+CREATE TABLE IF NOT EXISTS deny_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subject_type VARCHAR(20) NOT NULL CHECK (subject_type IN ('user', 'group', 'role')),
+    subject_id UUID NOT NULL,
+    permission_id UUID NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    resource_id VARCHAR(255) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (subject_type, subject_id, permission_id, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_deny_rules_subject ON deny_rules(subject_type, subject_id);
+CREATE INDEX IF NOT EXISTS idx_deny_rules_permission_id ON deny_rules(permission_id);
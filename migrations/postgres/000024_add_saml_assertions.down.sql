@@ -0,0 +1,2 @@
+-- Rollback script
+DROP TABLE IF EXISTS saml_assertions;
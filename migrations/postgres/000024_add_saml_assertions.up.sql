@@ -0,0 +1,11 @@
+-- Create saml_assertions table (consumed assertion IDs, kept until the assertion expires
+-- so a captured SAML response cannot be replayed on any instance)
+CREATE TABLE IF NOT EXISTS saml_assertions (
+    provider VARCHAR(100) NOT NULL,
+    assertion_id VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (provider, assertion_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saml_assertions_expires_at ON saml_assertions(expires_at);
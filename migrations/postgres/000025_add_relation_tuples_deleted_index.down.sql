@@ -0,0 +1,2 @@
+-- Rollback script
+DROP INDEX IF EXISTS idx_relation_tuples_deleted;
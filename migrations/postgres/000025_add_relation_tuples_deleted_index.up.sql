@@ -0,0 +1,3 @@
+-- Index tombstoned relation tuples for the history purge
+CREATE INDEX IF NOT EXISTS idx_relation_tuples_deleted ON relation_tuples(deleted_revision)
+    WHERE deleted_revision IS NOT NULL;
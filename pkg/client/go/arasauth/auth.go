@@ -269,12 +269,24 @@ func (c *Client) GetCurrentUser(ctx context.Context) (*User, error) {
 
 // CheckPermission checks if a user has a specific permission
 func (c *Client) CheckPermission(ctx context.Context, userID, resource, action string) (bool, error) {
-	req := CheckPermissionRequest{
-		UserID:   userID,
-		Resource: resource,
-		Action:   action,
-	}
+	return c.CheckResourcePermission(ctx, userID, resource, "", action)
+}
 
+// CheckResourcePermission checks if a user has a permission on one instance of a
+// resource, e.g. "documents" "123" "edit". Roles granted on that instance count as well
+// as global ones; an empty resourceID checks global grants only.
+func (c *Client) CheckResourcePermission(ctx context.Context, userID, resource, resourceID, action string) (bool, error) {
+	return c.CheckPermissionWithContext(ctx, &CheckPermissionRequest{
+		UserID:     userID,
+		Resource:   resource,
+		Action:     action,
+		ResourceID: resourceID,
+	})
+}
+
+// CheckPermissionWithContext checks a permission with req.Context, so that roles assigned
+// with conditions, e.g. on the client IP or the time of day, count if the conditions hold
+func (c *Client) CheckPermissionWithContext(ctx context.Context, req *CheckPermissionRequest) (bool, error) {
 	resp, err := c.makeRequest(ctx, "POST", "/api/v1/authz/check", req)
 	if err != nil {
 		return false, err
@@ -299,6 +311,32 @@ func (c *Client) CheckPermission(ctx context.Context, userID, resource, action s
 	return hasPermission, nil
 }
 
+// CheckPermissions decides up to 100 checks, e.g. every action of a list view or one
+// permission across many users, in one request. Results are in the order of checks.
+func (c *Client) CheckPermissions(ctx context.Context, checks []CheckPermissionRequest) ([]CheckPermissionResult, error) {
+	req := struct {
+		Checks []CheckPermissionRequest `json:"checks"`
+	}{
+		Checks: checks,
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/authz/check-batch", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		Data struct {
+			Results []CheckPermissionResult `json:"results"`
+		} `json:"data"`
+	}
+	if err := c.handleResponse(resp, &apiResp); err != nil {
+		return nil, err
+	}
+
+	return apiResp.Data.Results, nil
+}
+
 // ChangePassword changes the current user's password
 func (c *Client) ChangePassword(ctx context.Context, currentPassword, newPassword string) error {
 	req := ChangePasswordRequest{
@@ -8,12 +8,21 @@ import (
 // AssignRoleRequest represents the request to assign a role
 type AssignRoleRequest struct {
 	RoleID string `json:"role_id"`
+	// Condition is an optional CEL expression the assignment only applies under
+	Condition string `json:"condition,omitempty"`
 }
 
 // AssignRoleToUser assigns a role to a user
 func (c *Client) AssignRoleToUser(ctx context.Context, userID, roleID string) error {
+	return c.AssignConditionalRoleToUser(ctx, userID, roleID, "")
+}
+
+// AssignConditionalRoleToUser assigns a role to a user that only applies where the CEL
+// condition holds, e.g. inCIDR(request.ip, "10.0.0.0/8")
+func (c *Client) AssignConditionalRoleToUser(ctx context.Context, userID, roleID, condition string) error {
 	req := AssignRoleRequest{
-		RoleID: roleID,
+		RoleID:    roleID,
+		Condition: condition,
 	}
 
 	endpoint := fmt.Sprintf("/api/v1/users/%s/roles", userID)
@@ -96,8 +105,15 @@ func (c *Client) GetUserRoles(ctx context.Context, userID string) ([]*Role, erro
 
 // AssignRoleToGroup assigns a role to a group
 func (c *Client) AssignRoleToGroup(ctx context.Context, groupID, roleID string) error {
+	return c.AssignConditionalRoleToGroup(ctx, groupID, roleID, "")
+}
+
+// AssignConditionalRoleToGroup assigns a role to a group that only applies where the CEL
+// condition holds, e.g. inCIDR(request.ip, "10.0.0.0/8")
+func (c *Client) AssignConditionalRoleToGroup(ctx context.Context, groupID, roleID, condition string) error {
 	req := AssignRoleRequest{
-		RoleID: roleID,
+		RoleID:    roleID,
+		Condition: condition,
 	}
 
 	endpoint := fmt.Sprintf("/api/v1/groups/%s/roles", groupID)
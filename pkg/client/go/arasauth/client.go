@@ -123,9 +123,21 @@ type RegisterRequest struct {
 
 // CheckPermissionRequest represents the permission check request
 type CheckPermissionRequest struct {
-	UserID   string `json:"user_id"`
-	Resource string `json:"resource"`
-	Action   string `json:"action"`
+	UserID     string `json:"user_id"`
+	Resource   string `json:"resource"`
+	Action     string `json:"action"`
+	ResourceID string `json:"resource_id,omitempty"`
+	// Context is what conditional role grants are evaluated against
+	Context *AccessContext `json:"context,omitempty"`
+}
+
+// AccessContext describes the circumstances of an access for conditional role grants.
+// The server sets user.id, user.email, resource.type and resource.id itself.
+type AccessContext struct {
+	IP       string                 `json:"ip,omitempty"`
+	Time     *time.Time             `json:"time,omitempty"`
+	User     map[string]interface{} `json:"user,omitempty"`
+	Resource map[string]interface{} `json:"resource,omitempty"`
 }
 
 // CheckPermissionResponse represents the permission check response
@@ -133,6 +145,16 @@ type CheckPermissionResponse struct {
 	HasPermission bool `json:"has_permission"`
 }
 
+// CheckPermissionResult represents the result of one check of a batch
+type CheckPermissionResult struct {
+	UserID        string `json:"user_id"`
+	Resource      string `json:"resource"`
+	Action        string `json:"action"`
+	ResourceID    string `json:"resource_id,omitempty"`
+	HasPermission bool   `json:"has_permission"`
+	DenyRuleID    string `json:"deny_rule_id,omitempty"`
+}
+
 // ChangePasswordRequest represents the change password request
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password"`
@@ -5,8 +5,57 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
+// PermissionWildcard matches every resource or every action in a granted permission,
+// e.g. "*:*", "users:*" or "*:read". A resource ending in ".*" matches the resources
+// below it but not the resource itself.
+const PermissionWildcard = "*"
+
+// Matches reports whether the permission, as granted, covers resource and action.
+// Resources are hierarchical with "." as separator: a grant on "billing" also covers
+// "billing.invoices". The rules are the same as in the server's permission check.
+func (p *Permission) Matches(resource, action string) bool {
+	return PermissionMatches(p.Resource, p.Action, resource, action)
+}
+
+// PermissionMatches reports whether a granted resource and action pattern covers the
+// requested resource and action
+func PermissionMatches(grantedResource, grantedAction, resource, action string) bool {
+	if grantedAction != PermissionWildcard && grantedAction != action {
+		return false
+	}
+	switch {
+	case grantedResource == PermissionWildcard || grantedResource == resource:
+		return true
+	case strings.HasSuffix(grantedResource, ".*"):
+		return strings.HasPrefix(resource, strings.TrimSuffix(grantedResource, "*"))
+	default:
+		return strings.HasPrefix(resource, grantedResource+".")
+	}
+}
+
+// HasPermission reports whether any active permission in the list covers resource and
+// action, e.g. to evaluate permissions fetched with GetRolePermissions locally
+func HasPermission(permissions []*Permission, resource, action string) bool {
+	for _, permission := range permissions {
+		if permission.IsActive && !permission.IsDeleted && permission.Matches(resource, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePermission splits a "resource:action" string such as "billing.invoices:read"
+func ParsePermission(permission string) (resource, action string, err error) {
+	resource, action, ok := strings.Cut(permission, ":")
+	if !ok || resource == "" || action == "" || strings.Contains(action, ":") {
+		return "", "", fmt.Errorf("invalid permission %q, expected resource:action", permission)
+	}
+	return resource, action, nil
+}
+
 // CreatePermissionRequest represents the request to create a permission
 type CreatePermissionRequest struct {
 	Resource    string `json:"resource"`
@@ -280,3 +329,127 @@ func (c *Client) DeletePermission(ctx context.Context, permissionID string) erro
 
 	return nil
 }
+
+// PermissionSource is one way a user holds an effective permission: RoleName holds the
+// permission and is, or is inherited by, AssignedRoleName, which is assigned to the user
+// (Via "user") or to the group GroupName the user is an effective member of (Via "group")
+type PermissionSource struct {
+	Via                 string `json:"via"`
+	GroupID             string `json:"group_id,omitempty"`
+	GroupName           string `json:"group_name,omitempty"`
+	AssignedRoleID      string `json:"assigned_role_id"`
+	AssignedRoleName    string `json:"assigned_role_name"`
+	RoleID              string `json:"role_id"`
+	RoleName            string `json:"role_name"`
+	AssignmentCondition string `json:"assignment_condition,omitempty"`
+	PermissionCondition string `json:"permission_condition,omitempty"`
+}
+
+// DenyRule represents a permission explicitly denied to a user, group or role
+type DenyRule struct {
+	ID           string `json:"id"`
+	SubjectType  string `json:"subject_type"`
+	SubjectID    string `json:"subject_id"`
+	PermissionID string `json:"permission_id"`
+	Resource     string `json:"resource"`
+	Action       string `json:"action"`
+	ResourceID   string `json:"resource_id,omitempty"`
+	Description  string `json:"description,omitempty"`
+	CreatedAt    string `json:"created_at"`
+}
+
+// EffectivePermission represents a permission a user holds, with all of its sources
+type EffectivePermission struct {
+	PermissionID string             `json:"permission_id"`
+	Resource     string             `json:"resource"`
+	Action       string             `json:"action"`
+	Description  string             `json:"description"`
+	Sources      []PermissionSource `json:"sources"`
+	Conditional  bool               `json:"conditional"`
+	DeniedBy     *DenyRule          `json:"denied_by,omitempty"`
+}
+
+// ListEffectivePermissionsResponse represents a page of a user's effective permissions
+type ListEffectivePermissionsResponse struct {
+	Permissions []*EffectivePermission `json:"permissions"`
+	Total       int                    `json:"total"`
+	Page        int                    `json:"page"`
+	Limit       int                    `json:"limit"`
+}
+
+// PermissionHolder represents a user who can perform an action on a resource
+type PermissionHolder struct {
+	UserID      string `json:"user_id"`
+	Email       string `json:"email"`
+	FirstName   string `json:"first_name"`
+	LastName    string `json:"last_name"`
+	Status      string `json:"status"`
+	Conditional bool   `json:"conditional"`
+}
+
+// ListPermissionHoldersResponse represents a page of the users holding a permission
+type ListPermissionHoldersResponse struct {
+	Users []*PermissionHolder `json:"users"`
+	Total int                 `json:"total"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+}
+
+// GetUserEffectivePermissions retrieves the permissions a user holds through their roles
+// and groups, deduplicated, with the sources of each
+func (c *Client) GetUserEffectivePermissions(ctx context.Context, userID string, page, limit int) (*ListEffectivePermissionsResponse, error) {
+	params := url.Values{}
+	if page > 0 {
+		params.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	endpoint := fmt.Sprintf("/api/v1/users/%s/permissions", userID)
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+
+	resp, err := c.makeRequest(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		Data ListEffectivePermissionsResponse `json:"data"`
+	}
+	if err := c.handleResponse(resp, &apiResp); err != nil {
+		return nil, err
+	}
+
+	return &apiResp.Data, nil
+}
+
+// WhoCan retrieves the users who can perform the action on the resource, e.g. for access
+// reviews
+func (c *Client) WhoCan(ctx context.Context, resource, action string, page, limit int) (*ListPermissionHoldersResponse, error) {
+	params := url.Values{}
+	params.Set("resource", resource)
+	params.Set("action", action)
+	if page > 0 {
+		params.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
+	}
+
+	resp, err := c.makeRequest(ctx, "GET", "/api/v1/authz/who-can?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		Data ListPermissionHoldersResponse `json:"data"`
+	}
+	if err := c.handleResponse(resp, &apiResp); err != nil {
+		return nil, err
+	}
+
+	return &apiResp.Data, nil
+}
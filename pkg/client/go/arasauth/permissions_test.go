@@ -0,0 +1,34 @@
+package arasauth
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// TestPermissionMatches runs the server's matcher table against the client, which must
+// decide local checks exactly as the server does
+func TestPermissionMatches(t *testing.T) {
+	data, err := os.ReadFile("../../../../internal/domain/testdata/permission_matches.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tests []struct {
+		GrantedResource string `json:"granted_resource"`
+		GrantedAction   string `json:"granted_action"`
+		Resource        string `json:"resource"`
+		Action          string `json:"action"`
+		Want            bool   `json:"want"`
+	}
+	if err := json.Unmarshal(data, &tests); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		got := PermissionMatches(tt.GrantedResource, tt.GrantedAction, tt.Resource, tt.Action)
+		if got != tt.Want {
+			t.Errorf("PermissionMatches(%q, %q, %q, %q) = %v, want %v",
+				tt.GrantedResource, tt.GrantedAction, tt.Resource, tt.Action, got, tt.Want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package arasauth
+
+import (
+	"context"
+)
+
+// Consistency selects the revision of the relation store a query is evaluated at; set
+// at most one field to a consistency token returned by an earlier write or query
+type Consistency struct {
+	AtLeastAsFresh  string `json:"at_least_as_fresh,omitempty"`
+	AtExactSnapshot string `json:"at_exact_snapshot,omitempty"`
+}
+
+// WriteRelationTuplesRequest writes and deletes relation tuples such as
+// "doc:1#viewer@user:alice" in one revision
+type WriteRelationTuplesRequest struct {
+	Writes  []string `json:"writes,omitempty"`
+	Deletes []string `json:"deletes,omitempty"`
+}
+
+// CheckRelationRequest represents the relation check request
+type CheckRelationRequest struct {
+	Object      string       `json:"object"`
+	Relation    string       `json:"relation"`
+	Subject     string       `json:"subject"`
+	Consistency *Consistency `json:"consistency,omitempty"`
+}
+
+// CheckRelationResponse represents the relation check response
+type CheckRelationResponse struct {
+	Allowed          bool   `json:"allowed"`
+	ConsistencyToken string `json:"consistency_token"`
+}
+
+// LookupResourcesRequest represents the request to list the objects a subject has a relation to
+type LookupResourcesRequest struct {
+	Namespace   string       `json:"namespace"`
+	Relation    string       `json:"relation"`
+	Subject     string       `json:"subject"`
+	Limit       int          `json:"limit,omitempty"`
+	Consistency *Consistency `json:"consistency,omitempty"`
+}
+
+// LookupResourcesResponse represents the lookup response
+type LookupResourcesResponse struct {
+	ObjectIDs        []string `json:"object_ids"`
+	ConsistencyToken string   `json:"consistency_token"`
+}
+
+// WriteRelationTuples writes and deletes relation tuples and returns the consistency
+// token of the write. Pass the token as AtLeastAsFresh to later checks that must see it.
+func (c *Client) WriteRelationTuples(ctx context.Context, writes, deletes []string) (string, error) {
+	req := WriteRelationTuplesRequest{
+		Writes:  writes,
+		Deletes: deletes,
+	}
+
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/relations/tuples", req)
+	if err != nil {
+		return "", err
+	}
+
+	var apiResp struct {
+		Data struct {
+			ConsistencyToken string `json:"consistency_token"`
+		} `json:"data"`
+	}
+	if err := c.handleResponse(resp, &apiResp); err != nil {
+		return "", err
+	}
+
+	return apiResp.Data.ConsistencyToken, nil
+}
+
+// CheckRelation checks if the subject, e.g. "user:alice" or "group:eng#member", has the
+// relation to the object, e.g. "doc:1"
+func (c *Client) CheckRelation(ctx context.Context, req *CheckRelationRequest) (*CheckRelationResponse, error) {
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/relations/check", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		Data CheckRelationResponse `json:"data"`
+	}
+	if err := c.handleResponse(resp, &apiResp); err != nil {
+		return nil, err
+	}
+
+	return &apiResp.Data, nil
+}
+
+// LookupResources lists the IDs of the objects in a namespace the subject has the relation to
+func (c *Client) LookupResources(ctx context.Context, req *LookupResourcesRequest) (*LookupResourcesResponse, error) {
+	resp, err := c.makeRequest(ctx, "POST", "/api/v1/relations/lookup-resources", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResp struct {
+		Data LookupResourcesResponse `json:"data"`
+	}
+	if err := c.handleResponse(resp, &apiResp); err != nil {
+		return nil, err
+	}
+
+	return &apiResp.Data, nil
+}
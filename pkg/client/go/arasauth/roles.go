@@ -32,6 +32,8 @@ type ListRolesResponse struct {
 // AssignPermissionRequest represents the request to assign permission to role
 type AssignPermissionRequest struct {
 	PermissionID string `json:"permission_id"`
+	// Condition is an optional CEL expression the permission only applies under
+	Condition string `json:"condition,omitempty"`
 }
 
 // CreateRole creates a new role
@@ -273,8 +275,15 @@ func (c *Client) DeleteRole(ctx context.Context, roleID string) error {
 
 // AssignPermissionToRole assigns a permission to a role
 func (c *Client) AssignPermissionToRole(ctx context.Context, roleID, permissionID string) error {
+	return c.AssignConditionalPermissionToRole(ctx, roleID, permissionID, "")
+}
+
+// AssignConditionalPermissionToRole assigns a permission to a role that only applies
+// where the CEL condition holds, e.g. user.department == resource.department
+func (c *Client) AssignConditionalPermissionToRole(ctx context.Context, roleID, permissionID, condition string) error {
 	req := AssignPermissionRequest{
 		PermissionID: permissionID,
+		Condition:    condition,
 	}
 
 	endpoint := fmt.Sprintf("/api/v1/roles/%s/permissions", roleID)
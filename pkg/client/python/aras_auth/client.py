@@ -7,9 +7,11 @@ A Python client library for the ArasAuth authentication and authorization servic
 import json
 import requests
 from typing import Optional, Dict, Any, List
-from urllib.parse import urljoin
+from urllib.parse import urljoin, urlencode
 
-from .models import User, Group, Role, Permission, AuthResponse, ListResponse
+from .models import (
+    User, Group, Role, Permission, AuthResponse, ListResponse, EffectivePermission, PermissionHolder
+)
 
 
 class AuthClient:
@@ -186,7 +188,9 @@ class AuthClient:
         
         return User.from_dict(user_data)
     
-    def check_permission(self, user_id: str, resource: str, action: str) -> bool:
+    def check_permission(self, user_id: str, resource: str, action: str,
+                         resource_id: Optional[str] = None,
+                         context: Optional[Dict[str, Any]] = None) -> bool:
         """
         Check if a user has a specific permission
         
@@ -194,6 +198,9 @@ class AuthClient:
             user_id: User ID
             resource: Resource name
             action: Action name
+            resource_id: Optional resource instance, so that roles granted on it count too
+            context: Optional access context for conditional grants, e.g.
+                {'ip': '10.1.2.3', 'user': {'department': 'sales'}}
             
         Returns:
             True if user has permission, False otherwise
@@ -203,6 +210,10 @@ class AuthClient:
             'resource': resource,
             'action': action
         }
+        if resource_id:
+            data['resource_id'] = resource_id
+        if context:
+            data['context'] = context
         
         response_data = self._make_request('POST', '/api/v1/authz/check', data)
         permission_data = self._handle_response(response_data)
@@ -470,15 +481,19 @@ class AuthClient:
         """
         self._make_request('DELETE', f'/api/v1/roles/{role_id}')
     
-    def assign_role_to_user(self, user_id: str, role_id: str) -> None:
+    def assign_role_to_user(self, user_id: str, role_id: str,
+                            condition: Optional[str] = None) -> None:
         """
         Assign a role to a user
         
         Args:
             user_id: User ID
             role_id: Role ID
+            condition: Optional CEL expression the assignment only applies under
         """
         data = {'role_id': role_id}
+        if condition:
+            data['condition'] = condition
         self._make_request('POST', f'/api/v1/users/{user_id}/roles', data)
     
     def remove_role_from_user(self, user_id: str, role_id: str) -> None:
@@ -506,15 +521,19 @@ class AuthClient:
         
         return [Role.from_dict(role) for role in roles_data]
     
-    def assign_role_to_group(self, group_id: str, role_id: str) -> None:
+    def assign_role_to_group(self, group_id: str, role_id: str,
+                             condition: Optional[str] = None) -> None:
         """
         Assign a role to a group
         
         Args:
             group_id: Group ID
             role_id: Role ID
+            condition: Optional CEL expression the assignment only applies under
         """
         data = {'role_id': role_id}
+        if condition:
+            data['condition'] = condition
         self._make_request('POST', f'/api/v1/groups/{group_id}/roles', data)
     
     def remove_role_from_group(self, group_id: str, role_id: str) -> None:
@@ -627,15 +646,19 @@ class AuthClient:
         """
         self._make_request('DELETE', f'/api/v1/permissions/{permission_id}')
     
-    def assign_permission_to_role(self, role_id: str, permission_id: str) -> None:
+    def assign_permission_to_role(self, role_id: str, permission_id: str,
+                                  condition: Optional[str] = None) -> None:
         """
         Assign a permission to a role
         
         Args:
             role_id: Role ID
             permission_id: Permission ID
+            condition: Optional CEL expression the permission only applies under
         """
         data = {'permission_id': permission_id}
+        if condition:
+            data['condition'] = condition
         self._make_request('POST', f'/api/v1/roles/{role_id}/permissions', data)
     
     def remove_permission_from_role(self, role_id: str, permission_id: str) -> None:
@@ -662,5 +685,55 @@ class AuthClient:
         permissions_data = self._handle_response(response_data)
         
         return [Permission.from_dict(permission) for permission in permissions_data]
+    
+    def get_user_effective_permissions(self, user_id: str, page: int = 1,
+                                       limit: int = 20) -> ListResponse:
+        """
+        Get the permissions a user holds through their roles and groups,
+        deduplicated, with the sources of each
+        
+        Args:
+            user_id: User ID
+            page: Page number
+            limit: Items per page
+            
+        Returns:
+            ListResponse with effective permissions
+        """
+        params = f'?page={page}&limit={limit}'
+        response_data = self._make_request('GET', f'/api/v1/users/{user_id}/permissions{params}')
+        data = self._handle_response(response_data)
+        
+        return ListResponse(
+            items=[EffectivePermission.from_dict(item) for item in data.get('permissions') or []],
+            total=data.get('total', 0),
+            page=data.get('page', page),
+            limit=data.get('limit', limit)
+        )
+    
+    def who_can(self, resource: str, action: str, page: int = 1,
+                limit: int = 20) -> ListResponse:
+        """
+        Get the users who can perform an action on a resource, e.g. for access reviews
+        
+        Args:
+            resource: Resource name
+            action: Action name
+            page: Page number
+            limit: Items per page
+            
+        Returns:
+            ListResponse with permission holders
+        """
+        params = urlencode({'resource': resource, 'action': action, 'page': page, 'limit': limit})
+        response_data = self._make_request('GET', f'/api/v1/authz/who-can?{params}')
+        data = self._handle_response(response_data)
+        
+        return ListResponse(
+            items=[PermissionHolder.from_dict(item) for item in data.get('users') or []],
+            total=data.get('total', 0),
+            page=data.get('page', page),
+            limit=data.get('limit', limit)
+        )
 
 
@@ -2,7 +2,7 @@
 Data models for the ArasAuth Python SDK
 """
 
-from dataclasses import dataclass
+from dataclasses import dataclass, field
 from typing import Optional, List, Dict, Any
 
 
@@ -144,6 +144,54 @@ class AuthResponse:
         )
 
 
+@dataclass
+class EffectivePermission:
+    """Represents a permission a user holds, with all of its sources"""
+    permission_id: str
+    resource: str
+    action: str
+    description: str = ''
+    sources: List[Dict[str, Any]] = field(default_factory=list)
+    conditional: bool = False
+    denied_by: Optional[Dict[str, Any]] = None
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> 'EffectivePermission':
+        """Create an EffectivePermission instance from a dictionary"""
+        return cls(
+            permission_id=data.get('permission_id', ''),
+            resource=data.get('resource', ''),
+            action=data.get('action', ''),
+            description=data.get('description', ''),
+            sources=data.get('sources', []),
+            conditional=data.get('conditional', False),
+            denied_by=data.get('denied_by')
+        )
+
+
+@dataclass
+class PermissionHolder:
+    """Represents a user who can perform an action on a resource"""
+    user_id: str
+    email: str
+    first_name: str = ''
+    last_name: str = ''
+    status: str = ''
+    conditional: bool = False
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> 'PermissionHolder':
+        """Create a PermissionHolder instance from a dictionary"""
+        return cls(
+            user_id=data.get('user_id', ''),
+            email=data.get('email', ''),
+            first_name=data.get('first_name', ''),
+            last_name=data.get('last_name', ''),
+            status=data.get('status', ''),
+            conditional=data.get('conditional', False)
+        )
+
+
 @dataclass
 class ListResponse:
     """Represents a paginated list response"""
@@ -23,6 +23,7 @@ type JWTService struct {
 type TokenClaims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
+	Scope  string    `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -41,9 +42,16 @@ func NewJWTService(secretKey string, accessExpiry, refreshExpiry time.Duration)
 }
 
 func (j *JWTService) GenerateAccessToken(userID uuid.UUID, email string) (string, error) {
+	return j.GenerateScopedAccessToken(userID, email, "")
+}
+
+// GenerateScopedAccessToken creates an access token restricted to the given scope.
+// An empty scope yields a regular, unrestricted access token.
+func (j *JWTService) GenerateScopedAccessToken(userID uuid.UUID, email, scope string) (string, error) {
 	claims := TokenClaims{
 		UserID: userID,
 		Email:  email,
+		Scope:  scope,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.accessExpiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
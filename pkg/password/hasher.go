@@ -0,0 +1,282 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algorithm identifies a password hashing algorithm
+type Algorithm string
+
+const (
+	AlgorithmArgon2id Algorithm = "argon2id"
+	AlgorithmBcrypt   Algorithm = "bcrypt"
+)
+
+var (
+	ErrMismatchedHashAndPassword = errors.New("password does not match hash")
+	ErrUnknownAlgorithm          = errors.New("unknown password hash algorithm")
+	ErrInvalidHash               = errors.New("invalid password hash format")
+	ErrPepperMismatch            = errors.New("password hash was created with a different pepper")
+)
+
+// Argon2Params holds the tunable argon2id parameters
+type Argon2Params struct {
+	Memory      uint32 // Memory in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Options configures a Hasher
+type Options struct {
+	Algorithm  Algorithm
+	Argon2     Argon2Params
+	BcryptCost int
+	// Pepper is an optional server-side secret mixed into argon2id hashes with
+	// HMAC-SHA256. It is never stored; hashes only record a short key id so a
+	// rotated pepper can be detected. bcrypt hashes are never peppered.
+	Pepper string
+}
+
+// DefaultOptions returns the recommended hashing options (argon2id, 64 MiB, 3 iterations)
+func DefaultOptions() Options {
+	return Options{
+		Algorithm: AlgorithmArgon2id,
+		Argon2: Argon2Params{
+			Memory:      64 * 1024,
+			Iterations:  3,
+			Parallelism: 2,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+		BcryptCost: DefaultCost,
+	}
+}
+
+// Hasher hashes and verifies passwords using the configured algorithm while
+// still accepting hashes produced by any supported algorithm
+type Hasher struct {
+	opts     Options
+	pepperID string
+}
+
+// NewHasher validates the options and creates a Hasher
+func NewHasher(opts Options) (*Hasher, error) {
+	switch opts.Algorithm {
+	case AlgorithmArgon2id:
+		p := opts.Argon2
+		if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 {
+			return nil, fmt.Errorf("invalid argon2id parameters: m=%d t=%d p=%d", p.Memory, p.Iterations, p.Parallelism)
+		}
+		if p.SaltLength < 8 || p.KeyLength < 16 {
+			return nil, fmt.Errorf("argon2id salt must be at least 8 bytes and key at least 16 bytes")
+		}
+	case AlgorithmBcrypt:
+		if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", opts.BcryptCost)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAlgorithm, opts.Algorithm)
+	}
+
+	h := &Hasher{opts: opts}
+	if opts.Pepper != "" {
+		sum := sha256.Sum256([]byte("aras-auth-pepper-id:" + opts.Pepper))
+		h.pepperID = base64.RawStdEncoding.EncodeToString(sum[:6])
+	}
+
+	return h, nil
+}
+
+// Hash hashes a password with the configured algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	switch h.opts.Algorithm {
+	case AlgorithmBcrypt:
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hashed), nil
+	default:
+		salt := make([]byte, h.opts.Argon2.SaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+		return encodeArgon2id(h.opts.Argon2, h.pepperID, salt, h.argon2idKey(password, h.opts.Argon2, salt, h.pepperID != "")), nil
+	}
+}
+
+// Verify checks a password against an encoded hash of any supported algorithm
+func (h *Hasher) Verify(encoded, password string) error {
+	switch detectAlgorithm(encoded) {
+	case AlgorithmBcrypt:
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return ErrMismatchedHashAndPassword
+			}
+			return err
+		}
+		return nil
+	case AlgorithmArgon2id:
+		parsed, err := decodeArgon2id(encoded)
+		if err != nil {
+			return err
+		}
+		// Unpeppered hashes stay verifiable after a pepper is introduced; they
+		// are upgraded through NeedsRehash on the next successful login.
+		if parsed.keyID != "" && parsed.keyID != h.pepperID {
+			return ErrPepperMismatch
+		}
+		key := h.argon2idKey(password, parsed.params, parsed.salt, parsed.keyID != "")
+		if subtle.ConstantTimeCompare(key, parsed.key) != 1 {
+			return ErrMismatchedHashAndPassword
+		}
+		return nil
+	default:
+		return ErrUnknownAlgorithm
+	}
+}
+
+// NeedsRehash reports whether a hash was produced with a different algorithm,
+// weaker or outdated parameters, or a different pepper than currently configured.
+// Unrecognized hashes are reported as not needing a rehash since they cannot be verified.
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	algorithm := detectAlgorithm(encoded)
+	if algorithm == "" {
+		return false
+	}
+	if algorithm != h.opts.Algorithm {
+		return true
+	}
+
+	switch algorithm {
+	case AlgorithmBcrypt:
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.opts.BcryptCost
+	default:
+		parsed, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false
+		}
+		want := h.opts.Argon2
+		return parsed.version != argon2.Version ||
+			parsed.params.Memory != want.Memory ||
+			parsed.params.Iterations != want.Iterations ||
+			parsed.params.Parallelism != want.Parallelism ||
+			parsed.params.KeyLength != want.KeyLength ||
+			uint32(len(parsed.salt)) < want.SaltLength ||
+			parsed.keyID != h.pepperID
+	}
+}
+
+func (h *Hasher) argon2idKey(password string, p Argon2Params, salt []byte, peppered bool) []byte {
+	input := []byte(password)
+	if peppered {
+		mac := hmac.New(sha256.New, []byte(h.opts.Pepper))
+		mac.Write(input)
+		input = mac.Sum(nil)
+	}
+	return argon2.IDKey(input, salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+}
+
+// encodeArgon2id produces a PHC string such as
+// $argon2id$v=19$m=65536,t=3,p=2[,keyid=...]$<salt>$<hash>
+func encodeArgon2id(p Argon2Params, keyID string, salt, key []byte) string {
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", p.Memory, p.Iterations, p.Parallelism)
+	if keyID != "" {
+		params += ",keyid=" + keyID
+	}
+	return fmt.Sprintf("$argon2id$v=%d$%s$%s$%s",
+		argon2.Version,
+		params,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+type argon2idHash struct {
+	version int
+	params  Argon2Params
+	keyID   string
+	salt    []byte
+	key     []byte
+}
+
+func decodeArgon2id(encoded string) (*argon2idHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != string(AlgorithmArgon2id) {
+		return nil, ErrInvalidHash
+	}
+
+	parsed := &argon2idHash{}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &parsed.version); err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	for _, param := range strings.Split(parts[3], ",") {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return nil, ErrInvalidHash
+		}
+		switch name {
+		case "m":
+			n, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, ErrInvalidHash
+			}
+			parsed.params.Memory = uint32(n)
+		case "t":
+			n, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, ErrInvalidHash
+			}
+			parsed.params.Iterations = uint32(n)
+		case "p":
+			n, err := strconv.ParseUint(value, 10, 8)
+			if err != nil {
+				return nil, ErrInvalidHash
+			}
+			parsed.params.Parallelism = uint8(n)
+		case "keyid":
+			parsed.keyID = value
+		}
+	}
+	if parsed.params.Memory == 0 || parsed.params.Iterations == 0 || parsed.params.Parallelism == 0 {
+		return nil, ErrInvalidHash
+	}
+
+	var err error
+	if parsed.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	if parsed.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, ErrInvalidHash
+	}
+	parsed.params.SaltLength = uint32(len(parsed.salt))
+	parsed.params.KeyLength = uint32(len(parsed.key))
+
+	return parsed, nil
+}
+
+func detectAlgorithm(encoded string) Algorithm {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgorithmArgon2id
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,175 @@
+package password
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2 keeps argon2id cheap enough for unit tests
+var testArgon2 = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestHasher(t *testing.T, opts Options) *Hasher {
+	t.Helper()
+
+	h, err := NewHasher(opts)
+	if err != nil {
+		t.Fatalf("NewHasher() error = %v", err)
+	}
+	return h
+}
+
+func TestHasherRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "argon2id", opts: Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2}},
+		{name: "argon2id with pepper", opts: Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2, Pepper: "pepper-1"}},
+		{name: "bcrypt", opts: Options{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHasher(t, tt.opts)
+
+			hashed, err := h.Hash("Correct-Horse-1")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if err := h.Verify(hashed, "Correct-Horse-1"); err != nil {
+				t.Errorf("Verify() error = %v", err)
+			}
+			if err := h.Verify(hashed, "Wrong-Horse-1"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+				t.Errorf("Verify() with a wrong password error = %v, want %v", err, ErrMismatchedHashAndPassword)
+			}
+			if h.NeedsRehash(hashed) {
+				t.Errorf("NeedsRehash() = true for a hash made with the current options")
+			}
+
+			again, err := h.Hash("Correct-Horse-1")
+			if err != nil {
+				t.Fatalf("Hash() error = %v", err)
+			}
+			if again == hashed {
+				t.Errorf("Hash() returned the same hash twice; salts must differ")
+			}
+		})
+	}
+}
+
+func TestHasherPepper(t *testing.T) {
+	plain := newTestHasher(t, Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2})
+	peppered := newTestHasher(t, Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2, Pepper: "pepper-1"})
+	rotated := newTestHasher(t, Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2, Pepper: "pepper-2"})
+
+	pepperedHash, err := peppered.Hash("Correct-Horse-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainHash, err := plain.Hash("Correct-Horse-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, h := range map[string]*Hasher{"other pepper": rotated, "no pepper": plain} {
+		if err := h.Verify(pepperedHash, "Correct-Horse-1"); !errors.Is(err, ErrPepperMismatch) {
+			t.Errorf("%s: Verify() error = %v, want %v", name, err, ErrPepperMismatch)
+		}
+		if !h.NeedsRehash(pepperedHash) {
+			t.Errorf("%s: NeedsRehash() = false for a hash with another pepper", name)
+		}
+	}
+
+	// Hashes from before the pepper was introduced keep working and are upgraded
+	if err := peppered.Verify(plainHash, "Correct-Horse-1"); err != nil {
+		t.Errorf("Verify() of an unpeppered hash error = %v", err)
+	}
+	if !peppered.NeedsRehash(plainHash) {
+		t.Errorf("NeedsRehash() = false for an unpeppered hash once a pepper is set")
+	}
+}
+
+func TestHasherLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("Correct-Horse-1"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newTestHasher(t, Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2, Pepper: "pepper-1"})
+
+	if err := h.Verify(string(legacy), "Correct-Horse-1"); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if err := h.Verify(string(legacy), "Wrong-Horse-1"); !errors.Is(err, ErrMismatchedHashAndPassword) {
+		t.Errorf("Verify() with a wrong password error = %v, want %v", err, ErrMismatchedHashAndPassword)
+	}
+	if !h.NeedsRehash(string(legacy)) {
+		t.Errorf("NeedsRehash() = false for a bcrypt hash under argon2id")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	base := Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2}
+	hashed, err := newTestHasher(t, base).Hash("Correct-Horse-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := newTestHasher(t, Options{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}).Hash("Correct-Horse-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upgraded := func(change func(p *Argon2Params)) Options {
+		opts := base
+		change(&opts.Argon2)
+		return opts
+	}
+
+	tests := []struct {
+		name   string
+		opts   Options
+		hashed string
+		want   bool
+	}{
+		{name: "current parameters", opts: base, hashed: hashed},
+		{name: "more memory", opts: upgraded(func(p *Argon2Params) { p.Memory *= 2 }), hashed: hashed, want: true},
+		{name: "more iterations", opts: upgraded(func(p *Argon2Params) { p.Iterations++ }), hashed: hashed, want: true},
+		{name: "more parallelism", opts: upgraded(func(p *Argon2Params) { p.Parallelism++ }), hashed: hashed, want: true},
+		{name: "longer key", opts: upgraded(func(p *Argon2Params) { p.KeyLength = 64 }), hashed: hashed, want: true},
+		{name: "longer salt", opts: upgraded(func(p *Argon2Params) { p.SaltLength = 32 }), hashed: hashed, want: true},
+		{name: "shorter salt", opts: upgraded(func(p *Argon2Params) { p.SaltLength = 8 }), hashed: hashed},
+		{name: "switch to bcrypt", opts: Options{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost}, hashed: hashed, want: true},
+		{name: "higher bcrypt cost", opts: Options{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1}, hashed: bcryptHash, want: true},
+		{name: "unusable", opts: base, hashed: Unusable},
+		{name: "malformed argon2id", opts: base, hashed: "$argon2id$v=19$m=0$salt$key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTestHasher(t, tt.opts).NeedsRehash(tt.hashed); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsUnknownHashes(t *testing.T) {
+	h := newTestHasher(t, Options{Algorithm: AlgorithmArgon2id, Argon2: testArgon2})
+
+	tests := []struct {
+		hashed  string
+		wantErr error
+	}{
+		{hashed: Unusable, wantErr: ErrUnknownAlgorithm},
+		{hashed: "Correct-Horse-1", wantErr: ErrUnknownAlgorithm},
+		{hashed: "$argon2id$v=19$m=64,t=1$c2FsdA$a2V5", wantErr: ErrInvalidHash},
+		{hashed: "$argon2id$v=19$m=64,t=1,p=1$!!$a2V5", wantErr: ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		if err := h.Verify(tt.hashed, "Correct-Horse-1"); !errors.Is(err, tt.wantErr) {
+			t.Errorf("Verify(%q) error = %v, want %v", tt.hashed, err, tt.wantErr)
+		}
+	}
+}
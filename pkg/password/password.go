@@ -1,26 +1,51 @@
 package password
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"sync/atomic"
 )
 
 const (
 	// DefaultCost is the default bcrypt cost
 	DefaultCost = 12
+
+	// Unusable is stored as the hash of accounts that authenticate with an external
+	// identity provider. It never verifies, so such accounts have no local password.
+	Unusable = "!"
 )
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), DefaultCost)
+var defaultHasher atomic.Pointer[Hasher]
+
+func init() {
+	h, err := NewHasher(DefaultOptions())
 	if err != nil {
-		return "", err
+		panic(err)
 	}
-	return string(hashedBytes), nil
+	defaultHasher.Store(h)
+}
+
+// SetDefaultHasher replaces the hasher used by the package-level helpers
+func SetDefaultHasher(h *Hasher) {
+	defaultHasher.Store(h)
+}
+
+// HashPassword hashes a password using the default hasher
+func HashPassword(password string) (string, error) {
+	return defaultHasher.Load().Hash(password)
 }
 
 // VerifyPassword verifies a password against its hash
 func VerifyPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return defaultHasher.Load().Verify(hashedPassword, password)
+}
+
+// NeedsRehash reports whether a hash should be upgraded to the default hasher's settings
+func NeedsRehash(hashedPassword string) bool {
+	return defaultHasher.Load().NeedsRehash(hashedPassword)
+}
+
+// IsUsable reports whether a stored hash can verify a password at all
+func IsUsable(hashedPassword string) bool {
+	return hashedPassword != Unusable
 }
 
 // IsValidPassword checks if a password meets minimum requirements
@@ -37,5 +62,3 @@ func IsValidPassword(password string) bool {
 
 	return true
 }
-
-
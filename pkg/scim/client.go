@@ -0,0 +1,172 @@
+package scim
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the Users and Groups endpoints of a SCIM service provider
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the SCIM base URL (e.g. https://example.com/scim/v2)
+// that authenticates with a bearer token
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// FindUser returns the user with the userName, or nil when there is none
+func (c *Client) FindUser(ctx context.Context, userName string) (*User, error) {
+	var users []*User
+	if err := c.find(ctx, "/Users", "userName", userName, &users); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return users[0], nil
+}
+
+func (c *Client) CreateUser(ctx context.Context, user *User) (*User, error) {
+	var created User
+	if err := c.do(ctx, http.MethodPost, "/Users", user, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) ReplaceUser(ctx context.Context, id string, user *User) (*User, error) {
+	var replaced User
+	if err := c.do(ctx, http.MethodPut, "/Users/"+url.PathEscape(id), user, &replaced); err != nil {
+		return nil, err
+	}
+	return &replaced, nil
+}
+
+func (c *Client) DeleteUser(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/Users/"+url.PathEscape(id), nil, nil)
+}
+
+// FindGroup returns the group with the displayName, or nil when there is none
+func (c *Client) FindGroup(ctx context.Context, displayName string) (*Group, error) {
+	var groups []*Group
+	if err := c.find(ctx, "/Groups", "displayName", displayName, &groups); err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+	return groups[0], nil
+}
+
+func (c *Client) CreateGroup(ctx context.Context, group *Group) (*Group, error) {
+	var created Group
+	if err := c.do(ctx, http.MethodPost, "/Groups", group, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) ReplaceGroup(ctx context.Context, id string, group *Group) (*Group, error) {
+	var replaced Group
+	if err := c.do(ctx, http.MethodPut, "/Groups/"+url.PathEscape(id), group, &replaced); err != nil {
+		return nil, err
+	}
+	return &replaced, nil
+}
+
+func (c *Client) DeleteGroup(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/Groups/"+url.PathEscape(id), nil, nil)
+}
+
+// find runs an equality filter on an attribute and decodes the matching resources
+func (c *Client) find(ctx context.Context, endpoint, attribute, value string, resources interface{}) error {
+	filter := fmt.Sprintf(`%s eq "%s"`, attribute, strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value))
+
+	var response struct {
+		Resources json.RawMessage `json:"Resources"`
+	}
+	if err := c.do(ctx, http.MethodGet, endpoint+"?filter="+url.QueryEscape(filter), nil, &response); err != nil {
+		return err
+	}
+	if len(response.Resources) == 0 || string(response.Resources) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(response.Resources, resources); err != nil {
+		return fmt.Errorf("invalid SCIM list response: %w", err)
+	}
+	return nil
+}
+
+// do sends a request and decodes the response into result. Error responses are returned
+// as *Error so callers can inspect the status.
+func (c *Client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", ContentType)
+	if body != nil {
+		req.Header.Set("Content-Type", ContentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		scimErr := NewError(resp.StatusCode, "", strings.TrimSpace(string(payload)))
+		// status is a string in RFC 7644 but some providers send a number, so it is not decoded
+		var decoded struct {
+			ScimType string `json:"scimType"`
+			Detail   string `json:"detail"`
+		}
+		if json.Unmarshal(payload, &decoded) == nil && decoded.Detail != "" {
+			scimErr.ScimType, scimErr.Detail = decoded.ScimType, decoded.Detail
+		}
+		return scimErr
+	}
+
+	if result == nil || len(payload) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(payload, result); err != nil {
+		return fmt.Errorf("invalid SCIM response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,527 @@
+package scim
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter is a parsed filter expression (RFC 7644 section 3.4.2.2). It is evaluated
+// against the JSON object form of a resource.
+type Filter interface {
+	Matches(resource map[string]interface{}) bool
+}
+
+// Comparison operators
+const (
+	OpEqual          = "eq"
+	OpNotEqual       = "ne"
+	OpContains       = "co"
+	OpStartsWith     = "sw"
+	OpEndsWith       = "ew"
+	OpPresent        = "pr"
+	OpGreater        = "gt"
+	OpGreaterOrEqual = "ge"
+	OpLess           = "lt"
+	OpLessOrEqual    = "le"
+)
+
+// AttributeExpression compares an attribute with a value, e.g. userName eq "bjensen"
+type AttributeExpression struct {
+	Path     []string // attribute and optional sub-attribute
+	Operator string
+	Value    interface{} // string, float64, bool or nil
+}
+
+// LogicalExpression combines two filters with "and" or "or"
+type LogicalExpression struct {
+	Operator    string
+	Left, Right Filter
+}
+
+// NotExpression negates a filter
+type NotExpression struct {
+	Filter Filter
+}
+
+// ValuePathExpression applies a filter to the elements of a multi-valued attribute,
+// e.g. emails[type eq "work"]
+type ValuePathExpression struct {
+	Attribute string
+	Filter    Filter
+}
+
+// ParseFilter parses a filter expression
+func ParseFilter(input string) (Filter, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, Errorf(ErrorInvalidFilter, "unexpected %q in filter", p.peek().text)
+	}
+
+	return filter, nil
+}
+
+// ParseAttributePath splits an attribute path into lower-case attribute names, dropping
+// a schema URN prefix: "name.givenName" becomes ["name", "givenname"].
+func ParseAttributePath(path string) []string {
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		if i := strings.LastIndex(path, ":"); i >= 0 {
+			path = path[i+1:]
+		}
+	}
+
+	parts := strings.Split(path, ".")
+	for i := range parts {
+		parts[i] = strings.ToLower(parts[i])
+	}
+	return parts
+}
+
+func (e *AttributeExpression) Matches(resource map[string]interface{}) bool {
+	values := resolve(resource, e.Path)
+
+	if e.Operator == OpPresent {
+		for _, value := range values {
+			if !isEmpty(value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if e.Operator == OpNotEqual {
+		for _, value := range values {
+			if compare(value, OpEqual, e.Value) {
+				return false
+			}
+		}
+		return e.Value != nil || len(values) > 0
+	}
+
+	if e.Operator == OpEqual && e.Value == nil {
+		return len(values) == 0
+	}
+
+	for _, value := range values {
+		if compare(value, e.Operator, e.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *LogicalExpression) Matches(resource map[string]interface{}) bool {
+	if e.Operator == "and" {
+		return e.Left.Matches(resource) && e.Right.Matches(resource)
+	}
+	return e.Left.Matches(resource) || e.Right.Matches(resource)
+}
+
+func (e *NotExpression) Matches(resource map[string]interface{}) bool {
+	return !e.Filter.Matches(resource)
+}
+
+func (e *ValuePathExpression) Matches(resource map[string]interface{}) bool {
+	for _, element := range Elements(Lookup(resource, e.Attribute)) {
+		if e.Filter.Matches(element) {
+			return true
+		}
+	}
+	return false
+}
+
+// Lookup returns the value of an attribute, matching its name case-insensitively
+func Lookup(resource map[string]interface{}, name string) interface{} {
+	if value, ok := resource[name]; ok {
+		return value
+	}
+	for key, value := range resource {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+// Elements returns the complex values of a single- or multi-valued attribute
+func Elements(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return []map[string]interface{}{v}
+	case []interface{}:
+		elements := make([]map[string]interface{}, 0, len(v))
+		for _, item := range v {
+			if element, ok := item.(map[string]interface{}); ok {
+				elements = append(elements, element)
+			}
+		}
+		return elements
+	default:
+		return nil
+	}
+}
+
+// resolve returns every value found at path, flattening multi-valued attributes. A
+// multi-valued complex attribute without a sub-attribute resolves to its "value"s.
+func resolve(resource map[string]interface{}, path []string) []interface{} {
+	current := []interface{}{resource}
+	for _, name := range path {
+		var next []interface{}
+		for _, value := range current {
+			element, ok := value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			next = appendValues(next, Lookup(element, name))
+		}
+		current = next
+	}
+
+	var values []interface{}
+	for _, value := range current {
+		if element, ok := value.(map[string]interface{}); ok {
+			values = appendValues(values, Lookup(element, "value"))
+			continue
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func appendValues(values []interface{}, value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return values
+	case []interface{}:
+		for _, item := range v {
+			if item != nil {
+				values = append(values, item)
+			}
+		}
+		return values
+	default:
+		return append(values, v)
+	}
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
+
+// compare applies operator to an attribute value and a filter value. Strings compare
+// case-insensitively; timestamps compare correctly as RFC 3339 strings in UTC.
+func compare(value interface{}, operator string, operand interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		s, ok := operand.(string)
+		if !ok {
+			return false
+		}
+		a, b := strings.ToLower(v), strings.ToLower(s)
+		switch operator {
+		case OpEqual:
+			return a == b
+		case OpContains:
+			return strings.Contains(a, b)
+		case OpStartsWith:
+			return strings.HasPrefix(a, b)
+		case OpEndsWith:
+			return strings.HasSuffix(a, b)
+		case OpGreater:
+			return a > b
+		case OpGreaterOrEqual:
+			return a >= b
+		case OpLess:
+			return a < b
+		case OpLessOrEqual:
+			return a <= b
+		}
+	case bool:
+		b, ok := operand.(bool)
+		return ok && operator == OpEqual && v == b
+	case float64:
+		n, ok := operand.(float64)
+		if !ok {
+			return false
+		}
+		switch operator {
+		case OpEqual:
+			return v == n
+		case OpGreater:
+			return v > n
+		case OpGreaterOrEqual:
+			return v >= n
+		case OpLess:
+			return v < n
+		case OpLessOrEqual:
+			return v <= n
+		}
+	case json.Number:
+		f, err := v.Float64()
+		return err == nil && compare(f, operator, operand)
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenOpenBracket
+	tokenCloseBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokenOpenBracket, text: "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokenCloseBracket, text: "]"})
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(input) && input[end] != '"' {
+				if input[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(input) {
+				return nil, Errorf(ErrorInvalidFilter, "unterminated string in filter")
+			}
+			var value string
+			if err := json.Unmarshal([]byte(input[i:end+1]), &value); err != nil {
+				return nil, Errorf(ErrorInvalidFilter, "invalid string %s in filter", input[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(input) && !strings.ContainsRune(" \t\r\n()[]\"", rune(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: input[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) peek() token {
+	if p.done() {
+		return token{kind: tokenWord}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *filterParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	t := p.peek()
+	return !p.done() && t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+func (p *filterParser) expect(kind tokenKind, text string) error {
+	if p.done() || p.peek().kind != kind {
+		return Errorf(ErrorInvalidFilter, "expected %q in filter", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpression{Operator: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpression{Operator: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseFactor() (Filter, error) {
+	if p.done() {
+		return nil, Errorf(ErrorInvalidFilter, "unexpected end of filter")
+	}
+
+	if p.peekKeyword("not") {
+		p.pos++
+		if err := p.expect(tokenOpen, "("); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenClose, ")"); err != nil {
+			return nil, err
+		}
+		return &NotExpression{Filter: inner}, nil
+	}
+
+	if p.peek().kind == tokenOpen {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenClose, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+
+	attr := p.next()
+	if attr.kind != tokenWord || attr.text == "" {
+		return nil, Errorf(ErrorInvalidFilter, "expected an attribute name in filter")
+	}
+
+	if p.peek().kind == tokenOpenBracket {
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenCloseBracket, "]"); err != nil {
+			return nil, err
+		}
+		return &ValuePathExpression{Attribute: ParseAttributePath(attr.text)[0], Filter: inner}, nil
+	}
+
+	op := p.next()
+	operator := strings.ToLower(op.text)
+	if op.kind != tokenWord {
+		return nil, Errorf(ErrorInvalidFilter, "expected an operator after %s", attr.text)
+	}
+	expr := &AttributeExpression{Path: ParseAttributePath(attr.text), Operator: operator}
+
+	switch operator {
+	case OpPresent:
+		return expr, nil
+	case OpEqual, OpNotEqual, OpContains, OpStartsWith, OpEndsWith, OpGreater, OpGreaterOrEqual, OpLess, OpLessOrEqual:
+	default:
+		return nil, Errorf(ErrorInvalidFilter, "unknown operator %q", op.text)
+	}
+
+	if p.done() {
+		return nil, Errorf(ErrorInvalidFilter, "expected a value after %s %s", attr.text, op.text)
+	}
+	value, err := parseValue(p.next())
+	if err != nil {
+		return nil, err
+	}
+	expr.Value = value
+
+	return expr, nil
+}
+
+func parseValue(t token) (interface{}, error) {
+	if t.kind == tokenString {
+		return t.text, nil
+	}
+	if t.kind != tokenWord {
+		return nil, Errorf(ErrorInvalidFilter, "expected a value, got %q", t.text)
+	}
+
+	switch strings.ToLower(t.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+
+	if n, err := strconv.ParseFloat(t.text, 64); err == nil && (unicode.IsDigit(rune(t.text[0])) || t.text[0] == '-') {
+		return n, nil
+	}
+
+	return nil, Errorf(ErrorInvalidFilter, "invalid value %q in filter", t.text)
+}
+
+// EqualityValue returns the value compared by a filter of the form `<attribute> eq <value>`,
+// for lookups that can be answered from an index
+func EqualityValue(filter Filter, attribute string) (string, bool) {
+	expr, ok := filter.(*AttributeExpression)
+	if !ok || expr.Operator != OpEqual || len(expr.Path) != 1 || expr.Path[0] != strings.ToLower(attribute) {
+		return "", false
+	}
+	value, ok := expr.Value.(string)
+	return value, ok
+}
@@ -0,0 +1,306 @@
+package scim
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Patch operations
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+)
+
+// ApplyPatch applies PATCH operations (RFC 7644 section 3.5.2) to the JSON object form
+// of a resource. Attribute names match case-insensitively. Callers decode the result
+// back into the resource and decide which changed attributes they accept.
+func ApplyPatch(resource map[string]interface{}, operations []PatchOperation) error {
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != PatchAdd && op != PatchRemove && op != PatchReplace {
+			return Errorf(ErrorInvalidSyntax, "unsupported patch operation %q", operation.Op)
+		}
+
+		var value interface{}
+		if len(operation.Value) > 0 {
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return Errorf(ErrorInvalidValue, "invalid value: %v", err)
+			}
+		}
+
+		if operation.Path != "" {
+			if err := applyPath(resource, op, operation.Path, value); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Without a path the value is an object of attribute paths and their values
+		if op == PatchRemove {
+			return Errorf(ErrorNoTarget, "remove requires a path")
+		}
+		attributes, ok := value.(map[string]interface{})
+		if !ok {
+			return Errorf(ErrorInvalidValue, "%s without a path requires an object value", op)
+		}
+		for path, attributeValue := range attributes {
+			if strings.EqualFold(path, "schemas") {
+				continue
+			}
+			if err := applyPath(resource, op, path, attributeValue); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// patchPath is a parsed PATCH path: attribute[filter].subAttribute
+type patchPath struct {
+	attribute    string
+	filter       Filter
+	subAttribute string
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	parsed := &patchPath{}
+
+	head, rest := path, ""
+	if open := strings.Index(path, "["); open >= 0 {
+		end := strings.LastIndex(path, "]")
+		if end < open {
+			return nil, Errorf(ErrorInvalidPath, "invalid path %q", path)
+		}
+		filter, err := ParseFilter(path[open+1 : end])
+		if err != nil {
+			return nil, Errorf(ErrorInvalidPath, "invalid filter in path %q", path)
+		}
+		parsed.filter = filter
+		head, rest = path[:open], path[end+1:]
+		if rest != "" && !strings.HasPrefix(rest, ".") {
+			return nil, Errorf(ErrorInvalidPath, "invalid path %q", path)
+		}
+		rest = strings.TrimPrefix(rest, ".")
+	}
+
+	if strings.HasPrefix(strings.ToLower(head), "urn:") {
+		if i := strings.LastIndex(head, ":"); i >= 0 {
+			head = head[i+1:]
+		}
+	}
+
+	parts := strings.Split(head, ".")
+	switch {
+	case len(parts) == 1 && parts[0] != "":
+		parsed.attribute, parsed.subAttribute = parts[0], rest
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "" && parsed.filter == nil:
+		parsed.attribute, parsed.subAttribute = parts[0], parts[1]
+	default:
+		return nil, Errorf(ErrorInvalidPath, "invalid path %q", path)
+	}
+
+	return parsed, nil
+}
+
+func applyPath(resource map[string]interface{}, op, rawPath string, value interface{}) error {
+	path, err := parsePatchPath(rawPath)
+	if err != nil {
+		return err
+	}
+
+	if path.filter != nil {
+		return applyFiltered(resource, op, path, value)
+	}
+
+	if path.subAttribute != "" {
+		parents := Elements(Lookup(resource, path.attribute))
+		if len(parents) == 0 {
+			if op == PatchRemove {
+				return nil
+			}
+			parent := map[string]interface{}{}
+			setAttribute(resource, path.attribute, parent)
+			parents = []map[string]interface{}{parent}
+		}
+		for _, parent := range parents {
+			if op == PatchRemove {
+				deleteAttribute(parent, path.subAttribute)
+			} else {
+				setAttribute(parent, path.subAttribute, value)
+			}
+		}
+		return nil
+	}
+
+	existing := Lookup(resource, path.attribute)
+	switch op {
+	case PatchRemove:
+		// Some clients send the members to remove as the value instead of in a filter
+		if list, ok := existing.([]interface{}); ok && value != nil {
+			setAttribute(resource, path.attribute, withoutValues(list, value))
+			return nil
+		}
+		deleteAttribute(resource, path.attribute)
+	case PatchAdd:
+		if list, ok := existing.([]interface{}); ok {
+			setAttribute(resource, path.attribute, appendUnique(list, value))
+			return nil
+		}
+		setAttribute(resource, path.attribute, merge(existing, value))
+	case PatchReplace:
+		if _, ok := existing.([]interface{}); ok {
+			if _, ok := value.([]interface{}); !ok && value != nil {
+				value = []interface{}{value}
+			}
+			setAttribute(resource, path.attribute, value)
+			return nil
+		}
+		setAttribute(resource, path.attribute, merge(existing, value))
+	}
+
+	return nil
+}
+
+// applyFiltered applies an operation to the elements of a multi-valued attribute
+// selected by a value filter
+func applyFiltered(resource map[string]interface{}, op string, path *patchPath, value interface{}) error {
+	list, _ := Lookup(resource, path.attribute).([]interface{})
+
+	var kept []interface{}
+	matched := 0
+	for _, item := range list {
+		element, ok := item.(map[string]interface{})
+		if !ok || !path.filter.Matches(element) {
+			kept = append(kept, item)
+			continue
+		}
+		matched++
+
+		switch {
+		case op == PatchRemove && path.subAttribute == "":
+			continue
+		case op == PatchRemove:
+			deleteAttribute(element, path.subAttribute)
+		case path.subAttribute != "":
+			setAttribute(element, path.subAttribute, value)
+		default:
+			kept = append(kept, merge(element, value))
+			continue
+		}
+		kept = append(kept, element)
+	}
+
+	if matched == 0 {
+		switch op {
+		case PatchRemove:
+			return nil
+		case PatchReplace:
+			return Errorf(ErrorNoTarget, "no %s value matches the path filter", path.attribute)
+		}
+
+		// add with a filter creates the element the filter describes, e.g.
+		// emails[type eq "work"].value
+		element := map[string]interface{}{}
+		if expr, ok := path.filter.(*AttributeExpression); ok && expr.Operator == OpEqual && len(expr.Path) == 1 {
+			element[expr.Path[0]] = expr.Value
+		}
+		if path.subAttribute != "" {
+			element[path.subAttribute] = value
+			kept = append(kept, element)
+		} else {
+			kept = append(kept, merge(element, value))
+		}
+	}
+
+	if kept == nil {
+		kept = []interface{}{}
+	}
+	setAttribute(resource, path.attribute, kept)
+
+	return nil
+}
+
+// merge combines a complex value with the sub-attributes of update; other values are
+// replaced by update
+func merge(existing, update interface{}) interface{} {
+	current, ok := existing.(map[string]interface{})
+	changes, isMap := update.(map[string]interface{})
+	if !isMap {
+		return update
+	}
+	if !ok {
+		current = map[string]interface{}{}
+	}
+	for key, value := range changes {
+		setAttribute(current, key, value)
+	}
+	return current
+}
+
+func appendUnique(list []interface{}, value interface{}) []interface{} {
+	additions, ok := value.([]interface{})
+	if !ok {
+		additions = []interface{}{value}
+	}
+
+	for _, addition := range additions {
+		if !containsValue(list, addition) {
+			list = append(list, addition)
+		}
+	}
+	return list
+}
+
+func withoutValues(list []interface{}, value interface{}) []interface{} {
+	removals, ok := value.([]interface{})
+	if !ok {
+		removals = []interface{}{value}
+	}
+
+	kept := []interface{}{}
+	for _, item := range list {
+		if !containsValue(removals, item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// containsValue compares complex values by their "value" sub-attribute
+func containsValue(list []interface{}, value interface{}) bool {
+	key := valueKey(value)
+	for _, item := range list {
+		if valueKey(item) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func valueKey(value interface{}) string {
+	if element, ok := value.(map[string]interface{}); ok {
+		value = Lookup(element, "value")
+	}
+	encoded, _ := json.Marshal(value)
+	return strings.ToLower(string(encoded))
+}
+
+func setAttribute(resource map[string]interface{}, name string, value interface{}) {
+	for key := range resource {
+		if strings.EqualFold(key, name) {
+			resource[key] = value
+			return
+		}
+	}
+	resource[name] = value
+}
+
+func deleteAttribute(resource map[string]interface{}, name string) {
+	for key := range resource {
+		if strings.EqualFold(key, name) {
+			delete(resource, key)
+		}
+	}
+}
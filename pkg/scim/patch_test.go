@@ -0,0 +1,198 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const user = `{
+		"userName": "alice@example.com",
+		"active": true,
+		"name": {"givenName": "Alice", "familyName": "Liddell"},
+		"emails": [
+			{"type": "work", "value": "alice@example.com", "primary": true},
+			{"type": "other", "value": "alice@old.example.com"}
+		]
+	}`
+	const group = `{
+		"displayName": "engineering",
+		"members": [{"value": "u-1"}, {"value": "u-2"}]
+	}`
+
+	tests := []struct {
+		name       string
+		resource   string
+		operations string
+		want       string
+		// wantType is the scimType of the expected error
+		wantType string
+	}{
+		{
+			name:       "replace attribute case-insensitively",
+			resource:   `{"userName": "alice", "active": true}`,
+			operations: `[{"op": "Replace", "path": "ACTIVE", "value": false}]`,
+			want:       `{"userName": "alice", "active": false}`,
+		},
+		{
+			name:       "replace sub-attribute",
+			resource:   user,
+			operations: `[{"op": "replace", "path": "name.givenName", "value": "Alicia"}]`,
+			want: `{
+				"userName": "alice@example.com",
+				"active": true,
+				"name": {"givenName": "Alicia", "familyName": "Liddell"},
+				"emails": [
+					{"type": "work", "value": "alice@example.com", "primary": true},
+					{"type": "other", "value": "alice@old.example.com"}
+				]
+			}`,
+		},
+		{
+			name:       "schema URN prefix is stripped",
+			resource:   `{"userName": "alice"}`,
+			operations: `[{"op": "replace", "path": "urn:ietf:params:scim:schemas:core:2.0:User:userName", "value": "bob"}]`,
+			want:       `{"userName": "bob"}`,
+		},
+		{
+			name:       "replace without path merges complex attributes",
+			resource:   `{"active": true, "name": {"givenName": "Alice", "familyName": "Liddell"}}`,
+			operations: `[{"op": "replace", "value": {"active": false, "name": {"givenName": "Alicia"}}}]`,
+			want:       `{"active": false, "name": {"givenName": "Alicia", "familyName": "Liddell"}}`,
+		},
+		{
+			name:       "replace filtered sub-attribute",
+			resource:   user,
+			operations: `[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "alice@new.example.com"}]`,
+			want: `{
+				"userName": "alice@example.com",
+				"active": true,
+				"name": {"givenName": "Alice", "familyName": "Liddell"},
+				"emails": [
+					{"type": "work", "value": "alice@new.example.com", "primary": true},
+					{"type": "other", "value": "alice@old.example.com"}
+				]
+			}`,
+		},
+		{
+			name:       "add with unmatched filter creates the element",
+			resource:   `{"emails": [{"type": "work", "value": "alice@example.com"}]}`,
+			operations: `[{"op": "add", "path": "emails[type eq \"home\"].value", "value": "alice@home.example.com"}]`,
+			want:       `{"emails": [{"type": "work", "value": "alice@example.com"}, {"type": "home", "value": "alice@home.example.com"}]}`,
+		},
+		{
+			name:       "replace with unmatched filter",
+			resource:   user,
+			operations: `[{"op": "replace", "path": "emails[type eq \"home\"].value", "value": "alice@home.example.com"}]`,
+			wantType:   ErrorNoTarget,
+		},
+		{
+			name:       "remove filtered element",
+			resource:   user,
+			operations: `[{"op": "remove", "path": "emails[type eq \"other\"]"}]`,
+			want: `{
+				"userName": "alice@example.com",
+				"active": true,
+				"name": {"givenName": "Alice", "familyName": "Liddell"},
+				"emails": [{"type": "work", "value": "alice@example.com", "primary": true}]
+			}`,
+		},
+		{
+			name:       "add members skips existing values",
+			resource:   group,
+			operations: `[{"op": "add", "path": "members", "value": [{"value": "u-2"}, {"value": "u-3"}]}]`,
+			want:       `{"displayName": "engineering", "members": [{"value": "u-1"}, {"value": "u-2"}, {"value": "u-3"}]}`,
+		},
+		{
+			name:       "remove member by filter",
+			resource:   group,
+			operations: `[{"op": "remove", "path": "members[value eq \"u-1\"]"}]`,
+			want:       `{"displayName": "engineering", "members": [{"value": "u-2"}]}`,
+		},
+		{
+			name:       "remove members listed in the value",
+			resource:   group,
+			operations: `[{"op": "remove", "path": "members", "value": [{"value": "u-1"}, {"value": "u-2"}]}]`,
+			want:       `{"displayName": "engineering", "members": []}`,
+		},
+		{
+			name:       "replace multi-valued attribute with a single value",
+			resource:   group,
+			operations: `[{"op": "replace", "path": "members", "value": {"value": "u-9"}}]`,
+			want:       `{"displayName": "engineering", "members": [{"value": "u-9"}]}`,
+		},
+		{
+			name:       "remove attribute",
+			resource:   `{"userName": "alice", "title": "Engineer"}`,
+			operations: `[{"op": "remove", "path": "title"}, {"op": "remove", "path": "nickName"}]`,
+			want:       `{"userName": "alice"}`,
+		},
+		{
+			name:       "remove without path",
+			resource:   user,
+			operations: `[{"op": "remove"}]`,
+			wantType:   ErrorNoTarget,
+		},
+		{
+			name:       "add without path requires an object",
+			resource:   user,
+			operations: `[{"op": "add", "value": "alice"}]`,
+			wantType:   ErrorInvalidValue,
+		},
+		{
+			name:       "unsupported operation",
+			resource:   user,
+			operations: `[{"op": "move", "path": "userName", "value": "bob"}]`,
+			wantType:   ErrorInvalidSyntax,
+		},
+		{
+			name:       "unterminated path filter",
+			resource:   user,
+			operations: `[{"op": "replace", "path": "emails[type eq \"work\".value", "value": "x"}]`,
+			wantType:   ErrorInvalidPath,
+		},
+		{
+			name:       "invalid path filter",
+			resource:   user,
+			operations: `[{"op": "replace", "path": "emails[type zz \"work\"].value", "value": "x"}]`,
+			wantType:   ErrorInvalidPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resource map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.resource), &resource); err != nil {
+				t.Fatal(err)
+			}
+			var operations []PatchOperation
+			if err := json.Unmarshal([]byte(tt.operations), &operations); err != nil {
+				t.Fatal(err)
+			}
+
+			err := ApplyPatch(resource, operations)
+
+			if tt.wantType != "" {
+				var scimErr *Error
+				if !errors.As(err, &scimErr) || scimErr.ScimType != tt.wantType {
+					t.Fatalf("ApplyPatch() error = %v, want scimType %s", err, tt.wantType)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyPatch() error = %v", err)
+			}
+
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(resource, want) {
+				got, _ := json.Marshal(resource)
+				t.Errorf("ApplyPatch() = %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package scim
+
+// Attribute characteristics
+const (
+	MutabilityReadOnly  = "readOnly"
+	MutabilityReadWrite = "readWrite"
+	MutabilityImmutable = "immutable"
+	MutabilityWriteOnly = "writeOnly"
+
+	ReturnedAlways  = "always"
+	ReturnedNever   = "never"
+	ReturnedDefault = "default"
+
+	UniquenessNone   = "none"
+	UniquenessServer = "server"
+)
+
+func stringAttribute(name, description string) Attribute {
+	return Attribute{
+		Name:        name,
+		Type:        "string",
+		Description: description,
+		Mutability:  MutabilityReadWrite,
+		Returned:    ReturnedDefault,
+		Uniqueness:  UniquenessNone,
+	}
+}
+
+func referenceAttributes(referenceType string) []Attribute {
+	value := stringAttribute("value", "Identifier of the "+referenceType)
+	value.Mutability = MutabilityImmutable
+	ref := Attribute{
+		Name:           "$ref",
+		Type:           "reference",
+		Description:    "URI of the " + referenceType,
+		Mutability:     MutabilityImmutable,
+		Returned:       ReturnedDefault,
+		Uniqueness:     UniquenessNone,
+		ReferenceTypes: []string{referenceType},
+	}
+	display := stringAttribute("display", "Human-readable name of the "+referenceType)
+	display.Mutability = MutabilityReadOnly
+	return []Attribute{value, ref, display}
+}
+
+// UserSchema describes the core User attributes supported by this implementation
+func UserSchema() Schema {
+	userName := stringAttribute("userName", "Unique identifier of the user; an email address")
+	userName.Required = true
+	userName.Uniqueness = UniquenessServer
+
+	password := stringAttribute("password", "Sets the user's password")
+	password.Mutability = MutabilityWriteOnly
+	password.Returned = ReturnedNever
+
+	groups := Attribute{
+		Name:          "groups",
+		Type:          "complex",
+		MultiValued:   true,
+		Description:   "Groups the user belongs to",
+		Mutability:    MutabilityReadOnly,
+		Returned:      ReturnedDefault,
+		Uniqueness:    UniquenessNone,
+		SubAttributes: referenceAttributes("Group"),
+	}
+
+	return Schema{
+		Schemas:     []string{SchemaSchema},
+		ID:          SchemaUser,
+		Name:        "User",
+		Description: "User Account",
+		Attributes: []Attribute{
+			userName,
+			{
+				Name:        "name",
+				Type:        "complex",
+				Description: "Components of the user's name",
+				Mutability:  MutabilityReadWrite,
+				Returned:    ReturnedDefault,
+				Uniqueness:  UniquenessNone,
+				SubAttributes: []Attribute{
+					stringAttribute("formatted", "Full name"),
+					stringAttribute("givenName", "Given name"),
+					stringAttribute("familyName", "Family name"),
+				},
+			},
+			stringAttribute("displayName", "Name displayed to end users"),
+			{
+				Name:        "emails",
+				Type:        "complex",
+				MultiValued: true,
+				Description: "Email addresses; the primary address mirrors userName",
+				Mutability:  MutabilityReadWrite,
+				Returned:    ReturnedDefault,
+				Uniqueness:  UniquenessNone,
+				SubAttributes: []Attribute{
+					stringAttribute("value", "Email address"),
+					stringAttribute("type", "Type of the address, e.g. work"),
+					{Name: "primary", Type: "boolean", Description: "Primary address", Mutability: MutabilityReadWrite, Returned: ReturnedDefault, Uniqueness: UniquenessNone},
+				},
+			},
+			{
+				Name:        "active",
+				Type:        "boolean",
+				Description: "Whether the user may sign in",
+				Mutability:  MutabilityReadWrite,
+				Returned:    ReturnedDefault,
+				Uniqueness:  UniquenessNone,
+			},
+			password,
+			groups,
+		},
+	}
+}
+
+// GroupSchema describes the core Group attributes supported by this implementation
+func GroupSchema() Schema {
+	displayName := stringAttribute("displayName", "Name of the group")
+	displayName.Required = true
+
+	return Schema{
+		Schemas:     []string{SchemaSchema},
+		ID:          SchemaGroup,
+		Name:        "Group",
+		Description: "Group",
+		Attributes: []Attribute{
+			displayName,
+			{
+				Name:          "members",
+				Type:          "complex",
+				MultiValued:   true,
+				Description:   "Members of the group",
+				Mutability:    MutabilityReadWrite,
+				Returned:      ReturnedDefault,
+				Uniqueness:    UniquenessNone,
+				SubAttributes: referenceAttributes("User"),
+			},
+		},
+	}
+}
@@ -0,0 +1,27 @@
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// DefaultLength is the default number of random bytes in a token
+const DefaultLength = 32
+
+// Generate returns a URL-safe random token with n bytes of entropy
+func Generate(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Hash returns the hex-encoded SHA-256 of a token for storage and lookup.
+// Only hashes of one-time tokens are persisted, never the tokens themselves.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("%x", sum)
+}